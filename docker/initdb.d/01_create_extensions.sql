@@ -1 +0,0 @@
-CREATE EXTENSION pgcrypto;
\ No newline at end of file
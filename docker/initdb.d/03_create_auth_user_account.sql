@@ -1,6 +0,0 @@
-INSERT into auth.user_account (
-    username,
-    password
-)
-VALUES 
-('admin', crypt('admin', gen_salt('bf', 8)));
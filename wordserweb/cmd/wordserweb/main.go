@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,14 +14,39 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
 	authpkg "github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/batch"
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+	"github.com/nolandseigler/wordser/wordserweb/internal/document"
+	"github.com/nolandseigler/wordser/wordserweb/internal/fetch"
+	"github.com/nolandseigler/wordser/wordserweb/internal/glossary"
 	"github.com/nolandseigler/wordser/wordserweb/internal/handlers"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/langdetect"
+	"github.com/nolandseigler/wordser/wordserweb/internal/memory"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/sse"
 	"github.com/nolandseigler/wordser/wordserweb/internal/static"
 	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/subtitle"
 	"github.com/nolandseigler/wordser/wordserweb/internal/template"
+	"github.com/nolandseigler/wordser/wordserweb/internal/translate"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+	"github.com/nolandseigler/wordser/wordserweb/internal/wordser"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Setup
 	e := echo.New()
 	e.Logger.SetLevel(log.DEBUG)
@@ -43,6 +69,15 @@ func main() {
 	if err != nil {
 		e.Logger.Fatal(err)
 	}
+	if dbCfg.AutoMigrate {
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			e.Logger.Fatal(err)
+		}
+		for _, m := range applied {
+			e.Logger.Infof("applied migration %d_%s", m.Version, m.Name)
+		}
+	}
 
 	authCfg, err := authpkg.ConfigFromEnv()
 	if err != nil {
@@ -56,14 +91,219 @@ func main() {
 	
 	e.Use(auth.ValidateJWTMiddleWare)
 
+	usageCfg, err := usage.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	meter := usage.New(usageCfg, db)
+
+	orgCfg, err := org.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	orgs := org.New(orgCfg, db)
+
+	// the event stream is expected right after the form post that created it.
+	analyzeStreams := sse.NewPending[handlers.AnalyzeStreamRequest](time.Minute)
+
+	chunkingCfg, err := chunking.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	chunker := chunking.New(chunkingCfg)
+
+	resilienceCfg, err := resilience.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	wordserUpstream := resilience.New("wordser", resilienceCfg, wordser.Endpoints...)
+	translateUpstream := resilience.New("translate", resilienceCfg, translate.Endpoints...)
+
+	wordserCfg, err := wordser.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	wordserClient, err := wordser.New(wordserCfg, wordserUpstream)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	analyzerCfg, err := analyzer.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	readability := analyzer.NewReadability()
+	analyzers, err := analyzer.NewRegistry(
+		analyzer.NewSummary(analyzerCfg, wordserClient, e.Logger),
+		analyzer.NewSentiment(analyzerCfg, wordserClient, e.Logger),
+		analyzer.NewKeywords(analyzerCfg, wordserClient, e.Logger),
+		readability,
+	)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	fetchCfg, err := fetch.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	fetcher, err := fetch.New(fetchCfg)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	translateCfg, err := translate.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	translator, err := translate.New(translateCfg, translateUpstream)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	langdetectCfg, err := langdetect.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	detector := langdetect.New(langdetectCfg)
+
+	glossaryCfg, err := glossary.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	glossaries := glossary.New(glossaryCfg, db)
+
+	memoryCfg, err := memory.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	memories := memory.New(memoryCfg, db)
+
+	jobsCfg, err := jobser.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	jobs := jobser.New(jobsCfg, db, e.Logger)
+
+	webhookCfg, err := webhook.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	hooks, err := webhook.New(webhookCfg, db, jobs, e.Logger)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	jobs.Register(jobser.KindWebhook, hooks.Deliver)
+
+	deps := handlers.Deps{
+		Analyzers:    analyzers,
+		Chunker:      chunker,
+		Meter:        meter,
+		History:      db,
+		Translations: db,
+		Batches:      db,
+		Jobs:         jobs,
+		Hooks:        hooks,
+		Fetcher:      fetcher,
+		Translator:   translator,
+		Detector:     detector,
+		Glossaries:   glossaries,
+		Memories:     memories,
+		Logger:       e.Logger,
+	}
+
+	jobs.Register(jobser.KindAnalyze, handlers.AnalyzeJob(deps))
+	jobs.Register(jobser.KindTranslate, handlers.TranslateJob(deps))
+
+	batchCfg, err := batch.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	jobs.Register(jobser.KindBatch, handlers.BatchJob(batchCfg, deps))
+
+	subtitleCfg, err := subtitle.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	subtitles := subtitle.New(subtitleCfg)
+	jobs.Register(jobser.KindSubtitle, handlers.SubtitleJob(subtitles, deps))
+
+	documentCfg, err := document.ConfigFromEnv()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	documents := document.New(documentCfg)
+	jobs.Start(ctx)
+
 
 	e.GET("/signup", handlers.GetSignupHandler)
 	e.POST("/signup", handlers.PostSignupHandler(auth, db))
 	e.GET("/login", handlers.GetLoginHandler)
 	e.POST("/login", handlers.PostLoginHandler(auth, db))
-	e.GET("/dashboard", handlers.GetDashboardHandler)
-	e.GET("/translate", handlers.GetTranslateHandler)
-	e.GET("/analyze", handlers.GetAnalyzeHandler)
+	e.GET("/dashboard", handlers.GetDashboardHandler(analyzers, analyzer.Engine(analyzerCfg.Engine), translator))
+	e.GET("/translate", handlers.GetTranslateHandler(deps))
+	e.GET("/translate/targets", handlers.GetTranslateTargetsHandler(translator))
+	e.POST("/translate/document", handlers.PostTranslateDocumentHandler(documentCfg, documents, deps))
+	e.POST("/translate/subtitles", handlers.PostSubtitlesHandler(subtitleCfg, deps))
+	e.GET("/analyze", handlers.GetAnalyzeHandler(deps))
+	e.POST("/analyze", handlers.GetAnalyzeHandler(deps))
+	e.POST("/analyze/stream", handlers.PostAnalyzeStreamHandler(analyzeStreams, deps))
+	e.GET("/analyze/stream/:id", handlers.GetAnalyzeStreamHandler(analyzeStreams, deps))
+	e.GET("/analyze/retry", handlers.GetAnalyzeRetryHandler(deps))
+	e.POST("/analyze/retry", handlers.GetAnalyzeRetryHandler(deps))
+	e.GET("/readability", handlers.GetReadabilityHandler(readability, meter))
+	e.POST("/readability", handlers.GetReadabilityHandler(readability, meter))
+	e.GET("/synonyms", handlers.GetSynonymsHandler(wordserClient, meter))
+	e.GET("/usage", handlers.GetUsageHandler(meter))
+	e.GET("/history", handlers.GetHistoryHandler(db))
+	e.GET("/history/recent", handlers.GetRecentHistoryHandler(db))
+	e.GET("/history/:id", handlers.GetHistoryAnalysisHandler(analyzers, db))
+	e.DELETE("/history/:id", handlers.DeleteHistoryAnalysisHandler(db))
+	e.GET("/search", handlers.GetSearchHandler(db))
+	e.GET("/jobs", handlers.GetJobsHandler(jobs))
+	e.GET("/jobs/:id", handlers.GetJobHandler(jobs))
+	e.GET("/jobs/:id/status", handlers.GetJobStatusHandler(jobs))
+	e.GET("/jobs/:id/subtitles", handlers.GetJobSubtitlesHandler(jobs))
+	e.GET("/batches", handlers.GetBatchesHandler(batchCfg, analyzers, db))
+	e.POST("/batches", handlers.PostBatchHandler(batchCfg, deps))
+	e.GET("/batches/:id", handlers.GetBatchHandler(db, jobs))
+	e.GET("/batches/:id/progress", handlers.GetBatchProgressHandler(db, jobs))
+	e.GET("/batches/:id/results", handlers.GetBatchResultsHandler(analyzers, db))
+	e.POST("/batches/:id/rerun", handlers.PostBatchRerunHandler(meter, db, jobs))
+	e.GET("/webhooks", handlers.GetWebhooksHandler(hooks))
+	e.POST("/webhooks", handlers.PostWebhookHandler(hooks))
+	e.GET("/webhooks/:id", handlers.GetWebhookHandler(hooks))
+	e.GET("/webhooks/:id/deliveries", handlers.GetWebhookDeliveriesHandler(hooks))
+	e.POST("/webhooks/:id/ping", handlers.PostWebhookPingHandler(hooks))
+	e.POST("/webhooks/:id/deliveries/:delivery/replay", handlers.PostWebhookReplayHandler(hooks))
+	e.POST("/webhooks/:id/delete", handlers.PostWebhookDeleteHandler(hooks))
+
+	e.GET("/orgs", handlers.GetOrgsHandler(orgs))
+	e.POST("/orgs", handlers.PostOrgHandler(orgs))
+	e.GET("/orgs/:id", handlers.GetOrgHandler(orgs))
+	e.POST("/orgs/:id/invitations", handlers.PostOrgInvitationHandler(orgs))
+	e.POST("/orgs/:id/members/:username/role", handlers.PostOrgMemberRoleHandler(orgs))
+	e.POST("/orgs/:id/members/:username/remove", handlers.PostOrgMemberRemoveHandler(auth, orgs))
+	e.GET("/invitations/:token", handlers.GetInvitationHandler(orgs))
+	e.POST("/invitations/:token/accept", handlers.PostAcceptInvitationHandler(auth, orgs))
+	e.POST("/session/org", handlers.PostSwitchOrgHandler(auth, orgs))
+
+	e.GET("/glossaries", handlers.GetGlossariesHandler(glossaries, translator))
+	e.POST("/glossaries", handlers.PostGlossaryHandler(glossaries, translator))
+	e.GET("/glossaries/:id", handlers.GetGlossaryHandler(glossaryCfg, glossaries))
+	e.POST("/glossaries/:id/terms", handlers.PostGlossaryTermHandler(glossaryCfg, glossaries))
+	e.POST("/glossaries/:id/terms/:term/delete", handlers.PostGlossaryTermDeleteHandler(glossaryCfg, glossaries))
+	e.POST("/glossaries/:id/import", handlers.PostGlossaryImportHandler(glossaryCfg, glossaries))
+	e.GET("/glossaries/:id/export", handlers.GetGlossaryExportHandler(glossaries))
+	e.POST("/glossaries/:id/delete", handlers.PostGlossaryDeleteHandler(glossaryCfg, glossaries))
+
+	e.GET("/memory", handlers.GetMemoryHandler(memoryCfg, memories))
+	e.POST("/memory/import", handlers.PostMemoryImportHandler(memoryCfg, memories, translator))
+	e.GET("/memory/export", handlers.GetMemoryExportHandler(memories))
+	e.POST("/memory/delete", handlers.PostMemoryDeleteHandler(memoryCfg, memories))
+
+	admin := e.Group("/admin", authpkg.RequireRole(authpkg.RoleAdmin))
+	admin.GET("/usage", handlers.GetAdminUsageHandler(meter))
+	admin.GET("/upstreams", handlers.GetAdminUpstreamsHandler(wordserUpstream, translateUpstream))
 
 	// Start server
 	go func() {
@@ -80,6 +320,7 @@ func main() {
 	if err := e.Shutdown(ctx); err != nil {
 		e.Logger.Fatal(err)
 	}
+	jobs.Stop()
 }
 
 type TempKVStore struct {
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+)
+
+var ErrDuplicate = errors.New("analyzer already registered")
+
+// Result -> what an Analyzer made of a text. stored, sent to webhooks and returned by the api as its json.
+type Result interface {
+	// Values -> the result flattened for csv exports, one value per Options.Columns.
+	Values() []string
+}
+
+// Options -> how an Analyzer is offered on the forms and shown with its results.
+type Options struct {
+	// Title -> result heading.
+	Title string
+	// Label -> label of the analyzer's toggle.
+	Label string
+	// Field -> form field of the analyzer's toggle. "on" selects it.
+	Field string
+	// Default -> the toggle starts on.
+	Default bool
+	// EnglishOnly -> the analyzer only makes sense of english. other text gets a warning and an offer to translate
+	// it first.
+	EnglishOnly bool
+	// Columns -> csv export columns, see Result.Values.
+	Columns []string
+}
+
+type Analyzer interface {
+	// Name -> stable id of the analyzer: the usage api it is metered as and the key of its stored results,
+	// errors and timings.
+	Name() string
+	Options() Options
+	Run(ctx context.Context, txt string) (Result, error)
+	// Template -> the template rendering a Result, defined in an analyzer_*.html template file. it is executed
+	// with the handlers' AnalysisSection.
+	Template() string
+	// Decode -> a stored Result from its json.
+	Decode(data []byte) (Result, error)
+}
+
+// RunFunc -> an Analyzer's Run over text of any length, split into chunks as needed.
+type RunFunc func(ctx context.Context, txt string) (Result, error)
+
+// Merger -> an Analyzer that can analyze long text chunk by chunk. parts holds the result of every chunk, in chunk
+// order. analyzers that aren't Mergers always get the whole text.
+type Merger interface {
+	Merge(ctx context.Context, run RunFunc, txt string, chunks []chunking.Chunk, parts []Result) (Result, error)
+}
+
+// Registry -> the analyzers on offer, in the order forms and results list them.
+type Registry struct {
+	analyzers []Analyzer
+	byName    map[string]Analyzer
+}
+
+func NewRegistry(analyzers ...Analyzer) (*Registry, error) {
+	r := &Registry{byName: map[string]Analyzer{}}
+	for _, a := range analyzers {
+		if err := r.Register(a); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register -> add a after the analyzers already registered. names and form fields must be unique.
+func (r *Registry) Register(a Analyzer) error {
+	if _, ok := r.byName[a.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicate, a.Name())
+	}
+	for _, existing := range r.analyzers {
+		if existing.Options().Field == a.Options().Field {
+			return fmt.Errorf("%w: %s and %s share the form field %q", ErrDuplicate, existing.Name(), a.Name(), a.Options().Field)
+		}
+	}
+	r.analyzers = append(r.analyzers, a)
+	r.byName[a.Name()] = a
+	return nil
+}
+
+func (r *Registry) Get(name string) (Analyzer, bool) {
+	a, ok := r.byName[name]
+	return a, ok
+}
+
+// All -> every analyzer in registration order.
+func (r *Registry) All() []Analyzer {
+	return r.analyzers
+}
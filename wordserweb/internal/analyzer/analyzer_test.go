@@ -0,0 +1,267 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+	"github.com/nolandseigler/wordser/wordserweb/internal/wordser"
+)
+
+// fakeWordser -> a WordserClient failing with err, or answering.
+type fakeWordser struct {
+	err error
+}
+
+func (f *fakeWordser) Summary(ctx context.Context, txt string) (*wordser.SummaryResp, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &wordser.SummaryResp{Summary: "a summary"}, nil
+}
+
+func (f *fakeWordser) Sentiment(ctx context.Context, txt string) (*wordser.SentimentResp, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &wordser.SentimentResp{Polarity: "Positive", Score: 0.9}, nil
+}
+
+func (f *fakeWordser) Extract(ctx context.Context, txt string) (*wordser.ExtractResp, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &wordser.ExtractResp{}, nil
+}
+
+// logs -> a Logger keeping what it is given.
+type logs []string
+
+func (l *logs) Errorf(format string, args ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, args...))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "builtin", env: map[string]string{"ANALYZE_ENGINE": "builtin", "ANALYZE_BUILTIN_KEYWORDS": "1"}},
+		{name: "unknown engine", env: map[string]string{"ANALYZE_ENGINE": "gpt"}, wantErr: "ANALYZE_ENGINE"},
+		{name: "no keywords", env: map[string]string{"ANALYZE_BUILTIN_KEYWORDS": "0"}, wantErr: "ANALYZE_BUILTIN_KEYWORDS"},
+		{
+			name:    "negative summary",
+			env:     map[string]string{"ANALYZE_BUILTIN_SUMMARY_SENTENCES": "-2"},
+			wantErr: "ANALYZE_BUILTIN_SUMMARY_SENTENCES",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"ANALYZE_ENGINE", "ANALYZE_BUILTIN_KEYWORDS", "ANALYZE_BUILTIN_SUMMARY_SENTENCES"} {
+				t.Setenv(key, tt.env[key])
+			}
+			_, err := ConfigFromEnv()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("ConfigFromEnv() = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("ConfigFromEnv() = %v, want an error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunEngine(t *testing.T) {
+	down := errors.New("wordser down")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	config := Config{Engine: EngineAuto.String(), SummarySentences: 1, Keywords: 3}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		engine  Engine
+		err     error
+		want    string
+		wantErr error
+		logged  bool
+	}{
+		{name: "auto uses wordser", engine: EngineAuto, want: EngineWordser.String()},
+		{name: "auto falls back and logs", engine: EngineAuto, err: down, want: EngineBuiltin.String(), logged: true},
+		{name: "auto doesn't fall back for a caller gone", ctx: cancelled, engine: EngineAuto, err: down, wantErr: down},
+		{name: "wordser doesn't fall back", engine: EngineWordser, err: down, wantErr: down},
+		{name: "builtin", engine: EngineBuiltin, err: down, want: EngineBuiltin.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			var logged logs
+			a := NewSentiment(config, &fakeWordser{err: tt.err}, &logged).WithEngine(tt.engine)
+			result, err := a.Run(ctx, "This is good.")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && result.(*SentimentResult).Engine != tt.want {
+				t.Fatalf("Run() engine = %q, want %q", result.(*SentimentResult).Engine, tt.want)
+			}
+			if tt.logged != (len(logged) == 1) || (tt.logged && !strings.Contains(logged[0], "wordser down")) {
+				t.Fatalf("logged %q", logged)
+			}
+		})
+	}
+}
+
+func TestBuiltinResults(t *testing.T) {
+	config := Config{Engine: EngineBuiltin.String(), SummarySentences: 1, Keywords: 2}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		txt      string
+		polarity string
+	}{
+		{name: "empty", txt: "", polarity: "Neutral"},
+		{name: "no sentiment", txt: "The meeting is at noon.", polarity: "Neutral"},
+		{name: "positive", txt: "I love this great product.", polarity: "Positive"},
+		{name: "negative", txt: "This is terrible.", polarity: "Negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewSentiment(config, nil, nil).Run(ctx, tt.txt)
+			if err != nil {
+				t.Fatalf("Run() = %v", err)
+			}
+			sentiment := result.(*SentimentResult)
+			if sentiment.Polarity != tt.polarity || sentiment.Score < 0 || sentiment.Score > 1 {
+				t.Fatalf("Run(%q) = %+v, want %s", tt.txt, sentiment, tt.polarity)
+			}
+		})
+	}
+
+	txt := "Solar panels cut energy bills. Solar panels need sunlight. Cats sleep a lot."
+	result, err := NewKeywords(config, nil, nil).Run(ctx, txt)
+	if err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if keywords := result.(*KeywordsResult).Keywords; len(keywords) != 2 {
+		t.Fatalf("Run() = %+v, want %d keywords", keywords, config.Keywords)
+	}
+	result, err = NewSummary(config, nil, nil).Run(ctx, txt)
+	if err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if summary := result.(*SummaryResult).Summary; strings.Count(summary, ".") != 1 {
+		t.Fatalf("Run() = %q, want one sentence", summary)
+	}
+}
+
+func TestSummaryMerge(t *testing.T) {
+	parts := []Result{
+		&SummaryResult{Summary: " First part. ", Engine: EngineWordser.String()},
+		&SummaryResult{Summary: "Second part.", Engine: EngineBuiltin.String()},
+	}
+	tests := []struct {
+		name string
+		txt  string
+		// rerun -> the joined summaries are summarized again.
+		rerun bool
+	}{
+		{name: "shorter than the text", txt: strings.Repeat("A long text. ", 10), rerun: true},
+		// a model that doesn't shorten would be re-run forever.
+		{name: "as long as the text", txt: "First part. Second part."},
+		{name: "longer than the text", txt: "Short."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			run := func(ctx context.Context, txt string) (Result, error) {
+				ran = append(ran, txt)
+				return &SummaryResult{Summary: "rerun", Engine: EngineWordser.String()}, nil
+			}
+			result, err := (&Summary{}).Merge(context.Background(), run, tt.txt, nil, parts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			summary := result.(*SummaryResult)
+			if tt.rerun {
+				if len(ran) != 1 || ran[0] != "First part. Second part." || summary.Summary != "rerun" {
+					t.Fatalf("Merge() ran %q and = %+v, want the joined summaries summarized once", ran, summary)
+				}
+				return
+			}
+			want := SummaryResult{Summary: "First part. Second part.", Engine: "wordser+builtin"}
+			if len(ran) != 0 || *summary != want {
+				t.Fatalf("Merge() ran %q and = %+v, want %+v without a re-run", ran, summary, want)
+			}
+		})
+	}
+}
+
+func TestSentimentMerge(t *testing.T) {
+	chunk := func(n int) chunking.Chunk {
+		return chunking.Chunk{Text: strings.Repeat("a", n)}
+	}
+	tests := []struct {
+		name   string
+		chunks []chunking.Chunk
+		parts  []Result
+		want   SentimentResult
+	}{
+		{
+			name:   "weighted by chunk length",
+			chunks: []chunking.Chunk{chunk(300), chunk(100)},
+			parts: []Result{
+				&SentimentResult{Polarity: "Positive", Score: 0.8, Engine: "wordser"},
+				&SentimentResult{Polarity: "Positive", Score: 0.4, Engine: "wordser"},
+			},
+			// (0.8*300 + 0.4*100) / 400
+			want: SentimentResult{Polarity: "Positive", Score: 0.7, Engine: "wordser"},
+		},
+		{
+			name:   "negative chunks count against positive ones",
+			chunks: []chunking.Chunk{chunk(100), chunk(300)},
+			parts: []Result{
+				&SentimentResult{Polarity: "Positive", Score: 0.9, Engine: "wordser"},
+				&SentimentResult{Polarity: "negative", Score: 0.5, Engine: "builtin"},
+			},
+			// (0.9*100 - 0.5*300) / 400
+			want: SentimentResult{Polarity: "Negative", Score: 0.15, Engine: "wordser+builtin"},
+		},
+		{
+			name:   "cancelling out",
+			chunks: []chunking.Chunk{chunk(200), chunk(200)},
+			parts: []Result{
+				&SentimentResult{Polarity: "Positive", Score: 0.5, Engine: "builtin"},
+				&SentimentResult{Polarity: "Negative", Score: 0.5, Engine: "builtin"},
+			},
+			want: SentimentResult{Polarity: "Neutral", Score: 0, Engine: "builtin"},
+		},
+		{
+			name:   "empty chunks",
+			chunks: []chunking.Chunk{chunk(0)},
+			parts:  []Result{&SentimentResult{Polarity: "Positive", Score: 1, Engine: "builtin"}},
+			want:   SentimentResult{Polarity: "Neutral", Score: 0, Engine: "builtin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&Sentiment{}).Merge(context.Background(), nil, "", tt.chunks, tt.parts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := result.(*SentimentResult)
+			if got.Polarity != tt.want.Polarity || got.Engine != tt.want.Engine || math.Abs(got.Score-tt.want.Score) > 1e-9 {
+				t.Fatalf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
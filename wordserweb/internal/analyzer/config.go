@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// Engine -> engine of the analyzers that have more than one, when a request doesn't pick one.
+	Engine string `mapstructure:"ANALYZE_ENGINE"`
+	// SummarySentences -> sentences the builtin engine keeps in a summary.
+	SummarySentences int `mapstructure:"ANALYZE_BUILTIN_SUMMARY_SENTENCES"`
+	// Keywords -> keywords the builtin engine extracts.
+	Keywords int `mapstructure:"ANALYZE_BUILTIN_KEYWORDS"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("ANALYZE_ENGINE"); err != nil {
+		return c, fmt.Errorf("failed to bind 'ANALYZE_ENGINE'")
+	}
+	viper.SetDefault("ANALYZE_ENGINE", EngineAuto.String())
+
+	if err := viper.BindEnv("ANALYZE_BUILTIN_SUMMARY_SENTENCES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'ANALYZE_BUILTIN_SUMMARY_SENTENCES'")
+	}
+	viper.SetDefault("ANALYZE_BUILTIN_SUMMARY_SENTENCES", 3)
+
+	if err := viper.BindEnv("ANALYZE_BUILTIN_KEYWORDS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'ANALYZE_BUILTIN_KEYWORDS'")
+	}
+	viper.SetDefault("ANALYZE_BUILTIN_KEYWORDS", 10)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	if _, ok := EngineFromName(c.Engine); !ok {
+		return c, fmt.Errorf("invalid 'ANALYZE_ENGINE' %q", c.Engine)
+	}
+	if c.SummarySentences < 1 {
+		return c, fmt.Errorf("invalid 'ANALYZE_BUILTIN_SUMMARY_SENTENCES' %d; at least 1", c.SummarySentences)
+	}
+	if c.Keywords < 1 {
+		return c, fmt.Errorf("invalid 'ANALYZE_BUILTIN_KEYWORDS' %d; at least 1", c.Keywords)
+	}
+	return c, nil
+}
@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// Engine -> what runs an analyzer that can run on more than one.
+type Engine string
+
+const (
+	// EngineAuto -> wordser, falling back to the builtin engine when wordser fails.
+	EngineAuto    Engine = "auto"
+	EngineWordser Engine = "wordser"
+	// EngineBuiltin -> in process, pure go; see package nlp. quick and always available but cruder than the models.
+	EngineBuiltin Engine = "builtin"
+)
+
+// Engines -> every engine, in the order forms offer them.
+var Engines = []Engine{EngineAuto, EngineWordser, EngineBuiltin}
+
+func (e Engine) String() string {
+	return string(e)
+}
+
+func (e Engine) Title() string {
+	switch e {
+	case EngineWordser:
+		return "Wordser"
+	case EngineBuiltin:
+		return "Built-in"
+	default:
+		return "Auto"
+	}
+}
+
+func EngineFromName(name string) (Engine, bool) {
+	for _, e := range Engines {
+		if string(e) == name {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// Switcher -> an Analyzer that can run on more than one engine.
+type Switcher interface {
+	// WithEngine -> a copy of the analyzer running on engine.
+	WithEngine(engine Engine) Analyzer
+}
+
+// UseEngine -> analyzers set to run on engine. analyzers with a single engine are left as they are.
+func UseEngine(analyzers []Analyzer, engine Engine) []Analyzer {
+	switched := make([]Analyzer, 0, len(analyzers))
+	for _, a := range analyzers {
+		if s, ok := a.(Switcher); ok {
+			a = s.WithEngine(engine)
+		}
+		switched = append(switched, a)
+	}
+	return switched
+}
+
+// runEngine -> wordser or builtin as engine says. EngineAuto runs builtin when wordser fails, unless ctx is done
+// and the caller is gone anyway; the wordser error is logged as name's, the result hiding it.
+func runEngine(
+	ctx context.Context,
+	engine Engine,
+	logger Logger,
+	name string,
+	wordser func() (Result, error),
+	builtin func() (Result, error),
+) (Result, error) {
+	switch engine {
+	case EngineWordser:
+		return wordser()
+	case EngineBuiltin:
+		return builtin()
+	}
+	result, err := wordser()
+	if err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	logger.Errorf("%s: wordser failed, falling back to the builtin engine: %v", name, err)
+	return builtin()
+}
+
+// mergedEngine -> the engine label of a result merged from results labeled engines: the one engine they share or
+// each of them joined with "+".
+func mergedEngine(engines ...string) string {
+	var unique []string
+	for _, e := range engines {
+		if !slices.Contains(unique, e) {
+			unique = append(unique, e)
+		}
+	}
+	return strings.Join(unique, "+")
+}
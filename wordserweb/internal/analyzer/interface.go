@@ -0,0 +1,18 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/wordser"
+)
+
+// Logger -> where the auto engine reports the wordser failures it falls back from.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+}
+
+type WordserClient interface {
+	Summary(ctx context.Context, txt string) (*wordser.SummaryResp, error)
+	Sentiment(ctx context.Context, txt string) (*wordser.SentimentResp, error)
+	Extract(ctx context.Context, txt string) (*wordser.ExtractResp, error)
+}
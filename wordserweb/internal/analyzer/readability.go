@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/nlp"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+)
+
+const (
+	// readingWPM/speakingWPM -> average adult silent reading and presentation speaking rates.
+	readingWPM  = 238
+	speakingWPM = 150
+	// longestSentences -> sentences listed by ReadabilityResult.LongestSentences.
+	longestSentences = 3
+)
+
+type ReadabilityResult struct {
+	FleschReadingEase  float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade float64 `json:"flesch_kincaid_grade"`
+	GunningFog         float64 `json:"gunning_fog"`
+	SMOG               float64 `json:"smog"`
+	ColemanLiau        float64 `json:"coleman_liau"`
+
+	Words     int `json:"words"`
+	Sentences int `json:"sentences"`
+	Syllables int `json:"syllables"`
+	// ReadingSeconds/SpeakingSeconds -> estimated time to read the text silently or out loud.
+	ReadingSeconds  int `json:"reading_seconds"`
+	SpeakingSeconds int `json:"speaking_seconds"`
+	// LexicalDiversity -> unique words over words.
+	LexicalDiversity float64 `json:"lexical_diversity"`
+	// AverageSentenceLength -> words per sentence.
+	AverageSentenceLength float64        `json:"average_sentence_length"`
+	LongestSentences      []LongSentence `json:"longest_sentences"`
+}
+
+type LongSentence struct {
+	Text  string `json:"text"`
+	Words int    `json:"words"`
+}
+
+func (r *ReadabilityResult) Values() []string {
+	return []string{
+		formatScore(r.FleschReadingEase),
+		formatScore(r.FleschKincaidGrade),
+		formatScore(r.GunningFog),
+		formatScore(r.SMOG),
+		formatScore(r.ColemanLiau),
+		strconv.Itoa(r.Words),
+		strconv.Itoa(r.Sentences),
+		strconv.Itoa(r.Syllables),
+		strconv.Itoa(r.ReadingSeconds),
+		strconv.Itoa(r.SpeakingSeconds),
+		formatScore(r.LexicalDiversity),
+		formatScore(r.AverageSentenceLength),
+	}
+}
+
+func (r *ReadabilityResult) ReadingTime() time.Duration {
+	return time.Duration(r.ReadingSeconds) * time.Second
+}
+
+func (r *ReadabilityResult) SpeakingTime() time.Duration {
+	return time.Duration(r.SpeakingSeconds) * time.Second
+}
+
+// Grade -> Flesch Reading Ease as the usual plain words.
+func (r *ReadabilityResult) Grade() string {
+	switch ease := r.FleschReadingEase; {
+	case ease >= 90:
+		return "very easy"
+	case ease >= 80:
+		return "easy"
+	case ease >= 70:
+		return "fairly easy"
+	case ease >= 60:
+		return "plain english"
+	case ease >= 50:
+		return "fairly difficult"
+	case ease >= 30:
+		return "difficult"
+	default:
+		return "very difficult"
+	}
+}
+
+// formatScore -> v rounded to 2 decimals; the formulas aren't more precise than that.
+func formatScore(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// Readability -> readability formulas and text statistics, computed in process. it needs the whole text, so it is
+// no Merger, and it has a single engine.
+type Readability struct{}
+
+func NewReadability() *Readability {
+	return &Readability{}
+}
+
+func (r *Readability) Name() string {
+	return usage.APIReadability.String()
+}
+
+func (r *Readability) Options() Options {
+	return Options{
+		Title: "Readability",
+		Label: "Readability",
+		Field: "readability",
+		// syllable counts and the formulas' weights are english.
+		EnglishOnly: true,
+		Columns: []string{
+			"flesch_reading_ease",
+			"flesch_kincaid_grade",
+			"gunning_fog",
+			"smog",
+			"coleman_liau",
+			"word_count",
+			"sentence_count",
+			"syllable_count",
+			"reading_seconds",
+			"speaking_seconds",
+			"lexical_diversity",
+			"average_sentence_length",
+		},
+	}
+}
+
+func (r *Readability) Template() string {
+	return "analyzer_readability.html"
+}
+
+func (r *Readability) Run(ctx context.Context, txt string) (Result, error) {
+	stats := nlp.Analyze(txt, longestSentences)
+	longest := make([]LongSentence, 0, len(stats.LongestSentences))
+	for _, sentence := range stats.LongestSentences {
+		longest = append(longest, LongSentence{Text: sentence.Text, Words: sentence.Words})
+	}
+	return &ReadabilityResult{
+		FleschReadingEase:     stats.FleschReadingEase,
+		FleschKincaidGrade:    stats.FleschKincaidGrade,
+		GunningFog:            stats.GunningFog,
+		SMOG:                  stats.SMOG,
+		ColemanLiau:           stats.ColemanLiau,
+		Words:                 stats.Words,
+		Sentences:             stats.Sentences,
+		Syllables:             stats.Syllables,
+		ReadingSeconds:        minutesToSeconds(stats.Words, readingWPM),
+		SpeakingSeconds:       minutesToSeconds(stats.Words, speakingWPM),
+		LexicalDiversity:      stats.LexicalDiversity(),
+		AverageSentenceLength: stats.WordsPerSentence(),
+		LongestSentences:      longest,
+	}, nil
+}
+
+// minutesToSeconds -> seconds to get through words at wpm words a minute, rounded up.
+func minutesToSeconds(words int, wpm int) int {
+	return int(math.Ceil(float64(words) * 60 / float64(wpm)))
+}
+
+func (r *Readability) Decode(data []byte) (Result, error) {
+	var result ReadabilityResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,351 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+	"github.com/nolandseigler/wordser/wordserweb/internal/nlp"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+)
+
+// maxMergedKeywords -> keywords kept after combining every chunk's keywords.
+const maxMergedKeywords = 10
+
+// SummaryResult/SentimentResult/KeywordsResult -> Engine is the engine that produced the result, see mergedEngine
+// for merged ones.
+type SummaryResult struct {
+	Summary string `json:"summary"`
+	Engine  string `json:"engine,omitempty"`
+}
+
+func (r *SummaryResult) Values() []string {
+	return []string{r.Summary, r.Engine}
+}
+
+type SentimentResult struct {
+	Polarity string  `json:"polarity"`
+	Score    float64 `json:"score"`
+	Engine   string  `json:"engine,omitempty"`
+}
+
+func (r *SentimentResult) Values() []string {
+	return []string{r.Polarity, strconv.FormatFloat(r.Score, 'f', -1, 64), r.Engine}
+}
+
+type Keyword struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+type KeywordsResult struct {
+	Keywords []Keyword `json:"keywords"`
+	Engine   string    `json:"engine,omitempty"`
+}
+
+// Values -> the keywords joined with "; ".
+func (r *KeywordsResult) Values() []string {
+	texts := make([]string, 0, len(r.Keywords))
+	for _, k := range r.Keywords {
+		texts = append(texts, k.Text)
+	}
+	return []string{strings.Join(texts, "; "), r.Engine}
+}
+
+// Summary -> abstractive summary from wordser, or extractive from the builtin engine.
+type Summary struct {
+	client    WordserClient
+	logger    Logger
+	engine    Engine
+	sentences int
+}
+
+func NewSummary(config Config, client WordserClient, logger Logger) *Summary {
+	return &Summary{client: client, logger: logger, engine: Engine(config.Engine), sentences: config.SummarySentences}
+}
+
+func (s *Summary) WithEngine(engine Engine) Analyzer {
+	switched := *s
+	switched.engine = engine
+	return &switched
+}
+
+func (s *Summary) Name() string {
+	return usage.APISummary.String()
+}
+
+func (s *Summary) Options() Options {
+	return Options{
+		Title:       "Summary",
+		Label:       "Summarize",
+		Field:       "summarize",
+		Default:     true,
+		EnglishOnly: true,
+		Columns:     []string{"summary", "summary_engine"},
+	}
+}
+
+func (s *Summary) Template() string {
+	return "analyzer_summary.html"
+}
+
+func (s *Summary) Run(ctx context.Context, txt string) (Result, error) {
+	return runEngine(
+		ctx,
+		s.engine,
+		s.logger,
+		s.Name(),
+		func() (Result, error) {
+			resp, err := s.client.Summary(ctx, txt)
+			if err != nil {
+				return nil, err
+			}
+			return &SummaryResult{Summary: resp.Summary, Engine: EngineWordser.String()}, nil
+		},
+		func() (Result, error) {
+			return &SummaryResult{Summary: nlp.Summarize(txt, s.sentences), Engine: EngineBuiltin.String()}, nil
+		},
+	)
+}
+
+func (s *Summary) Decode(data []byte) (Result, error) {
+	var r SummaryResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	// results stored before there were engines all came from wordser.
+	if r.Engine == "" {
+		r.Engine = EngineWordser.String()
+	}
+	return &r, nil
+}
+
+// Merge -> a summary of the chunk summaries.
+func (s *Summary) Merge(ctx context.Context, run RunFunc, txt string, chunks []chunking.Chunk, parts []Result) (Result, error) {
+	summaries := make([]string, 0, len(parts))
+	engines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		summary := part.(*SummaryResult)
+		summaries = append(summaries, strings.TrimSpace(summary.Summary))
+		engines = append(engines, summary.Engine)
+	}
+	joined := strings.Join(summaries, " ")
+	// a model that doesn't shorten its input would never finish summarizing; stop with the chunk summaries.
+	if utf8.RuneCountInString(joined) >= utf8.RuneCountInString(txt) {
+		return &SummaryResult{Summary: joined, Engine: mergedEngine(engines...)}, nil
+	}
+	return run(ctx, joined)
+}
+
+// Sentiment -> polarity and score from wordser, or from the builtin engine's lexicon.
+type Sentiment struct {
+	client WordserClient
+	logger Logger
+	engine Engine
+}
+
+func NewSentiment(config Config, client WordserClient, logger Logger) *Sentiment {
+	return &Sentiment{client: client, logger: logger, engine: Engine(config.Engine)}
+}
+
+func (s *Sentiment) WithEngine(engine Engine) Analyzer {
+	switched := *s
+	switched.engine = engine
+	return &switched
+}
+
+func (s *Sentiment) Name() string {
+	return usage.APISentiment.String()
+}
+
+func (s *Sentiment) Options() Options {
+	return Options{
+		Title:       "Sentiment",
+		Label:       "Sentiment Analysis",
+		Field:       "sentiment",
+		EnglishOnly: true,
+		Columns:     []string{"polarity", "score", "sentiment_engine"},
+	}
+}
+
+func (s *Sentiment) Template() string {
+	return "analyzer_sentiment.html"
+}
+
+func (s *Sentiment) Run(ctx context.Context, txt string) (Result, error) {
+	return runEngine(
+		ctx,
+		s.engine,
+		s.logger,
+		s.Name(),
+		func() (Result, error) {
+			resp, err := s.client.Sentiment(ctx, txt)
+			if err != nil {
+				return nil, err
+			}
+			return &SentimentResult{Polarity: resp.Polarity, Score: resp.Score, Engine: EngineWordser.String()}, nil
+		},
+		func() (Result, error) {
+			return newSentimentResult(nlp.Sentiment(txt), EngineBuiltin.String()), nil
+		},
+	)
+}
+
+// newSentimentResult -> a signed score as wordser's two polarities, the score being how sure it is of either. a
+// score of 0, text without a word carrying any sentiment, is neither.
+func newSentimentResult(score float64, engine string) *SentimentResult {
+	polarity := "Positive"
+	switch {
+	case score < 0:
+		polarity = "Negative"
+	case score == 0:
+		polarity = "Neutral"
+	}
+	return &SentimentResult{Polarity: polarity, Score: math.Abs(score), Engine: engine}
+}
+
+func (s *Sentiment) Decode(data []byte) (Result, error) {
+	var r SentimentResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Engine == "" {
+		r.Engine = EngineWordser.String()
+	}
+	return &r, nil
+}
+
+// Merge -> length weighted mean of the signed chunk scores. negative chunks count against positive ones.
+func (s *Sentiment) Merge(ctx context.Context, run RunFunc, txt string, chunks []chunking.Chunk, parts []Result) (Result, error) {
+	var weighted, total float64
+	engines := make([]string, 0, len(parts))
+	for i, part := range parts {
+		sentiment := part.(*SentimentResult)
+		engines = append(engines, sentiment.Engine)
+		weight := float64(chunks[i].Len())
+		score := sentiment.Score
+		if strings.EqualFold(sentiment.Polarity, "negative") {
+			score = -score
+		}
+		weighted += score * weight
+		total += weight
+	}
+	mean := 0.0
+	if total > 0 {
+		mean = weighted / total
+	}
+	return newSentimentResult(mean, mergedEngine(engines...)), nil
+}
+
+// Keywords -> keyword extraction from wordser, or RAKE keyphrases from the builtin engine.
+type Keywords struct {
+	client   WordserClient
+	logger   Logger
+	engine   Engine
+	keywords int
+}
+
+func NewKeywords(config Config, client WordserClient, logger Logger) *Keywords {
+	return &Keywords{client: client, logger: logger, engine: Engine(config.Engine), keywords: config.Keywords}
+}
+
+func (k *Keywords) WithEngine(engine Engine) Analyzer {
+	switched := *k
+	switched.engine = engine
+	return &switched
+}
+
+func (k *Keywords) Name() string {
+	return usage.APIKeyword.String()
+}
+
+func (k *Keywords) Options() Options {
+	return Options{
+		Title:       "Keywords",
+		Label:       "Keyword Extraction",
+		Field:       "keyword",
+		EnglishOnly: true,
+		Columns:     []string{"keywords", "keywords_engine"},
+	}
+}
+
+func (k *Keywords) Template() string {
+	return "analyzer_keyword.html"
+}
+
+func (k *Keywords) Run(ctx context.Context, txt string) (Result, error) {
+	return runEngine(
+		ctx,
+		k.engine,
+		k.logger,
+		k.Name(),
+		func() (Result, error) {
+			resp, err := k.client.Extract(ctx, txt)
+			if err != nil {
+				return nil, err
+			}
+			result := &KeywordsResult{Keywords: make([]Keyword, 0, len(resp.Keywords)), Engine: EngineWordser.String()}
+			for _, keyword := range resp.Keywords {
+				result.Keywords = append(result.Keywords, Keyword{Text: keyword.Text, Score: keyword.Score})
+			}
+			return result, nil
+		},
+		func() (Result, error) {
+			keywords := nlp.Keywords(txt, k.keywords)
+			result := &KeywordsResult{Keywords: make([]Keyword, 0, len(keywords)), Engine: EngineBuiltin.String()}
+			for _, keyword := range keywords {
+				result.Keywords = append(result.Keywords, Keyword{Text: keyword.Text, Score: keyword.Score})
+			}
+			return result, nil
+		},
+	)
+}
+
+func (k *Keywords) Decode(data []byte) (Result, error) {
+	var r KeywordsResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Engine == "" {
+		r.Engine = EngineWordser.String()
+	}
+	return &r, nil
+}
+
+// Merge -> keywords across chunks, case insensitively combined with summed scores, best first.
+func (k *Keywords) Merge(ctx context.Context, run RunFunc, txt string, chunks []chunking.Chunk, parts []Result) (Result, error) {
+	byText := map[string]*Keyword{}
+	var merged []*Keyword
+	engines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		keywords := part.(*KeywordsResult)
+		engines = append(engines, keywords.Engine)
+		for _, keyword := range keywords.Keywords {
+			key := strings.ToLower(keyword.Text)
+			if existing, ok := byText[key]; ok {
+				existing.Score += keyword.Score
+				continue
+			}
+			kw := &Keyword{Text: keyword.Text, Score: keyword.Score}
+			byText[key] = kw
+			merged = append(merged, kw)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+
+	result := &KeywordsResult{Keywords: []Keyword{}, Engine: mergedEngine(engines...)}
+	for i, kw := range merged {
+		if i == maxMergedKeywords {
+			break
+		}
+		result.Keywords = append(result.Keywords, *kw)
+	}
+	return result, nil
+}
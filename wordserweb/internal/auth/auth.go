@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"crypto/rsa"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,6 +13,7 @@ import (
 	jwtlib "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
 )
 
 type Auth struct {
@@ -50,8 +52,41 @@ func New(ctx context.Context, config Config, kvStore KeyValStorer, userVerifier
 	}, nil
 }
 
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) String() string {
+	return string(r)
+}
+
 type UserContext struct {
 	Username string `json:"username"`
+	Role     Role   `json:"role"`
+	// OrgID -> the active organization. 0 when the session is in the user's personal workspace.
+	OrgID   int    `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+}
+
+// echo.Context keys ValidateJWTMiddleWare stores the validated session under.
+const (
+	userContextKey  = "user_context"
+	sessionTokenKey = "session_token"
+)
+
+// UserContextFromEcho -> the UserContext of the session validated by ValidateJWTMiddleWare.
+func UserContextFromEcho(c echo.Context) (UserContext, bool) {
+	userCtx, ok := c.Get(userContextKey).(UserContext)
+	return userCtx, ok
+}
+
+// SessionTokenFromEcho -> the current session jwt. differs from the request cookie when the middleware refreshed it.
+func SessionTokenFromEcho(c echo.Context) (string, bool) {
+	jwt, ok := c.Get(sessionTokenKey).(string)
+	return jwt, ok
 }
 
 type JWTClaims struct {
@@ -114,14 +149,9 @@ func (a *Auth) mintJWT(ctx context.Context, userCtx UserContext) (string, uuid.U
 }
 
 // newJWT -> mintJwt, and storeSession
-func (a *Auth) newJWT(ctx context.Context, username string) (string, uuid.UUID, error) {
+func (a *Auth) newJWT(ctx context.Context, userCtx UserContext) (string, uuid.UUID, error) {
 
-	jwt, jti, err := a.mintJWT(
-		ctx,
-		UserContext{
-			Username: username,
-		},
-	)
+	jwt, jti, err := a.mintJWT(ctx, userCtx)
 	if err != nil {
 		return "", uuid.Nil, nil
 	}
@@ -144,7 +174,18 @@ func (a *Auth) Login(ctx context.Context, username string, password string) (str
 		return "", fmt.Errorf("invalid username/password or user does not exist")
 	}
 
-	jwt, _, err := a.newJWT(ctx, username)
+	account, err := a.userVerifier.GetUserAccount(ctx, username)
+	if err != nil {
+		return "", err
+	}
+
+	jwt, _, err := a.newJWT(
+		ctx,
+		UserContext{
+			Username: account.Username,
+			Role:     Role(account.Role),
+		},
+	)
 
 	if err != nil {
 		return "", err
@@ -154,11 +195,11 @@ func (a *Auth) Login(ctx context.Context, username string, password string) (str
 }
 
 // refreshJWT -> part of validateJWT. if validJWT will expire in `n` minutes then NewJWT
-func (a *Auth) refreshJWT(ctx context.Context, jti uuid.UUID, username string) (string, uuid.UUID, error) {
+func (a *Auth) refreshJWT(ctx context.Context, jti uuid.UUID, userCtx UserContext) (string, uuid.UUID, error) {
 	if err := a.destroySesion(ctx, jti); err != nil {
 		return "", uuid.Nil, err
 	}
-	jwt, jti, err := a.newJWT(ctx, username)
+	jwt, jti, err := a.newJWT(ctx, userCtx)
 
 	if err != nil {
 		return "", uuid.Nil, err
@@ -168,35 +209,35 @@ func (a *Auth) refreshJWT(ctx context.Context, jti uuid.UUID, username string) (
 }
 
 // validateJWT -> does the work but isnt the public function. takes an argument refresh: bool so we can use this in logout without refresh.
-func (a *Auth) validateJWT(ctx context.Context, jwt string, refresh bool) (string, uuid.UUID, error) {
+func (a *Auth) validateJWT(ctx context.Context, jwt string, refresh bool) (string, uuid.UUID, UserContext, error) {
 	token, err := jwtlib.ParseWithClaims(jwt, &JWTClaims{}, func(token *jwtlib.Token) (interface{}, error) {
 		return a.pubKey, nil
 	})
 	if err != nil {
-		return "", uuid.Nil, err
+		return "", uuid.Nil, UserContext{}, err
 	}
 
 	if !token.Valid {
-		return "", uuid.Nil, fmt.Errorf("invalid token;")
+		return "", uuid.Nil, UserContext{}, fmt.Errorf("invalid token;")
 	}
 
 	claims, ok := token.Claims.(*JWTClaims)
 	if !ok {
-		return "", uuid.Nil, fmt.Errorf("invalid token claims;")
+		return "", uuid.Nil, UserContext{}, fmt.Errorf("invalid token claims;")
 	}
 
 	expiry, err := claims.RegisteredClaims.GetExpirationTime()
 	if err != nil {
-		return "", uuid.Nil, err
+		return "", uuid.Nil, UserContext{}, err
 	}
 
 	returnJti, err := uuid.Parse(claims.RegisteredClaims.ID)
 	if err != nil {
-		return "", uuid.Nil, err
+		return "", uuid.Nil, UserContext{}, err
 	}
 
 	if err := a.validateSession(ctx, returnJti); err != nil {
-		return "", uuid.Nil, err
+		return "", uuid.Nil, UserContext{}, err
 	}
 
 	returnJwt := jwt
@@ -204,24 +245,79 @@ func (a *Auth) validateJWT(ctx context.Context, jwt string, refresh bool) (strin
 	if refresh && expiry.Time.After(time.Now().UTC().Add(5*time.Minute)) {
 		subj, err := claims.RegisteredClaims.GetSubject()
 		if err != nil {
-			return "", uuid.Nil, err
+			return "", uuid.Nil, UserContext{}, err
+		}
+
+		userCtx := claims.UserContext
+		userCtx.Username = subj
+
+		userCtx, err = a.currentUserContext(ctx, userCtx)
+		if err != nil {
+			return "", uuid.Nil, UserContext{}, err
 		}
 
-		returnJwt, returnJti, err = a.refreshJWT(ctx, returnJti, subj)
+		returnJwt, returnJti, err = a.refreshJWT(ctx, returnJti, userCtx)
 
 		if err != nil {
-			return "", uuid.Nil, err
+			return "", uuid.Nil, UserContext{}, err
 		}
 		// success continue to return
+		return returnJwt, returnJti, userCtx, nil
 	}
 
-	return returnJwt, returnJti, nil
+	return returnJwt, returnJti, claims.UserContext, nil
+}
+
+// currentUserContext -> userCtx with the role and org role as they are now, so a refreshed session doesn't carry
+// claims that were taken away. the org is dropped once the membership is gone.
+func (a *Auth) currentUserContext(ctx context.Context, userCtx UserContext) (UserContext, error) {
+	account, err := a.userVerifier.GetUserAccount(ctx, userCtx.Username)
+	if err != nil {
+		return UserContext{}, err
+	}
+	userCtx.Role = Role(account.Role)
+
+	if userCtx.OrgID == 0 {
+		return userCtx, nil
+	}
+	membership, err := a.userVerifier.GetOrganizationMembership(ctx, userCtx.OrgID, userCtx.Username)
+	if errors.Is(err, postgres.ErrNotFound) {
+		userCtx.OrgID = 0
+		userCtx.OrgRole = ""
+		return userCtx, nil
+	}
+	if err != nil {
+		return UserContext{}, err
+	}
+	userCtx.OrgRole = membership.Role
+	return userCtx, nil
+}
+
+// SwitchOrg -> replace the session jwt with one scoped to orgID. orgID 0 switches back to the personal workspace.
+// callers must check membership first; auth has no notion of organizations beyond the claims.
+func (a *Auth) SwitchOrg(ctx context.Context, jwt string, orgID int, orgRole string) (string, error) {
+	_, jti, userCtx, err := a.validateJWT(ctx, jwt, false)
+	if err != nil {
+		return "", err
+	}
+
+	userCtx.OrgID = orgID
+	userCtx.OrgRole = orgRole
+	if orgID == 0 {
+		userCtx.OrgRole = ""
+	}
+
+	newJwt, _, err := a.refreshJWT(ctx, jti, userCtx)
+	if err != nil {
+		return "", err
+	}
+	return newJwt, nil
 }
 
 // Logout -> this is not behind ValidateJWT middleware func so we call validateJWT with refresh = false
 // no jwt no logout.
 func (a *Auth) Logout(ctx context.Context, jwt string) error {
-	_, _, err := a.validateJWT(ctx, jwt, false)
+	_, _, _, err := a.validateJWT(ctx, jwt, false)
 	if err != nil {
 		return err
 	}
@@ -246,10 +342,12 @@ func (a *Auth) ValidateJWTMiddleWare(next echo.HandlerFunc) echo.HandlerFunc {
 			}
 			return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
 		}
-		jwt, _, err := a.validateJWT(c.Request().Context(), sessionCookie.Value, true)
+		jwt, _, userCtx, err := a.validateJWT(c.Request().Context(), sessionCookie.Value, true)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
 		}
+		c.Set(userContextKey, userCtx)
+		c.Set(sessionTokenKey, jwt)
 
 		if sessionCookie.Value == jwt {
 			return next(c)
@@ -264,3 +362,21 @@ func (a *Auth) ValidateJWTMiddleWare(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// RequireRole -> must run after ValidateJWTMiddleWare. rejects sessions whose UserContext is not one of roles.
+func RequireRole(roles ...Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userCtx, ok := UserContextFromEcho(c)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "no credentials")
+			}
+			for _, role := range roles {
+				if userCtx.Role == role {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+		}
+	}
+}
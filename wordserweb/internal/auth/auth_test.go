@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type memKV struct {
+	m sync.Map
+}
+
+func (kv *memKV) Insert(key string, value string) error {
+	kv.m.Store(key, value)
+	return nil
+}
+
+func (kv *memKV) Delete(key string) error {
+	kv.m.Delete(key)
+	return nil
+}
+
+func (kv *memKV) Get(key string) (string, bool) {
+	val, ok := kv.m.Load(key)
+	if !ok {
+		return "", false
+	}
+	return val.(string), true
+}
+
+// fakeVerifier -> role is the account role, memberships the org roles by org id.
+type fakeVerifier struct {
+	role        string
+	memberships map[int]string
+}
+
+func (v *fakeVerifier) IsUserAccountPassword(ctx context.Context, username string, password string) (bool, error) {
+	return true, nil
+}
+
+func (v *fakeVerifier) GetUserAccount(ctx context.Context, username string) (*postgres.UserAccount, error) {
+	return &postgres.UserAccount{Username: username, Role: v.role}, nil
+}
+
+func (v *fakeVerifier) GetOrganizationMembership(ctx context.Context, orgID int, username string) (*postgres.OrganizationMembership, error) {
+	role, ok := v.memberships[orgID]
+	if !ok {
+		return nil, fmt.Errorf("membership %w", postgres.ErrNotFound)
+	}
+	return &postgres.OrganizationMembership{OrganizationID: orgID, Username: username, Role: role}, nil
+}
+
+func newTestAuth(t *testing.T, verifier *fakeVerifier) *Auth {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Auth{pubKey: &key.PublicKey, signingKey: key, kvStore: &memKV{}, userVerifier: verifier}
+}
+
+func TestValidateJWTRefreshesClaims(t *testing.T) {
+	tests := []struct {
+		name        string
+		session     UserContext
+		role        string
+		memberships map[int]string
+		want        UserContext
+	}{
+		{
+			name:    "unchanged",
+			session: UserContext{Username: "ann", Role: RoleUser, OrgID: 7, OrgRole: "member"},
+			role:    "user", memberships: map[int]string{7: "member"},
+			want: UserContext{Username: "ann", Role: RoleUser, OrgID: 7, OrgRole: "member"},
+		},
+		{
+			name:    "removed from org",
+			session: UserContext{Username: "ann", Role: RoleUser, OrgID: 7, OrgRole: "admin"},
+			role:    "user", memberships: map[int]string{},
+			want: UserContext{Username: "ann", Role: RoleUser},
+		},
+		{
+			name:    "org role changed",
+			session: UserContext{Username: "ann", Role: RoleUser, OrgID: 7, OrgRole: "admin"},
+			role:    "user", memberships: map[int]string{7: "member"},
+			want: UserContext{Username: "ann", Role: RoleUser, OrgID: 7, OrgRole: "member"},
+		},
+		{
+			name:    "role changed",
+			session: UserContext{Username: "ann", Role: RoleAdmin},
+			role:    "user",
+			want:    UserContext{Username: "ann", Role: RoleUser},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			a := newTestAuth(t, &fakeVerifier{role: tt.role, memberships: tt.memberships})
+			jwt, _, err := a.newJWT(ctx, tt.session)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			refreshed, _, userCtx, err := a.validateJWT(ctx, jwt, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if userCtx != tt.want {
+				t.Errorf("validateJWT() user context = %+v, want %+v", userCtx, tt.want)
+			}
+			if _, _, _, err := a.validateJWT(ctx, jwt, false); err == nil {
+				t.Error("validateJWT() kept the replaced session valid")
+			}
+			_, _, userCtx, err = a.validateJWT(ctx, refreshed, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if userCtx != tt.want {
+				t.Errorf("refreshed jwt user context = %+v, want %+v", userCtx, tt.want)
+			}
+		})
+	}
+}
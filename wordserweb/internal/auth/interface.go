@@ -1,6 +1,10 @@
 package auth
 
-import "context"
+import (
+	"context"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
 
 type KeyValStorer interface {
 	Insert(key string, value string) error
@@ -10,4 +14,7 @@ type KeyValStorer interface {
 
 type UserVerifier interface {
 	IsUserAccountPassword(ctx context.Context, username string, password string) (bool, error)
+	GetUserAccount(ctx context.Context, username string) (*postgres.UserAccount, error)
+	// GetOrganizationMembership -> postgres.ErrNotFound once username left or was removed from orgID.
+	GetOrganizationMembership(ctx context.Context, orgID int, username string) (*postgres.OrganizationMembership, error)
 }
@@ -0,0 +1,461 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+func (f Format) String() string {
+	return string(f)
+}
+
+func (f Format) ContentType() string {
+	if f == FormatJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// FormatFromFilename -> the format an upload's extension says it is.
+func FormatFromFilename(name string) (Format, bool) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return FormatCSV, true
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, true
+	default:
+		return "", false
+	}
+}
+
+// DefaultTextColumn -> picked when the upload doesn't say which column holds the text.
+const DefaultTextColumn = "text"
+
+// ErrorsColumn -> the last column of a download: why analyzers failed on the row.
+const ErrorsColumn = "errors"
+
+var (
+	ErrEmpty       = errors.New("file has no rows")
+	ErrTooManyRows = errors.New("file has too many rows")
+	ErrTextColumn  = errors.New("no text column")
+)
+
+// Row -> one parsed row. Source is the original row as a json object with its columns in file order.
+type Row struct {
+	Index  int
+	Source json.RawMessage
+	Text   string
+}
+
+type Parsed struct {
+	// Columns -> csv header, or jsonl keys in the order they first appear.
+	Columns []string
+	Rows    []Row
+}
+
+// Parse -> read every row of an upload. textColumn names the column, or jsonl key, to analyze.
+func Parse(r io.Reader, format Format, textColumn string, maxRows int) (*Parsed, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r, textColumn, maxRows)
+	case FormatJSONL:
+		return parseJSONL(r, textColumn, maxRows)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func parseCSV(r io.Reader, textColumn string, maxRows int) (*Parsed, error) {
+	reader := csv.NewReader(r)
+	// short rows are padded with empty values rather than rejected; spreadsheets drop trailing empty cells.
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		if column == "" {
+			return nil, fmt.Errorf("column %d has no name", i+1)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("column %q appears more than once", column)
+		}
+		seen[column] = true
+		header[i] = column
+	}
+
+	textIndex, err := textColumnIndex(header, textColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &Parsed{Columns: header}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(parsed.Rows) == maxRows {
+			return nil, fmt.Errorf("%w; at most %d;", ErrTooManyRows, maxRows)
+		}
+		if len(record) > len(header) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d has more values than the header has columns", line)
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, column := range header {
+			value := ""
+			if i < len(record) {
+				value = record[i]
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONField(&buf, column, jsonString(value))
+		}
+		buf.WriteByte('}')
+
+		text := ""
+		if textIndex < len(record) {
+			text = record[textIndex]
+		}
+		parsed.Rows = append(parsed.Rows, Row{
+			Index:  len(parsed.Rows),
+			Source: buf.Bytes(),
+			Text:   strings.TrimSpace(text),
+		})
+	}
+	if len(parsed.Rows) == 0 {
+		return nil, ErrEmpty
+	}
+	return parsed, nil
+}
+
+// textColumnIndex -> the named column, DefaultTextColumn or the only column there is, in that order.
+func textColumnIndex(columns []string, textColumn string) (int, error) {
+	name := strings.TrimSpace(textColumn)
+	if name == "" {
+		name = DefaultTextColumn
+		if len(columns) == 1 {
+			name = columns[0]
+		}
+	}
+	for i, column := range columns {
+		if column == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q; columns: %s;", ErrTextColumn, name, strings.Join(columns, ", "))
+}
+
+func parseJSONL(r io.Reader, textColumn string, maxRows int) (*Parsed, error) {
+	name := strings.TrimSpace(textColumn)
+	if name == "" {
+		name = DefaultTextColumn
+	}
+
+	scanner := bufio.NewScanner(r)
+	// a single comment can be long; the upload size limit bounds this anyway.
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+
+	parsed := &Parsed{}
+	seen := map[string]bool{}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if lineNumber == 1 {
+			line = bytes.TrimPrefix(line, []byte("\ufeff"))
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if len(parsed.Rows) == maxRows {
+			return nil, fmt.Errorf("%w; at most %d;", ErrTooManyRows, maxRows)
+		}
+
+		keys, values, err := decodeObject(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		text := ""
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				parsed.Columns = append(parsed.Columns, key)
+			}
+			if key == name {
+				if err := json.Unmarshal(values[i], &text); err != nil {
+					return nil, fmt.Errorf("line %d: %q is not a string", lineNumber, name)
+				}
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONField(&buf, key, values[i])
+		}
+		buf.WriteByte('}')
+
+		parsed.Rows = append(parsed.Rows, Row{
+			Index:  len(parsed.Rows),
+			Source: buf.Bytes(),
+			Text:   strings.TrimSpace(text),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(parsed.Rows) == 0 {
+		return nil, ErrEmpty
+	}
+	if !seen[name] {
+		return nil, fmt.Errorf("%w %q; keys: %s;", ErrTextColumn, name, strings.Join(parsed.Columns, ", "))
+	}
+	return parsed, nil
+}
+
+// decodeObject -> the keys of a json object in order with their compacted values.
+func decodeObject(data []byte) ([]string, []json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil, errors.New("not a json object")
+	}
+
+	var keys []string
+	var values []json.RawMessage
+	index := map[string]int{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return nil, nil, err
+		}
+		// the last duplicate wins, like encoding/json.
+		if i, ok := index[key]; ok {
+			values[i] = compact.Bytes()
+			continue
+		}
+		index[key] = len(keys)
+		keys = append(keys, key)
+		values = append(values, compact.Bytes())
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	if dec.More() {
+		return nil, nil, errors.New("more than one json value on the line")
+	}
+	return keys, values, nil
+}
+
+func jsonString(s string) json.RawMessage {
+	b, _ := json.Marshal(s)
+	return b
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value json.RawMessage) {
+	buf.Write(jsonString(key))
+	buf.WriteByte(':')
+	buf.Write(value)
+}
+
+// CSVResultColumns -> appended to the original columns in csv downloads: the Options.Columns of every analyzer,
+// then ErrorsColumn.
+func CSVResultColumns(analyzers []analyzer.Analyzer) []string {
+	var columns []string
+	for _, a := range analyzers {
+		columns = append(columns, a.Options().Columns...)
+	}
+	return append(columns, ErrorsColumn)
+}
+
+// JSONLResultKeys -> appended to the original keys in jsonl downloads: the name of every analyzer, then
+// ErrorsColumn.
+func JSONLResultKeys(analyzers []analyzer.Analyzer) []string {
+	var keys []string
+	for _, a := range analyzers {
+		keys = append(keys, a.Name())
+	}
+	return append(keys, ErrorsColumn)
+}
+
+// sourceColumns -> the original columns that make it into a download. results replace columns of the same name.
+func sourceColumns(columns []string, results []string) []string {
+	kept := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !slices.Contains(results, column) {
+			kept = append(kept, column)
+		}
+	}
+	return kept
+}
+
+func sourceValues(row *postgres.BatchRow) map[string]json.RawMessage {
+	values := map[string]json.RawMessage{}
+	_ = json.Unmarshal(row.Source, &values)
+	return values
+}
+
+// WriteCSV -> rows with their original columns followed by CSVResultColumns. errors are joined with "; ".
+func WriteCSV(w io.Writer, columns []string, analyzers []analyzer.Analyzer, rows []*postgres.BatchRow) error {
+	results := CSVResultColumns(analyzers)
+	columns = sourceColumns(columns, results)
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, columns...), results...)); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		values := sourceValues(row)
+		record := make([]string, 0, len(columns)+len(results))
+		for _, column := range columns {
+			record = append(record, csvValue(values[column]))
+		}
+		for _, a := range analyzers {
+			resultValues := make([]string, len(a.Options().Columns))
+			if raw, ok := row.Results[a.Name()]; ok {
+				result, err := a.Decode(raw)
+				if err != nil {
+					return fmt.Errorf("row %d: failed to decode %s result: %w", row.RowIndex, a.Name(), err)
+				}
+				copy(resultValues, result.Values())
+			}
+			record = append(record, resultValues...)
+		}
+		record = append(record, joinErrors(row.Errors))
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue -> strings as they were, anything else a jsonl row held as its json.
+func csvValue(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func joinErrors(errs map[string]string) string {
+	apis := make([]string, 0, len(errs))
+	for api := range errs {
+		apis = append(apis, api)
+	}
+	sort.Strings(apis)
+	parts := make([]string, 0, len(apis))
+	for _, api := range apis {
+		parts = append(parts, api+": "+errs[api])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// WriteJSONL -> one object per row: its original keys in order followed by JSONLResultKeys. an analyzer's key
+// holds its result as stored, null when it has none.
+func WriteJSONL(w io.Writer, columns []string, analyzers []analyzer.Analyzer, rows []*postgres.BatchRow) error {
+	columns = sourceColumns(columns, JSONLResultKeys(analyzers))
+	bw := bufio.NewWriter(w)
+	for _, row := range rows {
+		values := sourceValues(row)
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		first := true
+		field := func(key string, value any) error {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			writeJSONField(&buf, key, b)
+			return nil
+		}
+		for _, column := range columns {
+			if raw, ok := values[column]; ok {
+				if err := field(column, raw); err != nil {
+					return err
+				}
+			}
+		}
+		for _, a := range analyzers {
+			if err := field(a.Name(), row.Results[a.Name()]); err != nil {
+				return err
+			}
+		}
+		errs := row.Errors
+		if errs == nil {
+			errs = map[string]string{}
+		}
+		if err := field(ErrorsColumn, errs); err != nil {
+			return err
+		}
+		buf.WriteString("}\n")
+		if _, err := bw.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Write -> rows in format with the results of analyzers.
+func Write(w io.Writer, format Format, columns []string, analyzers []analyzer.Analyzer, rows []*postgres.BatchRow) error {
+	if format == FormatJSONL {
+		return WriteJSONL(w, columns, analyzers, rows)
+	}
+	return WriteCSV(w, columns, analyzers, rows)
+}
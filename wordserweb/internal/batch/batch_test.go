@@ -0,0 +1,346 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// wantRow -> a Row without its index, which is its position.
+type wantRow struct {
+	source string
+	text   string
+}
+
+func checkParsed(t *testing.T, got *Parsed, wantColumns []string, wantRows []wantRow) {
+	t.Helper()
+	if !reflect.DeepEqual(got.Columns, wantColumns) {
+		t.Errorf("columns = %q, want %q", got.Columns, wantColumns)
+	}
+	if len(got.Rows) != len(wantRows) {
+		t.Fatalf("got %d rows, want %d", len(got.Rows), len(wantRows))
+	}
+	for i, row := range got.Rows {
+		if row.Index != i || string(row.Source) != wantRows[i].source || row.Text != wantRows[i].text {
+			t.Errorf("row %d = %d %s %q, want %d %s %q", i, row.Index, row.Source, row.Text, i, wantRows[i].source, wantRows[i].text)
+		}
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Format
+		wantOK bool
+	}{
+		{name: "comments.csv", want: FormatCSV, wantOK: true},
+		{name: "COMMENTS.CSV", want: FormatCSV, wantOK: true},
+		{name: "comments.jsonl", want: FormatJSONL, wantOK: true},
+		{name: "comments.ndjson", want: FormatJSONL, wantOK: true},
+		{name: "comments.json"},
+		{name: "comments.csv.txt"},
+		{name: "comments"},
+	}
+	for _, tt := range tests {
+		if got, ok := FormatFromFilename(tt.name); got != tt.want || ok != tt.wantOK {
+			t.Errorf("FormatFromFilename(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		textColumn  string
+		maxRows     int
+		wantColumns []string
+		wantRows    []wantRow
+		wantErr     error
+		// wantErrText -> part of the message of an error with no sentinel.
+		wantErrText string
+	}{
+		{
+			name:        "default text column",
+			file:        "id,text\n1, hello \n2,\"a, \"\"quoted\"\"\nline\"\n",
+			wantColumns: []string{"id", "text"},
+			wantRows: []wantRow{
+				{source: `{"id":"1","text":" hello "}`, text: "hello"},
+				{source: `{"id":"2","text":"a, \"quoted\"\nline"}`, text: "a, \"quoted\"\nline"},
+			},
+		},
+		{
+			name:        "named text column",
+			file:        " id , body \n1,hi\n",
+			textColumn:  " body ",
+			wantColumns: []string{"id", "body"},
+			wantRows:    []wantRow{{source: `{"id":"1","body":"hi"}`, text: "hi"}},
+		},
+		{
+			name:        "only column",
+			file:        "\ufeffcomment\nhi\n",
+			wantColumns: []string{"comment"},
+			wantRows:    []wantRow{{source: `{"comment":"hi"}`, text: "hi"}},
+		},
+		{
+			name:        "short rows padded",
+			file:        "text,id,note\nhi\n",
+			wantColumns: []string{"text", "id", "note"},
+			wantRows:    []wantRow{{source: `{"text":"hi","id":"","note":""}`, text: "hi"}},
+		},
+		{
+			name:        "text column missing from a short row",
+			file:        "id,text\n1\n",
+			wantColumns: []string{"id", "text"},
+			wantRows:    []wantRow{{source: `{"id":"1","text":""}`, text: ""}},
+		},
+		{
+			name:        "at the row limit",
+			file:        "text\na\nb\n",
+			maxRows:     2,
+			wantColumns: []string{"text"},
+			wantRows:    []wantRow{{source: `{"text":"a"}`, text: "a"}, {source: `{"text":"b"}`, text: "b"}},
+		},
+		{name: "over the row limit", file: "text\na\nb\nc\n", maxRows: 2, wantErr: ErrTooManyRows},
+		{name: "empty", file: "", wantErr: ErrEmpty},
+		{name: "header only", file: "id,text\n", wantErr: ErrEmpty},
+		{name: "no text column", file: "id,body\n1,hi\n", wantErr: ErrTextColumn},
+		{name: "unknown text column", file: "id,text\n1,hi\n", textColumn: "body", wantErr: ErrTextColumn},
+		{name: "unnamed column", file: "id,,text\n1,2,hi\n", wantErrText: "column 2 has no name"},
+		{name: "repeated column", file: "text,id,text\nhi,1,hi\n", wantErrText: `column "text" appears more than once`},
+		{name: "row longer than the header", file: "id,text\n1,hi\n2,hi,extra\n", wantErrText: "line 3 has more values"},
+		{name: "unterminated quote", file: "id,text\n1,\"hi\n", wantErrText: "extraneous or missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxRows := tt.maxRows
+			if maxRows == 0 {
+				maxRows = 100
+			}
+			got, err := Parse(strings.NewReader(tt.file), FormatCSV, tt.textColumn, maxRows)
+			if tt.wantErr != nil || tt.wantErrText != "" {
+				if (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) || err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Fatalf("Parse() error = %v, want %v %q", err, tt.wantErr, tt.wantErrText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkParsed(t, got, tt.wantColumns, tt.wantRows)
+		})
+	}
+}
+
+func TestParseJSONL(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		textColumn  string
+		maxRows     int
+		wantColumns []string
+		wantRows    []wantRow
+		wantErr     error
+		wantErrText string
+	}{
+		{
+			name:        "keys in the order they first appear",
+			file:        "\ufeff{\"id\": 1, \"text\": \" hi \"}\n\n  \n{\"text\":\"there\",\"meta\":{ \"a\": [1, 2] },\"id\":null}\r\n",
+			wantColumns: []string{"id", "text", "meta"},
+			wantRows: []wantRow{
+				{source: `{"id":1,"text":" hi "}`, text: "hi"},
+				{source: `{"text":"there","meta":{"a":[1,2]},"id":null}`, text: "there"},
+			},
+		},
+		{
+			name:        "named text column",
+			file:        `{"body":"hi","text":"not this"}`,
+			textColumn:  "body",
+			wantColumns: []string{"body", "text"},
+			wantRows:    []wantRow{{source: `{"body":"hi","text":"not this"}`, text: "hi"}},
+		},
+		{
+			name:        "row without the text key",
+			file:        "{\"text\":\"hi\"}\n{\"id\":2}\n",
+			wantColumns: []string{"text", "id"},
+			wantRows:    []wantRow{{source: `{"text":"hi"}`, text: "hi"}, {source: `{"id":2}`, text: ""}},
+		},
+		{
+			name:        "repeated key",
+			file:        `{"text":"first","id":1,"text":"last"}`,
+			wantColumns: []string{"text", "id"},
+			wantRows:    []wantRow{{source: `{"text":"last","id":1}`, text: "last"}},
+		},
+		{
+			name:        "unicode escapes kept in the source",
+			file:        `{"text":"caf\u00e9 東京"}`,
+			wantColumns: []string{"text"},
+			wantRows:    []wantRow{{source: `{"text":"caf\u00e9 東京"}`, text: "café 東京"}},
+		},
+		{name: "over the row limit", file: "{\"text\":\"a\"}\n\n{\"text\":\"b\"}\n", maxRows: 1, wantErr: ErrTooManyRows},
+		{name: "empty", file: "", wantErr: ErrEmpty},
+		{name: "blank lines only", file: "\n \n\t\n", wantErr: ErrEmpty},
+		{name: "no text key", file: `{"body":"hi"}`, wantErr: ErrTextColumn},
+		{name: "text not a string", file: "{\"text\":\"a\"}\n{\"text\":5}\n", wantErrText: `line 2: "text" is not a string`},
+		{name: "array", file: "{\"text\":\"a\"}\n\n[\"text\"]\n", wantErrText: "line 3: not a json object"},
+		{name: "string", file: `"text"`, wantErrText: "line 1: not a json object"},
+		{name: "two objects on a line", file: `{"text":"a"} {"text":"b"}`, wantErrText: "line 1: more than one json value"},
+		{name: "truncated object", file: `{"text":"a"`, wantErrText: "line 1:"},
+		{name: "invalid json", file: `{"text":'a'}`, wantErrText: "line 1: invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxRows := tt.maxRows
+			if maxRows == 0 {
+				maxRows = 100
+			}
+			got, err := Parse(strings.NewReader(tt.file), FormatJSONL, tt.textColumn, maxRows)
+			if tt.wantErr != nil || tt.wantErrText != "" {
+				if (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) || err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Fatalf("Parse() error = %v, want %v %q", err, tt.wantErr, tt.wantErrText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkParsed(t, got, tt.wantColumns, tt.wantRows)
+		})
+	}
+}
+
+func TestParseUnsupportedFormat(t *testing.T) {
+	if _, err := Parse(strings.NewReader("text\nhi\n"), Format("xlsx"), "", 10); err == nil {
+		t.Error("Parse() of an unsupported format = nil error")
+	}
+}
+
+// fakeResult -> the Result of fakeAnalyzer.
+type fakeResult struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+func (r *fakeResult) Values() []string {
+	return []string{r.Label, strconv.FormatFloat(r.Score, 'g', -1, 64)}
+}
+
+// fakeAnalyzer -> an Analyzer with columns label and score, decoding fakeResult.
+type fakeAnalyzer struct {
+	name string
+}
+
+func (a *fakeAnalyzer) Name() string {
+	return a.name
+}
+
+func (a *fakeAnalyzer) Options() analyzer.Options {
+	return analyzer.Options{Columns: []string{a.name + "_label", a.name + "_score"}}
+}
+
+func (a *fakeAnalyzer) Run(ctx context.Context, txt string) (analyzer.Result, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *fakeAnalyzer) Template() string {
+	return ""
+}
+
+func (a *fakeAnalyzer) Decode(data []byte) (analyzer.Result, error) {
+	var r fakeResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// testRows -> a row every analyzer finished, one where an analyzer failed, and one where both failed.
+func testRows() []*postgres.BatchRow {
+	return []*postgres.BatchRow{
+		{
+			RowIndex: 0,
+			Source:   json.RawMessage(`{"id":"1","text":"good","tone_label":"stale"}`),
+			Results: map[string]json.RawMessage{
+				"tone":  json.RawMessage(`{"label":"positive","score":0.5}`),
+				"topic": json.RawMessage(`{"label":"food","score":1}`),
+			},
+		},
+		{
+			RowIndex: 1,
+			Source:   json.RawMessage(`{"id":"2","text":"half"}`),
+			Results:  map[string]json.RawMessage{"tone": json.RawMessage(`{"label":"neutral","score":0}`)},
+			Errors:   map[string]string{"topic": "upstream unavailable"},
+		},
+		{
+			RowIndex: 2,
+			Source:   json.RawMessage(`{"id":"3","text":""}`),
+			Errors:   map[string]string{"topic": "text is empty", "tone": "text is empty"},
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	analyzers := []analyzer.Analyzer{&fakeAnalyzer{name: "tone"}, &fakeAnalyzer{name: "topic"}}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, []string{"id", "text", "tone_label"}, analyzers, testRows()); err != nil {
+		t.Fatal(err)
+	}
+	// tone_label of the upload is replaced by the analyzer's; errors are per row, sorted by analyzer.
+	want := "id,text,tone_label,tone_score,topic_label,topic_score,errors\n" +
+		"1,good,positive,0.5,food,1,\n" +
+		"2,half,neutral,0,,,topic: upstream unavailable\n" +
+		"3,,,,,,tone: text is empty; topic: text is empty\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	analyzers := []analyzer.Analyzer{&fakeAnalyzer{name: "tone"}, &fakeAnalyzer{name: "topic"}}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSONL, []string{"id", "text", "tone"}, analyzers, testRows()); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"1","text":"good","tone":{"label":"positive","score":0.5},"topic":{"label":"food","score":1},"errors":{}}` + "\n" +
+		`{"id":"2","text":"half","tone":{"label":"neutral","score":0},"topic":null,"errors":{"topic":"upstream unavailable"}}` + "\n" +
+		`{"id":"3","text":"","tone":null,"topic":null,"errors":{"tone":"text is empty","topic":"text is empty"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteJSONL() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteCSVUndecodableResult(t *testing.T) {
+	rows := []*postgres.BatchRow{{
+		RowIndex: 7,
+		Source:   json.RawMessage(`{"text":"hi"}`),
+		Results:  map[string]json.RawMessage{"tone": json.RawMessage(`"not an object"`)},
+	}}
+	err := WriteCSV(&bytes.Buffer{}, []string{"text"}, []analyzer.Analyzer{&fakeAnalyzer{name: "tone"}}, rows)
+	if err == nil || !strings.Contains(err.Error(), "row 7: failed to decode tone result") {
+		t.Errorf("WriteCSV() error = %v, want the row and analyzer named", err)
+	}
+}
+
+func TestParseWriteRoundTrip(t *testing.T) {
+	parsed, err := Parse(strings.NewReader("id,text\n1,\"multi\nline, \"\"quoted\"\"\"\n"), FormatCSV, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []*postgres.BatchRow{{RowIndex: 0, Source: parsed.Rows[0].Source}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, parsed.Columns, nil, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,text,errors\n1,\"multi\nline, \"\"quoted\"\"\",\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", buf.String(), want)
+	}
+}
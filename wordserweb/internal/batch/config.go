@@ -0,0 +1,39 @@
+package batch
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// Config -> upload limits and how hard a batch may push wordser.
+type Config struct {
+	MaxUploadBytes int64 `mapstructure:"BATCH_MAX_UPLOAD_BYTES"`
+	MaxRows        int   `mapstructure:"BATCH_MAX_ROWS"`
+	// Concurrency -> rows of one batch analyzed at the same time.
+	Concurrency int64 `mapstructure:"BATCH_CONCURRENCY"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("BATCH_MAX_UPLOAD_BYTES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'BATCH_MAX_UPLOAD_BYTES'")
+	}
+	viper.SetDefault("BATCH_MAX_UPLOAD_BYTES", 10<<20)
+
+	if err := viper.BindEnv("BATCH_MAX_ROWS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'BATCH_MAX_ROWS'")
+	}
+	viper.SetDefault("BATCH_MAX_ROWS", 10000)
+
+	if err := viper.BindEnv("BATCH_CONCURRENCY"); err != nil {
+		return c, fmt.Errorf("failed to bind 'BATCH_CONCURRENCY'")
+	}
+	viper.SetDefault("BATCH_CONCURRENCY", 4)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,186 @@
+package chunking
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const minMaxChars = 100
+
+var (
+	paragraphRe = regexp.MustCompile(`\n[ \t\r]*\n\s*`)
+	// sentenceEndRe -> terminal punctuation, closing quotes or brackets, then whitespace.
+	sentenceEndRe = regexp.MustCompile(`[.!?…。！？]+["'”’)\]]*\s+`)
+)
+
+type Chunk struct {
+	Index int
+	Text  string
+}
+
+// Len -> the chunk length in runes, used to weight merged results.
+func (c Chunk) Len() int {
+	return utf8.RuneCountInString(c.Text)
+}
+
+type Chunker struct {
+	maxChars    int
+	concurrency int64
+}
+
+func New(config Config) *Chunker {
+	maxChars := config.MaxChars
+	if maxChars < minMaxChars {
+		maxChars = minMaxChars
+	}
+	concurrency := config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Chunker{
+		maxChars:    maxChars,
+		concurrency: concurrency,
+	}
+}
+
+func (c *Chunker) MaxChars() int {
+	return c.maxChars
+}
+
+func (c *Chunker) Concurrency() int64 {
+	return c.concurrency
+}
+
+// piece -> a unit that is never split further. paragraph marks the first piece of a paragraph.
+type piece struct {
+	text      string
+	paragraph bool
+}
+
+// Split -> txt in chunks of at most MaxChars runes. it cuts between paragraphs when it can, then between
+// sentences, then between words, and only cuts inside a word that is longer than a chunk on its own.
+func (c *Chunker) Split(txt string) []Chunk {
+	txt = strings.TrimSpace(txt)
+	if utf8.RuneCountInString(txt) <= c.maxChars {
+		return []Chunk{{Index: 0, Text: txt}}
+	}
+
+	var pieces []piece
+	for _, paragraph := range paragraphRe.Split(txt, -1) {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		first := true
+		add := func(text string) {
+			pieces = append(pieces, piece{text: text, paragraph: first})
+			first = false
+		}
+		if utf8.RuneCountInString(paragraph) <= c.maxChars {
+			add(paragraph)
+			continue
+		}
+		for _, sentence := range Sentences(paragraph) {
+			if utf8.RuneCountInString(sentence) <= c.maxChars {
+				add(sentence)
+				continue
+			}
+			for _, words := range c.splitWords(sentence) {
+				add(words)
+			}
+		}
+	}
+
+	var chunks []Chunk
+	var current strings.Builder
+	currentLen := 0
+	for _, p := range pieces {
+		sep := " "
+		if p.paragraph {
+			sep = "\n\n"
+		}
+		pLen := utf8.RuneCountInString(p.text)
+		if currentLen > 0 && currentLen+len(sep)+pLen > c.maxChars {
+			chunks = append(chunks, Chunk{Index: len(chunks), Text: current.String()})
+			current.Reset()
+			currentLen = 0
+		}
+		if currentLen > 0 {
+			current.WriteString(sep)
+			currentLen += len(sep)
+		}
+		current.WriteString(p.text)
+		currentLen += pLen
+	}
+	if currentLen > 0 {
+		chunks = append(chunks, Chunk{Index: len(chunks), Text: current.String()})
+	}
+	return chunks
+}
+
+// SentenceEnds -> where the sentences of txt end: for each, the index after its terminal punctuation and any
+// closing quotes or brackets, and the index after the whitespace that follows. what comes after the last end is a
+// sentence without terminal punctuation. abbreviations and the like end a sentence too.
+func SentenceEnds(txt string) [][2]int {
+	locs := sentenceEndRe.FindAllStringIndex(txt, -1)
+	ends := make([][2]int, 0, len(locs))
+	for _, loc := range locs {
+		end := loc[0] + len(strings.TrimRightFunc(txt[loc[0]:loc[1]], unicode.IsSpace))
+		ends = append(ends, [2]int{end, loc[1]})
+	}
+	return ends
+}
+
+// Sentences -> paragraph split into trimmed sentences at SentenceEnds.
+func Sentences(paragraph string) []string {
+	var sentences []string
+	start := 0
+	for _, end := range SentenceEnds(paragraph) {
+		if sentence := strings.TrimSpace(paragraph[start:end[0]]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = end[1]
+	}
+	if sentence := strings.TrimSpace(paragraph[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// splitWords -> sentence packed into runs of words of at most maxChars runes.
+func (c *Chunker) splitWords(sentence string) []string {
+	var parts []string
+	var current []string
+	currentLen := 0
+	flush := func() {
+		if len(current) > 0 {
+			parts = append(parts, strings.Join(current, " "))
+			current = nil
+			currentLen = 0
+		}
+	}
+	for _, word := range strings.Fields(sentence) {
+		runes := []rune(word)
+		for len(runes) > c.maxChars {
+			flush()
+			parts = append(parts, string(runes[:c.maxChars]))
+			runes = runes[c.maxChars:]
+		}
+		wordLen := len(runes)
+		if wordLen == 0 {
+			continue
+		}
+		if currentLen > 0 && currentLen+1+wordLen > c.maxChars {
+			flush()
+		}
+		if currentLen > 0 {
+			currentLen++
+		}
+		current = append(current, string(runes))
+		currentLen += wordLen
+	}
+	flush()
+	return parts
+}
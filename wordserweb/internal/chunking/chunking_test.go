@@ -0,0 +1,138 @@
+package chunking
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		want []string
+	}{
+		{name: "empty", txt: "", want: nil},
+		{name: "whitespace", txt: " \n\t ", want: nil},
+		{name: "no terminal punctuation", txt: "One sentence", want: []string{"One sentence"}},
+		{
+			name: "terminal punctuation",
+			txt:  "First one. Second one!  Third?\nFourth",
+			want: []string{"First one.", "Second one!", "Third?", "Fourth"},
+		},
+		{
+			name: "closing quotes and brackets",
+			txt:  `He said "stop." Then (quietly.) left.`,
+			want: []string{`He said "stop."`, "Then (quietly.)", "left."},
+		},
+		{name: "ellipsis", txt: "Wait… what... no", want: []string{"Wait…", "what...", "no"}},
+		{name: "no space after the point", txt: "3.14 is pi.", want: []string{"3.14 is pi."}},
+		{name: "cjk", txt: "今日は。 明日も！ 本当？ ", want: []string{"今日は。", "明日も！", "本当？"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sentences(tt.txt); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sentences(%q) = %q, want %q", tt.txt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentenceEnds(t *testing.T) {
+	txt := "One.  Two?) Three"
+	want := [][2]int{{4, 6}, {11, 12}}
+	if got := SentenceEnds(txt); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SentenceEnds(%q) = %v, want %v", txt, got, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	c := New(Config{MaxChars: 10, Concurrency: 0})
+	if c.MaxChars() != minMaxChars || c.Concurrency() != 1 {
+		t.Errorf("New() = max chars %d, concurrency %d; want %d, 1", c.MaxChars(), c.Concurrency(), minMaxChars)
+	}
+}
+
+// sentence -> a sentence of n runes, "aaa aaa... a." with words of up to 9 letters.
+func sentence(n int) string {
+	var b strings.Builder
+	for b.Len() < n-1 {
+		if b.Len() > 0 && b.Len()%10 == 9 {
+			b.WriteByte(' ')
+			continue
+		}
+		b.WriteByte('a')
+	}
+	return strings.TrimSpace(b.String()) + "."
+}
+
+func TestSplit(t *testing.T) {
+	long := strings.Repeat("x", 250)
+	tests := []struct {
+		name string
+		txt  string
+		want []string
+	}{
+		{name: "empty", txt: "", want: []string{""}},
+		{name: "whitespace", txt: " \n\n\t ", want: []string{""}},
+		{name: "fits", txt: "  short text.\n\nSecond paragraph.  ", want: []string{"short text.\n\nSecond paragraph."}},
+		{
+			name: "exactly max chars",
+			txt:  sentence(100),
+			want: []string{sentence(100)},
+		},
+		{
+			name: "between paragraphs",
+			txt:  sentence(60) + "\n\n" + sentence(30) + "\n  \n" + sentence(50),
+			want: []string{sentence(60) + "\n\n" + sentence(30), sentence(50)},
+		},
+		{
+			name: "between sentences of a long paragraph",
+			txt:  sentence(40) + " " + sentence(40) + " " + sentence(40) + " " + sentence(40),
+			want: []string{sentence(40) + " " + sentence(40), sentence(40) + " " + sentence(40)},
+		},
+		{
+			name: "between words of a long sentence",
+			txt:  strings.TrimSuffix(strings.Repeat("word ", 50), " "),
+			want: []string{
+				strings.TrimSuffix(strings.Repeat("word ", 20), " "),
+				strings.TrimSuffix(strings.Repeat("word ", 20), " "),
+				strings.TrimSuffix(strings.Repeat("word ", 10), " "),
+			},
+		},
+		{
+			name: "inside a word longer than a chunk",
+			txt:  "before " + long + " after",
+			want: []string{"before", long[:100], long[100:200], long[200:] + " after"},
+		},
+		{
+			name: "runes not bytes",
+			txt:  strings.Repeat("é", 150),
+			want: []string{strings.Repeat("é", 100), strings.Repeat("é", 50)},
+		},
+	}
+	c := New(Config{MaxChars: 100})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := c.Split(tt.txt)
+			got := make([]string, 0, len(chunks))
+			for i, chunk := range chunks {
+				if chunk.Index != i {
+					t.Errorf("chunk %d has Index %d", i, chunk.Index)
+				}
+				if chunk.Len() > c.MaxChars() || chunk.Len() != utf8.RuneCountInString(chunk.Text) {
+					t.Errorf("chunk %d is %d runes, over %d", i, chunk.Len(), c.MaxChars())
+				}
+				got = append(got, chunk.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Split() = %q, want %q", got, tt.want)
+			}
+			// no text is lost or repeated across chunks: they don't overlap.
+			if joined := strings.Join(got, ""); strings.Join(strings.Fields(joined), "") != strings.Join(strings.Fields(tt.txt), "") {
+				t.Errorf("Split() chunks joined = %q, want the text of %q", joined, tt.txt)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package chunking
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// MaxChars -> longest chunk sent to a single analyzer call, in runes.
+	MaxChars int `mapstructure:"ANALYZE_CHUNK_MAX_CHARS"`
+	// Concurrency -> chunks of one analyzer analyzed at the same time.
+	Concurrency int64 `mapstructure:"ANALYZE_CHUNK_CONCURRENCY"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	// keeps wordser's ?txt= query well under url limits and roughly inside the models' input windows.
+	if err := viper.BindEnv("ANALYZE_CHUNK_MAX_CHARS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'ANALYZE_CHUNK_MAX_CHARS'")
+	}
+	viper.SetDefault("ANALYZE_CHUNK_MAX_CHARS", 2000)
+
+	if err := viper.BindEnv("ANALYZE_CHUNK_CONCURRENCY"); err != nil {
+		return c, fmt.Errorf("failed to bind 'ANALYZE_CHUNK_CONCURRENCY'")
+	}
+	viper.SetDefault("ANALYZE_CHUNK_CONCURRENCY", 4)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
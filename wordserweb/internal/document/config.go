@@ -0,0 +1,39 @@
+package document
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// MaxChars -> length of the longest document translated.
+	MaxChars int `mapstructure:"DOCUMENT_MAX_CHARS"`
+	// BatchSegments/BatchChars -> most segments, and runes of them, sent to a provider in one translation.
+	BatchSegments int `mapstructure:"DOCUMENT_BATCH_SEGMENTS"`
+	BatchChars    int `mapstructure:"DOCUMENT_BATCH_CHARS"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("DOCUMENT_MAX_CHARS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'DOCUMENT_MAX_CHARS'")
+	}
+	viper.SetDefault("DOCUMENT_MAX_CHARS", 100000)
+
+	if err := viper.BindEnv("DOCUMENT_BATCH_SEGMENTS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'DOCUMENT_BATCH_SEGMENTS'")
+	}
+	viper.SetDefault("DOCUMENT_BATCH_SEGMENTS", 25)
+
+	if err := viper.BindEnv("DOCUMENT_BATCH_CHARS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'DOCUMENT_BATCH_CHARS'")
+	}
+	viper.SetDefault("DOCUMENT_BATCH_CHARS", 3000)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,216 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+func (f Format) String() string {
+	return string(f)
+}
+
+// ParseFormat -> the format named s, "markdown", "md" or "html".
+func ParseFormat(s string) (Format, bool) {
+	switch strings.ToLower(s) {
+	case "markdown", "md":
+		return FormatMarkdown, true
+	case "html", "htm":
+		return FormatHTML, true
+	default:
+		return "", false
+	}
+}
+
+var ErrEmpty = errors.New("document has no text to translate")
+
+var (
+	// placeholderPattern -> what stands in for markup inside the text sent to a provider, "⟪0⟫". unlike the
+	// glossaries' "⟦0⟧" so both can be in one text.
+	placeholderPattern = regexp.MustCompile(`⟪\s*(\d+)\s*⟫`)
+	spacesRe           = regexp.MustCompile(`\s+`)
+)
+
+// partKind -> how a stretch of a segment's source is sent for translation.
+type partKind int
+
+const (
+	// partText -> human-readable text, translated.
+	partText partKind = iota
+	// partMarkup -> markup kept as it is: tags, code, urls, emphasis markers, line breaks. sent as a placeholder.
+	partMarkup
+	// partSpace -> a markdown soft line break and the prefix of the line after it, sent as a space.
+	partSpace
+)
+
+type part struct {
+	start int
+	end   int
+	kind  partKind
+}
+
+// segment -> a run of text, a paragraph or heading say, translated as one line. the source between start and end
+// is replaced by its translation, the markup in it put back where the placeholders standing in for it end up.
+type segment struct {
+	start int
+	end   int
+	// text -> what is sent, markup replaced by placeholders numbered by their index in markup.
+	text       string
+	markup     []string
+	translated string
+	err        error
+}
+
+// Document -> a markdown or html document split into the segments translated, the rest of it kept as it is.
+type Document struct {
+	Format   Format
+	source   string
+	segments []*segment
+}
+
+// Parse -> the segments of txt: the human-readable text, leaving out code, urls, tags and their attributes.
+func Parse(txt string, format Format) (*Document, error) {
+	d := &Document{Format: format, source: txt}
+	var parts [][]part
+	switch format {
+	case FormatMarkdown:
+		parts = markdownParts(txt)
+	case FormatHTML:
+		parts = htmlParts(txt)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	for _, p := range parts {
+		if s := d.newSegment(p); s != nil {
+			d.segments = append(d.segments, s)
+		}
+	}
+	if len(d.segments) == 0 {
+		return nil, ErrEmpty
+	}
+	return d, nil
+}
+
+// partText -> the text of p as it is sent.
+func (d *Document) partText(p part) string {
+	switch {
+	case p.kind == partSpace:
+		return " "
+	case d.Format == FormatHTML:
+		return spacesRe.ReplaceAllString(html.UnescapeString(d.source[p.start:p.end]), " ")
+	default:
+		return unescapeMarkdown(d.source[p.start:p.end])
+	}
+}
+
+// newSegment -> the segment of parts, without the markup and spaces it starts or ends with. nil when it has no
+// words to translate.
+func (d *Document) newSegment(parts []part) *segment {
+	isBlank := func(p part) bool {
+		return p.kind != partText || strings.TrimSpace(d.source[p.start:p.end]) == ""
+	}
+	for len(parts) > 0 && isBlank(parts[0]) {
+		parts = parts[1:]
+	}
+	for len(parts) > 0 && isBlank(parts[len(parts)-1]) {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	first, last := &parts[0], &parts[len(parts)-1]
+	first.start = first.end - len(strings.TrimLeftFunc(d.source[first.start:first.end], unicode.IsSpace))
+	last.end = last.start + len(strings.TrimRightFunc(d.source[last.start:last.end], unicode.IsSpace))
+
+	s := &segment{start: parts[0].start, end: parts[len(parts)-1].end}
+	var b strings.Builder
+	words := false
+	for i, p := range parts {
+		if p.kind != partMarkup {
+			txt := d.partText(p)
+			words = words || strings.IndexFunc(txt, func(r rune) bool {
+				return unicode.IsLetter(r) || unicode.IsDigit(r)
+			}) >= 0
+			b.WriteString(txt)
+			continue
+		}
+		// markup right after markup is one placeholder.
+		if i > 0 && parts[i-1].kind == partMarkup {
+			s.markup[len(s.markup)-1] += d.source[p.start:p.end]
+			continue
+		}
+		fmt.Fprintf(&b, "⟪%d⟫", len(s.markup))
+		s.markup = append(s.markup, d.source[p.start:p.end])
+	}
+	if !words {
+		return nil
+	}
+	s.text = strings.TrimSpace(b.String())
+	return s
+}
+
+// restore -> translated with the markup back in place of its placeholders, text escaped for the document's format.
+// each placeholder must be there once; a translation that lost markup would break links or tags.
+func (d *Document) restore(s *segment, translated string) (string, error) {
+	escape := func(txt string, start bool) string { return escapeMarkdown(txt, start) }
+	if d.Format == FormatHTML {
+		escape = func(txt string, _ bool) string { return html.EscapeString(txt) }
+	}
+	seen := make([]bool, len(s.markup))
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(translated, -1) {
+		i, err := strconv.Atoi(translated[loc[2]:loc[3]])
+		if err != nil || i >= len(s.markup) || seen[i] {
+			return "", errors.New("the translation mangled the document's markup")
+		}
+		seen[i] = true
+		b.WriteString(escape(translated[last:loc[0]], last == 0))
+		b.WriteString(s.markup[i])
+		last = loc[1]
+	}
+	b.WriteString(escape(translated[last:], last == 0))
+	for _, ok := range seen {
+		if !ok {
+			return "", errors.New("the translation lost some of the document's markup")
+		}
+	}
+	return b.String(), nil
+}
+
+// Text -> the text of d's segments without their markup, one per line, for detecting its language and metering.
+func (d *Document) Text() string {
+	texts := make([]string, 0, len(d.segments))
+	for _, s := range d.segments {
+		texts = append(texts, strings.TrimSpace(placeholderPattern.ReplaceAllString(s.text, "")))
+	}
+	return strings.Join(texts, "\n")
+}
+
+// String -> d with its translated segments in place of the original ones; failed segments as they were.
+func (d *Document) String() string {
+	var b strings.Builder
+	last := 0
+	for _, s := range d.segments {
+		b.WriteString(d.source[last:s.start])
+		if s.translated != "" && s.err == nil {
+			b.WriteString(s.translated)
+		} else {
+			b.WriteString(d.source[s.start:s.end])
+		}
+		last = s.end
+	}
+	b.WriteString(d.source[last:])
+	return b.String()
+}
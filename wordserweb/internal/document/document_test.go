@@ -0,0 +1,207 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const markdownSample = `---
+title: Notes
+---
+# Getting started
+
+Run ` + "`make build`" + ` to *build* the [project](https://example.com/docs "Docs").
+It takes a minute.
+
+- first item
+- second item with **bold**
+
+> quoted text
+
+` + "```go\nfmt.Println(\"hello\")\n```" + `
+
+| Name | Value |
+|------|-------|
+| size | large |
+
+<div class="note">raw html</div>
+
+![logo](logo.png) and https://example.com too.
+`
+
+const htmlSample = `<!DOCTYPE html>
+<html lang="en">
+<head><title>Hello page</title><style>p { color: red; }</style></head>
+<body>
+<!-- a comment -->
+<h1 class="title">Hello, world</h1>
+<p>Click <a href="/go" title="Go there">here</a> to continue &amp; win.</p>
+<p translate="no">Brand Name</p>
+<pre>  keep   this  </pre>
+<script>var greeting = "hello";</script>
+<p>Press <kbd>Ctrl</kbd>+<kbd>C</kbd><br>then leave.</p>
+<img src="a.png" alt="a picture">
+</body>
+</html>
+`
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{name: "markdown", format: FormatMarkdown, input: markdownSample},
+		{name: "markdown crlf", format: FormatMarkdown, input: strings.ReplaceAll(markdownSample, "\n", "\r\n")},
+		{name: "markdown entities", format: FormatMarkdown, input: "Tom &amp; Jerry \\*live\\*\n"},
+		{name: "html", format: FormatHTML, input: htmlSample},
+		{name: "html fragment", format: FormatHTML, input: "Just <b>some</b> text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Parse(tt.input, tt.format)
+			if err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			if got := d.String(); got != tt.input {
+				t.Fatalf("Parse().String() = %q, want %q", got, tt.input)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse("```\ncode only\n```\n", FormatMarkdown); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Parse(code block) = %v, want %v", err, ErrEmpty)
+	}
+	if _, err := Parse("<script>var a;</script>", FormatHTML); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Parse(script) = %v, want %v", err, ErrEmpty)
+	}
+	if _, err := Parse("text", Format("pdf")); err == nil {
+		t.Errorf("Parse(pdf) = nil, want an error")
+	}
+}
+
+// upper -> a TranslateFunc upper casing txt, which leaves placeholders as they are.
+func upper(_ context.Context, txt string) (string, error) {
+	return strings.ToUpper(txt), nil
+}
+
+func translateDocument(t *testing.T, input string, format Format, translate TranslateFunc) (string, Report) {
+	t.Helper()
+	d, err := Parse(input, format)
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	report := New(Config{BatchSegments: 50, BatchChars: 5000}).Translate(context.Background(), d, translate)
+	return d.String(), report
+}
+
+func TestTranslateMarkdown(t *testing.T) {
+	got, report := translateDocument(t, markdownSample, FormatMarkdown, upper)
+	if len(report.Failures) != 0 {
+		t.Fatalf("Translate() failures = %+v", report.Failures)
+	}
+	want := `---
+title: Notes
+---
+# GETTING STARTED
+
+RUN ` + "`make build`" + ` TO *BUILD* THE [PROJECT](https://example.com/docs "Docs"). IT TAKES A MINUTE.
+
+- FIRST ITEM
+- SECOND ITEM WITH **BOLD**
+
+> QUOTED TEXT
+
+` + "```go\nfmt.Println(\"hello\")\n```" + `
+
+| NAME | VALUE |
+|------|-------|
+| SIZE | LARGE |
+
+<div class="note">raw html</div>
+
+![logo](logo.png) AND https://example.com TOO.
+`
+	// a paragraph is translated as one line, its soft line breaks becoming spaces.
+	if got != want {
+		t.Fatalf("Translate() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTranslateHTML(t *testing.T) {
+	got, report := translateDocument(t, htmlSample, FormatHTML, upper)
+	if len(report.Failures) != 0 {
+		t.Fatalf("Translate() failures = %+v", report.Failures)
+	}
+	want := `<!DOCTYPE html>
+<html lang="en">
+<head><title>HELLO PAGE</title><style>p { color: red; }</style></head>
+<body>
+<!-- a comment -->
+<h1 class="title">HELLO, WORLD</h1>
+<p>CLICK <a href="/go" title="Go there">HERE</a> TO CONTINUE &amp; WIN.</p>
+<p translate="no">Brand Name</p>
+<pre>  keep   this  </pre>
+<script>var greeting = "hello";</script>
+<p>PRESS <kbd>Ctrl</kbd>+<kbd>C</kbd><br>THEN LEAVE.</p>
+<img src="a.png" alt="a picture">
+</body>
+</html>
+`
+	if got != want {
+		t.Fatalf("Translate() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTranslateFallback(t *testing.T) {
+	input := "First paragraph.\n\nSecond [link](https://example.com).\n\nThird one.\n"
+	var calls int
+	// a batch comes back as one line, so its segments are translated again one by one.
+	got, report := translateDocument(t, input, FormatMarkdown, func(ctx context.Context, txt string) (string, error) {
+		calls++
+		return upper(ctx, strings.ReplaceAll(txt, "\n", " "))
+	})
+	if want := "FIRST PARAGRAPH.\n\nSECOND [LINK](https://example.com).\n\nTHIRD ONE.\n"; got != want {
+		t.Fatalf("Translate() = %q, want %q", got, want)
+	}
+	if calls != 4 {
+		t.Errorf("translate called %d times, want 4", calls)
+	}
+	// billed once, on the text without its markup, however often it was sent.
+	if want := len("First paragraph.") + len("Second link.") + len("Third one."); report.Chars != want {
+		t.Errorf("Report.Chars = %d, want %d", report.Chars, want)
+	}
+}
+
+func TestTranslateFailures(t *testing.T) {
+	input := "Keep [this](https://example.com) link.\n\nFail here.\n\nFine.\n"
+	d, err := Parse(input, FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	// batches of one segment, so the provider failing one fails only it.
+	documents := New(Config{BatchSegments: 1, BatchChars: 5000})
+	report := documents.Translate(context.Background(), d, func(ctx context.Context, txt string) (string, error) {
+		if strings.Contains(txt, "Fail") {
+			return "", errors.New("provider down")
+		}
+		// the translation loses the link's placeholders.
+		return upper(ctx, placeholderPattern.ReplaceAllString(txt, ""))
+	})
+	if len(report.Failures) != 2 || report.Translated() != 1 {
+		t.Fatalf("Translate() = %+v, want the link and the failing segment failed", report)
+	}
+	if report.Failures[0].Text != "Keep this link." || report.Failures[1].Error != "provider down" {
+		t.Fatalf("Failures = %+v", report.Failures)
+	}
+	if want := "Keep [this](https://example.com) link.\n\nFail here.\n\nFINE.\n"; d.String() != want {
+		t.Fatalf("String() = %q, want %q", d.String(), want)
+	}
+	if want := len("Fine."); report.Chars != want {
+		t.Fatalf("Report.Chars = %d, want %d", report.Chars, want)
+	}
+}
@@ -0,0 +1,113 @@
+package document
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// inlineElements -> elements inside a run of text, translated along with it. their tags are markup.
+	inlineElements = map[string]bool{
+		"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "br": true, "cite": true, "data": true,
+		"del": true, "dfn": true, "em": true, "font": true, "i": true, "img": true, "ins": true, "label": true,
+		"mark": true, "q": true, "s": true, "small": true, "span": true, "strong": true, "sub": true, "sup": true,
+		"time": true, "u": true, "wbr": true,
+	}
+	// codeElements -> inline elements kept whole, like an element with translate="no".
+	codeElements = map[string]bool{"code": true, "kbd": true, "samp": true, "var": true}
+	// skippedElements -> elements whose content isn't human-readable text, kept whole.
+	skippedElements = map[string]bool{
+		"script": true, "style": true, "pre": true, "textarea": true, "template": true, "svg": true, "math": true,
+	}
+	voidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+		"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+	}
+)
+
+// noTranslate -> the element has translate="no", html's way of marking text that isn't to be translated.
+func noTranslate(tok html.Token) bool {
+	for _, a := range tok.Attr {
+		if a.Key == "translate" && strings.EqualFold(strings.TrimSpace(a.Val), "no") {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlParts -> the runs of text of txt and the inline elements in them, a run ending at any other tag. the
+// document isn't parsed into a tree but tokenized, so all of it but the translated text is written back byte for
+// byte.
+func htmlParts(txt string) [][]part {
+	var runs [][]part
+	var run []part
+	flush := func() {
+		if len(run) > 0 {
+			runs = append(runs, run)
+			run = nil
+		}
+	}
+
+	// skipping -> the element being kept whole, its depth counting elements of the same name inside it.
+	var skipping string
+	var depth int
+	var skippingInline bool
+
+	z := html.NewTokenizer(strings.NewReader(txt))
+	pos := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		p := part{start: pos, end: pos + len(z.Raw()), kind: partMarkup}
+		pos = p.end
+		tok := z.Token()
+
+		if skipping != "" {
+			if skippingInline {
+				run = append(run, p)
+			}
+			switch {
+			case tt == html.StartTagToken && tok.Data == skipping:
+				depth++
+			case tt == html.EndTagToken && tok.Data == skipping:
+				depth--
+			}
+			if depth == 0 {
+				skipping = ""
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			p.kind = partText
+			run = append(run, p)
+		case html.CommentToken:
+			run = append(run, p)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			inline := inlineElements[tok.Data] || codeElements[tok.Data]
+			if inline {
+				run = append(run, p)
+			} else {
+				flush()
+			}
+			kept := skippedElements[tok.Data] || codeElements[tok.Data] || noTranslate(tok)
+			if tt == html.StartTagToken && kept && !voidElements[tok.Data] {
+				skipping, depth, skippingInline = tok.Data, 1, inline
+			}
+		case html.EndTagToken:
+			if inlineElements[tok.Data] || codeElements[tok.Data] {
+				run = append(run, p)
+			} else {
+				flush()
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return runs
+}
@@ -0,0 +1,146 @@
+package document
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	// frontMatterRe -> yaml front matter, which goldmark would take for a thematic break and a heading.
+	frontMatterRe = regexp.MustCompile(`^---[ \t]*\r?\n(?s:.*?)\r?\n---[ \t]*(?:\r?\n|$)`)
+	// softBreakRe -> what follows a soft line break up to the next line's text: the newline and a blockquote or list
+	// item's indentation.
+	softBreakRe = regexp.MustCompile(`^[ \t]*\r?\n[ \t>]*$`)
+	// markdownEscapeRe -> a backslash escaped ascii punctuation character or an entity, as text holds them.
+	markdownEscapeRe = regexp.MustCompile(`\\[!-/:-@\[-` + "`" + `{-~]|` + entityPattern)
+	// entityRe -> an entity at the start of a string, which an "&" there would begin.
+	entityRe = regexp.MustCompile(`^` + entityPattern)
+)
+
+const entityPattern = `&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`
+
+// unescapeMarkdown -> markdown text as it reads: backslash escapes and entities decoded.
+func unescapeMarkdown(txt string) string {
+	return markdownEscapeRe.ReplaceAllStringFunc(txt, func(escape string) string {
+		if escape[0] == '\\' {
+			return escape[1:]
+		}
+		return html.UnescapeString(escape)
+	})
+}
+
+// escapeMarkdown -> txt escaped to be markdown text, not markup: characters that would start emphasis, code, links,
+// html, table cells or an entity are backslash escaped, and those that would start a heading, quote or list when
+// txt starts a block.
+func escapeMarkdown(txt string, start bool) string {
+	var b strings.Builder
+	prev := rune(-1)
+	for i, r := range txt {
+		escape := false
+		switch r {
+		case '\\', '*', '`', '[', ']', '<', '|', '~':
+			escape = true
+		case '_':
+			// intraword underscores, snake_case say, can't be emphasis.
+			next, _ := utf8.DecodeRuneInString(txt[i+1:])
+			escape = !isWordRune(prev) || !isWordRune(next)
+		case '&':
+			escape = entityRe.MatchString(txt[i:])
+		case '#', '>', '-', '+', '=':
+			escape = start && i == 0
+		}
+		if escape {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// protectedInline -> inline nodes kept whole: code, autolinks and bare urls, raw html and images.
+func protectedInline(n ast.Node) bool {
+	switch n.Kind() {
+	case ast.KindCodeSpan, ast.KindAutoLink, ast.KindRawHTML, ast.KindImage:
+		return true
+	default:
+		return false
+	}
+}
+
+// markdownParts -> the parts of the blocks of txt holding inline content: paragraphs, headings, list items and table
+// cells. code blocks, html blocks and front matter have none. link text is text; the brackets and destination
+// around it are markup.
+func markdownParts(txt string) [][]part {
+	offset := len(frontMatterRe.FindString(txt))
+	source := []byte(txt[offset:])
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	var blocks [][]part
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Type() != ast.TypeBlock || n.FirstChild() == nil || n.FirstChild().Type() != ast.TypeInline {
+			return ast.WalkContinue, nil
+		}
+		var parts []part
+		// soft -> the last text ended its line with a soft break.
+		soft := false
+		_ = ast.Walk(n, func(inline ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering || inline == n {
+				return ast.WalkContinue, nil
+			}
+			if protectedInline(inline) {
+				return ast.WalkSkipChildren, nil
+			}
+			t, ok := inline.(*ast.Text)
+			if !ok {
+				return ast.WalkContinue, nil
+			}
+			start, end := t.Segment.Start+offset, t.Segment.Stop+offset
+			if len(parts) > 0 {
+				prev := parts[len(parts)-1].end
+				if start < prev {
+					// not in source order, as after a table's escaped pipe; left to the markup around it.
+					return ast.WalkContinue, nil
+				}
+				if start > prev {
+					kind := partMarkup
+					if soft && softBreakRe.MatchString(txt[prev:start]) {
+						kind = partSpace
+					}
+					parts = append(parts, part{start: prev, end: start, kind: kind})
+				}
+			}
+			if end > start {
+				parts = append(parts, part{start: start, end: end, kind: partText})
+			}
+			soft = t.SoftLineBreak()
+			return ast.WalkContinue, nil
+		})
+		blocks = append(blocks, parts)
+		return ast.WalkSkipChildren, nil
+	})
+	return blocks
+}
+
+// markdownHTML -> txt rendered as html. raw html is left out and dangerous links dropped, goldmark's defaults.
+func markdownHTML(txt string) (string, error) {
+	var b bytes.Buffer
+	if err := markdown.Convert([]byte(txt), &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
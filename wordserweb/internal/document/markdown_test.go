@@ -0,0 +1,72 @@
+package document
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUnescapeMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"A &amp; B":            "A & B",
+		`\*not emphasis\*`:     "*not emphasis*",
+		`a \| b`:               "a | b",
+		`back\\slash`:          `back\slash`,
+		"&#35;1 &#x41; &copy;": "#1 A ©",
+		`\&amp; stays`:         "&amp; stays",
+		"AT&T & co":            "AT&T & co",
+		`not \an escape`:       `not \an escape`,
+	}
+	for txt, want := range tests {
+		if got := unescapeMarkdown(txt); got != want {
+			t.Errorf("unescapeMarkdown(%q) = %q, want %q", txt, got, want)
+		}
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		txt   string
+		start bool
+		want  string
+	}{
+		{txt: "A & B", want: "A & B"},
+		{txt: "A &amp; B", want: `A \&amp; B`},
+		{txt: "2 * 3 < 7", want: `2 \* 3 \< 7`},
+		{txt: "a | b", want: `a \| b`},
+		{txt: "[not a link]", want: `\[not a link\]`},
+		{txt: "snake_case _emphasis_", want: `snake_case \_emphasis\_`},
+		{txt: `back\slash`, want: `back\\slash`},
+		{txt: "# not a heading", start: true, want: `\# not a heading`},
+		{txt: "- not a list", start: true, want: `\- not a list`},
+		{txt: "- a dash", want: "- a dash"},
+	}
+	for _, tt := range tests {
+		if got := escapeMarkdown(tt.txt, tt.start); got != tt.want {
+			t.Errorf("escapeMarkdown(%q, %v) = %q, want %q", tt.txt, tt.start, got, tt.want)
+		}
+	}
+}
+
+// TestMarkdownEntities -> text is sent decoded and comes back escaped, so a provider never sees "&amp;".
+func TestMarkdownEntities(t *testing.T) {
+	d, err := Parse("Tom &amp; Jerry \\*live\\* | here\n", FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	if got, want := d.Text(), "Tom & Jerry *live* | here"; got != want {
+		t.Fatalf("Text() = %q, want %q", got, want)
+	}
+	var sent string
+	documents := New(Config{BatchSegments: 10, BatchChars: 1000})
+	documents.Translate(context.Background(), d, func(_ context.Context, txt string) (string, error) {
+		sent = txt
+		return strings.ToUpper(txt), nil
+	})
+	if strings.Contains(sent, "&amp;") {
+		t.Fatalf("sent %q", sent)
+	}
+	if got, want := d.String(), "TOM & JERRY \\*LIVE\\* \\| HERE\n"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package document
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// TranslateFunc -> txt translated. txt holds one segment per line and the translation must too.
+type TranslateFunc func(ctx context.Context, txt string) (string, error)
+
+// Failure -> a segment left untranslated.
+type Failure struct {
+	// Text -> the segment's text, its markup left out.
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+// Report -> how translating a document went. failed segments keep their text.
+type Report struct {
+	Segments int       `json:"segments"`
+	Failures []Failure `json:"failures,omitempty"`
+	// Chars -> the characters of the segments translated, their markup left out; what is billed. segments sent
+	// again after a batch came back short and failed segments don't count.
+	Chars int `json:"chars"`
+}
+
+func (r Report) Translated() int {
+	return r.Segments - len(r.Failures)
+}
+
+// Documents -> translation of the segments of markdown and html documents in batches.
+type Documents struct {
+	config Config
+}
+
+func New(config Config) *Documents {
+	return &Documents{config: config}
+}
+
+// Translate -> the segments of d translated in place with translate, batches of segments at a time. a batch whose
+// translation doesn't come back with as many lines is translated again segment by segment. segments that failed,
+// or whose translation lost markup, keep their text and are reported.
+func (ds *Documents) Translate(ctx context.Context, d *Document, translate TranslateFunc) Report {
+	for start := 0; start < len(d.segments); {
+		end, chars := start, 0
+		for end < len(d.segments) && end-start < max(ds.config.BatchSegments, 1) {
+			n := utf8.RuneCountInString(d.segments[end].text)
+			if end > start && chars+n > ds.config.BatchChars {
+				break
+			}
+			chars += n
+			end++
+		}
+		ds.translateBatch(ctx, d, d.segments[start:end], translate)
+		start = end
+	}
+
+	report := Report{Segments: len(d.segments)}
+	for _, s := range d.segments {
+		txt := strings.TrimSpace(placeholderPattern.ReplaceAllString(s.text, ""))
+		if s.err != nil {
+			report.Failures = append(report.Failures, Failure{Text: txt, Error: s.err.Error()})
+			continue
+		}
+		report.Chars += utf8.RuneCountInString(txt)
+	}
+	return report
+}
+
+// translateBatch -> set the translation, with its markup restored, or the error of batch's segments.
+func (ds *Documents) translateBatch(ctx context.Context, d *Document, batch []*segment, translate TranslateFunc) {
+	texts := make([]string, len(batch))
+	for i, s := range batch {
+		texts[i] = s.text
+	}
+	translated, err := translate(ctx, strings.Join(texts, "\n"))
+	if err != nil {
+		for _, s := range batch {
+			s.err = err
+		}
+		return
+	}
+	var lines []string
+	for _, line := range strings.Split(translated, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(batch) == 1 {
+		// one segment may come back as several lines.
+		batch[0].translated, batch[0].err = d.restore(batch[0], strings.Join(lines, " "))
+		return
+	}
+	if len(lines) != len(batch) {
+		for _, s := range batch {
+			ds.translateBatch(ctx, d, []*segment{s}, translate)
+		}
+		return
+	}
+	for i, s := range batch {
+		s.translated, s.err = d.restore(s, lines[i])
+	}
+}
+
+// Preview -> d, as it is now, as html to show it rendered: markdown converted, without its front matter, and html
+// as it is.
+func (d *Document) Preview() (string, error) {
+	txt := d.String()
+	if d.Format == FormatHTML {
+		return txt, nil
+	}
+	return markdownHTML(txt[len(frontMatterRe.FindString(txt)):])
+}
@@ -0,0 +1,48 @@
+package fetch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// Timeout -> the whole fetch, redirects and body included.
+	Timeout time.Duration `mapstructure:"FETCH_TIMEOUT"`
+	// MaxBytes -> body bytes read; the rest of a larger page is cut off.
+	MaxBytes     int64 `mapstructure:"FETCH_MAX_BYTES"`
+	MaxRedirects int   `mapstructure:"FETCH_MAX_REDIRECTS"`
+	// Allowlist -> comma separated hosts, ips and cidrs that may be fetched even though they are private,
+	// loopback or otherwise internal.
+	Allowlist string `mapstructure:"FETCH_ALLOWLIST"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("FETCH_TIMEOUT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'FETCH_TIMEOUT'")
+	}
+	viper.SetDefault("FETCH_TIMEOUT", "10s")
+
+	if err := viper.BindEnv("FETCH_MAX_BYTES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'FETCH_MAX_BYTES'")
+	}
+	viper.SetDefault("FETCH_MAX_BYTES", 2<<20)
+
+	if err := viper.BindEnv("FETCH_MAX_REDIRECTS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'FETCH_MAX_REDIRECTS'")
+	}
+	viper.SetDefault("FETCH_MAX_REDIRECTS", 5)
+
+	if err := viper.BindEnv("FETCH_ALLOWLIST"); err != nil {
+		return c, fmt.Errorf("failed to bind 'FETCH_ALLOWLIST'")
+	}
+	viper.SetDefault("FETCH_ALLOWLIST", "")
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
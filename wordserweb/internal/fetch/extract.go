@@ -0,0 +1,230 @@
+package fetch
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Document -> what Extract gets out of a page.
+type Document struct {
+	Title       string
+	Description string
+	// Lang -> the lang attribute of <html>, if any.
+	Lang string
+	// Text -> the readable text, one paragraph per block element, separated by blank lines.
+	Text string
+}
+
+// skipTags -> elements whose text is never part of the readable text.
+var skipTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true, "svg": true, "canvas": true,
+	"iframe": true, "object": true, "nav": true, "header": true, "footer": true, "aside": true, "form": true,
+	"button": true, "select": true, "textarea": true, "menu": true, "dialog": true,
+}
+
+// blockTags -> elements that start a new paragraph.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "li": true, "ul": true, "ol": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "table": true, "tr": true, "td": true, "th": true, "figure": true,
+	"figcaption": true, "br": true, "hr": true, "body": true, "address": true, "details": true, "summary": true,
+}
+
+// containerTags -> elements whose classes describe the page or article as a whole, e.g. "has-sidebar" on <body>;
+// never taken for chrome.
+var containerTags = map[string]bool{"html": true, "body": true, "main": true, "article": true}
+
+// boilerplateWords -> class or id words of page chrome rather than content.
+var boilerplateWords = map[string]bool{
+	"nav": true, "navbar": true, "navigation": true, "menu": true, "header": true, "footer": true, "sidebar": true,
+	"breadcrumb": true, "breadcrumbs": true, "comments": true, "comment": true, "cookie": true, "cookies": true,
+	"consent": true, "share": true, "social": true, "related": true, "promo": true, "advert": true,
+	"advertisement": true, "ads": true, "newsletter": true, "subscribe": true, "banner": true, "popup": true,
+	"modal": true, "skip": true,
+}
+
+// maxLinkDensity -> share of a paragraph's text inside links above which it is taken for a list of links.
+const maxLinkDensity = 0.5
+
+type frame struct {
+	skip bool
+	main bool
+	link bool
+}
+
+type paragraph struct {
+	text string
+	main bool
+}
+
+type extractor struct {
+	paras   []paragraph
+	cur     strings.Builder
+	curLink int
+	curMain bool
+	title   string
+	ogTitle string
+	desc    string
+	lang    string
+}
+
+// Extract -> the readable text of an html page: the text of <article> or <main> when there is one, otherwise
+// of the whole body, without scripts, navigation, headers, footers and other page chrome. the page is parsed the
+// way a browser would, so unclosed and stray tags end up where a browser puts them.
+func Extract(page string) Document {
+	// html.Parse only fails when reading fails, which a strings.Reader doesn't.
+	root, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return Document{}
+	}
+	e := &extractor{}
+	e.walk(root, frame{})
+	e.flush()
+
+	doc := Document{Title: e.ogTitle, Description: e.desc, Lang: e.lang}
+	if doc.Title == "" {
+		doc.Title = collapseSpace(e.title)
+	}
+	hasMain := false
+	for _, p := range e.paras {
+		hasMain = hasMain || p.main
+	}
+	parts := make([]string, 0, len(e.paras))
+	for _, p := range e.paras {
+		if hasMain && !p.main {
+			continue
+		}
+		parts = append(parts, p.text)
+	}
+	doc.Text = strings.Join(parts, "\n\n")
+	return doc
+}
+
+// walk -> collect the text of n and everything under it, parent being what the elements around n make of it.
+func (e *extractor) walk(n *html.Node, parent frame) {
+	switch n.Type {
+	case html.TextNode:
+		e.text(n.Data, parent)
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			e.walk(c, parent)
+		}
+		return
+	}
+
+	tag := n.Data
+	attrs := make(map[string]string, len(n.Attr))
+	for _, a := range n.Attr {
+		attrs[a.Key] = a.Val
+	}
+	switch tag {
+	case "html":
+		e.lang = attrs["lang"]
+	case "meta":
+		e.meta(attrs)
+	case "title":
+		if e.title == "" {
+			e.title = nodeText(n)
+		}
+	}
+
+	f := frame{
+		skip: parent.skip || skipTags[tag] || (!containerTags[tag] && boilerplate(attrs)),
+		main: parent.main || tag == "article" || tag == "main" || attrs["role"] == "main",
+		link: parent.link || tag == "a",
+	}
+	if _, hidden := attrs["hidden"]; hidden || attrs["aria-hidden"] == "true" {
+		f.skip = true
+	}
+	if blockTags[tag] {
+		e.flush()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		e.walk(c, f)
+	}
+	if blockTags[tag] {
+		e.flush()
+	}
+}
+
+func (e *extractor) meta(attrs map[string]string) {
+	key := strings.ToLower(attrs["property"])
+	if key == "" {
+		key = strings.ToLower(attrs["name"])
+	}
+	content := collapseSpace(attrs["content"])
+	switch key {
+	case "og:title":
+		e.ogTitle = content
+	case "description":
+		e.desc = content
+	case "og:description":
+		if e.desc == "" {
+			e.desc = content
+		}
+	}
+}
+
+func (e *extractor) text(s string, f frame) {
+	if f.skip {
+		return
+	}
+	s = collapseSpace(s)
+	if s == "" {
+		return
+	}
+	if e.cur.Len() > 0 {
+		e.cur.WriteByte(' ')
+	}
+	e.cur.WriteString(s)
+	if f.link {
+		e.curLink += len(s)
+	}
+	e.curMain = e.curMain || f.main
+}
+
+func (e *extractor) flush() {
+	text := e.cur.String()
+	if text != "" && float64(e.curLink) <= maxLinkDensity*float64(len(text)) {
+		e.paras = append(e.paras, paragraph{text: text, main: e.curMain})
+	}
+	e.cur.Reset()
+	e.curLink = 0
+	e.curMain = false
+}
+
+// boilerplate -> the class, id or role of an element marks it as page chrome.
+func boilerplate(attrs map[string]string) bool {
+	switch attrs["role"] {
+	case "navigation", "banner", "contentinfo", "complementary", "search", "dialog":
+		return true
+	}
+	words := strings.FieldsFunc(strings.ToLower(attrs["class"]+" "+attrs["id"]), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '\t' || r == '\n'
+	})
+	for _, w := range words {
+		if boilerplateWords[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeText -> the text directly inside n, e.g. of a <title>.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.FieldsFunc(s, unicode.IsSpace), " ")
+}
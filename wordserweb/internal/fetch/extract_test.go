@@ -0,0 +1,88 @@
+package fetch
+
+import "testing"
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want Document
+	}{
+		{
+			name: "title, description and lang",
+			page: `<!DOCTYPE html><html lang="de"><head>
+				<title> Der   Titel </title>
+				<meta name="description" content="Eine &amp; Beschreibung">
+				</head><body><p>Hallo Welt.</p></body></html>`,
+			want: Document{Title: "Der Titel", Description: "Eine & Beschreibung", Lang: "de", Text: "Hallo Welt."},
+		},
+		{
+			name: "open graph title over title",
+			page: `<head><title>plain</title><meta property="og:title" content="Open Graph">
+				<meta property="og:description" content="og"></head><body>text</body>`,
+			want: Document{Title: "Open Graph", Description: "og", Text: "text"},
+		},
+		{
+			name: "article over the rest of the body",
+			page: `<body><div>Sign in</div><article><h1>Heading</h1><p>First <b>bold</b> paragraph.</p>
+				<p>Second&nbsp;one.</p></article><div>More stories</div></body>`,
+			want: Document{Text: "Heading\n\nFirst bold paragraph.\n\nSecond one."},
+		},
+		{
+			name: "role main",
+			page: `<body><p>chrome</p><div role="main"><p>content</p></div></body>`,
+			want: Document{Text: "content"},
+		},
+		{
+			name: "chrome, scripts and hidden elements skipped",
+			page: `<body><nav>Home | About</nav><header>Site</header>
+				<script>var x = "<p>not text</p>";</script><style>p { color: red }</style>
+				<div class="cookie-banner">We use cookies</div><div id="sidebar">Links</div>
+				<p hidden>hidden</p><p aria-hidden="true">also hidden</p>
+				<p>Body text.</p><footer>Copyright</footer></body>`,
+			want: Document{Text: "Body text."},
+		},
+		{
+			name: "container classes aren't chrome",
+			page: `<body class="has-sidebar"><p>Body text.</p></body>`,
+			want: Document{Text: "Body text."},
+		},
+		{
+			name: "list of links dropped",
+			page: `<body><p><a href="/a">one</a> <a href="/b">two</a> |</p><p>Read <a href="/c">this</a> article.</p></body>`,
+			want: Document{Text: "Read this article."},
+		},
+		{
+			name: "unclosed and stray tags",
+			page: `<body><p>one<p>two</span><li>three<div>four</b></div>`,
+			want: Document{Text: "one\n\ntwo\n\nthree\n\nfour"},
+		},
+		{
+			name: "comments and line breaks",
+			page: `<body><p>before<!-- <p>comment</p> -->after<br>next line</p></body>`,
+			want: Document{Text: "before after\n\nnext line"},
+		},
+		{
+			name: "tags in attribute values",
+			page: `<body><p title="a > b">kept</p><img alt="<p>not text</p>"></body>`,
+			want: Document{Text: "kept"},
+		},
+		{
+			name: "plain text",
+			page: "just some text",
+			want: Document{Text: "just some text"},
+		},
+		{
+			name: "empty",
+			page: "",
+			want: Document{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.page); got != tt.want {
+				t.Errorf("Extract() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidURL         = errors.New("invalid url")
+	ErrTooManyRedirects   = errors.New("too many redirects")
+	ErrUnsupportedContent = errors.New("unsupported content type")
+)
+
+// StatusError -> the page answered with something other than 2xx.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to fetch page; statusCode: %d", e.StatusCode)
+}
+
+// Page -> a fetched page's readable text and how it was fetched.
+type Page struct {
+	Document
+	// URL -> as requested. FinalURL is where redirects ended up.
+	URL         string
+	FinalURL    string
+	StatusCode  int
+	ContentType string
+	// Bytes -> body bytes read. Truncated when the body went on past Config.MaxBytes.
+	Bytes     int
+	Truncated bool
+	FetchedAt time.Time
+	Duration  time.Duration
+}
+
+// Fetcher -> fetches user supplied urls within time and size limits, and only from public addresses unless
+// allowlisted.
+type Fetcher struct {
+	config Config
+	http   *http.Client
+}
+
+func New(config Config) (*Fetcher, error) {
+	guard, err := NewGuard(strings.Split(config.Allowlist, ","))
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		// no proxy from the environment; it would dial on the guard's behalf.
+		Proxy:                 nil,
+		DialContext:           guard.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: config.Timeout,
+	}
+	return &Fetcher{
+		config: config,
+		http: &http.Client{
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > config.MaxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkURL(req.URL)
+			},
+		},
+	}, nil
+}
+
+func checkURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w; expected an absolute http or https url;", ErrInvalidURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w; credentials in urls aren't allowed;", ErrInvalidURL)
+	}
+	return nil
+}
+
+// Fetch -> GET rawURL and extract its readable text. html and plain text pages are supported.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("%w; %v;", ErrInvalidURL, err)
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	if f.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9")
+	req.Header.Set("User-Agent", "wordserweb")
+
+	start := time.Now()
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" && mediaType != "text/plain" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContent, mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.config.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{
+		URL:         u.String(),
+		FinalURL:    resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		ContentType: mediaType,
+		FetchedAt:   start,
+	}
+	if int64(len(body)) > f.config.MaxBytes {
+		// cut at a rune start so a utf-8 page still decodes as such.
+		n := int(f.config.MaxBytes)
+		for i := 0; i < utf8.UTFMax-1 && n > 0 && !utf8.RuneStart(body[n]); i++ {
+			n--
+		}
+		body = body[:n]
+		page.Truncated = true
+	}
+	page.Bytes = len(body)
+
+	text := decode(body, params["charset"])
+	if mediaType == "text/plain" {
+		page.Text = strings.TrimSpace(text)
+	} else {
+		page.Document = Extract(text)
+	}
+	page.Duration = time.Since(start)
+	return page, nil
+}
+
+// decode -> body as utf-8. anything that isn't valid utf-8 is read as latin-1, which windows-1252 and
+// iso-8859-1 pages mostly are; other charsets come out garbled.
+func decode(body []byte, charset string) string {
+	latin1 := false
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		latin1 = true
+	case "", "utf-8", "utf8", "us-ascii":
+		latin1 = !utf8.Valid(body)
+	}
+	if !latin1 {
+		return strings.ToValidUTF8(string(body), "\uFFFD")
+	}
+	runes := make([]rune, len(body))
+	for i, b := range body {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
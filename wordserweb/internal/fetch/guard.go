@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var ErrBlockedAddress = errors.New("address not allowed")
+
+// internalNets -> ranges beyond what net.IP's Is* methods cover that must not be reachable from a user's url.
+var internalNets = mustParseCIDRs(
+	"0.0.0.0/8",      // this network
+	"100.64.0.0/10",  // carrier grade nat
+	"192.0.0.0/24",   // ietf protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved, broadcast included
+	"64:ff9b::/96",   // nat64, can reach private v4 addresses
+	"64:ff9b:1::/48", // local use nat64
+	"2001:db8::/32",  // documentation
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// internalIP -> ip is loopback, private, link local or otherwise not on the public internet.
+func internalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, n := range internalNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Guard -> dials only public addresses, plus those allowlisted. the check happens on the resolved address at
+// connect time, for every redirect hop, so a name that resolves somewhere internal is caught too.
+type Guard struct {
+	hosts  map[string]bool
+	nets   []*net.IPNet
+	dialer *net.Dialer
+}
+
+// NewGuard -> allowlist entries are hosts, ips or cidrs.
+func NewGuard(allowlist []string) (*Guard, error) {
+	g := &Guard{hosts: map[string]bool{}, dialer: &net.Dialer{}}
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, n, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fetch allowlist cidr %q: %w", entry, err)
+			}
+			g.nets = append(g.nets, n)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			g.nets = append(g.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		g.hosts[entry] = true
+	}
+	return g, nil
+}
+
+func (g *Guard) allowed(ip net.IP) bool {
+	if !internalIP(ip) {
+		return true
+	}
+	for _, n := range g.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialContext -> net.Dialer.DialContext that refuses internal addresses. an allowlisted host is dialed as is.
+func (g *Guard) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if g.hosts[strings.ToLower(host)] {
+		return g.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	err = fmt.Errorf("%w: %s has no addresses", ErrBlockedAddress, host)
+	for _, ip := range ips {
+		if !g.allowed(ip.IP) {
+			err = fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, ip.IP)
+			continue
+		}
+		// dial the address that was checked rather than the name, which could resolve differently by now.
+		conn, dialErr := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if dialErr == nil {
+			return conn, nil
+		}
+		err = dialErr
+	}
+	return nil, err
+}
+
+// Check -> host, a name or ip, resolves only to addresses DialContext would dial. for refusing a url up front, when
+// it is saved; DialContext still checks at connect time, as the name may resolve differently by then.
+func (g *Guard) Check(ctx context.Context, host string) error {
+	if g.hosts[strings.ToLower(host)] {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !g.allowed(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+		}
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("%w: %s has no addresses", ErrBlockedAddress, host)
+	}
+	for _, ip := range ips {
+		if !g.allowed(ip.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, ip.IP)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestInternalIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		// loopback
+		{ip: "127.0.0.1", want: true},
+		{ip: "127.255.255.254", want: true},
+		{ip: "::1", want: true},
+		// private
+		{ip: "10.0.0.1", want: true},
+		{ip: "172.16.0.1", want: true},
+		{ip: "172.31.255.255", want: true},
+		{ip: "192.168.1.1", want: true},
+		{ip: "fd00::1", want: true},
+		// link local, cloud metadata included
+		{ip: "169.254.169.254", want: true},
+		{ip: "fe80::1", want: true},
+		// unspecified, multicast and broadcast
+		{ip: "0.0.0.0", want: true},
+		{ip: "::", want: true},
+		{ip: "224.0.0.1", want: true},
+		{ip: "ff02::1", want: true},
+		{ip: "255.255.255.255", want: true},
+		// internalNets
+		{ip: "0.1.2.3", want: true},
+		{ip: "100.64.0.1", want: true},
+		{ip: "192.0.0.8", want: true},
+		{ip: "198.18.0.1", want: true},
+		{ip: "240.0.0.1", want: true},
+		{ip: "64:ff9b::a00:1", want: true},
+		{ip: "2001:db8::1", want: true},
+		// v4 mapped v6
+		{ip: "::ffff:127.0.0.1", want: true},
+		{ip: "::ffff:10.0.0.1", want: true},
+		// public
+		{ip: "8.8.8.8", want: false},
+		{ip: "1.1.1.1", want: false},
+		{ip: "172.32.0.1", want: false},
+		{ip: "100.128.0.1", want: false},
+		{ip: "2606:4700:4700::1111", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			ip := net.ParseIP(tt.ip)
+			if ip == nil {
+				t.Fatalf("invalid test ip %q", tt.ip)
+			}
+			if got := internalIP(ip); got != tt.want {
+				t.Errorf("internalIP(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGuard(t *testing.T) {
+	if _, err := NewGuard([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("NewGuard() accepted an invalid cidr")
+	}
+
+	g, err := NewGuard([]string{" Intranet.Example ", "10.1.0.0/16", "192.168.1.5", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{host: "intranet.example"},
+		{host: "INTRANET.example"},
+		{host: "10.1.2.3"},
+		{host: "192.168.1.5"},
+		{host: "8.8.8.8"},
+		{host: "10.2.0.1", wantErr: true},
+		{host: "192.168.1.6", wantErr: true},
+		{host: "127.0.0.1", wantErr: true},
+		{host: "::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := g.Check(context.Background(), tt.host)
+			if tt.wantErr != errors.Is(err, ErrBlockedAddress) {
+				t.Errorf("Check(%q) error = %v, want blocked %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGuardDialContext(t *testing.T) {
+	g, err := NewGuard(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []string{"127.0.0.1:80", "[::1]:80", "169.254.169.254:80", "10.0.0.1:443"} {
+		conn, err := g.DialContext(context.Background(), "tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		if !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("DialContext(%q) error = %v, want %v", addr, err, ErrBlockedAddress)
+		}
+	}
+}
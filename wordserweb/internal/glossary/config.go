@@ -0,0 +1,33 @@
+package glossary
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// MaxTerms -> terms a glossary may hold.
+	MaxTerms int `mapstructure:"GLOSSARY_MAX_TERMS"`
+	// MaxImportBytes -> size of the largest csv file imported into a glossary.
+	MaxImportBytes int64 `mapstructure:"GLOSSARY_MAX_IMPORT_BYTES"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("GLOSSARY_MAX_TERMS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'GLOSSARY_MAX_TERMS'")
+	}
+	viper.SetDefault("GLOSSARY_MAX_TERMS", 5000)
+
+	if err := viper.BindEnv("GLOSSARY_MAX_IMPORT_BYTES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'GLOSSARY_MAX_IMPORT_BYTES'")
+	}
+	viper.SetDefault("GLOSSARY_MAX_IMPORT_BYTES", 1<<20)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,84 @@
+package glossary
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// CSVHeader -> the columns of exported glossaries. imports may leave the header out, and a row without a
+// target_term is a do not translate term.
+var CSVHeader = []string{"source_term", "target_term"}
+
+// ReadCSV -> the terms of a glossary csv file.
+func ReadCSV(r io.Reader) ([]*postgres.GlossaryTerm, error) {
+	reader := csv.NewReader(r)
+	// spreadsheets drop trailing empty cells; a do not translate row may have just one.
+	reader.FieldsPerRecord = -1
+
+	var terms []*postgres.GlossaryTerm
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+			if strings.EqualFold(strings.TrimSpace(record[0]), CSVHeader[0]) {
+				continue
+			}
+		}
+		if len(record) > len(CSVHeader) {
+			return nil, fmt.Errorf("line %d: expected at most %d columns: %s", line, len(CSVHeader), strings.Join(CSVHeader, ","))
+		}
+		if strings.TrimSpace(record[0]) == "" {
+			return nil, fmt.Errorf("line %d: source_term can't be empty", line)
+		}
+		target := ""
+		if len(record) > 1 {
+			target = record[1]
+		}
+		terms = append(terms, NewTerm(record[0], target))
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("no terms")
+	}
+	return terms, nil
+}
+
+// WriteCSV -> terms as a glossary csv file, header first.
+func WriteCSV(w io.Writer, terms []*postgres.GlossaryTerm) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CSVHeader); err != nil {
+		return err
+	}
+	for _, t := range terms {
+		target := ""
+		if t.TargetTerm != nil {
+			target = *t.TargetTerm
+		}
+		if err := writer.Write([]string{t.SourceTerm, target}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Import -> add the terms of a glossary csv file to glossary id, see AddTerms.
+func (g *Glossaries) Import(ctx context.Context, userCtx auth.UserContext, id int64, r io.Reader) (int, error) {
+	terms, err := ReadCSV(io.LimitReader(r, g.config.MaxImportBytes))
+	if err != nil {
+		return 0, fmt.Errorf("%w; %v;", ErrInvalid, err)
+	}
+	return g.AddTerms(ctx, userCtx, id, terms)
+}
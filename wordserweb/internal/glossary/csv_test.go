@@ -0,0 +1,82 @@
+package glossary
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []*postgres.GlossaryTerm
+		wantErr bool
+	}{
+		{
+			name:  "header",
+			input: "source_term,target_term\ndashboard,Armaturenbrett\n",
+			want:  terms("dashboard", "Armaturenbrett"),
+		},
+		{
+			name:  "no header",
+			input: "dashboard,Armaturenbrett\n",
+			want:  terms("dashboard", "Armaturenbrett"),
+		},
+		{
+			name:  "bom and crlf",
+			input: "\ufeffSource_Term,Target_Term\r\ndashboard,Armaturenbrett\r\n",
+			want:  terms("dashboard", "Armaturenbrett"),
+		},
+		{
+			name:  "do not translate rows",
+			input: "Kubernetes\nWordser,\n",
+			want:  terms("Kubernetes", "", "Wordser", ""),
+		},
+		{
+			name:  "quoted cells and spaces",
+			input: "\"New York, NY\", Nueva York \n",
+			want:  terms("New York, NY", "Nueva York"),
+		},
+		{name: "too many columns", input: "a,b,c\n", wantErr: true},
+		{name: "empty source", input: " ,b\n", wantErr: true},
+		{name: "header only", input: "source_term,target_term\n", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "bad quoting", input: "\"a,b\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCSV(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ReadCSV() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadCSV() = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ReadCSV() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteReadCSV(t *testing.T) {
+	want := terms("dashboard", "Armaturenbrett", "Kubernetes", "", "say \"hi\", bye", "sag \"hallo\"")
+	var b bytes.Buffer
+	if err := WriteCSV(&b, want); err != nil {
+		t.Fatalf("WriteCSV() = %v", err)
+	}
+	got, err := ReadCSV(&b)
+	if err != nil {
+		t.Fatalf("ReadCSV() = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadCSV(WriteCSV()) = %+v, want %+v", got, want)
+	}
+}
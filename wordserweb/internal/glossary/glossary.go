@@ -0,0 +1,211 @@
+package glossary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// maxTermChars -> longest source or target term.
+const maxTermChars = 200
+
+var (
+	ErrInvalid      = errors.New("invalid glossary request")
+	ErrForbidden    = errors.New("organization role does not allow editing its glossaries")
+	ErrTooManyTerms = errors.New("too many glossary terms")
+)
+
+// Glossaries -> the glossaries of users' workspaces and their enforcement on translations. the personal workspace's
+// glossaries are its user's; an organization's are its members', editable by its owners and admins.
+type Glossaries struct {
+	config Config
+	store  GlossaryStorer
+}
+
+func New(config Config, store GlossaryStorer) *Glossaries {
+	return &Glossaries{config: config, store: store}
+}
+
+// membership -> userCtx's membership of the organization of its workspace, nil for the personal workspace.
+// org.ErrNotMember once userCtx left it; the org id of a session is no proof of membership.
+func (g *Glossaries) membership(ctx context.Context, userCtx auth.UserContext) (*postgres.OrganizationMembership, error) {
+	if userCtx.OrgID == 0 {
+		return nil, nil
+	}
+	membership, err := g.store.GetOrganizationMembership(ctx, userCtx.OrgID, userCtx.Username)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return nil, org.ErrNotMember
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// CanEdit -> userCtx may change the glossaries of its workspace.
+func (g *Glossaries) CanEdit(ctx context.Context, userCtx auth.UserContext) (bool, error) {
+	membership, err := g.membership(ctx, userCtx)
+	if err != nil {
+		return false, err
+	}
+	return membership == nil || org.Role(membership.Role).CanManage(), nil
+}
+
+func (g *Glossaries) checkMember(ctx context.Context, userCtx auth.UserContext) error {
+	_, err := g.membership(ctx, userCtx)
+	return err
+}
+
+func (g *Glossaries) checkEdit(ctx context.Context, userCtx auth.UserContext) error {
+	ok, err := g.CanEdit(ctx, userCtx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Create -> a glossary for translating source to target language codes in userCtx's workspace. empty languages
+// make one for every language pair. returns the new glossary id.
+func (g *Glossaries) Create(ctx context.Context, userCtx auth.UserContext, name string, source string, target string) (int64, error) {
+	if err := g.checkEdit(ctx, userCtx); err != nil {
+		return 0, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 80 {
+		return 0, fmt.Errorf("%w; name must be 1 to 80 characters;", ErrInvalid)
+	}
+	if (source == "") != (target == "") {
+		return 0, fmt.Errorf("%w; pick both languages, or neither for every language pair;", ErrInvalid)
+	}
+	if source != "" && source == target {
+		return 0, fmt.Errorf("%w; the source and target language can't be the same;", ErrInvalid)
+	}
+
+	glossary := &postgres.Glossary{Name: name, SourceLanguage: source, TargetLanguage: target}
+	if userCtx.OrgID != 0 {
+		orgID := userCtx.OrgID
+		glossary.OrganizationID = &orgID
+	} else {
+		username := userCtx.Username
+		glossary.Username = &username
+	}
+	return g.store.InsertGlossary(ctx, glossary)
+}
+
+func (g *Glossaries) Glossary(ctx context.Context, userCtx auth.UserContext, id int64) (*postgres.Glossary, error) {
+	if err := g.checkMember(ctx, userCtx); err != nil {
+		return nil, err
+	}
+	return g.store.GetGlossary(ctx, userCtx.Username, userCtx.OrgID, id)
+}
+
+// Glossaries -> the glossaries of userCtx's workspace.
+func (g *Glossaries) Glossaries(ctx context.Context, userCtx auth.UserContext) ([]*postgres.Glossary, error) {
+	if err := g.checkMember(ctx, userCtx); err != nil {
+		return nil, err
+	}
+	return g.store.ListGlossaries(ctx, userCtx.Username, userCtx.OrgID)
+}
+
+func (g *Glossaries) Delete(ctx context.Context, userCtx auth.UserContext, id int64) error {
+	if err := g.checkEdit(ctx, userCtx); err != nil {
+		return err
+	}
+	return g.store.DeleteGlossary(ctx, userCtx.Username, userCtx.OrgID, id)
+}
+
+func (g *Glossaries) Terms(ctx context.Context, userCtx auth.UserContext, id int64) ([]*postgres.GlossaryTerm, error) {
+	glossary, err := g.Glossary(ctx, userCtx, id)
+	if err != nil {
+		return nil, err
+	}
+	return g.store.ListGlossaryTerms(ctx, glossary.ID)
+}
+
+// NewTerm -> a term translating source as target, or keeping it as it is when target is empty.
+func NewTerm(source string, target string) *postgres.GlossaryTerm {
+	term := &postgres.GlossaryTerm{SourceTerm: strings.TrimSpace(source)}
+	if target = strings.TrimSpace(target); target != "" {
+		term.TargetTerm = &target
+	}
+	return term
+}
+
+// AddTerms -> add terms to glossary id, replacing the target of source terms it already has. returns how many
+// terms were added or replaced.
+func (g *Glossaries) AddTerms(ctx context.Context, userCtx auth.UserContext, id int64, terms []*postgres.GlossaryTerm) (int, error) {
+	if err := g.checkEdit(ctx, userCtx); err != nil {
+		return 0, err
+	}
+	glossary, err := g.store.GetGlossary(ctx, userCtx.Username, userCtx.OrgID, id)
+	if err != nil {
+		return 0, err
+	}
+	if len(terms) == 0 {
+		return 0, fmt.Errorf("%w; no terms;", ErrInvalid)
+	}
+
+	existing, err := g.store.ListGlossaryTerms(ctx, glossary.ID)
+	if err != nil {
+		return 0, err
+	}
+	known := map[string]bool{}
+	for _, t := range existing {
+		known[t.SourceTerm] = true
+	}
+	for i, t := range terms {
+		if t.SourceTerm == "" || utf8.RuneCountInString(t.SourceTerm) > maxTermChars ||
+			(t.TargetTerm != nil && utf8.RuneCountInString(*t.TargetTerm) > maxTermChars) {
+			return 0, fmt.Errorf("%w; term %d: terms must be 1 to %d characters;", ErrInvalid, i+1, maxTermChars)
+		}
+		if t.TargetTerm != nil && glossary.SourceLanguage == "" {
+			return 0, fmt.Errorf(
+				"%w; term %d: a glossary for every language pair only holds do not translate terms;",
+				ErrInvalid,
+				i+1,
+			)
+		}
+		known[t.SourceTerm] = true
+	}
+	if g.config.MaxTerms > 0 && len(known) > g.config.MaxTerms {
+		return 0, fmt.Errorf("%w; at most %d;", ErrTooManyTerms, g.config.MaxTerms)
+	}
+
+	if err := g.store.UpsertGlossaryTerms(ctx, glossary.ID, terms); err != nil {
+		return 0, err
+	}
+	return len(terms), nil
+}
+
+func (g *Glossaries) DeleteTerm(ctx context.Context, userCtx auth.UserContext, id int64, termID int64) error {
+	if err := g.checkEdit(ctx, userCtx); err != nil {
+		return err
+	}
+	glossary, err := g.store.GetGlossary(ctx, userCtx.Username, userCtx.OrgID, id)
+	if err != nil {
+		return err
+	}
+	return g.store.DeleteGlossaryTerm(ctx, glossary.ID, termID)
+}
+
+// Protect -> txt with the terms of userCtx's glossaries for translating source to target protected, see
+// Protected.
+func (g *Glossaries) Protect(ctx context.Context, userCtx auth.UserContext, txt string, source string, target string) (*Protected, error) {
+	if err := g.checkMember(ctx, userCtx); err != nil {
+		return nil, err
+	}
+	terms, err := g.store.ListApplicableGlossaryTerms(ctx, userCtx.Username, userCtx.OrgID, source, target)
+	if err != nil {
+		return nil, err
+	}
+	return Protect(txt, terms), nil
+}
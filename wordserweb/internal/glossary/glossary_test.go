@@ -0,0 +1,142 @@
+package glossary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// fakeStore -> one glossary, id 1, in every workspace. memberships are org roles by username; calls counts the
+// calls that read or change glossaries.
+type fakeStore struct {
+	memberships map[string]string
+	calls       int
+}
+
+func (s *fakeStore) InsertGlossary(ctx context.Context, g *postgres.Glossary) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+
+func (s *fakeStore) GetGlossary(ctx context.Context, username string, orgID int, id int64) (*postgres.Glossary, error) {
+	s.calls++
+	return &postgres.Glossary{ID: id, Name: "terms", SourceLanguage: "en", TargetLanguage: "de"}, nil
+}
+
+func (s *fakeStore) ListGlossaries(ctx context.Context, username string, orgID int) ([]*postgres.Glossary, error) {
+	s.calls++
+	return []*postgres.Glossary{{ID: 1, Name: "terms"}}, nil
+}
+
+func (s *fakeStore) DeleteGlossary(ctx context.Context, username string, orgID int, id int64) error {
+	s.calls++
+	return nil
+}
+
+func (s *fakeStore) ListGlossaryTerms(ctx context.Context, glossaryID int64) ([]*postgres.GlossaryTerm, error) {
+	s.calls++
+	return nil, nil
+}
+
+func (s *fakeStore) UpsertGlossaryTerms(ctx context.Context, glossaryID int64, terms []*postgres.GlossaryTerm) error {
+	s.calls++
+	return nil
+}
+
+func (s *fakeStore) DeleteGlossaryTerm(ctx context.Context, glossaryID int64, id int64) error {
+	s.calls++
+	return nil
+}
+
+func (s *fakeStore) ListApplicableGlossaryTerms(ctx context.Context, username string, orgID int, source string, target string) ([]*postgres.GlossaryTerm, error) {
+	s.calls++
+	return []*postgres.GlossaryTerm{NewTerm("Wordser", "")}, nil
+}
+
+func (s *fakeStore) GetOrganizationMembership(ctx context.Context, orgID int, username string) (*postgres.OrganizationMembership, error) {
+	role, ok := s.memberships[username]
+	if !ok {
+		return nil, fmt.Errorf("membership %w", postgres.ErrNotFound)
+	}
+	return &postgres.OrganizationMembership{OrganizationID: orgID, Username: username, Role: role}, nil
+}
+
+func TestGlossariesMembership(t *testing.T) {
+	calls := []struct {
+		name string
+		// edit -> only managers may make the call.
+		edit bool
+		call func(g *Glossaries, userCtx auth.UserContext) error
+	}{
+		{name: "Glossaries", call: func(g *Glossaries, userCtx auth.UserContext) error {
+			_, err := g.Glossaries(context.Background(), userCtx)
+			return err
+		}},
+		{name: "Glossary", call: func(g *Glossaries, userCtx auth.UserContext) error {
+			_, err := g.Glossary(context.Background(), userCtx, 1)
+			return err
+		}},
+		{name: "Terms", call: func(g *Glossaries, userCtx auth.UserContext) error {
+			_, err := g.Terms(context.Background(), userCtx, 1)
+			return err
+		}},
+		{name: "Protect", call: func(g *Glossaries, userCtx auth.UserContext) error {
+			_, err := g.Protect(context.Background(), userCtx, "Wordser works", "en", "de")
+			return err
+		}},
+		{name: "Create", edit: true, call: func(g *Glossaries, userCtx auth.UserContext) error {
+			_, err := g.Create(context.Background(), userCtx, "terms", "en", "de")
+			return err
+		}},
+		{name: "AddTerms", edit: true, call: func(g *Glossaries, userCtx auth.UserContext) error {
+			_, err := g.AddTerms(context.Background(), userCtx, 1, []*postgres.GlossaryTerm{NewTerm("a", "b")})
+			return err
+		}},
+		{name: "DeleteTerm", edit: true, call: func(g *Glossaries, userCtx auth.UserContext) error {
+			return g.DeleteTerm(context.Background(), userCtx, 1, 1)
+		}},
+		{name: "Delete", edit: true, call: func(g *Glossaries, userCtx auth.UserContext) error {
+			return g.Delete(context.Background(), userCtx, 1)
+		}},
+	}
+	users := []struct {
+		name    string
+		userCtx auth.UserContext
+		// readErr/editErr -> nil when the call is allowed.
+		readErr error
+		editErr error
+	}{
+		{name: "personal", userCtx: auth.UserContext{Username: "ann"}},
+		{name: "org admin", userCtx: auth.UserContext{Username: "bob", OrgID: 3}},
+		{name: "org member", userCtx: auth.UserContext{Username: "cat", OrgID: 3}, editErr: ErrForbidden},
+		{
+			name:    "former member",
+			userCtx: auth.UserContext{Username: "dan", OrgID: 3, OrgRole: "admin"},
+			readErr: org.ErrNotMember,
+			editErr: org.ErrNotMember,
+		},
+	}
+	for _, u := range users {
+		for _, c := range calls {
+			t.Run(u.name+"/"+c.name, func(t *testing.T) {
+				store := &fakeStore{memberships: map[string]string{"bob": "admin", "cat": "member"}}
+				want := u.readErr
+				if c.edit {
+					want = u.editErr
+				}
+				err := c.call(New(Config{}, store), u.userCtx)
+				if !errors.Is(err, want) {
+					t.Fatalf("%s() error = %v, want %v", c.name, err, want)
+				}
+				if err != nil && store.calls != 0 {
+					t.Errorf("%s() made %d store calls, want none", c.name, store.calls)
+				}
+			})
+		}
+	}
+}
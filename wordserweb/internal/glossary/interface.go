@@ -0,0 +1,19 @@
+package glossary
+
+import (
+	"context"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type GlossaryStorer interface {
+	InsertGlossary(ctx context.Context, g *postgres.Glossary) (int64, error)
+	GetGlossary(ctx context.Context, username string, orgID int, id int64) (*postgres.Glossary, error)
+	ListGlossaries(ctx context.Context, username string, orgID int) ([]*postgres.Glossary, error)
+	DeleteGlossary(ctx context.Context, username string, orgID int, id int64) error
+	ListGlossaryTerms(ctx context.Context, glossaryID int64) ([]*postgres.GlossaryTerm, error)
+	UpsertGlossaryTerms(ctx context.Context, glossaryID int64, terms []*postgres.GlossaryTerm) error
+	DeleteGlossaryTerm(ctx context.Context, glossaryID int64, id int64) error
+	ListApplicableGlossaryTerms(ctx context.Context, username string, orgID int, source string, target string) ([]*postgres.GlossaryTerm, error)
+	GetOrganizationMembership(ctx context.Context, orgID int, username string) (*postgres.OrganizationMembership, error)
+}
@@ -0,0 +1,213 @@
+package glossary
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// placeholderPattern -> the placeholders Protect puts in place of terms, "⟦0⟧". providers leave them alone but
+// may space them out.
+var placeholderPattern = regexp.MustCompile(`⟦\s*(\d+)\s*⟧`)
+
+// Applied -> a glossary term found in the text and enforced on its translation.
+type Applied struct {
+	Source string `json:"source"`
+	// Target -> what the term was translated as. the source term, as the text had it, when it isn't translated.
+	Target         string `json:"target"`
+	DoNotTranslate bool   `json:"do_not_translate,omitempty"`
+	// Count -> times the term appeared.
+	Count int `json:"count"`
+}
+
+// Protected -> text whose glossary terms were swapped for placeholders, so the provider translates around them
+// and they are put back as the glossary has them.
+type Protected struct {
+	// Text -> what is sent to the provider.
+	Text         string
+	replacements []replacement
+}
+
+// replacement -> what the placeholder numbered by its index stands for.
+type replacement struct {
+	// term -> nil for text that looked like a placeholder already, put back as it was.
+	term *postgres.GlossaryTerm
+	// original -> the term as the text had it, kept by do not translate terms.
+	original string
+}
+
+func (r replacement) target() string {
+	if r.term == nil || r.term.TargetTerm == nil {
+		return r.original
+	}
+	return *r.term.TargetTerm
+}
+
+// Protect -> txt with every whole word, case insensitive occurrence of terms replaced by a placeholder. longer
+// terms win over the terms inside them, and the first of terms with the same source term wins. anything in txt
+// that looks like a placeholder is swapped for one too, so Restore puts it back rather than a term.
+func Protect(txt string, terms []*postgres.GlossaryTerm) *Protected {
+	p := &Protected{Text: txt}
+	byFold := map[string]*postgres.GlossaryTerm{}
+	var unique []*postgres.GlossaryTerm
+	for _, t := range terms {
+		key := strings.ToLower(t.SourceTerm)
+		if _, ok := byFold[key]; ok || t.SourceTerm == "" {
+			continue
+		}
+		byFold[key] = t
+		unique = append(unique, t)
+	}
+	if len(unique) == 0 {
+		return p
+	}
+	// alternatives match leftmost first; longest first makes "New York City" beat "New York".
+	sort.SliceStable(unique, func(i, j int) bool {
+		return utf8.RuneCountInString(unique[i].SourceTerm) > utf8.RuneCountInString(unique[j].SourceTerm)
+	})
+	quoted := make([]string, 0, len(unique))
+	for _, t := range unique {
+		quoted = append(quoted, regexp.QuoteMeta(t.SourceTerm))
+	}
+	// placeholders first, so a term can't match inside one.
+	pattern := regexp.MustCompile(`(?i)(` + placeholderPattern.String() + `)|(?:` + strings.Join(quoted, "|") + `)`)
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(txt, -1) {
+		match := txt[loc[0]:loc[1]]
+		if loc[2] >= 0 {
+			b.WriteString(txt[last:loc[0]])
+			fmt.Fprintf(&b, "⟦%d⟧", len(p.replacements))
+			p.replacements = append(p.replacements, replacement{original: match})
+			last = loc[1]
+			continue
+		}
+		if !wholeWord(txt, loc[0], loc[1]) {
+			continue
+		}
+		term, ok := byFold[strings.ToLower(match)]
+		if !ok {
+			// case folding and lowercasing disagree on a few letters.
+			for _, t := range unique {
+				if strings.EqualFold(t.SourceTerm, match) {
+					term = t
+					break
+				}
+			}
+		}
+		if term == nil {
+			continue
+		}
+		b.WriteString(txt[last:loc[0]])
+		fmt.Fprintf(&b, "⟦%d⟧", len(p.replacements))
+		p.replacements = append(p.replacements, replacement{term: term, original: match})
+		last = loc[1]
+	}
+	if len(p.replacements) == 0 {
+		return p
+	}
+	b.WriteString(txt[last:])
+	p.Text = b.String()
+	return p
+}
+
+// wholeWord -> txt[start:end] isn't part of a longer word. terms starting or ending in punctuation, like "C++",
+// need no boundary on that side.
+func wholeWord(txt string, start int, end int) bool {
+	first, _ := utf8.DecodeRuneInString(txt[start:end])
+	if before, size := utf8.DecodeLastRuneInString(txt[:start]); size > 0 && isWordRune(first) && isWordRune(before) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(txt[start:end])
+	if after, size := utf8.DecodeRuneInString(txt[end:]); size > 0 && isWordRune(last) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// Restore -> translated with its placeholders replaced by the glossary's terms, and the terms that made it through
+// the translation in the order the translation has them. a term whose placeholder the provider dropped isn't
+// Applied.
+func (p *Protected) Restore(translated string) (string, []Applied) {
+	if len(p.replacements) == 0 {
+		return translated, nil
+	}
+	var applied []Applied
+	index := map[*postgres.GlossaryTerm]int{}
+	restored := placeholderPattern.ReplaceAllStringFunc(translated, func(placeholder string) string {
+		i, err := strconv.Atoi(placeholderPattern.FindStringSubmatch(placeholder)[1])
+		if err != nil || i >= len(p.replacements) {
+			return placeholder
+		}
+		r := p.replacements[i]
+		if r.term == nil {
+			return r.original
+		}
+		if j, ok := index[r.term]; ok {
+			applied[j].Count++
+		} else {
+			index[r.term] = len(applied)
+			applied = append(applied, Applied{
+				Source:         r.term.SourceTerm,
+				Target:         r.target(),
+				DoNotTranslate: r.term.TargetTerm == nil,
+				Count:          1,
+			})
+		}
+		return r.target()
+	})
+	return restored, applied
+}
+
+// Enforced -> whether translation, made without p's placeholders like a translation memory's, has every one of
+// p's terms as the glossary has it, and the terms as Restore would report them. a translation made before a term
+// was added, or changed, misses it and isn't to be used as it is.
+func (p *Protected) Enforced(translation string) ([]Applied, bool) {
+	if len(p.replacements) == 0 {
+		return nil, true
+	}
+	var applied []Applied
+	index := map[*postgres.GlossaryTerm]int{}
+	for _, r := range p.replacements {
+		if r.term == nil {
+			continue
+		}
+		if j, ok := index[r.term]; ok {
+			applied[j].Count++
+			continue
+		}
+		if !containsWord(translation, r.target()) {
+			return nil, false
+		}
+		index[r.term] = len(applied)
+		applied = append(applied, Applied{
+			Source:         r.term.SourceTerm,
+			Target:         r.target(),
+			DoNotTranslate: r.term.TargetTerm == nil,
+			Count:          1,
+		})
+	}
+	return applied, true
+}
+
+// containsWord -> txt has term as a whole word, case insensitive, see wholeWord.
+func containsWord(txt string, term string) bool {
+	pattern := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(term))
+	for _, loc := range pattern.FindAllStringIndex(txt, -1) {
+		if wholeWord(txt, loc[0], loc[1]) {
+			return true
+		}
+	}
+	return false
+}
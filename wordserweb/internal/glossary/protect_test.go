@@ -0,0 +1,202 @@
+package glossary
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+func terms(pairs ...string) []*postgres.GlossaryTerm {
+	var ts []*postgres.GlossaryTerm
+	for i := 0; i < len(pairs); i += 2 {
+		ts = append(ts, NewTerm(pairs[i], pairs[i+1]))
+	}
+	return ts
+}
+
+func TestProtect(t *testing.T) {
+	tests := []struct {
+		name  string
+		txt   string
+		terms []*postgres.GlossaryTerm
+		want  string
+	}{
+		{
+			name: "no terms",
+			txt:  "Hello world",
+			want: "Hello world",
+		},
+		{
+			name:  "case insensitive",
+			txt:   "Open the DASHBOARD and the dashboard",
+			terms: terms("dashboard", "Armaturenbrett"),
+			want:  "Open the ⟦0⟧ and the ⟦1⟧",
+		},
+		{
+			name:  "whole words only",
+			txt:   "cat catalog scat cat.",
+			terms: terms("cat", "Katze"),
+			want:  "⟦0⟧ catalog scat ⟦1⟧.",
+		},
+		{
+			name:  "longest term wins",
+			txt:   "New York City is not New York state",
+			terms: terms("New York", "Nueva York", "New York City", "Nueva York Ciudad"),
+			want:  "⟦0⟧ is not ⟦1⟧ state",
+		},
+		{
+			name:  "first of duplicate terms wins",
+			txt:   "an API call",
+			terms: terms("API", "", "api", "Schnittstelle"),
+			want:  "an ⟦0⟧ call",
+		},
+		{
+			name:  "c++ style terms",
+			txt:   "C++ and C# beat C, not Cobol#",
+			terms: terms("C++", "", "C#", ""),
+			want:  "⟦0⟧ and ⟦1⟧ beat C, not Cobol#",
+		},
+		{
+			name:  "unicode word boundaries",
+			txt:   "Straße, Straßenbahn und straße",
+			terms: terms("Straße", "street"),
+			want:  "⟦0⟧, Straßenbahn und ⟦1⟧",
+		},
+		{
+			name:  "combining marks are part of a word",
+			txt:   "café cafe",
+			terms: terms("cafe", "coffee"),
+			want:  "café ⟦0⟧",
+		},
+		{
+			name:  "existing placeholders escaped",
+			txt:   "keep ⟦0⟧ and ⟦ 1 ⟧ but not the term",
+			terms: terms("term", "Begriff"),
+			want:  "keep ⟦0⟧ and ⟦1⟧ but not the ⟦2⟧",
+		},
+		{
+			name:  "no term inside an existing placeholder",
+			txt:   "⟦7⟧ 7",
+			terms: terms("7", "seven"),
+			want:  "⟦0⟧ ⟦1⟧",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Protect(tt.txt, tt.terms).Text; got != tt.want {
+				t.Fatalf("Protect(%q).Text = %q, want %q", tt.txt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name        string
+		txt         string
+		terms       []*postgres.GlossaryTerm
+		translation func(protected string) string
+		want        string
+		applied     []Applied
+	}{
+		{
+			name:        "terms enforced and counted",
+			txt:         "The dashboard shows the Dashboard.",
+			terms:       terms("dashboard", "Armaturenbrett"),
+			translation: func(string) string { return "Das ⟦0⟧ zeigt das ⟦1⟧." },
+			want:        "Das Armaturenbrett zeigt das Armaturenbrett.",
+			applied:     []Applied{{Source: "dashboard", Target: "Armaturenbrett", Count: 2}},
+		},
+		{
+			name:        "do not translate keeps the text's casing",
+			txt:         "Use KUBERNETES here",
+			terms:       terms("Kubernetes", ""),
+			translation: func(string) string { return "Usa ⟦0⟧ aquí" },
+			want:        "Usa KUBERNETES aquí",
+			applied:     []Applied{{Source: "Kubernetes", Target: "KUBERNETES", DoNotTranslate: true, Count: 1}},
+		},
+		{
+			name:        "spaced out placeholders",
+			txt:         "a widget",
+			terms:       terms("widget", "Gadget"),
+			translation: func(string) string { return "ein ⟦ 0 ⟧" },
+			want:        "ein Gadget",
+			applied:     []Applied{{Source: "widget", Target: "Gadget", Count: 1}},
+		},
+		{
+			name:        "dropped placeholder isn't applied",
+			txt:         "a widget",
+			terms:       terms("widget", "Gadget"),
+			translation: func(string) string { return "ein Ding" },
+			want:        "ein Ding",
+		},
+		{
+			name:        "unknown placeholder left alone",
+			txt:         "a widget",
+			terms:       terms("widget", "Gadget"),
+			translation: func(string) string { return "ein ⟦0⟧ ⟦5⟧" },
+			want:        "ein Gadget ⟦5⟧",
+			applied:     []Applied{{Source: "widget", Target: "Gadget", Count: 1}},
+		},
+		{
+			name:        "existing placeholders put back as they were",
+			txt:         "⟦0⟧ is a widget",
+			terms:       terms("widget", "Gadget"),
+			translation: func(protected string) string { return protected },
+			want:        "⟦0⟧ is a Gadget",
+			applied:     []Applied{{Source: "widget", Target: "Gadget", Count: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Protect(tt.txt, tt.terms)
+			got, applied := p.Restore(tt.translation(p.Text))
+			if got != tt.want {
+				t.Errorf("Restore() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(applied, tt.applied) {
+				t.Errorf("Restore() applied = %+v, want %+v", applied, tt.applied)
+			}
+		})
+	}
+}
+
+func TestEnforced(t *testing.T) {
+	p := Protect("⟦0⟧ opens the dashboard", terms("dashboard", "Armaturenbrett"))
+	if _, ok := p.Enforced("⟦0⟧ öffnet die Übersicht"); ok {
+		t.Errorf("Enforced() = true for a translation without the term")
+	}
+	applied, ok := p.Enforced("⟦0⟧ öffnet das Armaturenbrett")
+	if !ok || !reflect.DeepEqual(applied, []Applied{{Source: "dashboard", Target: "Armaturenbrett", Count: 1}}) {
+		t.Errorf("Enforced() = %+v, %v", applied, ok)
+	}
+}
+
+func TestWholeWord(t *testing.T) {
+	tests := []struct {
+		txt  string
+		term string
+		want bool
+	}{
+		{txt: "a cat sat", term: "cat", want: true},
+		{txt: "concatenate", term: "cat", want: false},
+		{txt: "cat's", term: "cat", want: true},
+		{txt: "C++ rocks", term: "C++", want: true},
+		// no boundary is needed after a term ending in punctuation.
+		{txt: "C++11", term: "C++", want: true},
+		{txt: "xC++", term: "C++", want: false},
+		{txt: "use .NET now", term: ".NET", want: true},
+		{txt: "ASP.NET", term: ".NET", want: true},
+		{txt: "東京都", term: "東京", want: false},
+		{txt: "東京 と 大阪", term: "東京", want: true},
+		{txt: "naïve", term: "na", want: false},
+		{txt: "Привет мир", term: "мир", want: true},
+		{txt: "мирный", term: "мир", want: false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.txt, tt.term); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.txt, tt.term, got, tt.want)
+		}
+	}
+}
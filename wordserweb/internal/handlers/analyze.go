@@ -1,301 +1,547 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
-	"net/url"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+	"github.com/nolandseigler/wordser/wordserweb/internal/fetch"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+	"github.com/nolandseigler/wordser/wordserweb/internal/wordser"
 )
 
 type GetAnalyzeHandlerReq struct {
-	AnalyzeText string `query:"analyze-text"`
-	Summarize   string `query:"summarize"`
-	Sentiment   string `query:"sentiment"`
-	Keyword     string `query:"keyword"`
+	AnalyzeText string `query:"analyze-text" form:"analyze-text"`
+	// AnalyzeURL -> analyze the readable text of this page instead of AnalyzeText.
+	AnalyzeURL string `query:"analyze-url" form:"analyze-url"`
+	// Analyzers -> analyzer names to run, on top of those whose form toggle is on.
+	Analyzers []string `query:"analyzer" form:"analyzer"`
+	// Background -> queue the analysis as a job and answer with a status fragment that polls for it.
+	Background bool `query:"background" form:"background"`
+	// Engine -> engine of the analyzers that have more than one, see analyzer.Engine. empty for the configured one.
+	Engine string `query:"engine" form:"engine"`
+	// TranslateFrom -> translate the text from this language to english before analyzing it.
+	TranslateFrom string `query:"translate-from" form:"translate-from"`
 }
 
-type supportedAPI string
+type APIResponse struct {
+	analyzer analyzer.Analyzer
+	result   analyzer.Result
+	err      error
+	duration time.Duration
+	// chunk/chunks -> set on the per chunk responses of a long document and on their merged response.
+	chunk  chunking.Chunk
+	chunks []APIResponse
+}
 
-const (
-	supportedAPISummary   supportedAPI = "SUPPORTED_API_SUMMARY"
-	supportedAPISentiment supportedAPI = "SUPPORTED_API_SENTIMENT"
-	supportedAPIKeyword   supportedAPI = "SUPPORTED_API_KEYWORD"
-)
+type AnalyzeData struct {
+	// HistoryID -> id of the saved analysis. 0 when it wasn't saved.
+	HistoryID    int64
+	OriginalText string
+	// Results -> each analyzer's result keyed by analyzer name. a failed analyzer has none.
+	Results map[string]analyzer.Result
+	// Errors -> why an analyzer failed, keyed by analyzer name.
+	Errors map[string]string
+	// Chunks -> per chunk details when the text was too long for a single analyzer call.
+	Chunks []ChunkResult
+	// Source -> the page the text was fetched from. nil for pasted text.
+	Source *AnalyzeSource
+	// Engine -> the engine the request picked, kept for retries. empty for the configured one.
+	Engine string
+	// Language -> set when the text isn't english or was translated to english, see analyzeLanguage.
+	Language *AnalyzeLanguage
+	// analyzers -> the analyzers that ran, in display order.
+	analyzers []analyzer.Analyzer
+}
 
-type APIResponse struct {
-	api  supportedAPI
-	data []byte
-	err  error
+// AnalyzeSource -> the page of an analyze-url request and how fetching it went.
+type AnalyzeSource struct {
+	URL string `json:"url"`
+	postgres.AnalysisSource
 }
 
-type SummaryAPIResp struct {
-	Summary string `json:"summary"`
+// AnalysisSection -> one analyzer's result or error. rendered by analysis_section.html, the result by the
+// analyzer's Template.
+type AnalysisSection struct {
+	API      string
+	Title    string
+	Template string
+	Error    string
+	Result   analyzer.Result
+	Data     AnalyzeData
 }
 
-type SentimentAPIResp struct {
-	Polarity string  `json:"polarity"`
-	Score    float64 `json:"score"`
+func newAnalysisSection(a analyzer.Analyzer, data AnalyzeData) AnalysisSection {
+	return AnalysisSection{
+		API:      a.Name(),
+		Title:    a.Options().Title,
+		Template: a.Template(),
+		Data:     data,
+	}
 }
 
-type Keyword struct {
-	Text  string  `json:"text"`
-	Score float64 `json:"score"`
+// Sections -> the analyzers that ran, succeeded or failed, in display order.
+func (a AnalyzeData) Sections() []AnalysisSection {
+	var sections []AnalysisSection
+	for _, an := range a.analyzers {
+		if section, ok := a.section(an); ok {
+			sections = append(sections, section)
+		}
+	}
+	return sections
 }
 
-type KeywordAPIResp struct {
-	Keywords []Keyword `json:"keywords"`
+// section -> false when an has neither a result nor an error.
+func (a AnalyzeData) section(an analyzer.Analyzer) (AnalysisSection, bool) {
+	section := newAnalysisSection(an, a)
+	section.Error = a.Errors[an.Name()]
+	section.Result = a.Results[an.Name()]
+	return section, section.Error != "" || section.Result != nil
 }
 
-type AnalyzeData struct {
-	OriginalText string
-	Summary      *SummaryAPIResp
-	Sentiment    *SentimentAPIResp
-	Keywords     *KeywordAPIResp
+// ChunkSections -> like Sections, for one chunk of a long document.
+func (a AnalyzeData) ChunkSections(chunk ChunkResult) []AnalysisSection {
+	var sections []AnalysisSection
+	for _, an := range a.analyzers {
+		if result, ok := chunk.Results[an.Name()]; ok {
+			section := newAnalysisSection(an, a)
+			section.Result = result
+			sections = append(sections, section)
+		}
+	}
+	return sections
 }
 
-func GetAnalyzeHandler(c echo.Context) error {
-	var params GetAnalyzeHandlerReq
+// AnalyzerNames -> the analyzers that ran, for forms that run them again.
+func (a AnalyzeData) AnalyzerNames() []string {
+	return analyzerNames(a.analyzers)
+}
+
+// Words -> txt split for rendering; analyzer templates link every word to the thesaurus.
+func (a AnalyzeData) Words(txt string) []TextToken {
+	return tokenizeWords(txt)
+}
 
+// bindAnalyzeRequest -> the text and selected analyzers. a non empty message is the bad request to send back.
+func bindAnalyzeRequest(c echo.Context, analyzers Analyzers) (GetAnalyzeHandlerReq, []analyzer.Analyzer, string) {
+	var params GetAnalyzeHandlerReq
 	if err := c.Bind(&params); err != nil {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v",
-				params,
-			),
-		)
+		return params, nil, fmt.Sprintf("invalid parameters: %v", params)
 	}
 
-	if params.AnalyzeText == "" {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v, analyze-text can't be empty;",
-				params,
-			),
-		)
+	params.AnalyzeURL = strings.TrimSpace(params.AnalyzeURL)
+	if params.AnalyzeText == "" && params.AnalyzeURL == "" {
+		return params, nil, fmt.Sprintf("invalid parameters: %v, analyze-text or analyze-url is required;", params)
 	}
-
-	if params.Summarize != "on" &&
-		params.Sentiment != "on" &&
-		params.Keyword != "on" {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v, must select at least one analyze option;",
-				params,
-			),
-		)
+	if params.AnalyzeText != "" && params.AnalyzeURL != "" {
+		return params, nil, fmt.Sprintf("invalid parameters: %v, send analyze-text or analyze-url, not both;", params)
 	}
 
-	respChan := make(chan APIResponse, 3)
-	wg := &sync.WaitGroup{}
-	txt := url.QueryEscape(params.AnalyzeText)
-
-	if params.Summarize == "on" {
-		wg.Add(1)
-		go doSummaryRequest(wg, txt, respChan)
+	selected, err := selectedAnalyzers(c, analyzers, params.Analyzers)
+	if err != nil {
+		return params, nil, fmt.Sprintf("invalid parameters: %v, %v;", params, err)
 	}
-
-	if params.Sentiment == "on" {
-		wg.Add(1)
-		go doSentimentRequest(wg, txt, respChan)
+	if len(selected) == 0 {
+		return params, nil, fmt.Sprintf("invalid parameters: %v, must select at least one analyze option;", params)
 	}
+	selected, err = useEngine(selected, params.Engine)
+	if err != nil {
+		return params, nil, fmt.Sprintf("invalid parameters: %v, %v;", params, err)
+	}
+	return params, selected, ""
+}
 
-	if params.Keyword == "on" {
-		wg.Add(1)
-		go doKeywordRequest(wg, txt, respChan)
+// useEngine -> selected set to run on the engine named. an empty name keeps the configured engine.
+func useEngine(selected []analyzer.Analyzer, name string) ([]analyzer.Analyzer, error) {
+	if name == "" {
+		return selected, nil
+	}
+	engine, ok := analyzer.EngineFromName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %q", name)
 	}
+	return analyzer.UseEngine(selected, engine), nil
+}
 
-	wg.Wait()
-	close(respChan)
+// analyzeInput -> the text to analyze: analyze-text as is, or the readable text of the page at analyze-url and
+// where it came from. on false the response has been sent.
+func analyzeInput(c echo.Context, fetcher Fetcher, params GetAnalyzeHandlerReq) (string, *AnalyzeSource, bool, error) {
+	if params.AnalyzeURL == "" {
+		return params.AnalyzeText, nil, true, nil
+	}
 
-	analysisData, err := newAnalyzeDataFromResps(
-		params.AnalyzeText,
-		respChan,
-	)
+	page, err := fetcher.Fetch(c.Request().Context(), params.AnalyzeURL)
 	if err != nil {
-		return c.String(
-			http.StatusInternalServerError,
-			err.Error(),
+		status, msg := fetchErrorMessage(err)
+		if status >= http.StatusInternalServerError {
+			c.Logger().Error(err)
+		}
+		return "", nil, false, c.String(status, fmt.Sprintf("failed to fetch %s: %s;", params.AnalyzeURL, msg))
+	}
+	if page.Text == "" {
+		return "", nil, false, c.String(
+			http.StatusUnprocessableEntity,
+			fmt.Sprintf("failed to fetch %s: no readable text found;", params.AnalyzeURL),
 		)
 	}
-
-	fmt.Printf("\n\n analysisData: %v \n\n", analysisData)
-
-	return c.Render(http.StatusOK, "analysis", analysisData)
+	return page.Text, newAnalyzeSource(page), true, nil
 }
 
-func doSummaryRequest(wg *sync.WaitGroup, txt string, respChan chan<- APIResponse) {
-	defer wg.Done()
-	resp, err := http.Get(
-		fmt.Sprintf(
-			"http://wordser:8080/api/v1/summary?txt=%s",
-			txt,
-		),
-	)
-	fmt.Printf("\n\n summarize resp: %v, err: %v \n\n", resp, err)
-	if err != nil {
-		respChan <- APIResponse{
-			api: supportedAPISummary,
-			err: err,
-		}
-		return
+func newAnalyzeSource(page *fetch.Page) *AnalyzeSource {
+	return &AnalyzeSource{
+		URL: page.URL,
+		AnalysisSource: postgres.AnalysisSource{
+			FinalURL:    page.FinalURL,
+			StatusCode:  page.StatusCode,
+			ContentType: page.ContentType,
+			Title:       page.Title,
+			Description: page.Description,
+			Lang:        page.Lang,
+			Bytes:       page.Bytes,
+			Truncated:   page.Truncated,
+			FetchedAt:   page.FetchedAt,
+			FetchMS:     page.Duration.Milliseconds(),
+		},
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		respChan <- APIResponse{
-			api: supportedAPISummary,
-			err: fmt.Errorf("failed to get summary; statusCode: %d", resp.StatusCode),
-		}
-		return
+}
+
+// fetchErrorMessage -> the status to answer a failed analyze-url fetch with and what to tell the user. the full
+// error is only logged.
+func fetchErrorMessage(err error) (int, string) {
+	var statusErr *fetch.StatusError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, fetch.ErrInvalidURL):
+		return http.StatusBadRequest, "expected an absolute http or https url without credentials"
+	case errors.Is(err, fetch.ErrBlockedAddress):
+		return http.StatusBadRequest, "the address isn't allowed"
+	case errors.Is(err, fetch.ErrTooManyRedirects):
+		return http.StatusBadRequest, "too many redirects"
+	case errors.Is(err, fetch.ErrUnsupportedContent):
+		return http.StatusUnprocessableEntity, "only html and plain text pages can be analyzed"
+	case errors.As(err, &statusErr):
+		return http.StatusBadGateway, fmt.Sprintf("the page answered with status %d", statusErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout, "the page took too long to load"
+	default:
+		return http.StatusBadGateway, "the page is unreachable"
 	}
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		respChan <- APIResponse{
-			api: supportedAPISummary,
-			err: err,
+// selectedAnalyzers -> the analyzers that are named or whose form toggle is on, in registry order.
+func selectedAnalyzers(c echo.Context, analyzers Analyzers, names []string) ([]analyzer.Analyzer, error) {
+	selected := append([]string{}, names...)
+	for _, a := range analyzers.All() {
+		if c.FormValue(a.Options().Field) == "on" {
+			selected = append(selected, a.Name())
 		}
-		return
 	}
+	return analyzersFromNames(analyzers, selected)
+}
 
-	respChan <- APIResponse{
-		api:  supportedAPISummary,
-		data: data,
-		err:  nil,
+// analyzerNames -> the names of analyzers, e.g. for Options of a stored analysis.
+func analyzerNames(analyzers []analyzer.Analyzer) []string {
+	names := make([]string, 0, len(analyzers))
+	for _, a := range analyzers {
+		names = append(names, a.Name())
 	}
+	return names
 }
 
-func doSentimentRequest(wg *sync.WaitGroup, txt string, respChan chan<- APIResponse) {
-	defer wg.Done()
-	resp, err := http.Get(
-		fmt.Sprintf(
-			"http://wordser:8080/api/v1/sentiment?txt=%s",
-			txt,
-		),
-	)
-	fmt.Printf("\n\n sentiment resp: %v, err: %v \n\n", resp, err)
-	if err != nil {
-		respChan <- APIResponse{
-			api: supportedAPISentiment,
-			err: err,
+// analyzersFromNames -> the registered analyzers named, in registry order. unknown names are an error.
+func analyzersFromNames(analyzers Analyzers, names []string) ([]analyzer.Analyzer, error) {
+	named := map[string]bool{}
+	for _, name := range names {
+		if _, ok := analyzers.Get(name); !ok {
+			return nil, fmt.Errorf("unknown analyzer %q", name)
 		}
-		return
+		named[name] = true
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		respChan <- APIResponse{
-			api: supportedAPISentiment,
-			err: fmt.Errorf("failed to get sentiment; statusCode: %d", resp.StatusCode),
+	selected := make([]analyzer.Analyzer, 0, len(named))
+	for _, a := range analyzers.All() {
+		if named[a.Name()] {
+			selected = append(selected, a)
 		}
-		return
 	}
+	return selected, nil
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		respChan <- APIResponse{
-			api: supportedAPISentiment,
-			err: err,
+// GetAnalyzeHandler -> routed for GET and POST; long documents don't fit in a query string.
+func GetAnalyzeHandler(deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		params, selected, invalid := bindAnalyzeRequest(c, deps.Analyzers)
+		if invalid != "" {
+			return c.String(http.StatusBadRequest, invalid)
 		}
-		return
-	}
 
-	respChan <- APIResponse{
-		api:  supportedAPISentiment,
-		data: data,
-		err:  nil,
-	}
-}
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
 
-func doKeywordRequest(wg *sync.WaitGroup, txt string, respChan chan<- APIResponse) {
-	defer wg.Done()
-	resp, err := http.Get(
-		fmt.Sprintf(
-			"http://wordser:8080/api/v1/extract?txt=%s",
+		txt, source, ok, err := analyzeInput(c, deps.Fetcher, params)
+		if !ok {
+			return err
+		}
+		txt, language, ok, err := analyzeLanguage(c, deps, userCtx, selected, txt, params.TranslateFrom)
+		if !ok {
+			return err
+		}
+
+		ctx := c.Request().Context()
+		chars := usage.CharCount(txt)
+		reservation, err := deps.Meter.Reserve(ctx, userCtx, chars*len(selected))
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+
+		if params.Background {
+			payload := AnalyzeJobPayload{
+				Text:        txt,
+				APIs:        analyzerNames(selected),
+				Engine:      params.Engine,
+				Source:      source,
+				Language:    language,
+				Reservation: reservation,
+			}
+			return enqueueJob(c, deps.Jobs, deps.Meter, reservation, userCtx, jobser.KindAnalyze, payload)
+		}
+		defer releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+
+		start := time.Now()
+		respChan := make(chan APIResponse, len(selected))
+		wg := &sync.WaitGroup{}
+
+		for _, a := range selected {
+			wg.Add(1)
+			go doAnalyzerRequest(ctx, wg, deps.Chunker, a, txt, respChan)
+		}
+
+		wg.Wait()
+		close(respChan)
+
+		for _, a := range selected {
+			if err := deps.Meter.Record(ctx, userCtx, usage.API(a.Name()), chars); err != nil {
+				c.Logger().Error(err)
+			}
+		}
+
+		resps := make([]APIResponse, 0, len(selected))
+		for resp := range respChan {
+			resps = append(resps, resp)
+		}
+
+		analysisData, apiErrs := newAnalyzeDataFromResps(
 			txt,
-		),
-	)
-	fmt.Printf("\n\n keyword resp: %v, err: %v \n\n", resp, err)
-	if err != nil {
-		respChan <- APIResponse{
-			api: supportedAPIKeyword,
-			err: err,
+			selected,
+			resps,
+		)
+		analysisData.Source = source
+		analysisData.Engine = params.Engine
+		analysisData.Language = language
+
+		for api, err := range apiErrs {
+			c.Logger().Errorf("failed get response from api: %v; err: %v;", api, err)
 		}
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		respChan <- APIResponse{
-			api: supportedAPIKeyword,
-			err: fmt.Errorf("failed to get extracted keywords; statusCode: %d", resp.StatusCode),
+
+		record, err := newAnalysisRecord(userCtx, selected, analysisData, resps, time.Since(start))
+		if err == nil {
+			analysisData.HistoryID, err = deps.History.InsertAnalysis(ctx, record)
 		}
-		return
+		if err != nil {
+			c.Logger().Error(err)
+		}
+		deps.Hooks.Publish(ctx, userCtx, webhook.EventAnalysisCompleted, newAnalysisCompletedEvent(selected, analysisData))
+
+		// failed analyzers render as errors with a retry next to the ones that succeeded.
+		return c.Render(http.StatusOK, "analysis", analysisData)
 	}
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		respChan <- APIResponse{
-			api: supportedAPIKeyword,
-			err: err,
+type GetAnalyzeRetryHandlerReq struct {
+	API         string `query:"api" form:"api"`
+	AnalyzeText string `query:"analyze-text" form:"analyze-text"`
+	HistoryID   int64  `query:"history-id" form:"history-id"`
+	Engine      string `query:"engine" form:"engine"`
+}
+
+// GetAnalyzeRetryHandler -> re-run one failed analyzer and render only its section. a saved analysis is updated
+// with the new result.
+func GetAnalyzeRetryHandler(deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var params GetAnalyzeRetryHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return c.String(http.StatusBadRequest, "invalid parameters")
+		}
+		a, ok := deps.Analyzers.Get(params.API)
+		if !ok {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("invalid parameters: unknown api %q;", params.API))
+		}
+		switched, err := useEngine([]analyzer.Analyzer{a}, params.Engine)
+		if err != nil {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("invalid parameters: %v;", err))
 		}
-		return
+		a = switched[0]
+
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		ctx := c.Request().Context()
+		txt := params.AnalyzeText
+		var record *postgres.Analysis
+		if params.HistoryID != 0 {
+			var err error
+			record, err = deps.History.GetAnalysis(ctx, userCtx.Username, params.HistoryID)
+			if err != nil {
+				if errors.Is(err, postgres.ErrNotFound) {
+					return c.String(http.StatusNotFound, err.Error())
+				}
+				c.Logger().Error(err)
+				return c.String(http.StatusInternalServerError, "failed to get analysis")
+			}
+			txt = record.OriginalText
+		}
+		if txt == "" {
+			return c.String(http.StatusBadRequest, "invalid parameters: analyze-text can't be empty;")
+		}
+
+		chars := usage.CharCount(txt)
+		reservation, err := deps.Meter.Reserve(ctx, userCtx, chars)
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+		defer releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+
+		resp := runAnalyzer(ctx, deps.Chunker, a, txt)
+		if err := deps.Meter.Record(ctx, userCtx, usage.API(a.Name()), chars); err != nil {
+			c.Logger().Error(err)
+		}
+
+		data, apiErrs := newAnalyzeDataFromResps(txt, []analyzer.Analyzer{a}, []APIResponse{resp})
+		data.Engine = params.Engine
+		for api, err := range apiErrs {
+			c.Logger().Errorf("failed get response from api: %v; err: %v;", api, err)
+		}
+
+		if record != nil {
+			if err := setRecordResults(record, data); err != nil {
+				c.Logger().Error(err)
+			}
+			if record.Errors == nil {
+				record.Errors = map[string]string{}
+			}
+			if record.TimingsMS == nil {
+				record.TimingsMS = map[string]int64{}
+			}
+			delete(record.Errors, a.Name())
+			if msg, ok := data.Errors[a.Name()]; ok {
+				record.Errors[a.Name()] = msg
+			}
+			record.TimingsMS[a.Name()] = resp.duration.Milliseconds()
+			if err := deps.History.UpdateAnalysisResults(ctx, record); err != nil {
+				c.Logger().Error(err)
+			}
+			data.HistoryID = record.ID
+		}
+
+		section, _ := data.section(a)
+		return c.Render(http.StatusOK, "analysis_section", section)
 	}
+}
+
+// doAnalyzerRequest -> run a and send the result, or why it failed, to respChan.
+func doAnalyzerRequest(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	chunker Chunker,
+	a analyzer.Analyzer,
+	txt string,
+	respChan chan<- APIResponse,
+) {
+	defer wg.Done()
+	respChan <- runAnalyzer(ctx, chunker, a, txt)
+}
 
-	respChan <- APIResponse{
-		api:  supportedAPIKeyword,
-		data: data,
-		err:  nil,
+func callAnalyzer(ctx context.Context, a analyzer.Analyzer, txt string) APIResponse {
+	start := time.Now()
+	resp := APIResponse{analyzer: a}
+	resp.result, resp.err = a.Run(ctx, txt)
+	resp.duration = time.Since(start)
+	return resp
+}
+
+// apiErrorMessage -> what to tell the user about a failed analyzer. the full error is only logged.
+func apiErrorMessage(err error) string {
+	var statusErr *wordser.StatusError
+	var networkErr *wordser.NetworkError
+	var decodeErr *wordser.DecodeError
+	var chunkErr *chunkError
+	if errors.As(err, &chunkErr) {
+		return fmt.Sprintf("chunk %d of %d: %s", chunkErr.index+1, chunkErr.total, apiErrorMessage(chunkErr.err))
+	}
+	switch {
+	case errors.Is(err, resilience.ErrCircuitOpen), errors.Is(err, resilience.ErrBulkheadFull):
+		return "the analyzer is temporarily unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "the analyzer timed out"
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("the analyzer failed with status %d", statusErr.StatusCode)
+	case errors.As(err, &networkErr):
+		return "the analyzer is unreachable"
+	case errors.As(err, &decodeErr):
+		return "the analyzer returned an unexpected response"
+	default:
+		return "the analyzer failed"
 	}
 }
 
-func newAnalyzeDataFromResps(originalText string, respChan <-chan APIResponse) (AnalyzeData, error) {
+// newAnalyzeDataFromResps -> collect every response of the selected analyzers. failed ones get a message in
+// AnalyzeData.Errors and their full error, keyed by analyzer name, in the returned map.
+func newAnalyzeDataFromResps(
+	originalText string,
+	selected []analyzer.Analyzer,
+	resps []APIResponse,
+) (AnalyzeData, map[string]error) {
 	analysisData := AnalyzeData{
 		OriginalText: originalText,
+		Results:      map[string]analyzer.Result{},
+		Errors:       map[string]string{},
+		analyzers:    selected,
 	}
+	apiErrs := map[string]error{}
 
-	for resp := range respChan {
-		fmt.Printf("\n\n %v \n\n", resp)
+	for _, resp := range resps {
+		name := resp.analyzer.Name()
 		if resp.err != nil {
-			fmt.Printf(
-				"failed get response from api: %v;",
-				resp.api,
-			)
-			return analysisData, fmt.Errorf("failed get response from api")
-		}
-		switch resp.api {
-		case supportedAPISummary:
-			analysisData.Summary = &SummaryAPIResp{}
-			if err := json.Unmarshal(resp.data, analysisData.Summary); err != nil {
-				fmt.Printf(
-					"failed to unmarshal Summary API response: %v;",
-					resp.data,
-				)
-				return analysisData, fmt.Errorf("failed to unmarshal Summary API response")
-			}
-		case supportedAPISentiment:
-			analysisData.Sentiment = &SentimentAPIResp{}
-			if err := json.Unmarshal(resp.data, analysisData.Sentiment); err != nil {
-				fmt.Printf(
-					"failed to unmarshal Sentiment API response: %v;",
-					resp.data,
-				)
-				return analysisData, fmt.Errorf("failed to unmarshal Sentiment API response")
-			}
-		case supportedAPIKeyword:
-			analysisData.Keywords = &KeywordAPIResp{}
-			if err := json.Unmarshal(resp.data, analysisData.Keywords); err != nil {
-				fmt.Printf(
-					"failed to unmarshal Keyword API response: %v;",
-					resp.data,
-				)
-				return analysisData, fmt.Errorf("failed to unmarshal Keyword API response")
-			}
+			apiErrs[name] = resp.err
+			analysisData.Errors[name] = apiErrorMessage(resp.err)
+			continue
 		}
+		analysisData.Results[name] = resp.result
 	}
-	return analysisData, nil
+	analysisData.Chunks = newChunkResults(resps)
+	return analysisData, apiErrs
 }
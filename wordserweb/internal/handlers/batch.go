@@ -0,0 +1,577 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/batch"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	batchesPageSize = 50
+	// batchFailedRowsShown -> failed rows listed on the batch page. the download has all of them.
+	batchFailedRowsShown = 100
+	// batchNoTextError -> Errors key of a row without text; no analyzer ran on it.
+	batchNoTextError = "text"
+)
+
+// BatchJobPayload -> input of a jobser.KindBatch job.
+type BatchJobPayload struct {
+	BatchID int64 `json:"batch_id"`
+	// Reservation -> the usage held for the rows when they were queued; handed on to a continuation, and released
+	// once the batch is done.
+	Reservation usage.Reservation `json:"reservation,omitempty"`
+}
+
+type BatchJobResult struct {
+	BatchID   int64 `json:"batch_id"`
+	Succeeded int   `json:"succeeded"`
+	Failed    int   `json:"failed"`
+	// ContinuedBy -> the job picking up the rows this one ran out of time for.
+	ContinuedBy int64 `json:"continued_by,omitempty"`
+}
+
+type BatchView struct {
+	*postgres.Batch
+	Job *postgres.Job
+	// ListedFailedRows -> the first batchFailedRowsShown failed rows.
+	ListedFailedRows []*postgres.BatchRow
+}
+
+// Done -> every row has been analyzed, successfully or not.
+func (b BatchView) Done() bool {
+	return b.PendingRows == 0
+}
+
+// Stalled -> rows are left but no job is working on them anymore.
+func (b BatchView) Stalled() bool {
+	return !b.Done() && (b.Job == nil || jobser.State(b.Job.State).Done())
+}
+
+// Percent -> share of rows analyzed so far.
+func (b BatchView) Percent() int {
+	if b.TotalRows == 0 {
+		return 100
+	}
+	return (b.SucceededRows + b.FailedRows) * 100 / b.TotalRows
+}
+
+// MoreFailedRows -> failed rows not in ListedFailedRows.
+func (b BatchView) MoreFailedRows() int {
+	return b.FailedRows - len(b.ListedFailedRows)
+}
+
+type BatchesPageData struct {
+	// Analyzers -> the analyzer toggles of the upload form.
+	Analyzers      []analyzer.Options
+	Batches        []BatchView
+	Error          string
+	MaxRows        int
+	MaxUploadBytes int64
+}
+
+// BatchJob -> analyze the pending rows of a batch, batch.Config.Concurrency rows at a time. a row fails when any
+// of its analyzers does; re-run rows only call the analyzers that failed. when the lease runs out mid batch the
+// rest is handed to a new job rather than counted as a failed attempt.
+func BatchJob(config batch.Config, deps Deps) jobser.Handler {
+	concurrency := config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return func(ctx context.Context, job *postgres.Job) (any, error) {
+		var payload BatchJobPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, resilience.Permanent(err)
+		}
+
+		userCtx := jobser.UserContext(job)
+		b, err := deps.Batches.GetBatch(ctx, userCtx.Username, payload.BatchID)
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return nil, resilience.Permanent(err)
+			}
+			return nil, err
+		}
+		selected, err := analyzersFromNames(deps.Analyzers, b.Options)
+		if err != nil {
+			return nil, resilience.Permanent(err)
+		}
+
+		// rows held by another attempt, a continuation still finishing say, are left to it.
+		rows, err := deps.Batches.ClaimBatchRows(ctx, b.ID, job.ID, job.Attempts, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var mu sync.Mutex
+		var storeErr error
+		sem := semaphore.NewWeighted(concurrency)
+		wg := &sync.WaitGroup{}
+		for _, row := range rows {
+			if err := sem.Acquire(ctx, 1); err != nil {
+				break
+			}
+			wg.Add(1)
+			go func(row *postgres.BatchRow) {
+				defer wg.Done()
+				defer sem.Release(1)
+				err := analyzeBatchRow(ctx, deps.Chunker, deps.Meter, userCtx, selected, row)
+				if err == nil {
+					if ctx.Err() != nil && row.State == postgres.BatchRowStateFailed {
+						// cut off by ctx: what came back is kept, and the row stays pending for the next job to
+						// run the analyzers that didn't finish.
+						row.State = postgres.BatchRowStatePending
+					}
+					// stored even once ctx is done, so the next job doesn't redo, and bill again, what this one did.
+					err = deps.Batches.UpdateBatchRow(context.WithoutCancel(ctx), row)
+				}
+				if err != nil {
+					mu.Lock()
+					storeErr = errors.Join(storeErr, err)
+					mu.Unlock()
+				}
+			}(row)
+		}
+		// every row started is stored before the rest are handed on.
+		wg.Wait()
+
+		result := BatchJobResult{BatchID: b.ID}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			if storeErr != nil {
+				return nil, storeErr
+			}
+			// the rows not started, for the next job to claim while this one is still finishing.
+			if err := deps.Batches.ReleaseBatchRows(context.WithoutCancel(ctx), b.ID, job.ID); err != nil {
+				return nil, err
+			}
+			next, err := deps.Jobs.Enqueue(context.WithoutCancel(ctx), userCtx, jobser.KindBatch, payload)
+			if err != nil {
+				return nil, err
+			}
+			if err := deps.Batches.SetBatchJob(context.WithoutCancel(ctx), b.ID, next); err != nil {
+				return nil, err
+			}
+			result.ContinuedBy = next
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if storeErr != nil {
+			return nil, storeErr
+		}
+
+		b, err = deps.Batches.GetBatch(ctx, userCtx.Username, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Succeeded = b.SucceededRows
+		result.Failed = b.FailedRows
+		releaseUsage(ctx, deps.Logger, deps.Meter, payload.Reservation)
+		deps.Hooks.Publish(ctx, userCtx, webhook.EventBatchCompleted, BatchCompletedEvent{
+			BatchID:   b.ID,
+			Filename:  b.Filename,
+			TotalRows: b.TotalRows,
+			Succeeded: b.SucceededRows,
+			Failed:    b.FailedRows,
+		})
+		return result, nil
+	}
+}
+
+// analyzeBatchRow -> run selected over row and set its results, errors and state. a row that failed before only
+// runs the analyzers it failed on. the error is for metering or encoding; analyzer failures end up in row.Errors.
+func analyzeBatchRow(
+	ctx context.Context,
+	chunker Chunker,
+	meter UsageMeterer,
+	userCtx auth.UserContext,
+	selected []analyzer.Analyzer,
+	row *postgres.BatchRow,
+) error {
+	if row.Text == "" {
+		row.State = postgres.BatchRowStateFailed
+		row.Errors = map[string]string{batchNoTextError: "the row has no text"}
+		return nil
+	}
+
+	run := selected
+	if len(row.Errors) > 0 && row.Errors[batchNoTextError] == "" {
+		run = nil
+		for _, a := range selected {
+			if _, failed := row.Errors[a.Name()]; failed {
+				run = append(run, a)
+			}
+		}
+	}
+
+	chars := usage.CharCount(row.Text)
+	resps := make([]APIResponse, 0, len(run))
+	for _, a := range run {
+		resps = append(resps, runAnalyzer(ctx, chunker, a, row.Text))
+		if err := meter.Record(context.WithoutCancel(ctx), userCtx, usage.API(a.Name()), chars); err != nil {
+			return err
+		}
+	}
+
+	data, _ := newAnalyzeDataFromResps(row.Text, run, resps)
+	if row.Results == nil {
+		row.Results = map[string]json.RawMessage{}
+	}
+	if row.Errors == nil {
+		row.Errors = map[string]string{}
+	}
+	for _, a := range run {
+		delete(row.Errors, a.Name())
+	}
+	for name, msg := range data.Errors {
+		row.Errors[name] = msg
+	}
+	for name, result := range data.Results {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s result: %w", name, err)
+		}
+		row.Results[name] = raw
+	}
+
+	row.State = postgres.BatchRowStateSucceeded
+	if len(row.Errors) > 0 {
+		row.State = postgres.BatchRowStateFailed
+	}
+	return nil
+}
+
+func renderBatches(
+	c echo.Context,
+	config batch.Config,
+	analyzers Analyzers,
+	batches BatchStorer,
+	status int,
+	msg string,
+) error {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return c.String(http.StatusUnauthorized, "no credentials")
+	}
+
+	list, err := batches.ListBatches(c.Request().Context(), userCtx.Username, batchesPageSize)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "failed to get batches")
+	}
+
+	data := BatchesPageData{
+		Analyzers:      analyzerOptions(analyzers),
+		Error:          msg,
+		MaxRows:        config.MaxRows,
+		MaxUploadBytes: config.MaxUploadBytes,
+	}
+	for _, b := range list {
+		data.Batches = append(data.Batches, BatchView{Batch: b})
+	}
+	return c.Render(status, "batches", data)
+}
+
+func GetBatchesHandler(config batch.Config, analyzers Analyzers, batches BatchStorer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return renderBatches(c, config, analyzers, batches, http.StatusOK, "")
+	}
+}
+
+// PostBatchHandler -> store an uploaded csv or jsonl file and queue the job analyzing it.
+func PostBatchHandler(config batch.Config, deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		badRequest := func(status int, msg string) error {
+			return renderBatches(c, config, deps.Analyzers, deps.Batches, status, msg)
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return badRequest(http.StatusBadRequest, "choose a file to upload")
+		}
+		if file.Size > config.MaxUploadBytes {
+			return badRequest(
+				http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("the file is too large; at most %d bytes;", config.MaxUploadBytes),
+			)
+		}
+		format, ok := batch.FormatFromFilename(file.Filename)
+		if !ok {
+			return badRequest(http.StatusBadRequest, "expected a .csv or .jsonl file")
+		}
+
+		selected, err := selectedAnalyzers(c, deps.Analyzers, nil)
+		if err != nil {
+			return badRequest(http.StatusBadRequest, err.Error())
+		}
+		if len(selected) == 0 {
+			return badRequest(http.StatusBadRequest, "select at least one analyze option")
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			c.Logger().Error(err)
+			return badRequest(http.StatusInternalServerError, "failed to read the file")
+		}
+		defer src.Close()
+
+		textColumn := strings.TrimSpace(c.FormValue("text-column"))
+		parsed, err := batch.Parse(io.LimitReader(src, config.MaxUploadBytes), format, textColumn, config.MaxRows)
+		if err != nil {
+			return badRequest(http.StatusBadRequest, fmt.Sprintf("failed to read %s: %v", file.Filename, err))
+		}
+
+		chars := 0
+		rows := make([]*postgres.BatchRow, 0, len(parsed.Rows))
+		for _, r := range parsed.Rows {
+			chars += usage.CharCount(r.Text)
+			rows = append(rows, &postgres.BatchRow{RowIndex: r.Index, Source: r.Source, Text: r.Text})
+		}
+
+		ctx := c.Request().Context()
+		reservation, err := deps.Meter.Reserve(ctx, userCtx, chars*len(selected))
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return badRequest(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return badRequest(http.StatusInternalServerError, "failed to check usage quota")
+		}
+
+		if textColumn == "" {
+			textColumn = batch.DefaultTextColumn
+			if format == batch.FormatCSV && len(parsed.Columns) == 1 {
+				textColumn = parsed.Columns[0]
+			}
+		}
+		record := &postgres.Batch{
+			Username:   userCtx.Username,
+			Filename:   path.Base(file.Filename),
+			Format:     format.String(),
+			TextColumn: textColumn,
+			Columns:    parsed.Columns,
+			Options:    analyzerNames(selected),
+		}
+		if userCtx.OrgID != 0 {
+			orgID := userCtx.OrgID
+			record.OrganizationID = &orgID
+		}
+
+		id, err := deps.Batches.InsertBatch(ctx, record, rows)
+		if err != nil {
+			c.Logger().Error(err)
+			releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+			return badRequest(http.StatusInternalServerError, "failed to save batch")
+		}
+		if err := startBatchJob(ctx, deps.Batches, deps.Jobs, userCtx, id, reservation); err != nil {
+			c.Logger().Error(err)
+			releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+			return badRequest(http.StatusInternalServerError, "failed to queue batch")
+		}
+
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/batches/%d", id))
+	}
+}
+
+func startBatchJob(
+	ctx context.Context,
+	batches BatchStorer,
+	jobs JobQueuer,
+	userCtx auth.UserContext,
+	id int64,
+	reservation usage.Reservation,
+) error {
+	jobID, err := jobs.Enqueue(ctx, userCtx, jobser.KindBatch, BatchJobPayload{BatchID: id, Reservation: reservation})
+	if err != nil {
+		return err
+	}
+	return batches.SetBatchJob(ctx, id, jobID)
+}
+
+// batchFromParam -> the :id batch of the session's user with its job and first failed rows. on false the
+// response has been sent.
+func batchFromParam(c echo.Context, batches BatchStorer, jobs JobQueuer) (BatchView, bool, error) {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return BatchView{}, false, c.String(http.StatusUnauthorized, "no credentials")
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return BatchView{}, false, c.String(http.StatusBadRequest, "invalid batch id")
+	}
+
+	ctx := c.Request().Context()
+	b, err := batches.GetBatch(ctx, userCtx.Username, id)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return BatchView{}, false, c.String(http.StatusNotFound, err.Error())
+		}
+		c.Logger().Error(err)
+		return BatchView{}, false, c.String(http.StatusInternalServerError, "failed to get batch")
+	}
+	view := BatchView{Batch: b}
+
+	if b.JobID != nil {
+		view.Job, err = jobs.Job(ctx, userCtx, *b.JobID)
+		if err != nil && !errors.Is(err, postgres.ErrNotFound) {
+			c.Logger().Error(err)
+		}
+	}
+	if b.FailedRows > 0 {
+		view.ListedFailedRows, err = batches.ListBatchRows(ctx, b.ID, postgres.BatchRowStateFailed, batchFailedRowsShown)
+		if err != nil {
+			c.Logger().Error(err)
+			return BatchView{}, false, c.String(http.StatusInternalServerError, "failed to get batch rows")
+		}
+	}
+	return view, true, nil
+}
+
+func GetBatchHandler(batches BatchStorer, jobs JobQueuer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		view, ok, err := batchFromParam(c, batches, jobs)
+		if !ok {
+			return err
+		}
+		return c.Render(http.StatusOK, "batch", view)
+	}
+}
+
+// GetBatchProgressHandler -> htmx fragment that polls itself while the batch runs.
+func GetBatchProgressHandler(batches BatchStorer, jobs JobQueuer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		view, ok, err := batchFromParam(c, batches, jobs)
+		if !ok {
+			return err
+		}
+		return c.Render(http.StatusOK, "batch_progress", view)
+	}
+}
+
+// GetBatchResultsHandler -> download every row with its results as csv or jsonl, the upload's format by default.
+func GetBatchResultsHandler(analyzers Analyzers, batches BatchStorer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid batch id")
+		}
+
+		ctx := c.Request().Context()
+		b, err := batches.GetBatch(ctx, userCtx.Username, id)
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return c.String(http.StatusNotFound, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get batch")
+		}
+
+		format := batch.Format(b.Format)
+		if f := c.QueryParam("format"); f != "" {
+			format = batch.Format(f)
+			if format != batch.FormatCSV && format != batch.FormatJSONL {
+				return c.String(http.StatusBadRequest, "invalid parameters: format must be csv or jsonl;")
+			}
+		}
+
+		selected, err := analyzersFromNames(analyzers, b.Options)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get batch analyzers")
+		}
+
+		rows, err := batches.ListBatchRows(ctx, b.ID, "", 0)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get batch rows")
+		}
+
+		name := strings.TrimSuffix(b.Filename, path.Ext(b.Filename)) + "-results." + format.String()
+		c.Response().Header().Set(echo.HeaderContentType, format.ContentType()+"; charset=utf-8")
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+		c.Response().WriteHeader(http.StatusOK)
+		if err := batch.Write(c.Response(), format, b.Columns, selected, rows); err != nil {
+			c.Logger().Error(err)
+		}
+		return nil
+	}
+}
+
+// PostBatchRerunHandler -> queue the failed rows of a finished batch again.
+func PostBatchRerunHandler(meter UsageMeterer, batches BatchStorer, jobs JobQueuer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		view, ok, err := batchFromParam(c, batches, jobs)
+		if !ok {
+			return err
+		}
+		if !view.Done() && !view.Stalled() {
+			return c.String(http.StatusConflict, "the batch is still running")
+		}
+		if view.Done() && view.FailedRows == 0 {
+			return c.String(http.StatusBadRequest, "the batch has no failed rows")
+		}
+
+		userCtx, _ := auth.UserContextFromEcho(c)
+		ctx := c.Request().Context()
+		failed, err := batches.ListBatchRows(ctx, view.ID, postgres.BatchRowStateFailed, 0)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get batch rows")
+		}
+		chars := 0
+		for _, row := range failed {
+			chars += usage.CharCount(row.Text) * len(row.Errors)
+		}
+		reservation, err := meter.Reserve(ctx, userCtx, chars)
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+
+		if _, err := batches.ResetFailedBatchRows(ctx, userCtx.Username, view.ID); err != nil {
+			c.Logger().Error(err)
+			releaseUsage(ctx, c.Logger(), meter, reservation)
+			return c.String(http.StatusInternalServerError, "failed to reset batch rows")
+		}
+		if err := startBatchJob(ctx, batches, jobs, userCtx, view.ID, reservation); err != nil {
+			c.Logger().Error(err)
+			releaseUsage(ctx, c.Logger(), meter, reservation)
+			return c.String(http.StatusInternalServerError, "failed to queue batch")
+		}
+
+		view, ok, err = batchFromParam(c, batches, jobs)
+		if !ok {
+			return err
+		}
+		return c.Render(http.StatusOK, "batch_progress", view)
+	}
+}
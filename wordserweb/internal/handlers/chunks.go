@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+	"golang.org/x/sync/semaphore"
+)
+
+// ChunkResult -> what each analyzer made of one chunk of a long document.
+type ChunkResult struct {
+	Index int
+	Text  string
+	// Results/Errors -> keyed by analyzer name, as in AnalyzeData.
+	Results map[string]analyzer.Result
+	Errors  map[string]string
+}
+
+// Number -> 1 based index for display.
+func (c ChunkResult) Number() int {
+	return c.Index + 1
+}
+
+func (c ChunkResult) Len() int {
+	return utf8.RuneCountInString(c.Text)
+}
+
+// chunkError -> the first chunk an analyzer failed on.
+type chunkError struct {
+	index int
+	total int
+	err   error
+}
+
+func (e *chunkError) Error() string {
+	return fmt.Sprintf("chunk %d of %d: %v", e.index+1, e.total, e.err)
+}
+
+func (e *chunkError) Unwrap() error {
+	return e.err
+}
+
+// runAnalyzer -> callAnalyzer when txt fits in one chunk or a can't merge chunks. longer text is analyzed chunk
+// by chunk and the results merged.
+func runAnalyzer(ctx context.Context, chunker Chunker, a analyzer.Analyzer, txt string) APIResponse {
+	merger, ok := a.(analyzer.Merger)
+	if !ok {
+		return callAnalyzer(ctx, a, txt)
+	}
+	chunks := chunker.Split(txt)
+	if len(chunks) <= 1 {
+		return callAnalyzer(ctx, a, txt)
+	}
+
+	start := time.Now()
+	parts := analyzeChunks(ctx, chunker, a, chunks)
+	resp := mergeChunkResponses(ctx, chunker, a, merger, txt, chunks, parts)
+	resp.duration = time.Since(start)
+	return resp
+}
+
+// analyzeChunks -> a over every chunk, at most chunker.Concurrency() at a time. responses are in chunk order.
+func analyzeChunks(ctx context.Context, chunker Chunker, a analyzer.Analyzer, chunks []chunking.Chunk) []APIResponse {
+	parts := make([]APIResponse, len(chunks))
+	sem := semaphore.NewWeighted(chunker.Concurrency())
+	wg := &sync.WaitGroup{}
+	for i, chunk := range chunks {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			parts[i] = APIResponse{analyzer: a, err: err}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, chunk chunking.Chunk) {
+			defer wg.Done()
+			defer sem.Release(1)
+			parts[i] = callAnalyzer(ctx, a, chunk.Text)
+		}(i, chunk)
+	}
+	wg.Wait()
+	for i := range parts {
+		parts[i].chunk = chunks[i]
+	}
+	return parts
+}
+
+// mergeChunkResponses -> one response for the whole document from the analyzer's Merge. the chunk responses
+// are kept for display.
+func mergeChunkResponses(
+	ctx context.Context,
+	chunker Chunker,
+	a analyzer.Analyzer,
+	merger analyzer.Merger,
+	txt string,
+	chunks []chunking.Chunk,
+	parts []APIResponse,
+) APIResponse {
+	resp := APIResponse{analyzer: a, chunks: parts}
+	results := make([]analyzer.Result, 0, len(parts))
+	for i, part := range parts {
+		if part.err != nil {
+			resp.err = &chunkError{index: i, total: len(parts), err: part.err}
+			return resp
+		}
+		results = append(results, part.result)
+	}
+
+	run := func(ctx context.Context, txt string) (analyzer.Result, error) {
+		again := runAnalyzer(ctx, chunker, a, txt)
+		return again.result, again.err
+	}
+	resp.result, resp.err = merger.Merge(ctx, run, txt, chunks, results)
+	return resp
+}
+
+// newChunkResults -> per chunk details from every analyzer's chunk responses. nil when nothing was chunked.
+func newChunkResults(resps []APIResponse) []ChunkResult {
+	var results []ChunkResult
+	for _, resp := range resps {
+		for _, part := range resp.chunks {
+			for len(results) <= part.chunk.Index {
+				results = append(results, ChunkResult{
+					Index:   len(results),
+					Results: map[string]analyzer.Result{},
+					Errors:  map[string]string{},
+				})
+			}
+			result := &results[part.chunk.Index]
+			result.Text = part.chunk.Text
+			if part.err != nil {
+				result.Errors[resp.analyzer.Name()] = apiErrorMessage(part.err)
+				continue
+			}
+			result.Results[resp.analyzer.Name()] = part.result
+		}
+	}
+	return results
+}
@@ -4,8 +4,46 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
 )
 
-func GetDashboardHandler(c echo.Context) error {
-	return c.Render(http.StatusOK, "dashboard", "")
+type DashboardPageData struct {
+	// Analyzers -> the analyzer toggles of the analyze form.
+	Analyzers []analyzer.Options
+	// Engine -> the configured engine, what the engine select defaults to.
+	Engine  analyzer.Engine
+	Engines []analyzer.Engine
+	// SourceLanguages/TargetLanguages -> the translate form's language options, as the providers list them. empty
+	// when none of them could.
+	SourceLanguages []LanguageOption
+	TargetLanguages []LanguageOption
+}
+
+// analyzerOptions -> the Options of every registered analyzer, for the forms' toggles.
+func analyzerOptions(analyzers Analyzers) []analyzer.Options {
+	all := analyzers.All()
+	options := make([]analyzer.Options, 0, len(all))
+	for _, a := range all {
+		options = append(options, a.Options())
+	}
+	return options
+}
+
+func GetDashboardHandler(analyzers Analyzers, engine analyzer.Engine, translator Translator) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		data := DashboardPageData{
+			Analyzers: analyzerOptions(analyzers),
+			Engine:    engine,
+			Engines:   analyzer.Engines,
+		}
+		catalog, err := translator.Languages(c.Request().Context())
+		if err != nil {
+			// the rest of the dashboard works without translation.
+			c.Logger().Error(err)
+			return c.Render(http.StatusOK, "dashboard", data)
+		}
+		data.SourceLanguages = languageOptions(catalog.Sources(), "", displayLanguage(c))
+		data.TargetLanguages = targetOptions(c, catalog, AutoDetect.String(), "")
+		return c.Render(http.StatusOK, "dashboard", data)
+	}
 }
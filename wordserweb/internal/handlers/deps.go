@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+)
+
+// Deps -> the services shared by handlers and job handlers that need more than a couple of them. each uses only
+// some; the others may be left nil.
+type Deps struct {
+	Analyzers    Analyzers
+	Chunker      Chunker
+	Meter        UsageMeterer
+	History      AnalysisStorer
+	Translations TranslationStorer
+	Batches      BatchStorer
+	Jobs         JobQueuer
+	Hooks        WebhookPublisher
+	Fetcher      Fetcher
+	Translator   Translator
+	Detector     LanguageDetector
+	Glossaries   Glossarier
+	Memories     Memorizer
+	// Logger -> where job handlers report what they can't return, e.g. a usage reservation that wasn't released.
+	Logger jobser.Logger
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/document"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+)
+
+type PostTranslateDocumentHandlerReq struct {
+	Document string `form:"translate-text"`
+	// Format -> markdown or html.
+	Format         string            `form:"document-format"`
+	SourceLanguage TranslateLanguage `form:"source-language"`
+	TargetLanguage TranslateLanguage `form:"target-language"`
+}
+
+type TranslateDocumentData struct {
+	SourceLanguage string
+	TargetLanguage string
+	Format         document.Format
+	Provider       string
+	Report         document.Report
+	// Output -> the translated document as it is, Preview it rendered as html.
+	Output  string
+	Preview string
+}
+
+// PostTranslateDocumentHandler -> translate a markdown or html document, only its human-readable text. code, urls,
+// tags and their attributes are kept as they are, see document.Parse.
+func PostTranslateDocumentHandler(config document.Config, documents Documenter, deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var params PostTranslateDocumentHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return c.String(http.StatusBadRequest, "invalid parameters")
+		}
+		if params.Document == "" {
+			return c.String(http.StatusBadRequest, "invalid parameters: translate-text can't be empty;")
+		}
+		format, ok := document.ParseFormat(params.Format)
+		if !ok {
+			return c.String(http.StatusBadRequest, "invalid parameters: document-format must be markdown or html;")
+		}
+		if utf8.RuneCountInString(params.Document) > config.MaxChars {
+			return c.String(
+				http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("the document is too long; at most %d characters;", config.MaxChars),
+			)
+		}
+		doc, err := document.Parse(params.Document, format)
+		if err != nil {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("invalid parameters: %v;", err))
+		}
+
+		ctx := c.Request().Context()
+		catalog, err := deps.Translator.Languages(ctx)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusServiceUnavailable, "translation is temporarily unavailable; try again shortly;")
+		}
+
+		ui := displayLanguage(c)
+		source := params.SourceLanguage.String()
+		if params.SourceLanguage == "" || params.SourceLanguage == AutoDetect {
+			detected := deps.Detector.Detect(doc.Text())
+			if !detected.Reliable {
+				return c.String(
+					http.StatusUnprocessableEntity,
+					"couldn't detect the language of the document; pick a source-language;",
+				)
+			}
+			source = detected.Language
+		}
+		source, ok = catalog.ResolveSource(source)
+		if !ok {
+			return c.String(http.StatusBadRequest, "invalid parameters: source-language not supported;")
+		}
+		target, ok := catalog.ResolveTarget(params.TargetLanguage.String())
+		if params.TargetLanguage == "" {
+			target, ok = preferredTarget(c, catalog)
+		}
+		if !ok {
+			return c.String(http.StatusBadRequest, "invalid parameters: target-language not supported;")
+		}
+		if source == target {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf("invalid parameters: the document is already in %s;", languageName(target, ui)),
+			)
+		}
+		if !catalog.Supports(source, target) {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf(
+					"invalid parameters: translating %s to %s is not supported;",
+					languageName(source, ui),
+					languageName(target, ui),
+				),
+			)
+		}
+
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		reservation, err := deps.Meter.Reserve(ctx, userCtx, usage.CharCount(doc.Text()))
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+		defer releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+
+		var provider string
+		var lastErr error
+		report := documents.Translate(ctx, doc, func(ctx context.Context, txt string) (string, error) {
+			protected, err := deps.Glossaries.Protect(ctx, userCtx, txt, source, target)
+			if err != nil {
+				lastErr = err
+				return "", err
+			}
+			translated, err := deps.Translator.Translate(ctx, protected.Text, source, target)
+			if err != nil {
+				lastErr = err
+				return "", err
+			}
+			provider = translated.Provider
+			restored, _ := protected.Restore(translated.Text)
+			return restored, nil
+		})
+		if provider == "" && lastErr != nil {
+			c.Logger().Error(lastErr)
+			if errors.Is(lastErr, org.ErrNotMember) {
+				return c.String(http.StatusForbidden, lastErr.Error())
+			}
+			if errors.Is(lastErr, resilience.ErrCircuitOpen) || errors.Is(lastErr, resilience.ErrBulkheadFull) {
+				return c.String(http.StatusServiceUnavailable, "translation is temporarily unavailable; try again shortly;")
+			}
+			return c.String(http.StatusBadGateway, "failed to get translation")
+		}
+
+		// billed once, on the segments delivered.
+		if report.Chars > 0 {
+			if err := deps.Meter.Record(context.WithoutCancel(ctx), userCtx, usage.APITranslate, report.Chars); err != nil {
+				c.Logger().Error(err)
+			}
+		}
+
+		output := doc.String()
+		preview, err := doc.Preview()
+		if err != nil {
+			c.Logger().Error(err)
+		}
+
+		record := &postgres.Translation{
+			Username:       userCtx.Username,
+			SourceLanguage: source,
+			TargetLanguage: target,
+			SourceText:     params.Document,
+			TranslatedText: output,
+			Provider:       provider,
+		}
+		if userCtx.OrgID != 0 {
+			orgID := userCtx.OrgID
+			record.OrganizationID = &orgID
+		}
+		// the event refers to the stored translation by id; there is none to send when storing it failed.
+		if translationID, err := deps.Translations.InsertTranslation(ctx, record); err != nil {
+			c.Logger().Error(err)
+		} else {
+			deps.Hooks.Publish(ctx, userCtx, webhook.EventTranslationCompleted, newTranslationCompletedEvent(translationID, record))
+		}
+
+		return c.Render(http.StatusOK, "translate_document", TranslateDocumentData{
+			SourceLanguage: languageName(source, ui),
+			TargetLanguage: languageName(target, ui),
+			Format:         format,
+			Provider:       provider,
+			Report:         report,
+			Output:         output,
+			Preview:        preview,
+		})
+	}
+}
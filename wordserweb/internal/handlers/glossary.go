@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/glossary"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"golang.org/x/text/language"
+)
+
+// GlossaryView -> a glossary with its language pair named for the page.
+type GlossaryView struct {
+	*postgres.Glossary
+	Pair string
+}
+
+func newGlossaryView(g *postgres.Glossary, in language.Tag) GlossaryView {
+	if g.SourceLanguage == "" {
+		return GlossaryView{Glossary: g, Pair: "Every language pair"}
+	}
+	return GlossaryView{
+		Glossary: g,
+		Pair:     fmt.Sprintf("%s -> %s", languageName(g.SourceLanguage, in), languageName(g.TargetLanguage, in)),
+	}
+}
+
+type GlossariesPageData struct {
+	// OrgID -> the workspace the glossaries belong to, 0 for the personal one.
+	OrgID      int
+	Glossaries []GlossaryView
+	CanEdit    bool
+	// SourceLanguages/TargetLanguages -> the create form's language options. empty when no provider could list
+	// them; a glossary for every language pair can still be made.
+	SourceLanguages []LanguageOption
+	TargetLanguages []LanguageOption
+	Error           string
+}
+
+type GlossaryPageData struct {
+	Glossary       GlossaryView
+	Terms          []*postgres.GlossaryTerm
+	CanEdit        bool
+	MaxImportBytes int64
+	Error          string
+}
+
+// glossaryErrorStatus -> the status code of an error of the glossary service.
+func glossaryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, glossary.ErrForbidden), errors.Is(err, org.ErrNotMember):
+		return http.StatusForbidden
+	case errors.Is(err, glossary.ErrInvalid), errors.Is(err, glossary.ErrTooManyTerms):
+		return http.StatusBadRequest
+	case errors.Is(err, postgres.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func renderGlossaries(c echo.Context, glossaries Glossarier, translator Translator, status int, msg string) error {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return c.String(http.StatusUnauthorized, "no credentials")
+	}
+	ctx := c.Request().Context()
+
+	list, err := glossaries.Glossaries(ctx, userCtx)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(glossaryErrorStatus(err), "failed to get glossaries")
+	}
+	canEdit, err := glossaries.CanEdit(ctx, userCtx)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(glossaryErrorStatus(err), "failed to get glossary permissions")
+	}
+
+	ui := displayLanguage(c)
+	data := GlossariesPageData{OrgID: userCtx.OrgID, CanEdit: canEdit, Error: msg}
+	for _, g := range list {
+		data.Glossaries = append(data.Glossaries, newGlossaryView(g, ui))
+	}
+	if catalog, err := translator.Languages(ctx); err != nil {
+		c.Logger().Error(err)
+	} else {
+		data.SourceLanguages = languageOptions(catalog.Sources(), "", ui)
+		data.TargetLanguages = languageOptions(catalog.Targets(""), "", ui)
+	}
+	return c.Render(status, "glossaries", data)
+}
+
+func GetGlossariesHandler(glossaries Glossarier, translator Translator) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return renderGlossaries(c, glossaries, translator, http.StatusOK, "")
+	}
+}
+
+type PostGlossaryHandlerReq struct {
+	Name string `form:"name"`
+	// SourceLanguage/TargetLanguage -> both empty for a glossary of every language pair.
+	SourceLanguage string `form:"source-language"`
+	TargetLanguage string `form:"target-language"`
+}
+
+func PostGlossaryHandler(glossaries Glossarier, translator Translator) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		var params PostGlossaryHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return renderGlossaries(c, glossaries, translator, http.StatusBadRequest, "invalid parameters")
+		}
+
+		ctx := c.Request().Context()
+		source, target := params.SourceLanguage, params.TargetLanguage
+		// one language without the other is refused by Create.
+		if source != "" && target != "" {
+			catalog, err := translator.Languages(ctx)
+			if err != nil {
+				c.Logger().Error(err)
+				return renderGlossaries(
+					c, glossaries, translator, http.StatusServiceUnavailable,
+					"translation languages are temporarily unavailable",
+				)
+			}
+			var sourceOK, targetOK bool
+			source, sourceOK = catalog.ResolveSource(source)
+			target, targetOK = catalog.ResolveTarget(target)
+			if !sourceOK || !targetOK {
+				return renderGlossaries(c, glossaries, translator, http.StatusBadRequest, "language not supported")
+			}
+			if source != target && !catalog.Supports(source, target) {
+				ui := displayLanguage(c)
+				return renderGlossaries(
+					c, glossaries, translator, http.StatusBadRequest,
+					fmt.Sprintf(
+						"translating %s to %s is not supported",
+						languageName(source, ui),
+						languageName(target, ui),
+					),
+				)
+			}
+		}
+
+		id, err := glossaries.Create(ctx, userCtx, params.Name, source, target)
+		if err != nil {
+			status := glossaryErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				c.Logger().Error(err)
+				return renderGlossaries(c, glossaries, translator, status, "failed to create glossary")
+			}
+			return renderGlossaries(c, glossaries, translator, status, err.Error())
+		}
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/glossaries/%d", id))
+	}
+}
+
+// glossaryFromParam -> the :id glossary of the session's workspace. on false the response has been sent.
+func glossaryFromParam(c echo.Context, glossaries Glossarier) (*postgres.Glossary, bool, error) {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return nil, false, c.String(http.StatusUnauthorized, "no credentials")
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, false, c.String(http.StatusBadRequest, "invalid glossary id")
+	}
+
+	g, err := glossaries.Glossary(c.Request().Context(), userCtx, id)
+	if err != nil {
+		if status := glossaryErrorStatus(err); status != http.StatusInternalServerError {
+			return nil, false, c.String(status, err.Error())
+		}
+		c.Logger().Error(err)
+		return nil, false, c.String(http.StatusInternalServerError, "failed to get glossary")
+	}
+	return g, true, nil
+}
+
+func renderGlossary(
+	c echo.Context,
+	config glossary.Config,
+	glossaries Glossarier,
+	g *postgres.Glossary,
+	status int,
+	msg string,
+) error {
+	userCtx, _ := auth.UserContextFromEcho(c)
+	ctx := c.Request().Context()
+	terms, err := glossaries.Terms(ctx, userCtx, g.ID)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(glossaryErrorStatus(err), "failed to get glossary terms")
+	}
+	canEdit, err := glossaries.CanEdit(ctx, userCtx)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(glossaryErrorStatus(err), "failed to get glossary permissions")
+	}
+	return c.Render(status, "glossary", GlossaryPageData{
+		Glossary:       newGlossaryView(g, displayLanguage(c)),
+		Terms:          terms,
+		CanEdit:        canEdit,
+		MaxImportBytes: config.MaxImportBytes,
+		Error:          msg,
+	})
+}
+
+// glossaryEditResponse -> after an edit of g: back to its page, or the page with why the edit failed.
+func glossaryEditResponse(c echo.Context, config glossary.Config, glossaries Glossarier, g *postgres.Glossary, err error) error {
+	if err == nil {
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/glossaries/%d", g.ID))
+	}
+	status := glossaryErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		c.Logger().Error(err)
+		return renderGlossary(c, config, glossaries, g, status, "failed to update glossary")
+	}
+	return renderGlossary(c, config, glossaries, g, status, err.Error())
+}
+
+func GetGlossaryHandler(config glossary.Config, glossaries Glossarier) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		g, ok, err := glossaryFromParam(c, glossaries)
+		if !ok {
+			return err
+		}
+		return renderGlossary(c, config, glossaries, g, http.StatusOK, "")
+	}
+}
+
+type PostGlossaryTermHandlerReq struct {
+	SourceTerm string `form:"source-term"`
+	// TargetTerm -> empty for a do not translate term.
+	TargetTerm string `form:"target-term"`
+}
+
+func PostGlossaryTermHandler(config glossary.Config, glossaries Glossarier) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		g, ok, err := glossaryFromParam(c, glossaries)
+		if !ok {
+			return err
+		}
+		var params PostGlossaryTermHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return renderGlossary(c, config, glossaries, g, http.StatusBadRequest, "invalid parameters")
+		}
+
+		userCtx, _ := auth.UserContextFromEcho(c)
+		_, err = glossaries.AddTerms(
+			c.Request().Context(),
+			userCtx,
+			g.ID,
+			[]*postgres.GlossaryTerm{glossary.NewTerm(params.SourceTerm, params.TargetTerm)},
+		)
+		return glossaryEditResponse(c, config, glossaries, g, err)
+	}
+}
+
+func PostGlossaryTermDeleteHandler(config glossary.Config, glossaries Glossarier) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		g, ok, err := glossaryFromParam(c, glossaries)
+		if !ok {
+			return err
+		}
+		termID, err := strconv.ParseInt(c.Param("term"), 10, 64)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid glossary term id")
+		}
+
+		userCtx, _ := auth.UserContextFromEcho(c)
+		err = glossaries.DeleteTerm(c.Request().Context(), userCtx, g.ID, termID)
+		return glossaryEditResponse(c, config, glossaries, g, err)
+	}
+}
+
+// PostGlossaryImportHandler -> add the terms of an uploaded csv file, see glossary.ReadCSV.
+func PostGlossaryImportHandler(config glossary.Config, glossaries Glossarier) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		g, ok, err := glossaryFromParam(c, glossaries)
+		if !ok {
+			return err
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return renderGlossary(c, config, glossaries, g, http.StatusBadRequest, "choose a file to import")
+		}
+		if file.Size > config.MaxImportBytes {
+			return renderGlossary(
+				c, config, glossaries, g, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("the file is too large; at most %d bytes;", config.MaxImportBytes),
+			)
+		}
+		src, err := file.Open()
+		if err != nil {
+			c.Logger().Error(err)
+			return renderGlossary(c, config, glossaries, g, http.StatusInternalServerError, "failed to read the file")
+		}
+		defer src.Close()
+
+		userCtx, _ := auth.UserContextFromEcho(c)
+		_, err = glossaries.Import(c.Request().Context(), userCtx, g.ID, src)
+		return glossaryEditResponse(c, config, glossaries, g, err)
+	}
+}
+
+// GetGlossaryExportHandler -> download the glossary's terms as csv, in the format imports take.
+func GetGlossaryExportHandler(glossaries Glossarier) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		g, ok, err := glossaryFromParam(c, glossaries)
+		if !ok {
+			return err
+		}
+		userCtx, _ := auth.UserContextFromEcho(c)
+		terms, err := glossaries.Terms(c.Request().Context(), userCtx, g.ID)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(glossaryErrorStatus(err), "failed to get glossary terms")
+		}
+
+		name := strings.Map(func(r rune) rune {
+			if r == '"' || r == '/' || r == '\\' || r < ' ' {
+				return '_'
+			}
+			return r
+		}, g.Name) + ".csv"
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+		c.Response().WriteHeader(http.StatusOK)
+		if err := glossary.WriteCSV(c.Response(), terms); err != nil {
+			c.Logger().Error(err)
+		}
+		return nil
+	}
+}
+
+func PostGlossaryDeleteHandler(config glossary.Config, glossaries Glossarier) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		g, ok, err := glossaryFromParam(c, glossaries)
+		if !ok {
+			return err
+		}
+		userCtx, _ := auth.UserContextFromEcho(c)
+		if err := glossaries.Delete(c.Request().Context(), userCtx, g.ID); err != nil {
+			return glossaryEditResponse(c, config, glossaries, g, err)
+		}
+		return c.Redirect(http.StatusSeeOther, "/glossaries")
+	}
+}
+
+// glossaryTermsHTML -> the glossary terms applied to a translation, for its result card.
+func glossaryTermsHTML(applied []glossary.Applied) string {
+	if len(applied) == 0 {
+		return ""
+	}
+	terms := make([]string, 0, len(applied))
+	for _, a := range applied {
+		term := html.EscapeString(a.Source) + " -> " + html.EscapeString(a.Target)
+		if a.DoNotTranslate {
+			term = html.EscapeString(a.Source) + " (kept)"
+		}
+		if a.Count > 1 {
+			term += fmt.Sprintf(" x%d", a.Count)
+		}
+		terms = append(terms, term)
+	}
+	return fmt.Sprintf(
+		`<p class="card-text"><small class="text-muted">Glossary terms: %s</small></p>`,
+		strings.Join(terms, ", "),
+	)
+}
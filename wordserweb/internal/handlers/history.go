@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+const (
+	historyPageSize   = 20
+	historyRecentSize = 5
+)
+
+type HistoryPageData struct {
+	Analyses []*postgres.Analysis
+	Total    int
+	Page     int
+	PrevPage int
+	NextPage int
+}
+
+type HistoryAnalysisPageData struct {
+	Analysis *postgres.Analysis
+	Data     AnalyzeData
+}
+
+// newAnalysisRecord -> the row saved for one /analyze request. failed analyzers keep their error and timing but no
+// result.
+func newAnalysisRecord(
+	userCtx auth.UserContext,
+	selected []analyzer.Analyzer,
+	data AnalyzeData,
+	resps []APIResponse,
+	duration time.Duration,
+) (*postgres.Analysis, error) {
+	record := &postgres.Analysis{
+		Username:     userCtx.Username,
+		OriginalText: data.OriginalText,
+		Options:      analyzerNames(selected),
+		TimingsMS:    map[string]int64{},
+		Errors:       map[string]string{},
+		DurationMS:   duration.Milliseconds(),
+	}
+	if userCtx.OrgID != 0 {
+		orgID := userCtx.OrgID
+		record.OrganizationID = &orgID
+	}
+	for _, resp := range resps {
+		record.TimingsMS[resp.analyzer.Name()] = resp.duration.Milliseconds()
+	}
+	for name, msg := range data.Errors {
+		record.Errors[name] = msg
+	}
+	if data.Source != nil {
+		sourceURL := data.Source.URL
+		source := data.Source.AnalysisSource
+		record.SourceURL = &sourceURL
+		record.Source = &source
+	}
+	if err := setRecordResults(record, data); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// setRecordResults -> copy the results data has onto record, leaving the others as they are.
+func setRecordResults(record *postgres.Analysis, data AnalyzeData) error {
+	if record.Results == nil {
+		record.Results = map[string]json.RawMessage{}
+	}
+	for name, result := range data.Results {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s result: %w", name, err)
+		}
+		record.Results[name] = raw
+	}
+	return setRecordChunks(record, data.Chunks)
+}
+
+// setRecordChunks -> like setRecordResults, chunk by chunk. a chunk's error for an analyzer is dropped once
+// that analyzer has a result for it.
+func setRecordChunks(record *postgres.Analysis, chunks []ChunkResult) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	if len(record.Chunks) != len(chunks) {
+		record.Chunks = make([]postgres.AnalysisChunk, len(chunks))
+	}
+	for i, chunk := range chunks {
+		rc := &record.Chunks[i]
+		rc.Index = chunk.Index
+		rc.Text = chunk.Text
+		if rc.Results == nil {
+			rc.Results = map[string]json.RawMessage{}
+		}
+		if rc.Errors == nil {
+			rc.Errors = map[string]string{}
+		}
+		for name, result := range chunk.Results {
+			raw, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s result of chunk %d: %w", name, chunk.Index, err)
+			}
+			rc.Results[name] = raw
+			delete(rc.Errors, name)
+		}
+		for name, msg := range chunk.Errors {
+			rc.Errors[name] = msg
+		}
+	}
+	return nil
+}
+
+// decodeResults -> the stored results of the registered analyzers. results of analyzers no longer registered
+// are skipped.
+func decodeResults(analyzers Analyzers, raw map[string]json.RawMessage) (map[string]analyzer.Result, error) {
+	results := map[string]analyzer.Result{}
+	for name, data := range raw {
+		a, ok := analyzers.Get(name)
+		if !ok {
+			continue
+		}
+		result, err := a.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s result: %w", name, err)
+		}
+		results[name] = result
+	}
+	return results, nil
+}
+
+// analyzeDataFromRecord -> rebuild what analysis.html rendered when the analysis was made.
+func analyzeDataFromRecord(analyzers Analyzers, a *postgres.Analysis) (AnalyzeData, error) {
+	data := AnalyzeData{
+		HistoryID:    a.ID,
+		OriginalText: a.OriginalText,
+		Errors:       a.Errors,
+	}
+	if a.SourceURL != nil {
+		data.Source = &AnalyzeSource{URL: *a.SourceURL}
+		if a.Source != nil {
+			data.Source.AnalysisSource = *a.Source
+		}
+	}
+
+	var err error
+	if data.Results, err = decodeResults(analyzers, a.Results); err != nil {
+		return data, err
+	}
+	for _, an := range analyzers.All() {
+		name := an.Name()
+		if slices.Contains(a.Options, name) || data.Results[name] != nil || data.Errors[name] != "" {
+			data.analyzers = append(data.analyzers, an)
+		}
+	}
+	for _, rc := range a.Chunks {
+		chunk := ChunkResult{
+			Index:  rc.Index,
+			Text:   rc.Text,
+			Errors: rc.Errors,
+		}
+		if chunk.Results, err = decodeResults(analyzers, rc.Results); err != nil {
+			return data, err
+		}
+		data.Chunks = append(data.Chunks, chunk)
+	}
+	return data, nil
+}
+
+func historyIDParam(c echo.Context) (int64, error) {
+	return strconv.ParseInt(c.Param("id"), 10, 64)
+}
+
+func GetHistoryHandler(history AnalysisStorer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		page, err := strconv.Atoi(c.QueryParam("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		analyses, total, err := history.ListAnalyses(
+			c.Request().Context(),
+			userCtx.Username,
+			historyPageSize,
+			(page-1)*historyPageSize,
+		)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get history")
+		}
+
+		data := HistoryPageData{
+			Analyses: analyses,
+			Total:    total,
+			Page:     page,
+		}
+		if page > 1 {
+			data.PrevPage = page - 1
+		}
+		if page*historyPageSize < total {
+			data.NextPage = page + 1
+		}
+
+		return c.Render(http.StatusOK, "history", data)
+	}
+}
+
+// GetRecentHistoryHandler -> htmx fragment with the last few analyses for the dashboard.
+func GetRecentHistoryHandler(history AnalysisStorer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		analyses, _, err := history.ListAnalyses(c.Request().Context(), userCtx.Username, historyRecentSize, 0)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get history")
+		}
+
+		return c.Render(http.StatusOK, "history_recent", analyses)
+	}
+}
+
+func GetHistoryAnalysisHandler(analyzers Analyzers, history AnalysisStorer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		id, err := historyIDParam(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid analysis id")
+		}
+
+		analysis, err := history.GetAnalysis(c.Request().Context(), userCtx.Username, id)
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return c.String(http.StatusNotFound, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get analysis")
+		}
+
+		data, err := analyzeDataFromRecord(analyzers, analysis)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get analysis")
+		}
+
+		return c.Render(http.StatusOK, "history_analysis", HistoryAnalysisPageData{
+			Analysis: analysis,
+			Data:     data,
+		})
+	}
+}
+
+// DeleteHistoryAnalysisHandler -> empty 200 so htmx swaps the deleted row away.
+func DeleteHistoryAnalysisHandler(history AnalysisStorer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		id, err := historyIDParam(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid analysis id")
+		}
+
+		if err := history.DeleteAnalysis(c.Request().Context(), userCtx.Username, id); err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return c.String(http.StatusNotFound, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to delete analysis")
+		}
+
+		// deleting from the analysis page itself has nothing left to show; send htmx back to the list.
+		if c.QueryParam("redirect") == "true" {
+			c.Response().Header().Set("HX-Redirect", "/history")
+		}
+		return c.String(http.StatusOK, "")
+	}
+}
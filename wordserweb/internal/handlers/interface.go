@@ -2,7 +2,25 @@ package handlers
 
 import (
 	"context"
+	"io"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+	"github.com/nolandseigler/wordser/wordserweb/internal/document"
+	"github.com/nolandseigler/wordser/wordserweb/internal/fetch"
+	"github.com/nolandseigler/wordser/wordserweb/internal/glossary"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/langdetect"
+	"github.com/nolandseigler/wordser/wordserweb/internal/memory"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
 	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/subtitle"
+	"github.com/nolandseigler/wordser/wordserweb/internal/translate"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+	"github.com/nolandseigler/wordser/wordserweb/internal/wordser"
 )
 
 type DBer interface {
@@ -12,4 +30,143 @@ type DBer interface {
 
 type Auther interface {
 	Login(ctx context.Context, username string, password string) (string, error)
+	SwitchOrg(ctx context.Context, jwt string, orgID int, orgRole string) (string, error)
+}
+
+type UsageMeterer interface {
+	Reserve(ctx context.Context, userCtx auth.UserContext, chars int) (usage.Reservation, error)
+	Release(ctx context.Context, reservation usage.Reservation) error
+	Record(ctx context.Context, userCtx auth.UserContext, api usage.API, chars int) error
+	Summary(ctx context.Context, userCtx auth.UserContext) (usage.Summary, error)
+	Report(ctx context.Context) ([]usage.Summary, error)
+}
+
+type Orger interface {
+	Create(ctx context.Context, userCtx auth.UserContext, name string) (*postgres.Organization, error)
+	List(ctx context.Context, userCtx auth.UserContext) ([]*postgres.OrganizationMembership, error)
+	Membership(ctx context.Context, userCtx auth.UserContext, orgID int) (*postgres.OrganizationMembership, error)
+	Members(ctx context.Context, userCtx auth.UserContext, orgID int) ([]*postgres.OrganizationMembership, error)
+	Invitations(ctx context.Context, userCtx auth.UserContext, orgID int) ([]*postgres.OrganizationInvitation, error)
+	PendingInvitations(ctx context.Context, userCtx auth.UserContext) ([]*postgres.OrganizationInvitation, error)
+	Invite(ctx context.Context, userCtx auth.UserContext, orgID int, invitee string, role org.Role) (*org.Invitation, error)
+	Invitation(ctx context.Context, token string) (*postgres.OrganizationInvitation, error)
+	Accept(ctx context.Context, userCtx auth.UserContext, token string) (int, error)
+	SetRole(ctx context.Context, userCtx auth.UserContext, orgID int, username string, role org.Role) error
+	Remove(ctx context.Context, userCtx auth.UserContext, orgID int, username string) error
+}
+
+type AnalysisStorer interface {
+	InsertAnalysis(ctx context.Context, a *postgres.Analysis) (int64, error)
+	ListAnalyses(ctx context.Context, username string, limit int, offset int) ([]*postgres.Analysis, int, error)
+	GetAnalysis(ctx context.Context, username string, id int64) (*postgres.Analysis, error)
+	UpdateAnalysisResults(ctx context.Context, a *postgres.Analysis) error
+	DeleteAnalysis(ctx context.Context, username string, id int64) error
+}
+
+type TranslationStorer interface {
+	InsertTranslation(ctx context.Context, t *postgres.Translation) (int64, error)
+}
+
+type Searcher interface {
+	Search(ctx context.Context, params postgres.SearchParams) ([]*postgres.SearchResult, error)
+}
+
+type Translator interface {
+	Translate(ctx context.Context, txt string, source string, target string) (*translate.Translation, error)
+	Languages(ctx context.Context) (*translate.Catalog, error)
+}
+
+type LanguageDetector interface {
+	Detect(txt string) langdetect.Detection
+}
+
+type WordserClient interface {
+	Synonyms(ctx context.Context, word string) (*wordser.SynonymsResp, error)
+}
+
+type Analyzers interface {
+	All() []analyzer.Analyzer
+	Get(name string) (analyzer.Analyzer, bool)
+}
+
+type Chunker interface {
+	Split(txt string) []chunking.Chunk
+	Concurrency() int64
+}
+
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*fetch.Page, error)
+}
+
+type AnalyzeStreamer interface {
+	Put(owner string, req AnalyzeStreamRequest) (string, error)
+	Take(owner string, id string) (AnalyzeStreamRequest, bool)
+}
+
+type JobQueuer interface {
+	Enqueue(ctx context.Context, userCtx auth.UserContext, kind jobser.Kind, payload any) (int64, error)
+	Job(ctx context.Context, userCtx auth.UserContext, id int64) (*postgres.Job, error)
+	Jobs(ctx context.Context, userCtx auth.UserContext, limit int) ([]*postgres.Job, error)
+}
+
+type BatchStorer interface {
+	InsertBatch(ctx context.Context, b *postgres.Batch, rows []*postgres.BatchRow) (int64, error)
+	GetBatch(ctx context.Context, username string, id int64) (*postgres.Batch, error)
+	ListBatches(ctx context.Context, username string, limit int) ([]*postgres.Batch, error)
+	SetBatchJob(ctx context.Context, id int64, jobID int64) error
+	ListBatchRows(ctx context.Context, id int64, state string, limit int) ([]*postgres.BatchRow, error)
+	ClaimBatchRows(ctx context.Context, id int64, jobID int64, attempt int, limit int) ([]*postgres.BatchRow, error)
+	ReleaseBatchRows(ctx context.Context, id int64, jobID int64) error
+	UpdateBatchRow(ctx context.Context, row *postgres.BatchRow) error
+	ResetFailedBatchRows(ctx context.Context, username string, id int64) (int64, error)
+}
+
+type WebhookPublisher interface {
+	Publish(ctx context.Context, userCtx auth.UserContext, event webhook.Event, data any)
+}
+
+type Webhooker interface {
+	CreateEndpoint(ctx context.Context, userCtx auth.UserContext, rawURL string, events []webhook.Event) (int64, error)
+	Endpoint(ctx context.Context, userCtx auth.UserContext, id int64) (*postgres.WebhookEndpoint, error)
+	Endpoints(ctx context.Context, userCtx auth.UserContext) ([]*postgres.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, userCtx auth.UserContext, id int64) error
+	Deliveries(ctx context.Context, userCtx auth.UserContext, endpointID int64, limit int) ([]*postgres.WebhookDelivery, error)
+	Ping(ctx context.Context, userCtx auth.UserContext, id int64) (int64, error)
+	Replay(ctx context.Context, userCtx auth.UserContext, id int64) error
+}
+
+type Glossarier interface {
+	CanEdit(ctx context.Context, userCtx auth.UserContext) (bool, error)
+	Create(ctx context.Context, userCtx auth.UserContext, name string, source string, target string) (int64, error)
+	Glossary(ctx context.Context, userCtx auth.UserContext, id int64) (*postgres.Glossary, error)
+	Glossaries(ctx context.Context, userCtx auth.UserContext) ([]*postgres.Glossary, error)
+	Delete(ctx context.Context, userCtx auth.UserContext, id int64) error
+	Terms(ctx context.Context, userCtx auth.UserContext, id int64) ([]*postgres.GlossaryTerm, error)
+	AddTerms(ctx context.Context, userCtx auth.UserContext, id int64, terms []*postgres.GlossaryTerm) (int, error)
+	DeleteTerm(ctx context.Context, userCtx auth.UserContext, id int64, termID int64) error
+	Import(ctx context.Context, userCtx auth.UserContext, id int64, r io.Reader) (int, error)
+	Protect(ctx context.Context, userCtx auth.UserContext, txt string, source string, target string) (*glossary.Protected, error)
+}
+
+type Memorizer interface {
+	CanEdit(ctx context.Context, userCtx auth.UserContext) (bool, error)
+	Lookup(ctx context.Context, userCtx auth.UserContext, txt string, source string, target string) (*memory.Match, error)
+	Remember(ctx context.Context, userCtx auth.UserContext, source string, target string, txt string, translated string) error
+	Import(ctx context.Context, userCtx auth.UserContext, r io.Reader, catalog *translate.Catalog) (memory.ImportResult, error)
+	Export(ctx context.Context, userCtx auth.UserContext, source string, target string, format memory.Format, w io.Writer) error
+	Pairs(ctx context.Context, userCtx auth.UserContext) ([]*postgres.MemoryPair, error)
+	DeletePair(ctx context.Context, userCtx auth.UserContext, source string, target string) error
+}
+
+type Documenter interface {
+	Translate(ctx context.Context, d *document.Document, translate document.TranslateFunc) document.Report
+}
+
+type Subtitler interface {
+	Translate(ctx context.Context, f *subtitle.File, translate subtitle.TranslateFunc) subtitle.Report
+}
+
+type UpstreamStatuser interface {
+	Upstream() string
+	Status() []resilience.EndpointStatus
 }
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/glossary"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/memory"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/translate"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+)
+
+const jobsPageSize = 50
+
+// AnalyzeJobPayload -> input of a jobser.KindAnalyze job. APIs are analyzer names.
+type AnalyzeJobPayload struct {
+	Text string   `json:"text"`
+	APIs []string `json:"apis"`
+	// Engine -> as GetAnalyzeHandlerReq.Engine.
+	Engine string `json:"engine,omitempty"`
+	// Language -> Text is translated, or not english; settled before the job is queued like Source.
+	Language *AnalyzeLanguage `json:"language,omitempty"`
+	// Source -> set when Text was fetched from a url; the page is fetched once, before the job is queued.
+	Source *AnalyzeSource `json:"source,omitempty"`
+	// Reservation -> the usage held for the job when it was queued; released once the job has recorded its usage.
+	Reservation usage.Reservation `json:"reservation,omitempty"`
+}
+
+type AnalyzeJobResult struct {
+	HistoryID int64 `json:"history_id"`
+}
+
+// TranslateJobPayload -> input of a jobser.KindTranslate job.
+type TranslateJobPayload struct {
+	Text   string `json:"text"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Reservation -> the usage held for the job when it was queued; released once the job has recorded its usage.
+	Reservation usage.Reservation `json:"reservation,omitempty"`
+}
+
+type TranslateJobResult struct {
+	TranslationID     int64               `json:"translation_id"`
+	TranslatedText    string              `json:"translated_text"`
+	Provider          string              `json:"provider"`
+	GlossaryTerms     []glossary.Applied  `json:"glossary_terms,omitempty"`
+	MemorySuggestions []memory.Suggestion `json:"memory_suggestions,omitempty"`
+}
+
+// JobView -> a job with its result decoded for job_status.html.
+type JobView struct {
+	*postgres.Job
+	Analysis    *AnalyzeJobResult
+	Translation *TranslateJobResult
+	Batch       *BatchJobResult
+	Webhook     *webhook.DeliveryResult
+	Subtitle    *SubtitleJobResult
+}
+
+func (j JobView) Done() bool {
+	return jobser.State(j.State).Done()
+}
+
+func newJobView(job *postgres.Job) JobView {
+	view := JobView{Job: job}
+	if job.State != postgres.JobStateSucceeded {
+		return view
+	}
+	switch jobser.Kind(job.Kind) {
+	case jobser.KindAnalyze:
+		var result AnalyzeJobResult
+		if err := json.Unmarshal(job.Result, &result); err == nil {
+			view.Analysis = &result
+		}
+	case jobser.KindTranslate:
+		var result TranslateJobResult
+		if err := json.Unmarshal(job.Result, &result); err == nil {
+			view.Translation = &result
+		}
+	case jobser.KindBatch:
+		var result BatchJobResult
+		if err := json.Unmarshal(job.Result, &result); err == nil {
+			view.Batch = &result
+		}
+	case jobser.KindWebhook:
+		var result webhook.DeliveryResult
+		if err := json.Unmarshal(job.Result, &result); err == nil {
+			view.Webhook = &result
+		}
+	case jobser.KindSubtitle:
+		var result SubtitleJobResult
+		if err := json.Unmarshal(job.Result, &result); err == nil {
+			view.Subtitle = &result
+		}
+	}
+	return view
+}
+
+// AnalyzeJob -> run a queued /analyze and save it to history like the inline one. fails the attempt, to be
+// retried, only when every analyzer failed; partial results are saved with retries offered per analyzer.
+func AnalyzeJob(deps Deps) jobser.Handler {
+	return func(ctx context.Context, job *postgres.Job) (any, error) {
+		var payload AnalyzeJobPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, resilience.Permanent(err)
+		}
+		selected, err := analyzersFromNames(deps.Analyzers, payload.APIs)
+		if err != nil {
+			return nil, resilience.Permanent(err)
+		}
+		selected, err = useEngine(selected, payload.Engine)
+		if err != nil {
+			return nil, resilience.Permanent(err)
+		}
+		if payload.Text == "" || len(selected) == 0 {
+			return nil, resilience.Permanent(errors.New("nothing to analyze"))
+		}
+
+		userCtx := jobser.UserContext(job)
+		start := time.Now()
+		respChan := make(chan APIResponse, len(selected))
+		wg := &sync.WaitGroup{}
+		for _, a := range selected {
+			wg.Add(1)
+			go doAnalyzerRequest(ctx, wg, deps.Chunker, a, payload.Text, respChan)
+		}
+		wg.Wait()
+		close(respChan)
+
+		resps := make([]APIResponse, 0, len(selected))
+		for resp := range respChan {
+			resps = append(resps, resp)
+		}
+		analysisData, apiErrs := newAnalyzeDataFromResps(payload.Text, selected, resps)
+		analysisData.Source = payload.Source
+		analysisData.Engine = payload.Engine
+		analysisData.Language = payload.Language
+		if len(apiErrs) == len(selected) {
+			// nothing worth saving; any one of the errors says why.
+			for _, err := range apiErrs {
+				return nil, err
+			}
+		}
+
+		record, err := newAnalysisRecord(userCtx, selected, analysisData, resps, time.Since(start))
+		if err != nil {
+			return nil, err
+		}
+		historyID, err := deps.History.InsertAnalysis(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		// metered once the analysis is saved, so an attempt that is retried isn't billed as well as the retry.
+		chars := usage.CharCount(payload.Text)
+		for _, a := range selected {
+			recordUsage(ctx, deps.Logger, deps.Meter, userCtx, usage.API(a.Name()), chars)
+		}
+		releaseUsage(ctx, deps.Logger, deps.Meter, payload.Reservation)
+		analysisData.HistoryID = historyID
+		deps.Hooks.Publish(ctx, userCtx, webhook.EventAnalysisCompleted, newAnalysisCompletedEvent(selected, analysisData))
+		return AnalyzeJobResult{HistoryID: historyID}, nil
+	}
+}
+
+// TranslateJob -> run a queued /translate and save it like the inline one.
+func TranslateJob(deps Deps) jobser.Handler {
+	return func(ctx context.Context, job *postgres.Job) (any, error) {
+		var payload TranslateJobPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, resilience.Permanent(err)
+		}
+
+		userCtx := jobser.UserContext(job)
+		// the memory and glossaries as they are when the job runs, not when it was queued.
+		match, err := deps.Memories.Lookup(ctx, userCtx, payload.Text, payload.Source, payload.Target)
+		if err != nil {
+			return nil, workspaceJobError(err)
+		}
+		protected, err := deps.Glossaries.Protect(ctx, userCtx, payload.Text, payload.Source, payload.Target)
+		if err != nil {
+			return nil, workspaceJobError(err)
+		}
+		var result TranslateJobResult
+		// a match made before the glossaries had their terms as they are now is translated again.
+		applied, fromMemory := protected.Enforced(match.Text)
+		fromMemory = fromMemory && match.Text != ""
+		if fromMemory {
+			result = TranslateJobResult{TranslatedText: match.Text, Provider: memory.Provider, GlossaryTerms: applied}
+		} else {
+			translated, err := deps.Translator.Translate(ctx, protected.Text, payload.Source, payload.Target)
+			// no provider was called; there is nothing to meter, and the pair won't be supported on a retry either.
+			if errors.Is(err, translate.ErrUnsupportedPair) {
+				return nil, resilience.Permanent(err)
+			}
+			if err != nil {
+				return nil, err
+			}
+			translatedText, applied := protected.Restore(translated.Text)
+			result = TranslateJobResult{
+				TranslatedText:    translatedText,
+				Provider:          translated.Provider,
+				GlossaryTerms:     applied,
+				MemorySuggestions: match.Suggestions,
+			}
+			// the stub's output isn't a translation worth reusing.
+			if translated.Provider != translate.ProviderStub {
+				// the translation is done either way; failing the attempt would only have it redone.
+				if err := deps.Memories.Remember(ctx, userCtx, payload.Source, payload.Target, payload.Text, translatedText); err != nil {
+					deps.Logger.Errorf("failed to remember translation of job %d: %v", job.ID, err)
+				}
+			}
+		}
+
+		record := &postgres.Translation{
+			Username:       userCtx.Username,
+			OrganizationID: job.OrganizationID,
+			SourceLanguage: payload.Source,
+			TargetLanguage: payload.Target,
+			SourceText:     payload.Text,
+			TranslatedText: result.TranslatedText,
+			Provider:       result.Provider,
+		}
+		id, err := deps.Translations.InsertTranslation(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		// metered once the translation is saved, so an attempt that is retried isn't billed as well as the retry.
+		if !fromMemory {
+			recordUsage(ctx, deps.Logger, deps.Meter, userCtx, usage.APITranslate, usage.CharCount(payload.Text))
+		}
+		releaseUsage(ctx, deps.Logger, deps.Meter, payload.Reservation)
+		deps.Hooks.Publish(ctx, userCtx, webhook.EventTranslationCompleted, newTranslationCompletedEvent(id, record))
+		result.TranslationID = id
+		return result, nil
+	}
+}
+
+// recordUsage -> meter.Record for a job, logging its error; the work it meters is done and saved by then, and
+// failing the attempt would only have it redone and billed again.
+func recordUsage(
+	ctx context.Context,
+	logger jobser.Logger,
+	meter UsageMeterer,
+	userCtx auth.UserContext,
+	api usage.API,
+	chars int,
+) {
+	if err := meter.Record(context.WithoutCancel(ctx), userCtx, api, chars); err != nil {
+		logger.Errorf("failed to record %s usage of %s: %v", api, userCtx.Username, err)
+	}
+}
+
+// releaseUsage -> Release reservation, logging a failure rather than failing the work it was held for; the
+// reservation runs out on its own.
+func releaseUsage(ctx context.Context, logger jobser.Logger, meter UsageMeterer, reservation usage.Reservation) {
+	if err := meter.Release(context.WithoutCancel(ctx), reservation); err != nil {
+		logger.Errorf("failed to release usage reservation %d: %v", reservation, err)
+	}
+}
+
+// workspaceJobError -> err of the workspace a job runs in. permanent once the user left the organization the job was
+// queued in; a retry won't bring the membership back.
+func workspaceJobError(err error) error {
+	if errors.Is(err, org.ErrNotMember) {
+		return resilience.Permanent(err)
+	}
+	return err
+}
+
+// enqueueJob -> queue a job and render its self polling status fragment.
+func enqueueJob(
+	c echo.Context,
+	jobs JobQueuer,
+	meter UsageMeterer,
+	reservation usage.Reservation,
+	userCtx auth.UserContext,
+	kind jobser.Kind,
+	payload any,
+) error {
+	ctx := c.Request().Context()
+	id, err := jobs.Enqueue(ctx, userCtx, kind, payload)
+	if err != nil {
+		c.Logger().Error(err)
+		releaseUsage(ctx, c.Logger(), meter, reservation)
+		return c.String(http.StatusInternalServerError, "failed to queue job")
+	}
+	job, err := jobs.Job(ctx, userCtx, id)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "failed to get job")
+	}
+	return c.Render(http.StatusAccepted, "job_status", newJobView(job))
+}
+
+func GetJobsHandler(jobs JobQueuer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		list, err := jobs.Jobs(c.Request().Context(), userCtx, jobsPageSize)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get jobs")
+		}
+
+		views := make([]JobView, 0, len(list))
+		for _, job := range list {
+			views = append(views, newJobView(job))
+		}
+		return c.Render(http.StatusOK, "jobs", views)
+	}
+}
+
+// jobFromParam -> the :id job of the session's user. on false the response has been sent.
+func jobFromParam(c echo.Context, jobs JobQueuer) (*postgres.Job, bool, error) {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return nil, false, c.String(http.StatusUnauthorized, "no credentials")
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, false, c.String(http.StatusBadRequest, "invalid job id")
+	}
+
+	job, err := jobs.Job(c.Request().Context(), userCtx, id)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return nil, false, c.String(http.StatusNotFound, err.Error())
+		}
+		c.Logger().Error(err)
+		return nil, false, c.String(http.StatusInternalServerError, "failed to get job")
+	}
+	return job, true, nil
+}
+
+func GetJobHandler(jobs JobQueuer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		job, ok, err := jobFromParam(c, jobs)
+		if !ok {
+			return err
+		}
+		return c.Render(http.StatusOK, "job", newJobView(job))
+	}
+}
+
+// GetJobStatusHandler -> htmx fragment that polls itself until the job is done.
+func GetJobStatusHandler(jobs JobQueuer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		job, ok, err := jobFromParam(c, jobs)
+		if !ok {
+			return err
+		}
+		return c.Render(http.StatusOK, "job_status", newJobView(job))
+	}
+}
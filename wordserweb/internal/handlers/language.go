@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+)
+
+// AnalyzeLanguage -> the language of text sent to english only analyzers when it isn't english, or the language
+// it was translated to english from before the analysis.
+type AnalyzeLanguage struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// Confidence -> of the detection, 0-1. 0 when the text was translated.
+	Confidence float64 `json:"confidence,omitempty"`
+	// Translated -> the analyzed text is the english translation.
+	Translated bool `json:"translated,omitempty"`
+}
+
+func (l AnalyzeLanguage) Percent() int {
+	return int(math.Round(l.Confidence * 100))
+}
+
+// englishOnly -> some of selected only make sense of english text.
+func englishOnly(selected []analyzer.Analyzer) bool {
+	for _, a := range selected {
+		if a.Options().EnglishOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeLanguage -> txt translated to english when translate-from asks for it. otherwise txt as is, with its
+// language when english only analyzers are about to get text that reliably isn't english. on false the response
+// has been sent.
+func analyzeLanguage(
+	c echo.Context,
+	deps Deps,
+	userCtx auth.UserContext,
+	selected []analyzer.Analyzer,
+	txt string,
+	translateFrom string,
+) (string, *AnalyzeLanguage, bool, error) {
+	ctx := c.Request().Context()
+	ui := displayLanguage(c)
+	if translateFrom == "" {
+		if !englishOnly(selected) {
+			return txt, nil, true, nil
+		}
+		detection := deps.Detector.Detect(txt)
+		if !detection.Reliable || TranslateLanguage(detection.Language) == English {
+			return txt, nil, true, nil
+		}
+		catalog, err := deps.Translator.Languages(ctx)
+		if err != nil {
+			c.Logger().Error(err)
+			return txt, nil, true, nil
+		}
+		code, ok := catalog.ResolveSource(detection.Language)
+		if !ok || !catalog.Supports(code, English.String()) {
+			// detected but not translatable; nothing to offer.
+			return txt, nil, true, nil
+		}
+		return txt, &AnalyzeLanguage{Code: code, Name: languageName(code, ui), Confidence: detection.Confidence}, true, nil
+	}
+
+	catalog, err := deps.Translator.Languages(ctx)
+	if err != nil {
+		c.Logger().Error(err)
+		return "", nil, false, c.String(
+			http.StatusServiceUnavailable,
+			"translation is temporarily unavailable; try again shortly;",
+		)
+	}
+	from, ok := catalog.ResolveSource(translateFrom)
+	if !ok || from == English.String() || !catalog.Supports(from, English.String()) {
+		return "", nil, false, c.String(
+			http.StatusBadRequest,
+			fmt.Sprintf("invalid parameters: translate-from %q not supported;", translateFrom),
+		)
+	}
+
+	chars := usage.CharCount(txt)
+	reservation, err := deps.Meter.Reserve(ctx, userCtx, chars)
+	if err != nil {
+		if errors.Is(err, usage.ErrQuotaExceeded) {
+			return "", nil, false, c.String(http.StatusTooManyRequests, err.Error())
+		}
+		c.Logger().Error(err)
+		return "", nil, false, c.String(http.StatusInternalServerError, "failed to check usage quota")
+	}
+	defer releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+	protected, err := deps.Glossaries.Protect(ctx, userCtx, txt, from, English.String())
+	if err != nil {
+		c.Logger().Error(err)
+		return "", nil, false, c.String(glossaryErrorStatus(err), "failed to get glossary terms")
+	}
+	translated, err := deps.Translator.Translate(ctx, protected.Text, from, English.String())
+	if err := deps.Meter.Record(ctx, userCtx, usage.APITranslate, chars); err != nil {
+		c.Logger().Error(err)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		if errors.Is(err, resilience.ErrCircuitOpen) || errors.Is(err, resilience.ErrBulkheadFull) {
+			return "", nil, false, c.String(
+				http.StatusServiceUnavailable,
+				"translation is temporarily unavailable; try again shortly;",
+			)
+		}
+		return "", nil, false, c.String(http.StatusBadGateway, "failed to translate the text to english")
+	}
+	translatedText, _ := protected.Restore(translated.Text)
+	return translatedText, &AnalyzeLanguage{Code: from, Name: languageName(from, ui), Translated: true}, true, nil
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/memory"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// MemoryPairView -> a language pair of the translation memory named for the page.
+type MemoryPairView struct {
+	*postgres.MemoryPair
+	Name string
+}
+
+type MemoryPageData struct {
+	// OrgID -> the workspace the memory belongs to, 0 for the personal one.
+	OrgID          int
+	Pairs          []MemoryPairView
+	Formats        []memory.Format
+	CanEdit        bool
+	MaxImportBytes int64
+	// Notice -> how the last import went.
+	Notice string
+	Error  string
+}
+
+// memoryErrorStatus -> the status code of an error of the translation memory.
+func memoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, memory.ErrForbidden), errors.Is(err, org.ErrNotMember):
+		return http.StatusForbidden
+	case errors.Is(err, memory.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, postgres.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func renderMemory(c echo.Context, config memory.Config, memories Memorizer, status int, notice string, msg string) error {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return c.String(http.StatusUnauthorized, "no credentials")
+	}
+	ctx := c.Request().Context()
+
+	pairs, err := memories.Pairs(ctx, userCtx)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(memoryErrorStatus(err), "failed to get translation memory")
+	}
+	canEdit, err := memories.CanEdit(ctx, userCtx)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(memoryErrorStatus(err), "failed to get translation memory permissions")
+	}
+
+	ui := displayLanguage(c)
+	data := MemoryPageData{
+		OrgID:          userCtx.OrgID,
+		Formats:        []memory.Format{memory.FormatTMX, memory.FormatXLIFF12, memory.FormatXLIFF20},
+		CanEdit:        canEdit,
+		MaxImportBytes: config.MaxImportBytes,
+		Notice:         notice,
+		Error:          msg,
+	}
+	for _, p := range pairs {
+		data.Pairs = append(data.Pairs, MemoryPairView{
+			MemoryPair: p,
+			Name:       fmt.Sprintf("%s -> %s", languageName(p.SourceLanguage, ui), languageName(p.TargetLanguage, ui)),
+		})
+	}
+	return c.Render(status, "memory", data)
+}
+
+// memoryEditResponse -> after an edit of the memory: its page, saying how the edit went or why it failed.
+func memoryEditResponse(c echo.Context, config memory.Config, memories Memorizer, notice string, err error) error {
+	if err == nil {
+		return renderMemory(c, config, memories, http.StatusOK, notice, "")
+	}
+	status := memoryErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		c.Logger().Error(err)
+		return renderMemory(c, config, memories, status, "", "failed to update translation memory")
+	}
+	return renderMemory(c, config, memories, status, "", err.Error())
+}
+
+func GetMemoryHandler(config memory.Config, memories Memorizer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return renderMemory(c, config, memories, http.StatusOK, "", "")
+	}
+}
+
+// PostMemoryImportHandler -> add the segment pairs of an uploaded tmx or xliff file, see memory.Read.
+func PostMemoryImportHandler(config memory.Config, memories Memorizer, translator Translator) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return renderMemory(c, config, memories, http.StatusBadRequest, "", "choose a file to import")
+		}
+		if file.Size > config.MaxImportBytes {
+			return renderMemory(
+				c, config, memories, http.StatusRequestEntityTooLarge, "",
+				fmt.Sprintf("the file is too large; at most %d bytes;", config.MaxImportBytes),
+			)
+		}
+		ctx := c.Request().Context()
+		catalog, err := translator.Languages(ctx)
+		if err != nil {
+			c.Logger().Error(err)
+			return renderMemory(
+				c, config, memories, http.StatusServiceUnavailable, "",
+				"translation languages are temporarily unavailable",
+			)
+		}
+		src, err := file.Open()
+		if err != nil {
+			c.Logger().Error(err)
+			return renderMemory(c, config, memories, http.StatusInternalServerError, "", "failed to read the file")
+		}
+		defer src.Close()
+
+		result, err := memories.Import(ctx, userCtx, src, catalog)
+		notice := fmt.Sprintf("imported %d segment pairs from %s", result.Imported, file.Filename)
+		if result.Skipped > 0 {
+			notice += fmt.Sprintf("; skipped %d empty, too long or in a language pair not on offer", result.Skipped)
+		}
+		return memoryEditResponse(c, config, memories, notice, err)
+	}
+}
+
+type GetMemoryExportHandlerReq struct {
+	SourceLanguage string `query:"source-language"`
+	TargetLanguage string `query:"target-language"`
+	// Format -> tmx, xliff12 or xliff20. tmx when empty.
+	Format string `query:"format"`
+}
+
+// GetMemoryExportHandler -> download a language pair of the memory as tmx or xliff, formats imports take.
+func GetMemoryExportHandler(memories Memorizer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		var params GetMemoryExportHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("invalid parameters: %v", params))
+		}
+		format := memory.FormatTMX
+		if params.Format != "" {
+			var ok bool
+			if format, ok = memory.ParseFormat(params.Format); !ok {
+				return c.String(http.StatusBadRequest, "invalid parameters: format must be tmx, xliff12 or xliff20;")
+			}
+		}
+
+		// written to a buffer first so a failure can still answer with an error status.
+		var b bytes.Buffer
+		err := memories.Export(c.Request().Context(), userCtx, params.SourceLanguage, params.TargetLanguage, format, &b)
+		if err != nil {
+			if status := memoryErrorStatus(err); status != http.StatusInternalServerError {
+				return c.String(status, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to export translation memory")
+		}
+
+		name := fmt.Sprintf("memory-%s-%s%s", params.SourceLanguage, params.TargetLanguage, format.Extension())
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+		return c.Blob(http.StatusOK, format.ContentType()+"; charset=utf-8", b.Bytes())
+	}
+}
+
+type PostMemoryDeleteHandlerReq struct {
+	SourceLanguage string `form:"source-language"`
+	TargetLanguage string `form:"target-language"`
+}
+
+// PostMemoryDeleteHandler -> forget a language pair of the memory.
+func PostMemoryDeleteHandler(config memory.Config, memories Memorizer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		var params PostMemoryDeleteHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return renderMemory(c, config, memories, http.StatusBadRequest, "", "invalid parameters")
+		}
+		if err := memories.DeletePair(c.Request().Context(), userCtx, params.SourceLanguage, params.TargetLanguage); err != nil {
+			return memoryEditResponse(c, config, memories, "", err)
+		}
+		return c.Redirect(http.StatusSeeOther, "/memory")
+	}
+}
+
+// memorySuggestionsHTML -> the translation memory's fuzzy matches of a translation, for its result card.
+func memorySuggestionsHTML(suggestions []memory.Suggestion) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	items := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		items = append(items, fmt.Sprintf(
+			`<li><small>%d%%: %s -> %s</small></li>`,
+			s.Percent(),
+			html.EscapeString(s.Source),
+			html.EscapeString(s.Target),
+		))
+	}
+	return fmt.Sprintf(
+		`<p class="card-text mb-0"><small class="text-muted">Translation memory suggestions:</small></p><ul class="mb-0">%s</ul>`,
+		strings.Join(items, ""),
+	)
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type OrgsPageData struct {
+	ActiveOrgID        int
+	Memberships        []*postgres.OrganizationMembership
+	PendingInvitations []*postgres.OrganizationInvitation
+}
+
+type OrgPageData struct {
+	ActiveOrgID int
+	Membership  *postgres.OrganizationMembership
+	CanManage   bool
+	Members     []*postgres.OrganizationMembership
+	Invitations []*postgres.OrganizationInvitation
+	Roles       []org.Role
+}
+
+type InvitationPageData struct {
+	Token      string
+	Invitation *postgres.OrganizationInvitation
+	Link       string
+}
+
+// orgErrorResponse -> map org and storage errors onto a status code the user can act on.
+func orgErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, org.ErrNotMember), errors.Is(err, org.ErrForbidden):
+		return c.String(http.StatusForbidden, err.Error())
+	case errors.Is(err, org.ErrInvalid),
+		errors.Is(err, postgres.ErrLastOwner),
+		errors.Is(err, postgres.ErrInvitationUnusable):
+		return c.String(http.StatusBadRequest, err.Error())
+	case errors.Is(err, postgres.ErrNotFound):
+		return c.String(http.StatusNotFound, err.Error())
+	default:
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "organization request failed")
+	}
+}
+
+func orgIDParam(c echo.Context) (int, error) {
+	orgID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || orgID <= 0 {
+		return 0, fmt.Errorf("invalid organization id: %s", c.Param("id"))
+	}
+	return orgID, nil
+}
+
+// setSessionCookie -> replace the session cookie after the jwt was reissued.
+func setSessionCookie(c echo.Context, jwt string) {
+	c.SetCookie(&http.Cookie{
+		Name:    "session_token",
+		Value:   jwt,
+		Expires: time.Now().UTC().Add(60 * time.Minute),
+	})
+}
+
+func GetOrgsHandler(orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		ctx := c.Request().Context()
+
+		memberships, err := orgs.List(ctx, userCtx)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+		invitations, err := orgs.PendingInvitations(ctx, userCtx)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		return c.Render(http.StatusOK, "orgs", OrgsPageData{
+			ActiveOrgID:        userCtx.OrgID,
+			Memberships:        memberships,
+			PendingInvitations: invitations,
+		})
+	}
+}
+
+type PostOrgRequest struct {
+	Name string `form:"name"`
+}
+
+func PostOrgHandler(orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		req := new(PostOrgRequest)
+		if err := c.Bind(req); err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusBadRequest, "bad request")
+		}
+
+		organization, err := orgs.Create(c.Request().Context(), userCtx, req.Name)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		return c.Redirect(http.StatusFound, fmt.Sprintf("/orgs/%d", organization.ID))
+	}
+}
+
+func GetOrgHandler(orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		orgID, err := orgIDParam(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		ctx := c.Request().Context()
+
+		membership, err := orgs.Membership(ctx, userCtx, orgID)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+		members, err := orgs.Members(ctx, userCtx, orgID)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+		invitations, err := orgs.Invitations(ctx, userCtx, orgID)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		return c.Render(http.StatusOK, "org", OrgPageData{
+			ActiveOrgID: userCtx.OrgID,
+			Membership:  membership,
+			CanManage:   org.Role(membership.Role).CanManage(),
+			Members:     members,
+			Invitations: invitations,
+			Roles:       []org.Role{org.RoleMember, org.RoleAdmin, org.RoleOwner},
+		})
+	}
+}
+
+type PostOrgInvitationRequest struct {
+	Invitee string   `form:"invitee"`
+	Role    org.Role `form:"role"`
+}
+
+// PostOrgInvitationHandler -> htmx fragment with the invite link. the link is shown once since only its hash is stored.
+func PostOrgInvitationHandler(orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		orgID, err := orgIDParam(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		req := new(PostOrgInvitationRequest)
+		if err := c.Bind(req); err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusBadRequest, "bad request")
+		}
+
+		invitation, err := orgs.Invite(c.Request().Context(), userCtx, orgID, req.Invitee, req.Role)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		return c.Render(http.StatusOK, "org_invitation", InvitationPageData{
+			Token:      invitation.Token,
+			Invitation: invitation.OrganizationInvitation,
+			Link: fmt.Sprintf(
+				"%s://%s/invitations/%s",
+				c.Scheme(),
+				c.Request().Host,
+				invitation.Token,
+			),
+		})
+	}
+}
+
+type PostOrgMemberRoleRequest struct {
+	Role org.Role `form:"role"`
+}
+
+func PostOrgMemberRoleHandler(orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		orgID, err := orgIDParam(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		req := new(PostOrgMemberRoleRequest)
+		if err := c.Bind(req); err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusBadRequest, "bad request")
+		}
+
+		if err := orgs.SetRole(c.Request().Context(), userCtx, orgID, c.Param("username"), req.Role); err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		return c.Redirect(http.StatusFound, fmt.Sprintf("/orgs/%d", orgID))
+	}
+}
+
+func PostOrgMemberRemoveHandler(auther Auther, orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		orgID, err := orgIDParam(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		username := c.Param("username")
+
+		if err := orgs.Remove(c.Request().Context(), userCtx, orgID, username); err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		if username != userCtx.Username {
+			return c.Redirect(http.StatusFound, fmt.Sprintf("/orgs/%d", orgID))
+		}
+
+		// left the org. drop it from the session if it was the active one.
+		if userCtx.OrgID == orgID {
+			sessionToken, ok := auth.SessionTokenFromEcho(c)
+			if !ok {
+				return c.String(http.StatusUnauthorized, "no credentials")
+			}
+			jwt, err := auther.SwitchOrg(c.Request().Context(), sessionToken, 0, "")
+			if err != nil {
+				c.Logger().Error(err)
+				return c.String(http.StatusInternalServerError, "failed to switch organization")
+			}
+			setSessionCookie(c, jwt)
+		}
+		return c.Redirect(http.StatusFound, "/orgs")
+	}
+}
+
+func GetInvitationHandler(orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		token := c.Param("token")
+		invitation, err := orgs.Invitation(c.Request().Context(), token)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		return c.Render(http.StatusOK, "invitation", InvitationPageData{
+			Token:      token,
+			Invitation: invitation,
+		})
+	}
+}
+
+// PostAcceptInvitationHandler -> join the org and make it the active org of the session.
+func PostAcceptInvitationHandler(auther Auther, orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		ctx := c.Request().Context()
+
+		orgID, err := orgs.Accept(ctx, userCtx, c.Param("token"))
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+		membership, err := orgs.Membership(ctx, userCtx, orgID)
+		if err != nil {
+			return orgErrorResponse(c, err)
+		}
+
+		sessionToken, ok := auth.SessionTokenFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		jwt, err := auther.SwitchOrg(ctx, sessionToken, orgID, membership.Role)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to switch organization")
+		}
+		setSessionCookie(c, jwt)
+
+		return c.Redirect(http.StatusFound, fmt.Sprintf("/orgs/%d", orgID))
+	}
+}
+
+type PostSwitchOrgRequest struct {
+	OrgID int `form:"org-id"`
+}
+
+// PostSwitchOrgHandler -> org-id 0 switches back to the personal workspace.
+func PostSwitchOrgHandler(auther Auther, orgs Orger) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		req := new(PostSwitchOrgRequest)
+		if err := c.Bind(req); err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusBadRequest, "bad request")
+		}
+		ctx := c.Request().Context()
+
+		orgRole := ""
+		if req.OrgID != 0 {
+			membership, err := orgs.Membership(ctx, userCtx, req.OrgID)
+			if err != nil {
+				return orgErrorResponse(c, err)
+			}
+			orgRole = membership.Role
+		}
+
+		sessionToken, ok := auth.SessionTokenFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+		jwt, err := auther.SwitchOrg(ctx, sessionToken, req.OrgID, orgRole)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to switch organization")
+		}
+		setSessionCookie(c, jwt)
+
+		return c.Redirect(http.StatusFound, "/orgs")
+	}
+}
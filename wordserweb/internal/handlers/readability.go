@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+)
+
+type GetReadabilityHandlerReq struct {
+	AnalyzeText string `query:"analyze-text" form:"analyze-text"`
+}
+
+// GetReadabilityHandler -> the readability and text statistics of analyze-text as json, for api clients. /analyze
+// with analyzer=readability renders the same as a card. routed for GET and POST like /analyze.
+func GetReadabilityHandler(readability analyzer.Analyzer, meter UsageMeterer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var params GetReadabilityHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return c.String(http.StatusBadRequest, "invalid parameters")
+		}
+		if params.AnalyzeText == "" {
+			return c.String(http.StatusBadRequest, "invalid parameters: analyze-text can't be empty;")
+		}
+
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		ctx := c.Request().Context()
+		chars := usage.CharCount(params.AnalyzeText)
+		reservation, err := meter.Reserve(ctx, userCtx, chars)
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+		defer releaseUsage(ctx, c.Logger(), meter, reservation)
+
+		result, err := readability.Run(ctx, params.AnalyzeText)
+		if err := meter.Record(ctx, userCtx, usage.API(readability.Name()), chars); err != nil {
+			c.Logger().Error(err)
+		}
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to analyze readability")
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	htmpl "html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/search"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+const (
+	searchResultLimit = 50
+	searchDateLayout  = "2006-01-02"
+)
+
+type GetSearchHandlerReq struct {
+	Query    string `query:"q"`
+	From     string `query:"from"`
+	To       string `query:"to"`
+	Polarity string `query:"polarity"`
+	Keyword  string `query:"keyword"`
+	Kind     string `query:"kind"`
+}
+
+type SearchResultView struct {
+	*postgres.SearchResult
+	Headline          htmpl.HTML
+	SecondaryHeadline htmpl.HTML
+}
+
+type SearchPageData struct {
+	Params  GetSearchHandlerReq
+	Results []SearchResultView
+	Error   string
+	// Searched -> false on the first visit so the page doesn't claim there were no results.
+	Searched bool
+}
+
+// highlight -> html escape a ts_headline and only then mark up the matches it delimited.
+func highlight(headline string) htmpl.HTML {
+	escaped := htmpl.HTMLEscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, postgres.HeadlineStartSel, "<mark>")
+	escaped = strings.ReplaceAll(escaped, postgres.HeadlineStopSel, "</mark>")
+	return htmpl.HTML(escaped)
+}
+
+func parseSearchDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(searchDateLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func GetSearchHandler(searcher Searcher) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		var params GetSearchHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return c.Render(http.StatusBadRequest, "search", SearchPageData{Error: "invalid parameters"})
+		}
+
+		data := SearchPageData{Params: params}
+		if params.Query == "" && params.From == "" && params.To == "" && params.Polarity == "" && params.Keyword == "" {
+			return c.Render(http.StatusOK, "search", data)
+		}
+		data.Searched = true
+
+		from, err := parseSearchDate(params.From)
+		if err != nil {
+			data.Error = "invalid from date; expected YYYY-MM-DD;"
+			return c.Render(http.StatusBadRequest, "search", data)
+		}
+		to, err := parseSearchDate(params.To)
+		if err != nil {
+			data.Error = "invalid to date; expected YYYY-MM-DD;"
+			return c.Render(http.StatusBadRequest, "search", data)
+		}
+		if to != nil {
+			// the to date is inclusive.
+			end := to.AddDate(0, 0, 1)
+			to = &end
+		}
+
+		tsquery := search.ToTSQuery(params.Query)
+		if params.Query != "" && tsquery == "" {
+			data.Error = "nothing searchable in query"
+			return c.Render(http.StatusBadRequest, "search", data)
+		}
+
+		kind := params.Kind
+		if kind != postgres.SearchKindAnalysis && kind != postgres.SearchKindTranslation {
+			kind = ""
+		}
+
+		results, err := searcher.Search(c.Request().Context(), postgres.SearchParams{
+			Username: userCtx.Username,
+			TSQuery:  tsquery,
+			From:     from,
+			To:       to,
+			Polarity: params.Polarity,
+			Keyword:  strings.TrimSpace(params.Keyword),
+			Kind:     kind,
+			Limit:    searchResultLimit,
+		})
+		if err != nil {
+			c.Logger().Error(err)
+			data.Error = "search failed"
+			return c.Render(http.StatusInternalServerError, "search", data)
+		}
+
+		for _, r := range results {
+			data.Results = append(data.Results, SearchResultView{
+				SearchResult:      r,
+				Headline:          highlight(r.Headline),
+				SecondaryHeadline: highlight(r.SecondaryHeadline),
+			})
+		}
+
+		return c.Render(http.StatusOK, "search", data)
+	}
+}
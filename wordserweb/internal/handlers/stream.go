@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/sse"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+)
+
+const analyzeStreamPingInterval = 15 * time.Second
+
+// AnalyzeStreamRequest -> what PostAnalyzeStreamHandler leaves for GetAnalyzeStreamHandler to run.
+type AnalyzeStreamRequest struct {
+	Text      string
+	Analyzers []analyzer.Analyzer
+	Source    *AnalyzeSource
+	// Engine -> as GetAnalyzeHandlerReq.Engine; Analyzers are already set to run on it.
+	Engine   string
+	Language *AnalyzeLanguage
+	// Reservation -> the usage held for the analyzers; released once the stream has metered them. a stream that is
+	// never opened leaves it to run out.
+	Reservation usage.Reservation
+}
+
+type AnalyzeStreamData struct {
+	ID           string
+	OriginalText string
+	Source       *AnalyzeSource
+	Engine       string
+	Language     *AnalyzeLanguage
+	// Sections -> placeholders, without results, for the analyzers still running.
+	Sections []AnalysisSection
+}
+
+// PostAnalyzeStreamHandler -> validate and quota check like /analyze, then render a card that fills in from
+// /analyze/stream/:id as each analyzer finishes.
+func PostAnalyzeStreamHandler(streams AnalyzeStreamer, deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		params, selected, invalid := bindAnalyzeRequest(c, deps.Analyzers)
+		if invalid != "" {
+			return c.String(http.StatusBadRequest, invalid)
+		}
+
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		txt, source, ok, err := analyzeInput(c, deps.Fetcher, params)
+		if !ok {
+			return err
+		}
+		txt, language, ok, err := analyzeLanguage(c, deps, userCtx, selected, txt, params.TranslateFrom)
+		if !ok {
+			return err
+		}
+
+		ctx := c.Request().Context()
+		reservation, err := deps.Meter.Reserve(ctx, userCtx, usage.CharCount(txt)*len(selected))
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+
+		id, err := streams.Put(userCtx.Username, AnalyzeStreamRequest{
+			Text:        txt,
+			Analyzers:   selected,
+			Source:      source,
+			Engine:      params.Engine,
+			Language:    language,
+			Reservation: reservation,
+		})
+		if err != nil {
+			c.Logger().Error(err)
+			releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+			return c.String(http.StatusInternalServerError, "failed to start analysis")
+		}
+
+		data := AnalyzeStreamData{
+			ID:           id,
+			OriginalText: txt,
+			Source:       source,
+			Engine:       params.Engine,
+			Language:     language,
+		}
+		for _, a := range selected {
+			data.Sections = append(data.Sections, AnalysisSection{
+				API:   a.Name(),
+				Title: a.Options().Title,
+			})
+		}
+		return c.Render(http.StatusOK, "analysis_stream", data)
+	}
+}
+
+// AnalyzerNames -> as AnalyzeData.AnalyzerNames.
+func (a AnalyzeStreamData) AnalyzerNames() []string {
+	names := make([]string, 0, len(a.Sections))
+	for _, section := range a.Sections {
+		names = append(names, section.API)
+	}
+	return names
+}
+
+// GetAnalyzeStreamHandler -> server sent events with one analysis_section fragment per analyzer as soon as it
+// finishes, then a done event with the whole saved analysis. closing the tab cancels the request context and
+// with it the outstanding analyzer calls.
+func GetAnalyzeStreamHandler(streams AnalyzeStreamer, deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		req, ok := streams.Take(userCtx.Username, c.Param("id"))
+		if !ok {
+			// 204 stops EventSource from reconnecting; a finished stream can't be replayed.
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		ctx := c.Request().Context()
+		start := time.Now()
+		respChan := make(chan APIResponse, len(req.Analyzers))
+		for _, a := range req.Analyzers {
+			go func(a analyzer.Analyzer) {
+				respChan <- runAnalyzer(ctx, deps.Chunker, a, req.Text)
+			}(a)
+		}
+
+		sse.Start(c)
+		ticker := time.NewTicker(analyzeStreamPingInterval)
+		defer ticker.Stop()
+
+		chars := usage.CharCount(req.Text)
+		// the call was made even if the tab is gone by now. the logger is taken up front; c is reused once the
+		// handler returns.
+		logger := c.Logger()
+		meterResp := func(resp APIResponse) {
+			if err := deps.Meter.Record(context.WithoutCancel(ctx), userCtx, usage.API(resp.analyzer.Name()), chars); err != nil {
+				logger.Error(err)
+			}
+		}
+		resps := make([]APIResponse, 0, len(req.Analyzers))
+		defer func() {
+			// a stream ended early leaves calls running; they are metered as they return, cut short by ctx.
+			go func(outstanding int) {
+				for ; outstanding > 0; outstanding-- {
+					meterResp(<-respChan)
+				}
+				releaseUsage(ctx, logger, deps.Meter, req.Reservation)
+			}(len(req.Analyzers) - len(resps))
+		}()
+		for len(resps) < len(req.Analyzers) {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := sse.Ping(c); err != nil {
+					return nil
+				}
+			case resp := <-respChan:
+				resps = append(resps, resp)
+				meterResp(resp)
+				if resp.err != nil {
+					c.Logger().Errorf("failed get response from api: %v; err: %v;", resp.analyzer.Name(), resp.err)
+				}
+
+				data, _ := newAnalyzeDataFromResps(req.Text, []analyzer.Analyzer{resp.analyzer}, []APIResponse{resp})
+				data.Engine = req.Engine
+				section, _ := data.section(resp.analyzer)
+				if err := renderSSEEvent(c, resp.analyzer.Name(), "analysis_section", section); err != nil {
+					c.Logger().Error(err)
+					return nil
+				}
+			}
+		}
+
+		analysisData, _ := newAnalyzeDataFromResps(req.Text, req.Analyzers, resps)
+		analysisData.Source = req.Source
+		analysisData.Engine = req.Engine
+		analysisData.Language = req.Language
+		record, err := newAnalysisRecord(userCtx, req.Analyzers, analysisData, resps, time.Since(start))
+		if err == nil {
+			analysisData.HistoryID, err = deps.History.InsertAnalysis(ctx, record)
+		}
+		if err != nil {
+			c.Logger().Error(err)
+		}
+		deps.Hooks.Publish(ctx, userCtx, webhook.EventAnalysisCompleted, newAnalysisCompletedEvent(req.Analyzers, analysisData))
+
+		if err := renderSSEEvent(c, "done", "analysis", analysisData); err != nil {
+			c.Logger().Error(err)
+		}
+		return nil
+	}
+}
+
+func renderSSEEvent(c echo.Context, event string, name string, data any) error {
+	var buf bytes.Buffer
+	if err := c.Echo().Renderer.Render(&buf, name, data, c); err != nil {
+		return err
+	}
+	return sse.Event(c, event, buf.String())
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/subtitle"
+	"github.com/nolandseigler/wordser/wordserweb/internal/translate"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+)
+
+// SubtitleJobPayload -> input of a jobser.KindSubtitle job.
+type SubtitleJobPayload struct {
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+	// Content -> the uploaded file, parsed again by the job.
+	Content string `json:"content"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	// Reservation -> the usage held for the job when it was queued; released once the job has recorded its usage.
+	Reservation usage.Reservation `json:"reservation,omitempty"`
+}
+
+type SubtitleJobResult struct {
+	// Filename -> the download's name, the upload's with the target language before its extension.
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+	// Content -> the translated file, failed cues as they were.
+	Content    string             `json:"content"`
+	Cues       int                `json:"cues"`
+	Translated int                `json:"translated"`
+	Failures   []subtitle.Failure `json:"failures,omitempty"`
+}
+
+// subtitleText -> the cue text of f, for detecting its language and metering.
+func subtitleText(f *subtitle.File) string {
+	texts := make([]string, 0, len(f.Cues))
+	for _, cue := range f.Cues {
+		texts = append(texts, cue.Text())
+	}
+	return strings.Join(texts, "\n")
+}
+
+// PostSubtitlesHandler -> queue the translation of an uploaded srt or webvtt file. the languages are the translate
+// form's.
+func PostSubtitlesHandler(config subtitle.Config, deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.String(http.StatusBadRequest, "choose a subtitle file to translate")
+		}
+		if file.Size > config.MaxBytes {
+			return c.String(
+				http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("the file is too large; at most %d bytes;", config.MaxBytes),
+			)
+		}
+		format, ok := subtitle.FormatFromFilename(file.Filename)
+		if !ok {
+			return c.String(http.StatusBadRequest, "expected a .srt or .vtt file")
+		}
+		src, err := file.Open()
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to read the file")
+		}
+		defer src.Close()
+		data, err := io.ReadAll(io.LimitReader(src, config.MaxBytes))
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to read the file")
+		}
+		parsed, err := subtitle.Parse(data, format)
+		if err != nil {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("failed to read %s: %v", file.Filename, err))
+		}
+		txt := subtitleText(parsed)
+
+		ctx := c.Request().Context()
+		catalog, err := deps.Translator.Languages(ctx)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusServiceUnavailable, "translation is temporarily unavailable; try again shortly;")
+		}
+
+		ui := displayLanguage(c)
+		source := c.FormValue("source-language")
+		if source == "" || source == AutoDetect.String() {
+			detected := deps.Detector.Detect(txt)
+			if !detected.Reliable {
+				return c.String(
+					http.StatusUnprocessableEntity,
+					"couldn't detect the language of the subtitles; pick a source-language;",
+				)
+			}
+			source = detected.Language
+		}
+		source, ok = catalog.ResolveSource(source)
+		if !ok {
+			return c.String(http.StatusBadRequest, "invalid parameters: source-language not supported;")
+		}
+		target, ok := catalog.ResolveTarget(c.FormValue("target-language"))
+		if c.FormValue("target-language") == "" {
+			target, ok = preferredTarget(c, catalog)
+		}
+		if !ok {
+			return c.String(http.StatusBadRequest, "invalid parameters: target-language not supported;")
+		}
+		if source == target {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf("invalid parameters: the subtitles are already in %s;", languageName(target, ui)),
+			)
+		}
+		if !catalog.Supports(source, target) {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf(
+					"invalid parameters: translating %s to %s is not supported;",
+					languageName(source, ui),
+					languageName(target, ui),
+				),
+			)
+		}
+
+		reservation, err := deps.Meter.Reserve(ctx, userCtx, usage.CharCount(txt))
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+
+		return enqueueJob(c, deps.Jobs, deps.Meter, reservation, userCtx, jobser.KindSubtitle, SubtitleJobPayload{
+			Filename:    path.Base(file.Filename),
+			Format:      format.String(),
+			Content:     string(data),
+			Source:      source,
+			Target:      target,
+			Reservation: reservation,
+		})
+	}
+}
+
+// SubtitleJob -> translate a queued subtitle file, glossary terms enforced on every batch of cues. fails the attempt
+// only when no cue could be translated; otherwise the failed cues are reported and left as they were.
+func SubtitleJob(subtitles Subtitler, deps Deps) jobser.Handler {
+	return func(ctx context.Context, job *postgres.Job) (any, error) {
+		var payload SubtitleJobPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, resilience.Permanent(err)
+		}
+		format := subtitle.Format(payload.Format)
+		f, err := subtitle.Parse([]byte(payload.Content), format)
+		if err != nil {
+			return nil, resilience.Permanent(err)
+		}
+
+		userCtx := jobser.UserContext(job)
+		var lastErr error
+		report := subtitles.Translate(ctx, f, func(ctx context.Context, txt string) (string, error) {
+			protected, err := deps.Glossaries.Protect(ctx, userCtx, txt, payload.Source, payload.Target)
+			if err != nil {
+				lastErr = err
+				return "", err
+			}
+			translation, err := deps.Translator.Translate(ctx, protected.Text, payload.Source, payload.Target)
+			if err != nil {
+				lastErr = err
+				return "", err
+			}
+			restored, _ := protected.Restore(translation.Text)
+			return restored, nil
+		})
+		if report.Chars == 0 && lastErr != nil {
+			// nothing was translated, so nothing is billed and a retry can't bill twice. an unsupported pair won't
+			// be supported on a retry either, nor will a workspace the user left.
+			if errors.Is(lastErr, translate.ErrUnsupportedPair) || errors.Is(lastErr, org.ErrNotMember) {
+				return nil, resilience.Permanent(lastErr)
+			}
+			return nil, lastErr
+		}
+
+		var b bytes.Buffer
+		if err := f.Write(&b); err != nil {
+			return nil, err
+		}
+		// billed once, on the cue text delivered; never failing the job, whose translations are done.
+		if report.Chars > 0 {
+			recordUsage(ctx, deps.Logger, deps.Meter, userCtx, usage.APITranslate, report.Chars)
+		}
+		releaseUsage(ctx, deps.Logger, deps.Meter, payload.Reservation)
+
+		ext := path.Ext(payload.Filename)
+		return SubtitleJobResult{
+			Filename:   fmt.Sprintf("%s.%s%s", strings.TrimSuffix(payload.Filename, ext), payload.Target, ext),
+			Format:     format.String(),
+			Content:    b.String(),
+			Cues:       report.Cues,
+			Translated: report.Translated(),
+			Failures:   report.Failures,
+		}, nil
+	}
+}
+
+// GetJobSubtitlesHandler -> download the translated file of a finished subtitle job.
+func GetJobSubtitlesHandler(jobs JobQueuer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		job, ok, err := jobFromParam(c, jobs)
+		if !ok {
+			return err
+		}
+		view := newJobView(job)
+		if view.Subtitle == nil {
+			return c.String(http.StatusNotFound, "the job has no translated subtitles")
+		}
+
+		format := subtitle.Format(view.Subtitle.Format)
+		c.Response().Header().Set(
+			echo.HeaderContentDisposition,
+			fmt.Sprintf("attachment; filename=%q", view.Subtitle.Filename),
+		)
+		return c.Blob(http.StatusOK, format.ContentType()+"; charset=utf-8", []byte(view.Subtitle.Content))
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+)
+
+const maxSynonymWordLen = 64
+
+var whitespaceRe = regexp.MustCompile(`\s+`)
+
+type GetSynonymsHandlerReq struct {
+	Word string `query:"word"`
+}
+
+type SynonymsData struct {
+	Word     string
+	Synonyms []string
+}
+
+// TextToken -> a run of text. Word tokens are rendered as thesaurus links, the rest as is.
+type TextToken struct {
+	Text string
+	Word bool
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// tokenizeWords -> split txt into words and the text between them. apostrophes and hyphens inside a word keep it whole.
+func tokenizeWords(txt string) []TextToken {
+	var tokens []TextToken
+	runes := []rune(txt)
+	start := 0
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(runes) {
+			if isWordRune(runes[j]) {
+				j++
+				continue
+			}
+			if (runes[j] == '\'' || runes[j] == '’' || runes[j] == '-') && j+1 < len(runes) && isWordRune(runes[j+1]) {
+				j += 2
+				continue
+			}
+			break
+		}
+		if start < i {
+			tokens = append(tokens, TextToken{Text: string(runes[start:i])})
+		}
+		tokens = append(tokens, TextToken{Text: string(runes[i:j]), Word: true})
+		start = j
+		i = j
+	}
+	if start < len(runes) {
+		tokens = append(tokens, TextToken{Text: string(runes[start:])})
+	}
+	return tokens
+}
+
+// normalizeSynonymWord -> the lower case word or phrase ("look up") to look up, or false if word isn't one.
+func normalizeSynonymWord(word string) (string, bool) {
+	word = whitespaceRe.ReplaceAllString(strings.TrimSpace(word), " ")
+	if word == "" || utf8.RuneCountInString(word) > maxSynonymWordLen {
+		return "", false
+	}
+	for _, token := range tokenizeWords(word) {
+		if !token.Word && token.Text != " " {
+			return "", false
+		}
+	}
+	return strings.ToLower(word), true
+}
+
+// GetSynonymsHandler -> htmx fragment with the thesaurus entries for one word.
+func GetSynonymsHandler(client WordserClient, meter UsageMeterer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var params GetSynonymsHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return c.String(http.StatusBadRequest, "invalid parameters")
+		}
+
+		word, ok := normalizeSynonymWord(params.Word)
+		if !ok {
+			return c.String(http.StatusBadRequest, "invalid parameters: word must be a word or phrase;")
+		}
+
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		ctx := c.Request().Context()
+		chars := usage.CharCount(word)
+		reservation, err := meter.Reserve(ctx, userCtx, chars)
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+		defer releaseUsage(ctx, c.Logger(), meter, reservation)
+
+		resp, err := client.Synonyms(ctx, word)
+		if err := meter.Record(ctx, userCtx, usage.APISynonyms, chars); err != nil {
+			c.Logger().Error(err)
+		}
+		if err != nil {
+			c.Logger().Error(err)
+			if errors.Is(err, resilience.ErrCircuitOpen) || errors.Is(err, resilience.ErrBulkheadFull) {
+				return c.String(http.StatusServiceUnavailable, "the thesaurus is temporarily unavailable; try again shortly;")
+			}
+			return c.String(http.StatusBadGateway, "failed to get synonyms")
+		}
+
+		return c.Render(http.StatusOK, "synonyms", SynonymsData{
+			Word:     word,
+			Synonyms: resp.Synonyms,
+		})
+	}
+}
@@ -1,172 +1,403 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
+	"slices"
+	"sort"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/glossary"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/langdetect"
+	"github.com/nolandseigler/wordser/wordserweb/internal/memory"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/translate"
+	"github.com/nolandseigler/wordser/wordserweb/internal/usage"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
 )
 
 type TranslateLanguage string
 
 const (
-	Arabic    TranslateLanguage = "ar"
-	Chinese   TranslateLanguage = "zh"
-	English   TranslateLanguage = "en"
-	French    TranslateLanguage = "fr"
-	German    TranslateLanguage = "de"
-	Greek     TranslateLanguage = "el"
-	Italian   TranslateLanguage = "it"
-	Portugese TranslateLanguage = "pt"
-	Spanish   TranslateLanguage = "es"
-	Russian   TranslateLanguage = "ru"
+	English TranslateLanguage = "en"
+	// AutoDetect -> source-language detected from the text.
+	AutoDetect TranslateLanguage = "auto"
 )
 
 func (t TranslateLanguage) String() string {
 	return string(t)
 }
 
-func (t TranslateLanguage) ToLanguageName() (string, error) {
-	switch t {
-	case Arabic:
-		return "Arabic", nil
-	case Chinese:
-		return "Chinese", nil
-	case English:
-		return "English", nil
-	case French:
-		return "French", nil
-	case German:
-		return "German", nil
-	case Greek:
-		return "Greek", nil
-	case Italian:
-		return "Italian", nil
-	case Portugese:
-		return "Portugese", nil
-	case Spanish:
-		return "Spanish", nil
-	case Russian:
-		return "Russian", nil
-	default:
-		return "", fmt.Errorf(
-			"invalid parameters: %v; source-language not supported;",
-			t,
-		)
-	}
-}
+// displayTags/displayLanguages -> the languages display can name languages in, and matching of Accept-Language
+// onto them.
+var (
+	displayTags      = display.Supported.Tags()
+	displayLanguages = language.NewMatcher(displayTags)
+)
 
-type TranslateResp struct {
-	TranslatedText string `json:"translated_text"`
+// acceptLanguages -> the tags of the request's Accept-Language, most preferred first.
+func acceptLanguages(c echo.Context) []language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(c.Request().Header.Get("Accept-Language"))
+	if err != nil {
+		return nil
+	}
+	return tags
 }
 
-type GetTranslateHandlerReq struct {
-	TranslateText  string            `query:"translate-text"`
-	SourceLanguage TranslateLanguage `query:"source-language"`
-	TargetLanguage TranslateLanguage `query:"target-language"`
+// displayLanguage -> the language to name languages in: english unless Accept-Language prefers one display knows.
+func displayLanguage(c echo.Context) language.Tag {
+	tags := acceptLanguages(c)
+	if len(tags) == 0 {
+		return language.English
+	}
+	_, i, confidence := displayLanguages.Match(tags...)
+	if confidence < language.High {
+		return language.English
+	}
+	return displayTags[i]
 }
 
-func GetTranslateHandler(c echo.Context) error {
-	var params GetTranslateHandlerReq
-	err := c.Bind(&params)
+// languageName -> code named in the display language in. codes display can't name are their own name.
+func languageName(code string, in language.Tag) string {
+	tag, err := language.Parse(code)
 	if err != nil {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v",
-				params,
-			),
-		)
+		return code
 	}
-
-	if params.TranslateText == "" {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v, translate-text can't be empty;",
-				params,
-			),
-		)
+	if name := display.Tags(in).Name(tag); name != "" {
+		return name
 	}
+	return code
+}
 
-	sourceLangName, err := params.SourceLanguage.ToLanguageName()
-	if err != nil {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v; source-language not supported;",
-				params,
-			),
-		)
+// LanguageOption -> an option of the translate form's language selects.
+type LanguageOption struct {
+	Code     string
+	Name     string
+	Selected bool
+}
+
+// languageOptions -> codes named in the display language in, in its alphabetical order.
+func languageOptions(codes []string, selected string, in language.Tag) []LanguageOption {
+	options := make([]LanguageOption, 0, len(codes))
+	for _, code := range codes {
+		options = append(options, LanguageOption{Code: code, Name: languageName(code, in), Selected: code == selected})
 	}
+	collator := collate.New(in)
+	sort.SliceStable(options, func(i, j int) bool {
+		return collator.CompareString(options[i].Name, options[j].Name) < 0
+	})
+	return options
+}
 
-	targLangName, err := params.TargetLanguage.ToLanguageName()
-	if err != nil {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v; target-language not supported;",
-				params,
-			),
-		)
+// preferredTarget -> the target for a request naming none: the one Accept-Language prefers, otherwise english, as
+// the translate form has it selected.
+func preferredTarget(c echo.Context, catalog *translate.Catalog) (string, bool) {
+	if target, ok := catalog.PreferredTarget(acceptLanguages(c)...); ok {
+		return target, true
 	}
+	return catalog.ResolveTarget(English.String())
+}
 
-	if params.SourceLanguage == params.TargetLanguage {
-		return c.String(
-			http.StatusBadRequest,
-			fmt.Sprintf(
-				"invalid parameters: %v; support-language cannot equal target-language;",
-				params,
-			),
-		)
+// targetOptions -> the target languages of source, an empty or AutoDetect source having every target. target stays
+// selected when it is one of them, otherwise the one Accept-Language prefers, otherwise english.
+func targetOptions(c echo.Context, catalog *translate.Catalog, source string, target string) []LanguageOption {
+	if resolved, ok := catalog.ResolveSource(source); ok {
+		source = resolved
+	} else {
+		source = ""
 	}
+	targets := catalog.Targets(source)
 
-	resp, err := http.Get(
-		fmt.Sprintf(
-			"http://backend:5000/translate?inputText=%s&sourceLanguage=%s&targetLanguage=%s",
-			url.QueryEscape(params.TranslateText),
-			url.QueryEscape(params.SourceLanguage.String()),
-			url.QueryEscape(params.TargetLanguage.String()),
-		),
-	)
-	fmt.Printf("\n\n resp: %v, err: %v \n\n", resp, err)
-	if err != nil {
-		return err
+	selected, ok := catalog.ResolveTarget(target)
+	if !ok || !slices.Contains(targets, selected) {
+		selected, ok = catalog.PreferredTarget(acceptLanguages(c)...)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to get translation from partner's backend; statusCode: %d", resp.StatusCode)
+	if !ok || !slices.Contains(targets, selected) {
+		selected = English.String()
 	}
+	return languageOptions(targets, selected, displayLanguage(c))
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+type GetTranslateTargetsHandlerReq struct {
+	SourceLanguage TranslateLanguage `query:"source-language"`
+	// TargetLanguage -> the current target, kept selected when source-language has it.
+	TargetLanguage TranslateLanguage `query:"target-language"`
+}
+
+// GetTranslateTargetsHandler -> the target-language options of the selected source-language, swapped into the
+// translate form whenever the source changes.
+func GetTranslateTargetsHandler(translator Translator) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var params GetTranslateTargetsHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("invalid parameters: %v", params))
+		}
+
+		catalog, err := translator.Languages(c.Request().Context())
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusServiceUnavailable, "translation languages are temporarily unavailable")
+		}
+		return c.Render(
+			http.StatusOK,
+			"translate_targets",
+			targetOptions(c, catalog, params.SourceLanguage.String(), params.TargetLanguage.String()),
+		)
 	}
+}
+
+type TranslateResp struct {
+	TranslatedText string `json:"translated_text"`
+	Provider       string `json:"provider"`
+	// GlossaryTerms -> the glossary terms enforced on the translation.
+	GlossaryTerms []glossary.Applied `json:"glossary_terms,omitempty"`
+	// MemorySuggestions -> translation memory fuzzy matches of a translation the memory had no exact match for.
+	MemorySuggestions []memory.Suggestion `json:"memory_suggestions,omitempty"`
+}
+
+type GetTranslateHandlerReq struct {
+	TranslateText string `query:"translate-text"`
+	// SourceLanguage -> a language code or any BCP 47 tag matching one, e.g. "pt-BR". AutoDetect or empty to detect
+	// it.
+	SourceLanguage TranslateLanguage `query:"source-language"`
+	// TargetLanguage -> like SourceLanguage. empty for the one Accept-Language prefers.
+	TargetLanguage TranslateLanguage `query:"target-language"`
+	// Background -> queue the translation as a job and answer with a status fragment that polls for it.
+	Background bool `query:"background"`
+}
+
+func GetTranslateHandler(deps Deps) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var params GetTranslateHandlerReq
+		err := c.Bind(&params)
+		if err != nil {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf(
+					"invalid parameters: %v",
+					params,
+				),
+			)
+		}
+
+		if params.TranslateText == "" {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf(
+					"invalid parameters: %v, translate-text can't be empty;",
+					params,
+				),
+			)
+		}
+
+		ctx := c.Request().Context()
+		catalog, err := deps.Translator.Languages(ctx)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusServiceUnavailable, "translation is temporarily unavailable; try again shortly;")
+		}
+
+		var detection *langdetect.Detection
+		source := params.SourceLanguage.String()
+		if params.SourceLanguage == "" || params.SourceLanguage == AutoDetect {
+			detected := deps.Detector.Detect(params.TranslateText)
+			if !detected.Reliable {
+				return c.String(
+					http.StatusUnprocessableEntity,
+					"couldn't detect the language of translate-text; pick a source-language;",
+				)
+			}
+			detection = &detected
+			source = detected.Language
+		}
+		source, ok := catalog.ResolveSource(source)
+		if !ok && detection != nil {
+			return c.String(
+				http.StatusUnprocessableEntity,
+				fmt.Sprintf(
+					"translate-text is in %s, which can't be translated;",
+					languageName(detection.Language, displayLanguage(c)),
+				),
+			)
+		}
+		if !ok {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf(
+					"invalid parameters: %v; source-language not supported;",
+					params,
+				),
+			)
+		}
+
+		// no target-language is the one Accept-Language prefers, or english.
+		target, ok := catalog.ResolveTarget(params.TargetLanguage.String())
+		if params.TargetLanguage == "" {
+			target, ok = preferredTarget(c, catalog)
+		}
+		if !ok {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf(
+					"invalid parameters: %v; target-language not supported;",
+					params,
+				),
+			)
+		}
 
-	transResp := &TranslateResp{}
-	json.Unmarshal(data, transResp)
+		ui := displayLanguage(c)
+		sourceLangName := languageName(source, ui)
+		targLangName := languageName(target, ui)
+		if source == target && detection != nil {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf("invalid parameters: translate-text is already in %s;", targLangName),
+			)
+		}
+		if source == target {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf(
+					"invalid parameters: %v; support-language cannot equal target-language;",
+					params,
+				),
+			)
+		}
+		// checked here so an unsupported pair never reaches a provider.
+		if !catalog.Supports(source, target) {
+			return c.String(
+				http.StatusBadRequest,
+				fmt.Sprintf("invalid parameters: translating %s to %s is not supported;", sourceLangName, targLangName),
+			)
+		}
 
-	fmt.Printf("\n translated text: %v \n", transResp)
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
 
-	return c.HTML(
-		http.StatusOK,
-		fmt.Sprintf(
-			`
-			<div class="card" style="width: 18rem;">
-				<div class="card-body">
-					<h5 class="card-title">%s -> %s</h5>
-					<h6 class="card-subtitle mb-2 text-muted">Original Text: %s</h6>
-					<p class="card-text font-weight-bold">Translated Text: %s</p>
+		chars := usage.CharCount(params.TranslateText)
+		reservation, err := deps.Meter.Reserve(ctx, userCtx, chars)
+		if err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				return c.String(http.StatusTooManyRequests, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to check usage quota")
+		}
+
+		if params.Background {
+			return enqueueJob(c, deps.Jobs, deps.Meter, reservation, userCtx, jobser.KindTranslate, TranslateJobPayload{
+				Text:        params.TranslateText,
+				Source:      source,
+				Target:      target,
+				Reservation: reservation,
+			})
+		}
+		defer releaseUsage(ctx, c.Logger(), deps.Meter, reservation)
+
+		// an exact match is served from the memory without calling a provider; a failing memory only costs that.
+		match, err := deps.Memories.Lookup(ctx, userCtx, params.TranslateText, source, target)
+		if err != nil {
+			c.Logger().Error(err)
+			match = &memory.Match{}
+		}
+		protected, err := deps.Glossaries.Protect(ctx, userCtx, params.TranslateText, source, target)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(glossaryErrorStatus(err), "failed to get glossary terms")
+		}
+		var transResp *TranslateResp
+		if match.Text != "" {
+			// a match made before the glossaries had their terms as they are now is translated again.
+			if applied, ok := protected.Enforced(match.Text); ok {
+				transResp = &TranslateResp{TranslatedText: match.Text, Provider: memory.Provider, GlossaryTerms: applied}
+			}
+		}
+		if transResp == nil {
+			translated, err := deps.Translator.Translate(
+				ctx,
+				protected.Text,
+				source,
+				target,
+			)
+			if err := deps.Meter.Record(ctx, userCtx, usage.APITranslate, chars); err != nil {
+				c.Logger().Error(err)
+			}
+			if err != nil {
+				c.Logger().Error(err)
+				if errors.Is(err, resilience.ErrCircuitOpen) || errors.Is(err, resilience.ErrBulkheadFull) {
+					return c.String(http.StatusServiceUnavailable, "translation is temporarily unavailable; try again shortly;")
+				}
+				return c.String(http.StatusBadGateway, "failed to get translation")
+			}
+			translatedText, applied := protected.Restore(translated.Text)
+			transResp = &TranslateResp{
+				TranslatedText:    translatedText,
+				Provider:          translated.Provider,
+				GlossaryTerms:     applied,
+				MemorySuggestions: match.Suggestions,
+			}
+			// the stub's output isn't a translation worth reusing.
+			if translated.Provider != translate.ProviderStub {
+				if err := deps.Memories.Remember(ctx, userCtx, source, target, params.TranslateText, translatedText); err != nil {
+					c.Logger().Error(err)
+				}
+			}
+		}
+		if detection != nil {
+			sourceLangName = fmt.Sprintf("%s (detected, %.0f%%)", sourceLangName, detection.Confidence*100)
+		}
+
+		record := &postgres.Translation{
+			Username:       userCtx.Username,
+			SourceLanguage: source,
+			TargetLanguage: target,
+			SourceText:     params.TranslateText,
+			TranslatedText: transResp.TranslatedText,
+			Provider:       transResp.Provider,
+		}
+		if userCtx.OrgID != 0 {
+			orgID := userCtx.OrgID
+			record.OrganizationID = &orgID
+		}
+		// the event refers to the stored translation by id; there is none to send when storing it failed.
+		if translationID, err := deps.Translations.InsertTranslation(ctx, record); err != nil {
+			c.Logger().Error(err)
+		} else {
+			deps.Hooks.Publish(ctx, userCtx, webhook.EventTranslationCompleted, newTranslationCompletedEvent(translationID, record))
+		}
+
+		return c.HTML(
+			http.StatusOK,
+			fmt.Sprintf(
+				`
+				<div class="card" style="width: 18rem;">
+					<div class="card-body">
+						<h5 class="card-title">%s -> %s</h5>
+						<h6 class="card-subtitle mb-2 text-muted">Original Text: %s</h6>
+						<p class="card-text font-weight-bold">Translated Text: %s</p>
+						<p class="card-text"><small class="text-muted">Translated by %s</small></p>
+						%s
+						%s
+					</div>
 				</div>
-			</div>
-			`,
-			sourceLangName,
-			targLangName,
-			params.TranslateText,
-			transResp.TranslatedText,
-		),
-	)
+				`,
+				sourceLangName,
+				targLangName,
+				params.TranslateText,
+				transResp.TranslatedText,
+				transResp.Provider,
+				glossaryTermsHTML(transResp.GlossaryTerms),
+				memorySuggestionsHTML(transResp.MemorySuggestions),
+			),
+		)
+	}
 }
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+)
+
+// GetAdminUpstreamsHandler -> circuit breaker and bulkhead state of every upstream endpoint. routed behind
+// auth.RequireRole(auth.RoleAdmin).
+func GetAdminUpstreamsHandler(upstreams ...UpstreamStatuser) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var statuses []resilience.EndpointStatus
+		for _, upstream := range upstreams {
+			statuses = append(statuses, upstream.Status()...)
+		}
+		return c.Render(http.StatusOK, "admin_upstreams", statuses)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+)
+
+// GetUsageHandler -> htmx fragment with the session user's remaining allowance.
+func GetUsageHandler(meter UsageMeterer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		summary, err := meter.Summary(c.Request().Context(), userCtx)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get usage")
+		}
+
+		return c.Render(http.StatusOK, "usage", summary)
+	}
+}
+
+// GetAdminUsageHandler -> per user usage report. routed behind auth.RequireRole(auth.RoleAdmin).
+func GetAdminUsageHandler(meter UsageMeterer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		report, err := meter.Report(c.Request().Context())
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get usage report")
+		}
+
+		return c.Render(http.StatusOK, "admin_usage", report)
+	}
+}
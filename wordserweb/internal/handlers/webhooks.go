@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nolandseigler/wordser/wordserweb/internal/analyzer"
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/webhook"
+)
+
+// webhookDeliveriesShown -> deliveries listed on an endpoint's page.
+const webhookDeliveriesShown = 50
+
+// AnalysisCompletedEvent -> data of a webhook.EventAnalysisCompleted. HistoryID is 0 when the analysis wasn't
+// saved.
+type AnalysisCompletedEvent struct {
+	HistoryID int64    `json:"history_id"`
+	Text      string   `json:"text"`
+	SourceURL string   `json:"source_url,omitempty"`
+	APIs      []string `json:"apis"`
+	// Results -> each analyzer's result keyed by analyzer name.
+	Results map[string]analyzer.Result `json:"results,omitempty"`
+	// Errors -> why an analyzer failed, keyed by analyzer name.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+func newAnalysisCompletedEvent(selected []analyzer.Analyzer, data AnalyzeData) AnalysisCompletedEvent {
+	event := AnalysisCompletedEvent{
+		HistoryID: data.HistoryID,
+		Text:      data.OriginalText,
+		APIs:      analyzerNames(selected),
+		Results:   data.Results,
+		Errors:    data.Errors,
+	}
+	if data.Source != nil {
+		event.SourceURL = data.Source.URL
+	}
+	return event
+}
+
+// TranslationCompletedEvent -> data of a webhook.EventTranslationCompleted.
+type TranslationCompletedEvent struct {
+	TranslationID  int64  `json:"translation_id"`
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	SourceText     string `json:"source_text"`
+	TranslatedText string `json:"translated_text"`
+	Provider       string `json:"provider"`
+}
+
+func newTranslationCompletedEvent(id int64, t *postgres.Translation) TranslationCompletedEvent {
+	return TranslationCompletedEvent{
+		TranslationID:  id,
+		SourceLanguage: t.SourceLanguage,
+		TargetLanguage: t.TargetLanguage,
+		SourceText:     t.SourceText,
+		TranslatedText: t.TranslatedText,
+		Provider:       t.Provider,
+	}
+}
+
+// BatchCompletedEvent -> data of a webhook.EventBatchCompleted. sent once every row has been analyzed.
+type BatchCompletedEvent struct {
+	BatchID   int64  `json:"batch_id"`
+	Filename  string `json:"filename"`
+	TotalRows int    `json:"total_rows"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+}
+
+type WebhooksPageData struct {
+	Endpoints []*postgres.WebhookEndpoint
+	Events    []webhook.Event
+	Error     string
+}
+
+// WebhookDeliveriesData -> the delivery log of an endpoint. rendered by webhook_deliveries.html.
+type WebhookDeliveriesData struct {
+	EndpointID int64
+	Deliveries []*postgres.WebhookDelivery
+}
+
+// Pending -> some delivery is still being attempted.
+func (w WebhookDeliveriesData) Pending() bool {
+	for _, d := range w.Deliveries {
+		if d.State == postgres.WebhookDeliveryStatePending {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookPageData struct {
+	Endpoint *postgres.WebhookEndpoint
+	WebhookDeliveriesData
+}
+
+func renderWebhooks(c echo.Context, hooks Webhooker, status int, msg string) error {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return c.String(http.StatusUnauthorized, "no credentials")
+	}
+
+	endpoints, err := hooks.Endpoints(c.Request().Context(), userCtx)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "failed to get webhooks")
+	}
+	return c.Render(status, "webhooks", WebhooksPageData{
+		Endpoints: endpoints,
+		Events:    webhook.Events,
+		Error:     msg,
+	})
+}
+
+func GetWebhooksHandler(hooks Webhooker) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return renderWebhooks(c, hooks, http.StatusOK, "")
+	}
+}
+
+type PostWebhookHandlerReq struct {
+	URL    string   `form:"url"`
+	Events []string `form:"events"`
+}
+
+func PostWebhookHandler(hooks Webhooker) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		userCtx, ok := auth.UserContextFromEcho(c)
+		if !ok {
+			return c.String(http.StatusUnauthorized, "no credentials")
+		}
+
+		var params PostWebhookHandlerReq
+		if err := c.Bind(&params); err != nil {
+			return renderWebhooks(c, hooks, http.StatusBadRequest, "invalid parameters")
+		}
+		events := make([]webhook.Event, 0, len(params.Events))
+		for _, name := range params.Events {
+			event, ok := webhook.EventFromName(name)
+			if !ok {
+				return renderWebhooks(c, hooks, http.StatusBadRequest, fmt.Sprintf("unknown event %q", name))
+			}
+			events = append(events, event)
+		}
+
+		id, err := hooks.CreateEndpoint(c.Request().Context(), userCtx, params.URL, events)
+		if err != nil {
+			if errors.Is(err, webhook.ErrInvalidURL) || errors.Is(err, webhook.ErrNoEvents) ||
+				errors.Is(err, webhook.ErrTooManyEndpoints) {
+				return renderWebhooks(c, hooks, http.StatusBadRequest, err.Error())
+			}
+			c.Logger().Error(err)
+			return renderWebhooks(c, hooks, http.StatusInternalServerError, "failed to create webhook")
+		}
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/webhooks/%d", id))
+	}
+}
+
+// webhookFromParam -> the :id endpoint of the session's user. on false the response has been sent.
+func webhookFromParam(c echo.Context, hooks Webhooker) (*postgres.WebhookEndpoint, bool, error) {
+	userCtx, ok := auth.UserContextFromEcho(c)
+	if !ok {
+		return nil, false, c.String(http.StatusUnauthorized, "no credentials")
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, false, c.String(http.StatusBadRequest, "invalid webhook id")
+	}
+
+	endpoint, err := hooks.Endpoint(c.Request().Context(), userCtx, id)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return nil, false, c.String(http.StatusNotFound, err.Error())
+		}
+		c.Logger().Error(err)
+		return nil, false, c.String(http.StatusInternalServerError, "failed to get webhook")
+	}
+	return endpoint, true, nil
+}
+
+func webhookDeliveries(c echo.Context, hooks Webhooker, endpointID int64) (WebhookDeliveriesData, error) {
+	userCtx, _ := auth.UserContextFromEcho(c)
+	deliveries, err := hooks.Deliveries(c.Request().Context(), userCtx, endpointID, webhookDeliveriesShown)
+	if err != nil {
+		return WebhookDeliveriesData{}, err
+	}
+	return WebhookDeliveriesData{EndpointID: endpointID, Deliveries: deliveries}, nil
+}
+
+func GetWebhookHandler(hooks Webhooker) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		endpoint, ok, err := webhookFromParam(c, hooks)
+		if !ok {
+			return err
+		}
+		data, err := webhookDeliveries(c, hooks, endpoint.ID)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get webhook deliveries")
+		}
+		return c.Render(http.StatusOK, "webhook", WebhookPageData{Endpoint: endpoint, WebhookDeliveriesData: data})
+	}
+}
+
+// GetWebhookDeliveriesHandler -> htmx fragment that polls itself while deliveries are pending.
+func GetWebhookDeliveriesHandler(hooks Webhooker) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		endpoint, ok, err := webhookFromParam(c, hooks)
+		if !ok {
+			return err
+		}
+		data, err := webhookDeliveries(c, hooks, endpoint.ID)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get webhook deliveries")
+		}
+		return c.Render(http.StatusOK, "webhook_deliveries", data)
+	}
+}
+
+// PostWebhookPingHandler -> send the endpoint a ping event and render its delivery log.
+func PostWebhookPingHandler(hooks Webhooker) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		endpoint, ok, err := webhookFromParam(c, hooks)
+		if !ok {
+			return err
+		}
+		userCtx, _ := auth.UserContextFromEcho(c)
+		if _, err := hooks.Ping(c.Request().Context(), userCtx, endpoint.ID); err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to queue ping")
+		}
+		data, err := webhookDeliveries(c, hooks, endpoint.ID)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get webhook deliveries")
+		}
+		return c.Render(http.StatusOK, "webhook_deliveries", data)
+	}
+}
+
+// PostWebhookReplayHandler -> send a finished delivery again and render the delivery log.
+func PostWebhookReplayHandler(hooks Webhooker) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		endpoint, ok, err := webhookFromParam(c, hooks)
+		if !ok {
+			return err
+		}
+		deliveryID, err := strconv.ParseInt(c.Param("delivery"), 10, 64)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid delivery id")
+		}
+
+		userCtx, _ := auth.UserContextFromEcho(c)
+		if err := hooks.Replay(c.Request().Context(), userCtx, deliveryID); err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return c.String(http.StatusNotFound, err.Error())
+			}
+			if errors.Is(err, webhook.ErrDeliveryPending) {
+				return c.String(http.StatusConflict, err.Error())
+			}
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to replay delivery")
+		}
+		data, err := webhookDeliveries(c, hooks, endpoint.ID)
+		if err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to get webhook deliveries")
+		}
+		return c.Render(http.StatusOK, "webhook_deliveries", data)
+	}
+}
+
+func PostWebhookDeleteHandler(hooks Webhooker) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		endpoint, ok, err := webhookFromParam(c, hooks)
+		if !ok {
+			return err
+		}
+		userCtx, _ := auth.UserContextFromEcho(c)
+		if err := hooks.DeleteEndpoint(c.Request().Context(), userCtx, endpoint.ID); err != nil {
+			c.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "failed to delete webhook")
+		}
+		return c.Redirect(http.StatusSeeOther, "/webhooks")
+	}
+}
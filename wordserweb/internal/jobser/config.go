@@ -0,0 +1,61 @@
+package jobser
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config -> worker pool and retry settings of the job queue.
+type Config struct {
+	// Workers -> jobs run at the same time by this process. 0 only enqueues; another process runs them.
+	Workers int `mapstructure:"JOBSER_WORKERS"`
+	// PollInterval -> how long an idle worker waits before looking for due jobs again.
+	PollInterval time.Duration `mapstructure:"JOBSER_POLL_INTERVAL"`
+	// MaxAttempts -> attempts for a job including the first.
+	MaxAttempts int           `mapstructure:"JOBSER_MAX_ATTEMPTS"`
+	BaseBackoff time.Duration `mapstructure:"JOBSER_BASE_BACKOFF"`
+	MaxBackoff  time.Duration `mapstructure:"JOBSER_MAX_BACKOFF"`
+	// Lease -> longest a single attempt may run. a running job is claimed again after this, its worker presumed dead.
+	Lease time.Duration `mapstructure:"JOBSER_LEASE"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("JOBSER_WORKERS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'JOBSER_WORKERS'")
+	}
+	viper.SetDefault("JOBSER_WORKERS", 2)
+
+	if err := viper.BindEnv("JOBSER_POLL_INTERVAL"); err != nil {
+		return c, fmt.Errorf("failed to bind 'JOBSER_POLL_INTERVAL'")
+	}
+	viper.SetDefault("JOBSER_POLL_INTERVAL", "1s")
+
+	if err := viper.BindEnv("JOBSER_MAX_ATTEMPTS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'JOBSER_MAX_ATTEMPTS'")
+	}
+	viper.SetDefault("JOBSER_MAX_ATTEMPTS", 5)
+
+	if err := viper.BindEnv("JOBSER_BASE_BACKOFF"); err != nil {
+		return c, fmt.Errorf("failed to bind 'JOBSER_BASE_BACKOFF'")
+	}
+	viper.SetDefault("JOBSER_BASE_BACKOFF", "5s")
+
+	if err := viper.BindEnv("JOBSER_MAX_BACKOFF"); err != nil {
+		return c, fmt.Errorf("failed to bind 'JOBSER_MAX_BACKOFF'")
+	}
+	viper.SetDefault("JOBSER_MAX_BACKOFF", "5m")
+
+	if err := viper.BindEnv("JOBSER_LEASE"); err != nil {
+		return c, fmt.Errorf("failed to bind 'JOBSER_LEASE'")
+	}
+	viper.SetDefault("JOBSER_LEASE", "10m")
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,24 @@
+package jobser
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type JobStorer interface {
+	InsertJob(ctx context.Context, j *postgres.Job) (int64, error)
+	ClaimJob(ctx context.Context, lease time.Duration) (*postgres.Job, error)
+	CompleteJob(ctx context.Context, id int64, attempt int, result json.RawMessage) error
+	RetryJob(ctx context.Context, id int64, attempt int, lastError string, runAt time.Time) error
+	FailJob(ctx context.Context, id int64, attempt int, lastError string) error
+	GetJob(ctx context.Context, username string, id int64) (*postgres.Job, error)
+	ListJobs(ctx context.Context, username string, limit int) ([]*postgres.Job, error)
+}
+
+// Logger -> where workers report what they can't return to anyone.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+}
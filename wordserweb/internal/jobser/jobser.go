@@ -0,0 +1,252 @@
+package jobser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type Kind string
+
+const (
+	KindAnalyze   Kind = "analyze"
+	KindTranslate Kind = "translate"
+	KindBatch     Kind = "batch"
+	KindWebhook   Kind = "webhook"
+	KindSubtitle  Kind = "subtitle"
+)
+
+func (k Kind) String() string {
+	return string(k)
+}
+
+type State string
+
+const (
+	StateQueued    State = postgres.JobStateQueued
+	StateRunning   State = postgres.JobStateRunning
+	StateSucceeded State = postgres.JobStateSucceeded
+	StateFailed    State = postgres.JobStateFailed
+)
+
+func (s State) String() string {
+	return string(s)
+}
+
+// Done -> the job won't change anymore.
+func (s State) Done() bool {
+	return s == StateSucceeded || s == StateFailed
+}
+
+var ErrUnknownKind = errors.New("unknown job kind")
+
+// Handler -> run one attempt of a job. the returned value is stored as the job's json result. an error marked
+// with resilience.Permanent, e.g. a payload that doesn't decode, fails the job right away instead of retrying it.
+type Handler func(ctx context.Context, job *postgres.Job) (any, error)
+
+// UserContext -> who a job runs on behalf of. enough to meter usage and save results; the role isn't kept.
+func UserContext(job *postgres.Job) auth.UserContext {
+	userCtx := auth.UserContext{Username: job.Username}
+	if job.OrganizationID != nil {
+		userCtx.OrgID = *job.OrganizationID
+	}
+	return userCtx
+}
+
+// Queue -> postgres backed job queue and the in process worker pool draining it.
+type Queue struct {
+	config   Config
+	store    JobStorer
+	logger   Logger
+	handlers map[Kind]Handler
+	now      func() time.Time
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func New(config Config, store JobStorer, logger Logger) *Queue {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	return &Queue{
+		config:   config,
+		store:    store,
+		logger:   logger,
+		handlers: map[Kind]Handler{},
+		now:      time.Now,
+	}
+}
+
+// Register -> run jobs of kind with h. call before Start.
+func (q *Queue) Register(kind Kind, h Handler) {
+	q.handlers[kind] = h
+}
+
+// Enqueue -> queue a job of kind for userCtx with payload as its json input. returns the job id.
+func (q *Queue) Enqueue(ctx context.Context, userCtx auth.UserContext, kind Kind, payload any) (int64, error) {
+	if _, ok := q.handlers[kind]; !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	job := &postgres.Job{
+		Username:    userCtx.Username,
+		Kind:        kind.String(),
+		Payload:     b,
+		MaxAttempts: q.config.MaxAttempts,
+	}
+	if userCtx.OrgID != 0 {
+		orgID := userCtx.OrgID
+		job.OrganizationID = &orgID
+	}
+	return q.store.InsertJob(ctx, job)
+}
+
+func (q *Queue) Job(ctx context.Context, userCtx auth.UserContext, id int64) (*postgres.Job, error) {
+	return q.store.GetJob(ctx, userCtx.Username, id)
+}
+
+// Jobs -> userCtx's newest jobs first.
+func (q *Queue) Jobs(ctx context.Context, userCtx auth.UserContext, limit int) ([]*postgres.Job, error) {
+	return q.store.ListJobs(ctx, userCtx.Username, limit)
+}
+
+// Start -> start the workers. they run until Stop.
+func (q *Queue) Start(ctx context.Context) {
+	ctx, q.cancel = context.WithCancel(ctx)
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+}
+
+// Stop -> cancel the running jobs and wait for the workers to record how they ended. a cancelled job is retried
+// by whichever worker claims it next.
+func (q *Queue) Stop() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		job, err := q.store.ClaimJob(ctx, q.config.Lease)
+		if err != nil && ctx.Err() == nil {
+			q.logger.Errorf("failed to claim job: %v", err)
+		}
+		if job != nil {
+			q.run(ctx, job)
+			// there may be more due; only sleep once the queue is drained.
+			continue
+		}
+
+		t := time.NewTimer(q.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *postgres.Job) {
+	kind := Kind(job.Kind)
+	running.WithLabelValues(job.Kind).Inc()
+	defer running.WithLabelValues(job.Kind).Dec()
+
+	var result any
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// claimed again after its worker died on the last attempt.
+		err = resilience.Permanent(errors.New("attempts exhausted"))
+	} else if h, ok := q.handlers[kind]; !ok {
+		err = resilience.Permanent(fmt.Errorf("%w: %s", ErrUnknownKind, kind))
+	} else {
+		result, err = q.attempt(ctx, h, job)
+	}
+
+	// the outcome is recorded even when shutting down, otherwise the job sits running until its lease ends.
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.recordTimeout())
+	defer cancel()
+
+	if err == nil {
+		b, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			err = resilience.Permanent(marshalErr)
+		} else {
+			processed.WithLabelValues(job.Kind, string(StateSucceeded)).Inc()
+			if err := q.store.CompleteJob(storeCtx, job.ID, job.Attempts, b); err != nil {
+				q.logger.Errorf("failed to complete job %d: %v", job.ID, err)
+			}
+			return
+		}
+	}
+
+	if resilience.IsPermanent(err) || job.Attempts >= job.MaxAttempts {
+		processed.WithLabelValues(job.Kind, string(StateFailed)).Inc()
+		q.logger.Errorf("job %d (%s) failed on attempt %d: %v", job.ID, job.Kind, job.Attempts, err)
+		if err := q.store.FailJob(storeCtx, job.ID, job.Attempts, err.Error()); err != nil {
+			q.logger.Errorf("failed to fail job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	processed.WithLabelValues(job.Kind, "retried").Inc()
+	runAt := q.now().Add(q.backoff(job.Attempts))
+	if err := q.store.RetryJob(storeCtx, job.ID, job.Attempts, err.Error(), runAt); err != nil {
+		q.logger.Errorf("failed to retry job %d: %v", job.ID, err)
+	}
+}
+
+// recordTimeout -> how long recording an attempt's outcome may take: the end of the lease the attempt is kept
+// out of, so the outcome is in before the job can be claimed again.
+func (q *Queue) recordTimeout() time.Duration {
+	if q.config.Lease <= 0 {
+		return 10 * time.Second
+	}
+	return min(q.config.Lease/4, 10*time.Second)
+}
+
+// attempt -> h bounded by the lease, less the time to record its outcome, so a slow attempt can't outlive its
+// claim; and with panics turned into errors.
+func (q *Queue) attempt(ctx context.Context, h Handler, job *postgres.Job) (result any, err error) {
+	if q.config.Lease > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.config.Lease-q.recordTimeout())
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// backoff -> base * 2^(attempt-1) capped at max, plus up to half again so retries of a burst spread out.
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.config.BaseBackoff << (attempt - 1)
+	if d <= 0 || d > q.config.MaxBackoff {
+		d = q.config.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
@@ -0,0 +1,393 @@
+package jobser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// memoryStore -> an in memory JobStorer. like postgres, an outcome is only recorded while its attempt still holds
+// the job.
+type memoryStore struct {
+	mu     sync.Mutex
+	jobs   map[int64]*postgres.Job
+	nextID int64
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: map[int64]*postgres.Job{}}
+}
+
+func (s *memoryStore) InsertJob(ctx context.Context, j *postgres.Job) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	stored := *j
+	stored.ID = s.nextID
+	stored.State = postgres.JobStateQueued
+	s.jobs[stored.ID] = &stored
+	return stored.ID, nil
+}
+
+// ClaimJob -> the lowest id queued job, ignoring run_at and the lease; tests claim a job again by calling it.
+func (s *memoryStore) ClaimJob(ctx context.Context, lease time.Duration) (*postgres.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed *postgres.Job
+	for _, j := range s.jobs {
+		if j.State != postgres.JobStateQueued && j.State != postgres.JobStateRunning {
+			continue
+		}
+		if claimed == nil || j.ID < claimed.ID {
+			claimed = j
+		}
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+	claimed.State = postgres.JobStateRunning
+	claimed.Attempts++
+	copied := *claimed
+	return &copied, nil
+}
+
+func (s *memoryStore) CompleteJob(ctx context.Context, id int64, attempt int, result json.RawMessage) error {
+	return s.finish(ctx, id, attempt, func(j *postgres.Job) {
+		j.State = postgres.JobStateSucceeded
+		j.Result = result
+	})
+}
+
+func (s *memoryStore) RetryJob(ctx context.Context, id int64, attempt int, lastError string, runAt time.Time) error {
+	return s.finish(ctx, id, attempt, func(j *postgres.Job) {
+		j.State = postgres.JobStateQueued
+		j.LastError = &lastError
+		j.RunAt = runAt
+	})
+}
+
+func (s *memoryStore) FailJob(ctx context.Context, id int64, attempt int, lastError string) error {
+	return s.finish(ctx, id, attempt, func(j *postgres.Job) {
+		j.State = postgres.JobStateFailed
+		j.LastError = &lastError
+	})
+}
+
+func (s *memoryStore) finish(ctx context.Context, id int64, attempt int, update func(j *postgres.Job)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok || j.State != postgres.JobStateRunning || j.Attempts != attempt {
+		return fmt.Errorf("job %d attempt %d: %w", id, attempt, postgres.ErrJobLost)
+	}
+	update(j)
+	return nil
+}
+
+func (s *memoryStore) GetJob(ctx context.Context, username string, id int64) (*postgres.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok || j.Username != username {
+		return nil, fmt.Errorf("job %w", postgres.ErrNotFound)
+	}
+	copied := *j
+	return &copied, nil
+}
+
+func (s *memoryStore) ListJobs(ctx context.Context, username string, limit int) ([]*postgres.Job, error) {
+	return nil, errors.New("not implemented")
+}
+
+// testLogger -> a Logger keeping what it was given.
+type testLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *testLogger) Errorf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, log := range l.logs {
+		if strings.Contains(log, s) {
+			return true
+		}
+	}
+	return false
+}
+
+var testNow = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// newTestQueue -> a Queue of a memoryStore with h registered for KindAnalyze and one job of it claimed.
+func newTestQueue(t *testing.T, config Config, h Handler) (*Queue, *memoryStore, *testLogger, *postgres.Job) {
+	t.Helper()
+	store := newMemoryStore()
+	logger := &testLogger{}
+	q := New(config, store, logger)
+	q.now = func() time.Time { return testNow }
+	q.Register(KindAnalyze, h)
+	if _, err := q.Enqueue(context.Background(), auth.UserContext{Username: "user"}, KindAnalyze, "payload"); err != nil {
+		t.Fatal(err)
+	}
+	job, err := store.ClaimJob(context.Background(), config.Lease)
+	if err != nil || job == nil {
+		t.Fatalf("ClaimJob() = %v, %v", job, err)
+	}
+	return q, store, logger, job
+}
+
+// stored -> job id as the store has it.
+func stored(t *testing.T, store *memoryStore, id int64) *postgres.Job {
+	t.Helper()
+	j, err := store.GetJob(context.Background(), "user", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestRun(t *testing.T) {
+	errUpstream := errors.New("upstream down")
+	tests := []struct {
+		name        string
+		maxAttempts int
+		// attempts -> extra claims of the job before the one that runs, as after its earlier workers died.
+		attempts   int
+		h          Handler
+		wantState  string
+		wantError  string
+		wantResult string
+	}{
+		{
+			name:        "succeeded",
+			maxAttempts: 3,
+			h: func(ctx context.Context, job *postgres.Job) (any, error) {
+				return map[string]string{"payload": string(job.Payload)}, nil
+			},
+			wantState:  postgres.JobStateSucceeded,
+			wantResult: `{"payload":"\"payload\""}`,
+		},
+		{
+			name:        "retried",
+			maxAttempts: 3,
+			h: func(ctx context.Context, job *postgres.Job) (any, error) {
+				return nil, errUpstream
+			},
+			wantState: postgres.JobStateQueued,
+			wantError: errUpstream.Error(),
+		},
+		{
+			name:        "failed on the last attempt",
+			maxAttempts: 2,
+			attempts:    1,
+			h: func(ctx context.Context, job *postgres.Job) (any, error) {
+				return nil, errUpstream
+			},
+			wantState: postgres.JobStateFailed,
+			wantError: errUpstream.Error(),
+		},
+		{
+			name:        "permanent",
+			maxAttempts: 3,
+			h: func(ctx context.Context, job *postgres.Job) (any, error) {
+				return nil, fmt.Errorf("decoding payload: %w", resilience.Permanent(errors.New("bad json")))
+			},
+			wantState: postgres.JobStateFailed,
+			wantError: "decoding payload: bad json",
+		},
+		{
+			name:        "result that doesn't marshal",
+			maxAttempts: 3,
+			h: func(ctx context.Context, job *postgres.Job) (any, error) {
+				return func() {}, nil
+			},
+			wantState: postgres.JobStateFailed,
+			wantError: "unsupported type",
+		},
+		{
+			name:        "panicked",
+			maxAttempts: 3,
+			h: func(ctx context.Context, job *postgres.Job) (any, error) {
+				panic("boom")
+			},
+			wantState: postgres.JobStateQueued,
+			wantError: "job panicked: boom",
+		},
+		{
+			name:        "attempts exhausted",
+			maxAttempts: 1,
+			attempts:    1,
+			h: func(ctx context.Context, job *postgres.Job) (any, error) {
+				return nil, errors.New("handler ran after the attempts were used up")
+			},
+			wantState: postgres.JobStateFailed,
+			wantError: "attempts exhausted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{MaxAttempts: tt.maxAttempts, BaseBackoff: time.Second, MaxBackoff: time.Minute}
+			q, store, _, job := newTestQueue(t, config, tt.h)
+			for i := 0; i < tt.attempts; i++ {
+				var err error
+				if job, err = store.ClaimJob(context.Background(), 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			q.run(context.Background(), job)
+
+			got := stored(t, store, job.ID)
+			if got.State != tt.wantState {
+				t.Errorf("state = %q, want %q", got.State, tt.wantState)
+			}
+			if string(got.Result) != tt.wantResult {
+				t.Errorf("result = %s, want %s", got.Result, tt.wantResult)
+			}
+			if (got.LastError == nil) != (tt.wantError == "") || (got.LastError != nil && !strings.Contains(*got.LastError, tt.wantError)) {
+				t.Errorf("last error = %v, want %q", got.LastError, tt.wantError)
+			}
+			if got.State == postgres.JobStateQueued {
+				// base 1s for the first attempt, plus up to half again.
+				if wait := got.RunAt.Sub(testNow); wait < time.Second || wait > 1500*time.Millisecond {
+					t.Errorf("retried after %v, want 1s to 1.5s", wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRunUnknownKind(t *testing.T) {
+	q, store, _, job := newTestQueue(t, Config{MaxAttempts: 3}, func(ctx context.Context, job *postgres.Job) (any, error) {
+		return nil, nil
+	})
+	job.Kind = "unregistered"
+	q.run(context.Background(), job)
+	if got := stored(t, store, job.ID); got.State != postgres.JobStateFailed {
+		t.Errorf("state = %q, want %q on the first attempt", got.State, postgres.JobStateFailed)
+	}
+}
+
+func TestRunStaleAttempt(t *testing.T) {
+	var store *memoryStore
+	var newer *postgres.Job
+	q, store, logger, job := newTestQueue(t, Config{MaxAttempts: 3}, func(ctx context.Context, job *postgres.Job) (any, error) {
+		if job.Attempts == 1 {
+			// the lease ran out while this attempt was still going and another worker claimed the job.
+			var err error
+			if newer, err = store.ClaimJob(ctx, 0); err != nil {
+				t.Fatal(err)
+			}
+			return "stale", nil
+		}
+		return "newer", nil
+	})
+
+	q.run(context.Background(), job)
+	got := stored(t, store, job.ID)
+	if got.State != postgres.JobStateRunning || got.Attempts != 2 || got.Result != nil {
+		t.Errorf("job = %s attempt %d result %s, want the stale outcome dropped", got.State, got.Attempts, got.Result)
+	}
+	if !logger.contains(postgres.ErrJobLost.Error()) {
+		t.Errorf("logs = %q, want the lost job reported", logger.logs)
+	}
+
+	q.run(context.Background(), newer)
+	if got := stored(t, store, job.ID); got.State != postgres.JobStateSucceeded || string(got.Result) != `"newer"` {
+		t.Errorf("job = %s result %s, want the newer attempt's outcome", got.State, got.Result)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	lease := 200 * time.Millisecond
+	var deadline time.Duration
+	q, store, _, job := newTestQueue(t, Config{MaxAttempts: 3, Lease: lease}, func(ctx context.Context, job *postgres.Job) (any, error) {
+		start := time.Now()
+		<-ctx.Done()
+		deadline = time.Since(start)
+		return nil, ctx.Err()
+	})
+
+	q.run(context.Background(), job)
+	// the attempt gets the lease less the time to record its outcome.
+	if want := lease - q.recordTimeout(); deadline < want || deadline > lease {
+		t.Errorf("attempt ran %v, want %v up to the lease %v", deadline, want, lease)
+	}
+	got := stored(t, store, job.ID)
+	if got.State != postgres.JobStateQueued || got.LastError == nil || *got.LastError != context.DeadlineExceeded.Error() {
+		t.Errorf("job = %s last error %v, want retried after the deadline", got.State, got.LastError)
+	}
+}
+
+func TestRunRecordsOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q, store, _, job := newTestQueue(t, Config{MaxAttempts: 3}, func(ctx context.Context, job *postgres.Job) (any, error) {
+		cancel()
+		return nil, ctx.Err()
+	})
+
+	q.run(ctx, job)
+	if got := stored(t, store, job.ID); got.State != postgres.JobStateQueued {
+		t.Errorf("state = %q, want the cancelled attempt queued again", got.State)
+	}
+}
+
+func TestRecordTimeout(t *testing.T) {
+	tests := []struct {
+		lease time.Duration
+		want  time.Duration
+	}{
+		{lease: 0, want: 10 * time.Second},
+		{lease: 2 * time.Second, want: 500 * time.Millisecond},
+		{lease: 40 * time.Second, want: 10 * time.Second},
+		{lease: 10 * time.Minute, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		q := New(Config{Lease: tt.lease}, nil, nil)
+		if got := q.recordTimeout(); got != tt.want {
+			t.Errorf("recordTimeout() with lease %v = %v, want %v", tt.lease, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := New(Config{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}, nil, nil)
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		// a shift past the width of a duration mustn't wrap around to a short wait.
+		{attempt: 80, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := q.backoff(tt.attempt); got < tt.want || got > tt.want+tt.want/2 {
+				t.Fatalf("backoff(%d) = %v, want %v to %v", tt.attempt, got, tt.want, tt.want+tt.want/2)
+			}
+		}
+	}
+	if got := New(Config{}, nil, nil).backoff(3); got != 0 {
+		t.Errorf("backoff() without a base or max = %v, want 0", got)
+	}
+}
@@ -0,0 +1,30 @@
+package jobser
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	processed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "wordserweb",
+			Subsystem: "jobs",
+			Name:      "attempts_total",
+			Help:      "Job attempts by kind and outcome: succeeded, retried or failed.",
+		},
+		[]string{"kind", "outcome"},
+	)
+	running = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "wordserweb",
+			Subsystem: "jobs",
+			Name:      "running",
+			Help:      "Jobs being run by this process.",
+		},
+		[]string{"kind"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(processed, running)
+}
@@ -0,0 +1,33 @@
+package langdetect
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// MinConfidence -> detections below it aren't Reliable and aren't acted on.
+	MinConfidence float64 `mapstructure:"LANGDETECT_MIN_CONFIDENCE"`
+	// MaxChars -> text detected, in runes. the start of a long document is plenty.
+	MaxChars int `mapstructure:"LANGDETECT_MAX_CHARS"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("LANGDETECT_MIN_CONFIDENCE"); err != nil {
+		return c, fmt.Errorf("failed to bind 'LANGDETECT_MIN_CONFIDENCE'")
+	}
+	viper.SetDefault("LANGDETECT_MIN_CONFIDENCE", 0.6)
+
+	if err := viper.BindEnv("LANGDETECT_MAX_CHARS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'LANGDETECT_MAX_CHARS'")
+	}
+	viper.SetDefault("LANGDETECT_MAX_CHARS", 5000)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
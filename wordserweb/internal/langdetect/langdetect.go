@@ -0,0 +1,148 @@
+package langdetect
+
+import (
+	"math"
+	"unicode"
+)
+
+// minLetters -> shorter text is too little to go on.
+const minLetters = 8
+
+// script -> a writing system and the languages written in it that can be told apart. a script with a single
+// language identifies it.
+type script struct {
+	table     *unicode.RangeTable
+	languages []string
+}
+
+// scripts -> every language detected, by script. the languages of a script with more than one are told apart by
+// their trigram profiles; the handlers turn away the ones no provider translates.
+var scripts = []script{
+	{table: unicode.Latin, languages: []string{"en", "fr", "de", "it", "pt", "es"}},
+	{table: unicode.Arabic, languages: []string{"ar", "fa", "ur"}},
+	{table: unicode.Han, languages: []string{"zh"}},
+	{table: unicode.Greek, languages: []string{"el"}},
+	{table: unicode.Cyrillic, languages: []string{"ru", "uk", "bg", "sr"}},
+}
+
+// Detection -> the language of a text as an iso 639-1 code. Language is empty when nothing could be detected.
+type Detection struct {
+	Language string `json:"language"`
+	// Confidence -> 0-1, how much of the text is in the language's script times how sure the trigram profiles
+	// are among the languages sharing it.
+	Confidence float64 `json:"confidence"`
+	// Reliable -> Confidence is at least the configured minimum.
+	Reliable bool `json:"reliable"`
+}
+
+type Detector struct {
+	minConfidence float64
+	maxChars      int
+	// classifiers -> the trigram classifier of each of scripts, nil for a script with a single language.
+	classifiers []*classifier
+}
+
+// classifier -> the profiles of the languages sharing a script.
+type classifier struct {
+	profiles []*profile
+	// vocab -> distinct trigrams across the profiles.
+	vocab float64
+}
+
+func newClassifier(languages ...string) *classifier {
+	c := &classifier{profiles: loadProfiles(languages...)}
+	seen := map[string]bool{}
+	for _, p := range c.profiles {
+		for trigram := range p.counts {
+			seen[trigram] = true
+		}
+	}
+	c.vocab = float64(len(seen)) + 1
+	return c
+}
+
+func New(config Config) *Detector {
+	d := &Detector{
+		minConfidence: config.MinConfidence,
+		maxChars:      config.MaxChars,
+		classifiers:   make([]*classifier, len(scripts)),
+	}
+	for i, s := range scripts {
+		if len(s.languages) > 1 {
+			d.classifiers[i] = newClassifier(s.languages...)
+		}
+	}
+	return d
+}
+
+// Detect -> the most likely language of txt by its script, then by trigram profile.
+func (d *Detector) Detect(txt string) Detection {
+	if runes := []rune(txt); d.maxChars > 0 && len(runes) > d.maxChars {
+		txt = string(runes[:d.maxChars])
+	}
+
+	letters := 0
+	byScript := make([]int, len(scripts))
+	for _, r := range txt {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, s := range scripts {
+			if unicode.Is(s.table, r) {
+				byScript[i]++
+				break
+			}
+		}
+	}
+	if letters < minLetters {
+		return Detection{}
+	}
+	best := 0
+	for i := range scripts {
+		if byScript[i] > byScript[best] {
+			best = i
+		}
+	}
+	if byScript[best] == 0 {
+		return Detection{}
+	}
+	share := float64(byScript[best]) / float64(letters)
+
+	detection := Detection{Language: scripts[best].languages[0], Confidence: share}
+	if c := d.classifiers[best]; c != nil {
+		language, probability := c.classify(txt)
+		detection.Language = language
+		detection.Confidence = share * probability
+	}
+	detection.Reliable = detection.Confidence >= d.minConfidence
+	return detection
+}
+
+// classify -> the profile most likely to have produced txt and its probability among them. the log likelihoods
+// are scaled by 1/sqrt(trigrams) before normalizing, or a few words would already be certain.
+func (c *classifier) classify(txt string) (string, float64) {
+	grams := trigrams(txt)
+	if len(grams) == 0 {
+		return "", 0
+	}
+	scores := make([]float64, len(c.profiles))
+	for i, p := range c.profiles {
+		for _, trigram := range grams {
+			scores[i] += p.logProb(trigram, c.vocab)
+		}
+		scores[i] /= math.Sqrt(float64(len(grams)))
+	}
+
+	best := 0
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	var sum float64
+	for _, score := range scores {
+		sum += math.Exp(score - scores[best])
+	}
+	return c.profiles[best].language, 1 / sum
+}
@@ -0,0 +1,86 @@
+package langdetect
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+var config = Config{MinConfidence: 0.6, MaxChars: 5000}
+
+func TestDetectSamples(t *testing.T) {
+	f, err := os.Open("testdata/samples.tsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	d := New(config)
+	samples, reliable := 0, 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		language, txt, ok := strings.Cut(line, "\t")
+		if !ok {
+			t.Fatalf("malformed sample %q", line)
+		}
+		samples++
+		got := d.Detect(txt)
+		if got.Language != language {
+			t.Errorf("Detect(%q) = %q, want %q", txt, got.Language, language)
+		}
+		if got.Reliable {
+			reliable++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if samples == 0 {
+		t.Fatal("no samples")
+	}
+	// a single sentence of a closely related language can be right and still unsure.
+	if float64(reliable) < 0.9*float64(samples) {
+		t.Errorf("%d of %d samples reliable, want at least 90%%", reliable, samples)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		txt      string
+		language string
+		reliable bool
+	}{
+		{name: "empty", txt: ""},
+		{name: "no letters", txt: "12345 67890 !?"},
+		{name: "too short", txt: "Hi there"},
+		{name: "single language script", txt: "Καλημέρα σε όλους", language: "el", reliable: true},
+		{name: "mostly another script", txt: "Hello world, see you soon Привет", language: "en", reliable: true},
+		{name: "mixed scripts", txt: "Καλημέρα σας, hello Maria", language: "el", reliable: false},
+	}
+	d := New(config)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := d.Detect(tt.txt)
+			if got.Language != tt.language || got.Reliable != tt.reliable {
+				t.Errorf("Detect(%q) = %+v, want language %q reliable %v", tt.txt, got, tt.language, tt.reliable)
+			}
+			if got.Confidence < 0 || got.Confidence > 1 {
+				t.Errorf("Detect(%q) confidence %v out of [0, 1]", tt.txt, got.Confidence)
+			}
+		})
+	}
+}
+
+func TestDetectMaxChars(t *testing.T) {
+	d := New(Config{MinConfidence: 0.6, MaxChars: 40})
+	txt := "Das Museum bleibt am Montag geschlossen. " + strings.Repeat("Музей будет закрыт в понедельник. ", 10)
+	if got := d.Detect(txt); got.Language != "de" {
+		t.Errorf("Detect() = %q, want only the first 40 runes looked at and %q", got.Language, "de")
+	}
+}
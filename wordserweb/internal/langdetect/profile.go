@@ -0,0 +1,60 @@
+package langdetect
+
+import (
+	"embed"
+	"math"
+	"path"
+	"strings"
+	"unicode"
+)
+
+//go:embed profiles
+var profileFS embed.FS
+
+// smoothing -> added to every trigram count so trigrams a profile never saw don't rule its language out.
+const smoothing = 0.5
+
+// profile -> trigram counts of a language's sample text in profiles/<language>.txt.
+type profile struct {
+	language string
+	counts   map[string]float64
+	total    float64
+}
+
+func loadProfiles(languages ...string) []*profile {
+	profiles := make([]*profile, 0, len(languages))
+	for _, language := range languages {
+		data, err := profileFS.ReadFile(path.Join("profiles", language+".txt"))
+		if err != nil {
+			// embedded; a missing profile is a build mistake.
+			panic(err)
+		}
+		p := &profile{language: language, counts: map[string]float64{}}
+		for _, trigram := range trigrams(string(data)) {
+			p.counts[trigram]++
+			p.total++
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
+// logProb -> log probability of trigram in p's language, over a vocabulary of vocab trigrams.
+func (p *profile) logProb(trigram string, vocab float64) float64 {
+	return math.Log((p.counts[trigram] + smoothing) / (p.total + smoothing*vocab))
+}
+
+// trigrams -> the letter trigrams of each lower cased word of txt, padded with a space on either side so word
+// starts and ends count.
+func trigrams(txt string) []string {
+	var grams []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(txt), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+3]))
+		}
+	}
+	return grams
+}
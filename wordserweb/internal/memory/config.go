@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// FuzzyThreshold -> lowest similarity, 0 to 1, of a segment pair suggested for a text it doesn't match exactly.
+	FuzzyThreshold float64 `mapstructure:"MEMORY_FUZZY_THRESHOLD"`
+	// MaxSuggestions -> fuzzy matches suggested per translation. 0 suggests none.
+	MaxSuggestions int `mapstructure:"MEMORY_MAX_SUGGESTIONS"`
+	// MaxSegmentChars -> longest segment, in runes, kept in or matched against the memory.
+	MaxSegmentChars int `mapstructure:"MEMORY_MAX_SEGMENT_CHARS"`
+	// MaxImportBytes -> size of the largest tmx or xliff file imported.
+	MaxImportBytes int64 `mapstructure:"MEMORY_MAX_IMPORT_BYTES"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("MEMORY_FUZZY_THRESHOLD"); err != nil {
+		return c, fmt.Errorf("failed to bind 'MEMORY_FUZZY_THRESHOLD'")
+	}
+	viper.SetDefault("MEMORY_FUZZY_THRESHOLD", 0.75)
+
+	if err := viper.BindEnv("MEMORY_MAX_SUGGESTIONS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'MEMORY_MAX_SUGGESTIONS'")
+	}
+	viper.SetDefault("MEMORY_MAX_SUGGESTIONS", 3)
+
+	if err := viper.BindEnv("MEMORY_MAX_SEGMENT_CHARS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'MEMORY_MAX_SEGMENT_CHARS'")
+	}
+	viper.SetDefault("MEMORY_MAX_SEGMENT_CHARS", 2000)
+
+	if err := viper.BindEnv("MEMORY_MAX_IMPORT_BYTES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'MEMORY_MAX_IMPORT_BYTES'")
+	}
+	viper.SetDefault("MEMORY_MAX_IMPORT_BYTES", 10<<20)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,390 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+type Format string
+
+const (
+	// FormatTMX -> TMX 1.4.
+	FormatTMX Format = "tmx"
+	// FormatXLIFF12/FormatXLIFF20 -> XLIFF 1.2 and 2.0.
+	FormatXLIFF12 Format = "xliff12"
+	FormatXLIFF20 Format = "xliff20"
+)
+
+func (f Format) String() string {
+	return string(f)
+}
+
+func (f Format) Extension() string {
+	if f == FormatTMX {
+		return ".tmx"
+	}
+	return ".xlf"
+}
+
+func (f Format) ContentType() string {
+	if f == FormatTMX {
+		return "application/x-tmx+xml"
+	}
+	return "application/xliff+xml"
+}
+
+// ParseFormat -> the format named s, "tmx", "xliff12" or "xliff20".
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatTMX, FormatXLIFF12, FormatXLIFF20:
+		return f, true
+	default:
+		return "", false
+	}
+}
+
+// Unit -> a segment pair of a tmx or xliff file, with the file's language tags.
+type Unit struct {
+	SourceLanguage string
+	TargetLanguage string
+	Source         string
+	Target         string
+}
+
+// nativeCodeElements -> inline elements holding the original format's codes, like an html tag, rather than text.
+var nativeCodeElements = map[string]bool{"bpt": true, "ept": true, "ph": true, "it": true, "ut": true}
+
+// inlineText -> the text of a segment. the native codes of inline markup are left out, and the text inside the
+// rest, like <g>, <pc>, <hi> or <mrk>, kept.
+type inlineText string
+
+func (t *inlineText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var b strings.Builder
+	if err := readInline(d, &b); err != nil {
+		return err
+	}
+	*t = inlineText(b.String())
+	return nil
+}
+
+// readInline -> the text up to the end of the element being read.
+func readInline(d *xml.Decoder, b *strings.Builder) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			b.Write(tok)
+		case xml.StartElement:
+			var err error
+			if nativeCodeElements[tok.Name.Local] {
+				err = d.Skip()
+			} else {
+				err = readInline(d, b)
+			}
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type tmxDocument struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  tmxHeader `xml:"header"`
+	Units   []tmxUnit `xml:"body>tu"`
+}
+
+type tmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+}
+
+type tmxUnit struct {
+	// SrcLang -> the unit's source language when it isn't the header's.
+	SrcLang  string       `xml:"srclang,attr,omitempty"`
+	Variants []tmxVariant `xml:"tuv"`
+}
+
+type tmxVariant struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	// LegacyLang -> lang, as TMX 1.1 has it.
+	LegacyLang string     `xml:"lang,attr,omitempty"`
+	Seg        inlineText `xml:"seg"`
+}
+
+func (v tmxVariant) lang() string {
+	if v.Lang != "" {
+		return v.Lang
+	}
+	return v.LegacyLang
+}
+
+// units -> a pair of the source variant with each other variant. the source variant is the one in srcLang, or the
+// first when srcLang is "*all*" or none is.
+func (u tmxUnit) units(srcLang string) []Unit {
+	if u.SrcLang != "" {
+		srcLang = u.SrcLang
+	}
+	if len(u.Variants) < 2 {
+		return nil
+	}
+	sourceIndex := 0
+	for i, v := range u.Variants {
+		if strings.EqualFold(v.lang(), srcLang) {
+			sourceIndex = i
+			break
+		}
+	}
+	source := u.Variants[sourceIndex]
+	var units []Unit
+	for i, v := range u.Variants {
+		if i == sourceIndex {
+			continue
+		}
+		units = append(units, Unit{
+			SourceLanguage: source.lang(),
+			TargetLanguage: v.lang(),
+			Source:         string(source.Seg),
+			Target:         string(v.Seg),
+		})
+	}
+	return units
+}
+
+type xliff12Document struct {
+	XMLName xml.Name      `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string        `xml:"version,attr"`
+	Files   []xliff12File `xml:"file"`
+}
+
+type xliff12File struct {
+	Original       string        `xml:"original,attr"`
+	SourceLanguage string        `xml:"source-language,attr"`
+	TargetLanguage string        `xml:"target-language,attr"`
+	DataType       string        `xml:"datatype,attr"`
+	Units          []xliff12Unit `xml:"body>trans-unit"`
+}
+
+type xliff12Unit struct {
+	ID     string      `xml:"id,attr"`
+	Source inlineText  `xml:"source"`
+	Target *inlineText `xml:"target"`
+}
+
+type xliff20Document struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr"`
+	File    xliff20File `xml:"file"`
+}
+
+type xliff20File struct {
+	ID    string        `xml:"id,attr"`
+	Units []xliff20Unit `xml:"unit"`
+}
+
+type xliff20Unit struct {
+	ID      string         `xml:"id,attr"`
+	Segment xliff20Segment `xml:"segment"`
+}
+
+type xliff20Segment struct {
+	Source inlineText  `xml:"source"`
+	Target *inlineText `xml:"target"`
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// newDecoder -> a decoder of data, utf-8 or, with its byte order mark, utf-16 as TMX files often are.
+func newDecoder(data []byte) *xml.Decoder {
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte{0xff, 0xfe}) || bytes.HasPrefix(data, []byte{0xfe, 0xff}) {
+		r = transform.NewReader(r, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder())
+	}
+	d := xml.NewDecoder(r)
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "utf-8", "utf8", "us-ascii", "utf-16", "utf-16le", "utf-16be":
+			// utf-16 has been decoded by now.
+			return input, nil
+		default:
+			return nil, fmt.Errorf("unsupported encoding %q", charset)
+		}
+	}
+	return d
+}
+
+// Read -> the segment pairs of a TMX 1.4, XLIFF 1.2 or XLIFF 2.0 file, told apart by their root element. units
+// without a target are left out.
+func Read(r io.Reader) ([]Unit, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(data)
+
+	var root, version, srcLang, trgLang string
+	var xliff1, xliff2 bool
+	var units []Unit
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root, version = start.Name.Local, attr(start, "version")
+			xliff1 = root == "xliff" && strings.HasPrefix(version, "1.")
+			xliff2 = root == "xliff" && strings.HasPrefix(version, "2.")
+			switch {
+			case root == "tmx", xliff1:
+			case xliff2:
+				srcLang, trgLang = attr(start, "srcLang"), attr(start, "trgLang")
+			default:
+				return nil, errors.New("not a TMX or XLIFF 1.2 or 2.0 file")
+			}
+			continue
+		}
+
+		switch {
+		case root == "tmx" && start.Name.Local == "header":
+			srcLang = attr(start, "srclang")
+		case root == "tmx" && start.Name.Local == "tu":
+			var tu tmxUnit
+			if err := d.DecodeElement(&tu, &start); err != nil {
+				return nil, err
+			}
+			units = append(units, tu.units(srcLang)...)
+		case xliff1 && start.Name.Local == "file":
+			srcLang, trgLang = attr(start, "source-language"), attr(start, "target-language")
+		case xliff1 && start.Name.Local == "trans-unit":
+			var tu xliff12Unit
+			if err := d.DecodeElement(&tu, &start); err != nil {
+				return nil, err
+			}
+			if tu.Target != nil {
+				units = append(units, Unit{
+					SourceLanguage: srcLang,
+					TargetLanguage: trgLang,
+					Source:         string(tu.Source),
+					Target:         string(*tu.Target),
+				})
+			}
+		case xliff2 && start.Name.Local == "segment":
+			var segment xliff20Segment
+			if err := d.DecodeElement(&segment, &start); err != nil {
+				return nil, err
+			}
+			if segment.Target != nil {
+				units = append(units, Unit{
+					SourceLanguage: srcLang,
+					TargetLanguage: trgLang,
+					Source:         string(segment.Source),
+					Target:         string(*segment.Target),
+				})
+			}
+		}
+	}
+	if root == "" {
+		return nil, errors.New("empty file")
+	}
+	if len(units) == 0 {
+		return nil, errors.New("no translated segments")
+	}
+	return units, nil
+}
+
+// Write -> segments, a memory's pairs for translating source to target, as format.
+func Write(w io.Writer, format Format, source string, target string, segments []*postgres.MemorySegment) error {
+	var doc any
+	switch format {
+	case FormatTMX:
+		tmx := tmxDocument{
+			Version: "1.4",
+			Header: tmxHeader{
+				CreationTool:        "wordser",
+				CreationToolVersion: "1",
+				SegType:             "sentence",
+				OTMF:                "wordser",
+				AdminLang:           "en",
+				SrcLang:             source,
+				DataType:            "plaintext",
+			},
+		}
+		for _, s := range segments {
+			tmx.Units = append(tmx.Units, tmxUnit{Variants: []tmxVariant{
+				{Lang: source, Seg: inlineText(s.SourceText)},
+				{Lang: target, Seg: inlineText(s.TargetText)},
+			}})
+		}
+		doc = tmx
+	case FormatXLIFF12:
+		file := xliff12File{Original: "wordser", SourceLanguage: source, TargetLanguage: target, DataType: "plaintext"}
+		for i, s := range segments {
+			targetText := inlineText(s.TargetText)
+			file.Units = append(file.Units, xliff12Unit{
+				ID:     strconv.Itoa(i + 1),
+				Source: inlineText(s.SourceText),
+				Target: &targetText,
+			})
+		}
+		doc = xliff12Document{Version: "1.2", Files: []xliff12File{file}}
+	case FormatXLIFF20:
+		xliff := xliff20Document{Version: "2.0", SrcLang: source, TrgLang: target, File: xliff20File{ID: "f1"}}
+		for i, s := range segments {
+			targetText := inlineText(s.TargetText)
+			xliff.File.Units = append(xliff.File.Units, xliff20Unit{
+				ID:      fmt.Sprintf("u%d", i+1),
+				Segment: xliff20Segment{Source: inlineText(s.SourceText), Target: &targetText},
+			})
+		}
+		doc = xliff
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	segments := []*postgres.MemorySegment{
+		{SourceText: "Hello, world.", TargetText: "Hola, mundo."},
+		{SourceText: `Tom & Jerry say "<hi>"`, TargetText: `Tom y Jerry dicen "<hi>"`},
+		{SourceText: "Ünïcödé ✓ 日本語", TargetText: "Юникод ✓ 日本語"},
+		{SourceText: "  spaced  out  ", TargetText: "  espaciado  "},
+	}
+	want := make([]Unit, 0, len(segments))
+	for _, s := range segments {
+		want = append(want, Unit{SourceLanguage: "en", TargetLanguage: "es", Source: s.SourceText, Target: s.TargetText})
+	}
+
+	for _, format := range []Format{FormatTMX, FormatXLIFF12, FormatXLIFF20} {
+		t.Run(format.String(), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Write(&b, format, "en", "es", segments); err != nil {
+				t.Fatalf("Write() = %v", err)
+			}
+			got, err := Read(&b)
+			if err != nil {
+				t.Fatalf("Read() = %v\n%s", err, b.String())
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Read(Write()) = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, Format("po"), "en", "es", nil); err == nil {
+		t.Fatal("Write() = nil, want an error")
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want []Unit
+	}{
+		{
+			name: "tmx with several variants and inline codes",
+			file: `<?xml version="1.0" encoding="UTF-8"?>
+<tmx version="1.4">
+  <header creationtool="x" creationtoolversion="1" segtype="sentence" o-tmf="x" adminlang="en" srclang="en-US" datatype="html"/>
+  <body>
+    <tu>
+      <tuv xml:lang="de-DE"><seg>Hallo <bpt i="1">&lt;b&gt;</bpt>Welt<ept i="1">&lt;/b&gt;</ept></seg></tuv>
+      <tuv xml:lang="en-US"><seg>Hello <bpt i="1">&lt;b&gt;</bpt>world<ept i="1">&lt;/b&gt;</ept></seg></tuv>
+      <tuv xml:lang="fr-FR"><seg>Bonjour <hi type="b">le monde</hi></seg></tuv>
+    </tu>
+    <tu srclang="fr-FR">
+      <tuv xml:lang="en-US"><seg>Thanks</seg></tuv>
+      <tuv xml:lang="fr-FR"><seg>Merci</seg></tuv>
+    </tu>
+    <tu>
+      <tuv xml:lang="en-US"><seg>alone</seg></tuv>
+    </tu>
+  </body>
+</tmx>`,
+			want: []Unit{
+				{SourceLanguage: "en-US", TargetLanguage: "de-DE", Source: "Hello world", Target: "Hallo Welt"},
+				{SourceLanguage: "en-US", TargetLanguage: "fr-FR", Source: "Hello world", Target: "Bonjour le monde"},
+				{SourceLanguage: "fr-FR", TargetLanguage: "en-US", Source: "Merci", Target: "Thanks"},
+			},
+		},
+		{
+			name: "tmx 1.1 lang attributes",
+			file: `<tmx version="1.1"><header srclang="en"/><body>
+<tu><tuv lang="en"><seg>Yes</seg></tuv><tuv lang="it"><seg>Sì</seg></tuv></tu>
+</body></tmx>`,
+			want: []Unit{{SourceLanguage: "en", TargetLanguage: "it", Source: "Yes", Target: "Sì"}},
+		},
+		{
+			name: "xliff 1.2 with several files, untranslated units and inline markup",
+			file: `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="a" source-language="en" target-language="es" datatype="plaintext">
+    <body>
+      <trans-unit id="1"><source>Open <g id="1">the file</g></source><target>Abre <g id="1">el archivo</g></target></trans-unit>
+      <trans-unit id="2"><source>Not yet</source></trans-unit>
+      <trans-unit id="3"><source>Line<x id="2"/>break <ph id="3">{0}</ph></source><target>Salto<x id="2"/> <ph id="3">{0}</ph></target></trans-unit>
+    </body>
+  </file>
+  <file original="b" source-language="en" target-language="pt" datatype="plaintext">
+    <body>
+      <trans-unit id="1"><source>Save</source><target>Salvar</target></trans-unit>
+    </body>
+  </file>
+</xliff>`,
+			want: []Unit{
+				{SourceLanguage: "en", TargetLanguage: "es", Source: "Open the file", Target: "Abre el archivo"},
+				{SourceLanguage: "en", TargetLanguage: "es", Source: "Linebreak ", Target: "Salto "},
+				{SourceLanguage: "en", TargetLanguage: "pt", Source: "Save", Target: "Salvar"},
+			},
+		},
+		{
+			name: "xliff 2.0 with several segments a unit and inline markup",
+			file: `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="ja">
+  <file id="f1">
+    <unit id="u1">
+      <segment><source>One.</source><target>一つ。</target></segment>
+      <ignorable><source> </source></ignorable>
+      <segment><source>Two <pc id="1">bold</pc>.</source><target>二つ<pc id="1">太字</pc>。</target></segment>
+    </unit>
+    <unit id="u2"><segment><source>Untranslated</source></segment></unit>
+  </file>
+</xliff>`,
+			want: []Unit{
+				{SourceLanguage: "en", TargetLanguage: "ja", Source: "One.", Target: "一つ。"},
+				{SourceLanguage: "en", TargetLanguage: "ja", Source: "Two bold.", Target: "二つ太字。"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("Read() = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Read() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadUTF16(t *testing.T) {
+	file := `<?xml version="1.0" encoding="UTF-16"?>
+<tmx version="1.4"><header srclang="en"/><body>
+<tu><tuv xml:lang="en"><seg>Good night</seg></tuv><tuv xml:lang="ru"><seg>Спокойной ночи</seg></tuv></tu>
+</body></tmx>`
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			var b bytes.Buffer
+			for _, u := range append([]uint16{0xfeff}, utf16.Encode([]rune(file))...) {
+				_ = binary.Write(&b, order, u)
+			}
+			got, err := Read(&b)
+			if err != nil {
+				t.Fatalf("Read() = %v", err)
+			}
+			want := []Unit{{SourceLanguage: "en", TargetLanguage: "ru", Source: "Good night", Target: "Спокойной ночи"}}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Read() = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	for name, file := range map[string]string{
+		"empty":          "",
+		"other xml":      `<html><body>hi</body></html>`,
+		"xliff 3":        `<xliff version="3.0"></xliff>`,
+		"no targets":     `<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2"><file><body><trans-unit id="1"><source>a</source></trans-unit></body></file></xliff>`,
+		"bad encoding":   `<?xml version="1.0" encoding="ISO-8859-1"?><tmx version="1.4"></tmx>`,
+		"malformed":      `<tmx version="1.4"><body><tu>`,
+		"not xml at all": "source,target\nhello,hola\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(file)); err == nil {
+				t.Fatal("Read() = nil, want an error")
+			}
+		})
+	}
+}
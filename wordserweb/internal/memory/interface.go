@@ -0,0 +1,17 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type MemoryStorer interface {
+	UpsertMemorySegments(ctx context.Context, segments []*postgres.MemorySegment) error
+	GetMemorySegments(ctx context.Context, username string, orgID int, source string, target string, keys []string) ([]*postgres.MemorySegment, error)
+	ListSimilarMemorySegments(ctx context.Context, username string, orgID int, source string, target string, key string, limit int) ([]*postgres.MemorySegment, error)
+	ListMemorySegments(ctx context.Context, username string, orgID int, source string, target string) ([]*postgres.MemorySegment, error)
+	ListMemoryPairs(ctx context.Context, username string, orgID int) ([]*postgres.MemoryPair, error)
+	DeleteMemoryPair(ctx context.Context, username string, orgID int, source string, target string) (int64, error)
+	GetOrganizationMembership(ctx context.Context, orgID int, username string) (*postgres.OrganizationMembership, error)
+}
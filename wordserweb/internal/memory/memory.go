@@ -0,0 +1,453 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+	"github.com/nolandseigler/wordser/wordserweb/internal/translate"
+)
+
+// Provider -> the provider recorded for translations served from the memory.
+const Provider = "memory"
+
+// origins of segment pairs.
+const (
+	OriginTranslation = "translation"
+	OriginImport      = "import"
+)
+
+// similarCandidates -> segment pairs fetched by trigram similarity for each segment before they are scored.
+const similarCandidates = 10
+
+// maxFuzzySegments -> segments of a text looked up for fuzzy matches; long texts stop there.
+const maxFuzzySegments = 10
+
+var (
+	ErrInvalid   = errors.New("invalid translation memory request")
+	ErrForbidden = errors.New("organization role does not allow editing its translation memory")
+)
+
+var spaceRe = regexp.MustCompile(`\s+`)
+
+// Key -> txt as the memory matches it: trimmed, with its whitespace collapsed to single spaces.
+func Key(txt string) string {
+	return spaceRe.ReplaceAllString(strings.TrimSpace(txt), " ")
+}
+
+// segment -> a sentence of a text and the whitespace after it.
+type segment struct {
+	text string
+	sep  string
+}
+
+// segments -> txt split into sentences as chunking splits them, keeping what separates them so translations of
+// them can be put back together.
+func segments(txt string) []segment {
+	txt = strings.TrimSpace(txt)
+	var segs []segment
+	start := 0
+	for _, end := range chunking.SentenceEnds(txt) {
+		segs = append(segs, segment{text: txt[start:end[0]], sep: txt[end[0]:end[1]]})
+		start = end[1]
+	}
+	if start < len(txt) {
+		segs = append(segs, segment{text: txt[start:]})
+	}
+	return segs
+}
+
+// maxEditDistanceRunes -> longest text Similarity compares by edit distance, which takes time and memory in the
+// product of the lengths. longer texts are compared by their trigrams.
+const maxEditDistanceRunes = 500
+
+// Similarity -> how alike a and b are, 0 to 1: one less their edit distance in runes over the longer one's length.
+// past maxEditDistanceRunes, the share of trigrams they have in common, as pg_trgm's similarity.
+func Similarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	if longest > maxEditDistanceRunes {
+		return trigramSimilarity(a, b)
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// trigrams -> the trigrams of the lowercased words of txt, each word padded with two spaces in front and one
+// after, as pg_trgm makes them.
+func trigrams(txt string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(txt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		r := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+func trigramSimilarity(a string, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 && len(tb) == 0 {
+		return 1
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// Memory -> segment pairs of earlier translations and imports, by workspace and language pair. the personal
+// workspace's memory is its user's; an organization's is shared by its members and edited by its owners and admins.
+type Memory struct {
+	config Config
+	store  MemoryStorer
+}
+
+func New(config Config, store MemoryStorer) *Memory {
+	return &Memory{config: config, store: store}
+}
+
+// membership -> userCtx's membership of the organization of its workspace, nil for the personal workspace.
+// org.ErrNotMember once userCtx left it; the org id of a session is no proof of membership.
+func (m *Memory) membership(ctx context.Context, userCtx auth.UserContext) (*postgres.OrganizationMembership, error) {
+	if userCtx.OrgID == 0 {
+		return nil, nil
+	}
+	membership, err := m.store.GetOrganizationMembership(ctx, userCtx.OrgID, userCtx.Username)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return nil, org.ErrNotMember
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// CanEdit -> userCtx may import into and delete from the memory of its workspace.
+func (m *Memory) CanEdit(ctx context.Context, userCtx auth.UserContext) (bool, error) {
+	membership, err := m.membership(ctx, userCtx)
+	if err != nil {
+		return false, err
+	}
+	return membership == nil || org.Role(membership.Role).CanManage(), nil
+}
+
+func (m *Memory) checkMember(ctx context.Context, userCtx auth.UserContext) error {
+	_, err := m.membership(ctx, userCtx)
+	return err
+}
+
+func (m *Memory) checkEdit(ctx context.Context, userCtx auth.UserContext) error {
+	ok, err := m.CanEdit(ctx, userCtx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Suggestion -> a segment pair similar to a segment of the text to translate.
+type Suggestion struct {
+	// Segment -> the segment of the text it is similar to.
+	Segment string  `json:"segment"`
+	Source  string  `json:"source"`
+	Target  string  `json:"target"`
+	Score   float64 `json:"score"`
+}
+
+// Percent -> Score as a whole percentage.
+func (s Suggestion) Percent() int {
+	return int(math.Round(s.Score * 100))
+}
+
+// Match -> what the memory has for a text.
+type Match struct {
+	// Text -> the text's translation, from an exact match of it or of every one of its segments. empty otherwise.
+	Text string
+	// Suggestions -> fuzzy matches of the segments without an exact match, most similar first.
+	Suggestions []Suggestion
+}
+
+// Lookup -> the memory's match for translating txt from source to target in userCtx's workspace.
+func (m *Memory) Lookup(ctx context.Context, userCtx auth.UserContext, txt string, source string, target string) (*Match, error) {
+	if err := m.checkMember(ctx, userCtx); err != nil {
+		return nil, err
+	}
+	whole := Key(txt)
+	segs := segments(txt)
+	keys := []string{whole}
+	if len(segs) > 1 {
+		for _, s := range segs {
+			keys = append(keys, Key(s.text))
+		}
+	}
+	found, err := m.store.GetMemorySegments(ctx, userCtx.Username, userCtx.OrgID, source, target, keys)
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, f := range found {
+		targets[f.SourceKey] = f.TargetText
+	}
+	if t, ok := targets[whole]; ok {
+		return &Match{Text: t}, nil
+	}
+
+	var misses []string
+	if len(segs) > 1 {
+		var b strings.Builder
+		for _, s := range segs {
+			key := Key(s.text)
+			t, ok := targets[key]
+			if !ok {
+				if !slices.Contains(misses, key) {
+					misses = append(misses, key)
+				}
+				continue
+			}
+			b.WriteString(t)
+			b.WriteString(s.sep)
+		}
+		if len(misses) == 0 {
+			return &Match{Text: b.String()}, nil
+		}
+	} else {
+		misses = []string{whole}
+	}
+
+	match := &Match{}
+	if m.config.MaxSuggestions <= 0 {
+		return match, nil
+	}
+	for i, key := range misses {
+		if i == maxFuzzySegments || len(match.Suggestions) >= m.config.MaxSuggestions {
+			break
+		}
+		if utf8.RuneCountInString(key) > m.config.MaxSegmentChars {
+			continue
+		}
+		similar, err := m.store.ListSimilarMemorySegments(
+			ctx,
+			userCtx.Username,
+			userCtx.OrgID,
+			source,
+			target,
+			key,
+			similarCandidates,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range similar {
+			if score := Similarity(key, s.SourceKey); score >= m.config.FuzzyThreshold {
+				match.Suggestions = append(match.Suggestions, Suggestion{
+					Segment: key,
+					Source:  s.SourceText,
+					Target:  s.TargetText,
+					Score:   score,
+				})
+			}
+		}
+	}
+	sort.SliceStable(match.Suggestions, func(i, j int) bool {
+		return match.Suggestions[i].Score > match.Suggestions[j].Score
+	})
+	if len(match.Suggestions) > m.config.MaxSuggestions {
+		match.Suggestions = match.Suggestions[:m.config.MaxSuggestions]
+	}
+	return match, nil
+}
+
+// newSegment -> a segment pair of userCtx's workspace, or nil when either side is empty or too long to keep.
+func (m *Memory) newSegment(
+	userCtx auth.UserContext,
+	source string,
+	target string,
+	sourceText string,
+	targetText string,
+	origin string,
+) *postgres.MemorySegment {
+	key := Key(sourceText)
+	sourceText, targetText = strings.TrimSpace(sourceText), strings.TrimSpace(targetText)
+	if key == "" || targetText == "" ||
+		utf8.RuneCountInString(key) > m.config.MaxSegmentChars ||
+		utf8.RuneCountInString(targetText) > m.config.MaxSegmentChars {
+		return nil
+	}
+	s := &postgres.MemorySegment{
+		SourceLanguage: source,
+		TargetLanguage: target,
+		SourceKey:      key,
+		SourceText:     sourceText,
+		TargetText:     targetText,
+		Origin:         origin,
+	}
+	if userCtx.OrgID != 0 {
+		orgID := userCtx.OrgID
+		s.OrganizationID = &orgID
+	} else {
+		username := userCtx.Username
+		s.Username = &username
+	}
+	return s
+}
+
+// Remember -> keep the translation of txt from source to target in userCtx's workspace. its sentences are kept
+// pair by pair when the translation has as many, otherwise txt is kept whole.
+func (m *Memory) Remember(
+	ctx context.Context,
+	userCtx auth.UserContext,
+	source string,
+	target string,
+	txt string,
+	translated string,
+) error {
+	sourceSegs, targetSegs := segments(txt), segments(translated)
+	if len(sourceSegs) < 2 || len(sourceSegs) != len(targetSegs) {
+		sourceSegs, targetSegs = []segment{{text: txt}}, []segment{{text: translated}}
+	}
+	var pairs []*postgres.MemorySegment
+	for i := range sourceSegs {
+		if s := m.newSegment(userCtx, source, target, sourceSegs[i].text, targetSegs[i].text, OriginTranslation); s != nil {
+			pairs = append(pairs, s)
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	if err := m.checkMember(ctx, userCtx); err != nil {
+		return err
+	}
+	return m.store.UpsertMemorySegments(ctx, pairs)
+}
+
+// ImportResult -> how a tmx or xliff file was imported. Skipped segment pairs were empty, too long or in a
+// language pair not on offer.
+type ImportResult struct {
+	Imported int
+	Skipped  int
+}
+
+// Import -> add the segment pairs of a tmx or xliff file to userCtx's workspace, see Read. their language tags
+// are matched onto the language codes of catalog, so "en-US" is kept as "en".
+func (m *Memory) Import(ctx context.Context, userCtx auth.UserContext, r io.Reader, catalog *translate.Catalog) (ImportResult, error) {
+	var result ImportResult
+	if err := m.checkEdit(ctx, userCtx); err != nil {
+		return result, err
+	}
+	units, err := Read(io.LimitReader(r, m.config.MaxImportBytes))
+	if err != nil {
+		return result, fmt.Errorf("%w; %v;", ErrInvalid, err)
+	}
+
+	var pairs []*postgres.MemorySegment
+	for _, u := range units {
+		source, sourceOK := catalog.ResolveSource(u.SourceLanguage)
+		target, targetOK := catalog.ResolveTarget(u.TargetLanguage)
+		if !sourceOK || !targetOK || !catalog.Supports(source, target) {
+			result.Skipped++
+			continue
+		}
+		s := m.newSegment(userCtx, source, target, u.Source, u.Target, OriginImport)
+		if s == nil {
+			result.Skipped++
+			continue
+		}
+		pairs = append(pairs, s)
+	}
+	if len(pairs) == 0 {
+		return result, fmt.Errorf(
+			"%w; none of the file's %d segment pairs are in a language pair on offer;",
+			ErrInvalid,
+			len(units),
+		)
+	}
+	if err := m.store.UpsertMemorySegments(ctx, pairs); err != nil {
+		return result, err
+	}
+	result.Imported = len(pairs)
+	return result, nil
+}
+
+// Export -> the segment pairs for translating source to target in userCtx's workspace, written as format.
+func (m *Memory) Export(
+	ctx context.Context,
+	userCtx auth.UserContext,
+	source string,
+	target string,
+	format Format,
+	w io.Writer,
+) error {
+	if err := m.checkMember(ctx, userCtx); err != nil {
+		return err
+	}
+	segs, err := m.store.ListMemorySegments(ctx, userCtx.Username, userCtx.OrgID, source, target)
+	if err != nil {
+		return err
+	}
+	if len(segs) == 0 {
+		return fmt.Errorf("translation memory segments %w", postgres.ErrNotFound)
+	}
+	return Write(w, format, source, target, segs)
+}
+
+// Pairs -> the language pairs of the memory of userCtx's workspace.
+func (m *Memory) Pairs(ctx context.Context, userCtx auth.UserContext) ([]*postgres.MemoryPair, error) {
+	if err := m.checkMember(ctx, userCtx); err != nil {
+		return nil, err
+	}
+	return m.store.ListMemoryPairs(ctx, userCtx.Username, userCtx.OrgID)
+}
+
+// DeletePair -> forget the segment pairs for translating source to target in userCtx's workspace.
+func (m *Memory) DeletePair(ctx context.Context, userCtx auth.UserContext, source string, target string) error {
+	if err := m.checkEdit(ctx, userCtx); err != nil {
+		return err
+	}
+	n, err := m.store.DeleteMemoryPair(ctx, userCtx.Username, userCtx.OrgID, source, target)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("translation memory segments %w", postgres.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/org"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		txt  string
+		want []segment
+	}{
+		{txt: "", want: nil},
+		{txt: "One sentence", want: []segment{{text: "One sentence"}}},
+		{txt: "  Padded.  ", want: []segment{{text: "Padded."}}},
+		{
+			txt:  "First one. Second one!  Third?\nFourth",
+			want: []segment{{text: "First one.", sep: " "}, {text: "Second one!", sep: "  "}, {text: "Third?", sep: "\n"}, {text: "Fourth"}},
+		},
+		{
+			txt:  `He said "stop." Then left.`,
+			want: []segment{{text: `He said "stop."`, sep: " "}, {text: "Then left."}},
+		},
+		{
+			txt:  "Wait... (really?) Yes.",
+			want: []segment{{text: "Wait...", sep: " "}, {text: "(really?)", sep: " "}, {text: "Yes."}},
+		},
+		{txt: "3.14 is pi.", want: []segment{{text: "3.14 is pi."}}},
+		{txt: "今日は。 明日も！ ", want: []segment{{text: "今日は。", sep: " "}, {text: "明日も！"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.txt, func(t *testing.T) {
+			got := segments(tt.txt)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("segments(%q) = %#v, want %#v", tt.txt, got, tt.want)
+			}
+			// put back together, the segments are the trimmed text.
+			var b strings.Builder
+			for _, s := range got {
+				b.WriteString(s.text + s.sep)
+			}
+			if b.String() != strings.TrimSpace(tt.txt) {
+				t.Fatalf("segments(%q) joined = %q", tt.txt, b.String())
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := Key("  Hello,\n\tworld  again "); got != "Hello, world again" {
+		t.Fatalf("Key() = %q", got)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "", b: "", want: 1},
+		{a: "abc", b: "", want: 0},
+		{a: "kitten", b: "sitting", want: 1 - 3.0/7},
+		{a: "Open the file", b: "Open the file", want: 1},
+		{a: "Öffne die Datei", b: "Öffne die Daten", want: 1 - 1.0/15},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarityOfLongTexts(t *testing.T) {
+	long := strings.Repeat("the quick brown fox jumps over the lazy dog ", 50)
+	if got := Similarity(long, long); got != 1 {
+		t.Fatalf("Similarity(long, long) = %v, want 1", got)
+	}
+	changed := strings.Replace(long, "lazy", "sleepy", 1)
+	if got := Similarity(long, changed); got < 0.75 || got >= 1 {
+		t.Fatalf("Similarity(long, changed) = %v, want a near match", got)
+	}
+	other := strings.Repeat("lorem ipsum dolor sit amet consectetur adipiscing ", 50)
+	if got := Similarity(long, other); got > 0.2 {
+		t.Fatalf("Similarity(long, other) = %v, want a poor match", got)
+	}
+}
+
+// fakeStore -> a memory with one segment pair in every workspace. memberships are org roles by username; calls
+// counts the calls that read or change segment pairs.
+type fakeStore struct {
+	memberships map[string]string
+	calls       int
+}
+
+func (s *fakeStore) UpsertMemorySegments(ctx context.Context, segments []*postgres.MemorySegment) error {
+	s.calls++
+	return nil
+}
+
+func (s *fakeStore) GetMemorySegments(ctx context.Context, username string, orgID int, source string, target string, keys []string) ([]*postgres.MemorySegment, error) {
+	s.calls++
+	return nil, nil
+}
+
+func (s *fakeStore) ListSimilarMemorySegments(ctx context.Context, username string, orgID int, source string, target string, key string, limit int) ([]*postgres.MemorySegment, error) {
+	s.calls++
+	return nil, nil
+}
+
+func (s *fakeStore) ListMemorySegments(ctx context.Context, username string, orgID int, source string, target string) ([]*postgres.MemorySegment, error) {
+	s.calls++
+	return []*postgres.MemorySegment{{SourceLanguage: source, TargetLanguage: target, SourceKey: "Hi", SourceText: "Hi", TargetText: "Hallo"}}, nil
+}
+
+func (s *fakeStore) ListMemoryPairs(ctx context.Context, username string, orgID int) ([]*postgres.MemoryPair, error) {
+	s.calls++
+	return nil, nil
+}
+
+func (s *fakeStore) DeleteMemoryPair(ctx context.Context, username string, orgID int, source string, target string) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+
+func (s *fakeStore) GetOrganizationMembership(ctx context.Context, orgID int, username string) (*postgres.OrganizationMembership, error) {
+	role, ok := s.memberships[username]
+	if !ok {
+		return nil, fmt.Errorf("membership %w", postgres.ErrNotFound)
+	}
+	return &postgres.OrganizationMembership{OrganizationID: orgID, Username: username, Role: role}, nil
+}
+
+func TestMemoryMembership(t *testing.T) {
+	calls := []struct {
+		name string
+		// edit -> only managers may make the call.
+		edit bool
+		call func(m *Memory, userCtx auth.UserContext) error
+	}{
+		{name: "Lookup", call: func(m *Memory, userCtx auth.UserContext) error {
+			_, err := m.Lookup(context.Background(), userCtx, "Hi", "en", "de")
+			return err
+		}},
+		{name: "Remember", call: func(m *Memory, userCtx auth.UserContext) error {
+			return m.Remember(context.Background(), userCtx, "en", "de", "Hi", "Hallo")
+		}},
+		{name: "Export", call: func(m *Memory, userCtx auth.UserContext) error {
+			return m.Export(context.Background(), userCtx, "en", "de", FormatTMX, io.Discard)
+		}},
+		{name: "Pairs", call: func(m *Memory, userCtx auth.UserContext) error {
+			_, err := m.Pairs(context.Background(), userCtx)
+			return err
+		}},
+		{name: "DeletePair", edit: true, call: func(m *Memory, userCtx auth.UserContext) error {
+			return m.DeletePair(context.Background(), userCtx, "en", "de")
+		}},
+	}
+	users := []struct {
+		name    string
+		userCtx auth.UserContext
+		// readErr/editErr -> nil when the call is allowed.
+		readErr error
+		editErr error
+	}{
+		{name: "personal", userCtx: auth.UserContext{Username: "ann"}},
+		{name: "org admin", userCtx: auth.UserContext{Username: "bob", OrgID: 3}},
+		{name: "org member", userCtx: auth.UserContext{Username: "cat", OrgID: 3}, editErr: ErrForbidden},
+		{
+			name:    "former member",
+			userCtx: auth.UserContext{Username: "dan", OrgID: 3, OrgRole: "admin"},
+			readErr: org.ErrNotMember,
+			editErr: org.ErrNotMember,
+		},
+	}
+	for _, u := range users {
+		for _, c := range calls {
+			t.Run(u.name+"/"+c.name, func(t *testing.T) {
+				store := &fakeStore{memberships: map[string]string{"bob": "admin", "cat": "member"}}
+				want := u.readErr
+				if c.edit {
+					want = u.editErr
+				}
+				err := c.call(New(Config{MaxSegmentChars: 1000}, store), u.userCtx)
+				if !errors.Is(err, want) {
+					t.Fatalf("%s() error = %v, want %v", c.name, err, want)
+				}
+				if err != nil && store.calls != 0 {
+					t.Errorf("%s() made %d store calls, want none", c.name, store.calls)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package nlp
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/chunking"
+)
+
+var paragraphRe = regexp.MustCompile(`\n[ \t\r]*\n\s*`)
+
+// Sentences -> txt split between paragraphs and then as chunking.Sentences splits them. abbreviations split too;
+// good enough for scoring.
+func Sentences(txt string) []string {
+	var sentences []string
+	for _, paragraph := range paragraphRe.Split(strings.TrimSpace(txt), -1) {
+		sentences = append(sentences, chunking.Sentences(paragraph)...)
+	}
+	return sentences
+}
+
+// Token -> a word, or a run of punctuation that separates words, as it appears in the text.
+type Token struct {
+	Text string
+	Word bool
+}
+
+// Tokens -> txt as words and punctuation, whitespace dropped. apostrophes and hyphens inside a word keep it whole.
+func Tokens(txt string) []Token {
+	var tokens []Token
+	runes := []rune(txt)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && (isWordRune(runes[i]) || isJoiner(runes, i)) {
+				i++
+			}
+			tokens = append(tokens, Token{Text: string(runes[start:i]), Word: true})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, Token{Text: string(runes[start:i])})
+		}
+	}
+	return tokens
+}
+
+// Words -> the lower cased words of txt.
+func Words(txt string) []string {
+	var words []string
+	for _, t := range Tokens(txt) {
+		if t.Word {
+			words = append(words, strings.ToLower(t.Text))
+		}
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// isJoiner -> an apostrophe or hyphen between two word runes, as in "don't" or "well-known".
+func isJoiner(runes []rune, i int) bool {
+	switch runes[i] {
+	case '\'', '’', '-':
+		return i > 0 && i+1 < len(runes) && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package nlp
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = "Machine learning models need training data. Training data quality matters for machine learning. " +
+	"The weather was nice yesterday. Good training data makes machine learning models better."
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		txt  string
+		want []string
+	}{
+		{txt: "", want: nil},
+		{txt: "One sentence", want: []string{"One sentence"}},
+		{txt: sample, want: []string{
+			"Machine learning models need training data.",
+			"Training data quality matters for machine learning.",
+			"The weather was nice yesterday.",
+			"Good training data makes machine learning models better.",
+		}},
+		{txt: "First paragraph\n\nSecond one. Still second", want: []string{
+			"First paragraph",
+			"Second one.",
+			"Still second",
+		}},
+		{txt: "今日は晴れです。 明日は雨！ 本当？ はい", want: []string{"今日は晴れです。", "明日は雨！", "本当？", "はい"}},
+	}
+	for _, tt := range tests {
+		if got := Sentences(tt.txt); len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("Sentences(%q) = %q, want %q", tt.txt, got, tt.want)
+		}
+	}
+}
+
+func TestSentiment(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		// sign -> -1 negative, 0 neutral, 1 positive.
+		sign int
+	}{
+		{name: "empty", txt: "", sign: 0},
+		{name: "spaces", txt: "   ", sign: 0},
+		{name: "no sentiment", txt: "The meeting is at noon.", sign: 0},
+		{name: "positive", txt: "I love this great product.", sign: 1},
+		{name: "negative", txt: "This is terrible and awful.", sign: -1},
+		{name: "negated", txt: "This is not good.", sign: -1},
+		{name: "negated contraction", txt: "I don't hate it.", sign: 1},
+		{name: "but outweighs", txt: "The food was good, but the service was horrible.", sign: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sentiment(tt.txt)
+			if got < -1 || got > 1 {
+				t.Fatalf("Sentiment(%q) = %v, out of [-1, 1]", tt.txt, got)
+			}
+			sign := 0
+			switch {
+			case got > 0:
+				sign = 1
+			case got < 0:
+				sign = -1
+			}
+			if sign != tt.sign {
+				t.Fatalf("Sentiment(%q) = %v, want sign %d", tt.txt, got, tt.sign)
+			}
+		})
+	}
+}
+
+func TestSentimentIntensity(t *testing.T) {
+	tests := []struct {
+		name     string
+		stronger string
+		weaker   string
+	}{
+		{name: "booster", stronger: "This is very good.", weaker: "This is good."},
+		{name: "dampener", stronger: "This is good.", weaker: "This is slightly good."},
+		{name: "dampened negative", stronger: "This is bad.", weaker: "This is slightly bad."},
+		{name: "boosted negative", stronger: "This is very bad.", weaker: "This is bad."},
+		{name: "caps", stronger: "This is GOOD.", weaker: "This is good."},
+		{name: "exclamations", stronger: "This is good!!!", weaker: "This is good."},
+		{name: "negation is weaker than the opposite", stronger: "This is bad.", weaker: "This is not good."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stronger, weaker := Sentiment(tt.stronger), Sentiment(tt.weaker)
+			if abs(stronger) <= abs(weaker) {
+				t.Fatalf("Sentiment(%q) = %v, not stronger than Sentiment(%q) = %v", tt.stronger, stronger, tt.weaker, weaker)
+			}
+		})
+	}
+	// all caps text isn't shouting any word in particular.
+	if got, want := Sentiment("THIS IS GOOD."), Sentiment("This is good."); got != want {
+		t.Errorf("Sentiment(all caps) = %v, want %v", got, want)
+	}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		n    int
+		want []string
+	}{
+		{name: "empty", txt: "", n: 5, want: nil},
+		{name: "negative n", txt: sample, n: -1, want: nil},
+		{name: "zero n", txt: sample, n: 0, want: nil},
+		{name: "best first", txt: sample, n: 3, want: []string{"training data quality matters", "machine learning", "nice yesterday"}},
+		{name: "stopwords only", txt: "It is what it is.", n: 5, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keywords := Keywords(tt.txt, tt.n)
+			var got []string
+			for i, k := range keywords {
+				got = append(got, k.Text)
+				if i == 0 && k.Score != 1 {
+					t.Errorf("best keyword scores %v, want 1", k.Score)
+				}
+				if i > 0 && k.Score > keywords[i-1].Score {
+					t.Errorf("keyword %q scores more than the one before it", k.Text)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Keywords(%q, %d) = %q, want %q", tt.txt, tt.n, got, tt.want)
+			}
+		})
+	}
+	if got := Keywords(sample, 100); len(got) < 3 {
+		t.Fatalf("Keywords(sample, 100) = %+v, want every candidate", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		n    int
+		want string
+	}{
+		{name: "empty", txt: "", n: 3, want: ""},
+		{name: "negative n", txt: sample, n: -1, want: ""},
+		{name: "zero n", txt: sample, n: 0, want: ""},
+		{name: "shorter than n", txt: "One. Two.", n: 3, want: "One. Two."},
+		{
+			name: "central sentences in order",
+			txt:  sample,
+			n:    2,
+			want: "Machine learning models need training data. Good training data makes machine learning models better.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.txt, tt.n); got != tt.want {
+				t.Fatalf("Summarize(%q, %d) = %q, want %q", tt.txt, tt.n, got, tt.want)
+			}
+		})
+	}
+	if got := Summarize(sample, 3); strings.Contains(got, "weather") {
+		t.Errorf("Summarize(sample, 3) = %q, kept the off topic sentence", got)
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	tests := []struct {
+		word string
+		want int
+	}{
+		{word: "", want: 0},
+		{word: "42", want: 0},
+		{word: "a", want: 1},
+		{word: "the", want: 1},
+		{word: "cat", want: 1},
+		{word: "make", want: 1},
+		{word: "table", want: 2},
+		{word: "little", want: 2},
+		{word: "jumped", want: 1},
+		{word: "wanted", want: 2},
+		{word: "boxes", want: 2},
+		{word: "yellow", want: 2},
+		{word: "beautiful", want: 3},
+		{word: "Readability", want: 5},
+		{word: "don't", want: 1},
+		// accented vowels are vowels; a byte at a time they were consonants, or half of one.
+		{word: "café", want: 2},
+		{word: "résumé", want: 3},
+		{word: "über", want: 2},
+		{word: "naïve", want: 2},
+		{word: "Noël", want: 2},
+		{word: "Müller", want: 2},
+	}
+	for _, tt := range tests {
+		if got := Syllables(tt.word); got != tt.want {
+			t.Errorf("Syllables(%q) = %d, want %d", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	if got := Analyze("", 3); !reflect.DeepEqual(got, Readability{}) {
+		t.Errorf("Analyze(\"\") = %+v, want the zero Readability", got)
+	}
+
+	// 11 words, 2 sentences, 13 syllables, 35 letters, 1 polysyllable ("beautiful"), 10 unique words.
+	r := Analyze("The cat sat on the mat. It was a beautiful day.", 1)
+	counts := []int{r.Words, r.Sentences, r.Syllables, r.Letters, r.Polysyllables, r.UniqueWords}
+	if want := []int{11, 2, 13, 35, 1, 10}; !reflect.DeepEqual(counts, want) {
+		t.Fatalf("Analyze() counts = %v, want %v", counts, want)
+	}
+	// golden values worked out by hand from the formulas' published definitions.
+	formulas := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		// 206.835 - 1.015*(11/2) - 84.6*(13/11)
+		{name: "FleschReadingEase", got: r.FleschReadingEase, want: 101.2707},
+		// 0.39*(11/2) + 11.8*(13/11) - 15.59
+		{name: "FleschKincaidGrade", got: r.FleschKincaidGrade, want: 0.5005},
+		// 0.4*((11/2) + 100*(1/11))
+		{name: "GunningFog", got: r.GunningFog, want: 5.8364},
+		// 1.043*sqrt(1*30/2) + 3.1291
+		{name: "SMOG", got: r.SMOG, want: 7.1686},
+		// 0.0588*(35/11*100) - 0.296*(2/11*100) - 15.8
+		{name: "ColemanLiau", got: r.ColemanLiau, want: -2.4727},
+	}
+	for _, f := range formulas {
+		if math.Abs(f.got-f.want) > 0.0001 {
+			t.Errorf("Analyze() %s = %.4f, want %.4f", f.name, f.got, f.want)
+		}
+	}
+	if r.WordsPerSentence() != 5.5 || math.Abs(r.LexicalDiversity()-10.0/11) > 1e-9 {
+		t.Errorf("WordsPerSentence() = %v, LexicalDiversity() = %v; want 5.5, 10/11", r.WordsPerSentence(), r.LexicalDiversity())
+	}
+	if want := []Sentence{{Text: "The cat sat on the mat.", Words: 6}}; !reflect.DeepEqual(r.LongestSentences, want) {
+		t.Errorf("Analyze() LongestSentences = %+v, want %+v", r.LongestSentences, want)
+	}
+}
@@ -0,0 +1,114 @@
+package nlp
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxPhraseWords -> longer runs of content words are rarely keywords; RAKE drops them.
+const maxPhraseWords = 4
+
+type Keyword struct {
+	Text string
+	// Score -> relative to the best keyword of the text, which scores 1.
+	Score float64
+}
+
+// Keywords -> at most n keywords of txt, best first. candidates are the runs of content words between stopwords
+// and punctuation (RAKE). a candidate scores the degree to frequency ratio of its words, weighted by their
+// inverse sentence frequency and by how often the phrase occurs (TF-IDF with sentences as documents).
+func Keywords(txt string, n int) []Keyword {
+	n = max(n, 0)
+	sentences := Sentences(txt)
+	type candidate struct {
+		words []string
+		count int
+		first int
+	}
+	candidates := map[string]*candidate{}
+	freq := map[string]float64{}
+	degree := map[string]float64{}
+	sentenceFreq := map[string]float64{}
+
+	for _, sentence := range sentences {
+		inSentence := map[string]bool{}
+		var phrase []string
+		flush := func() {
+			if len(phrase) == 0 || len(phrase) > maxPhraseWords {
+				phrase = nil
+				return
+			}
+			for _, w := range phrase {
+				freq[w]++
+				degree[w] += float64(len(phrase))
+				inSentence[w] = true
+			}
+			key := strings.Join(phrase, " ")
+			if c, ok := candidates[key]; ok {
+				c.count++
+			} else {
+				candidates[key] = &candidate{words: phrase, count: 1, first: len(candidates)}
+			}
+			phrase = nil
+		}
+		for _, t := range Tokens(sentence) {
+			w := strings.ToLower(t.Text)
+			if !t.Word || IsStopword(w) || !isContentWord(w) {
+				flush()
+				continue
+			}
+			phrase = append(phrase, w)
+		}
+		flush()
+		for w := range inSentence {
+			sentenceFreq[w]++
+		}
+	}
+
+	type scored struct {
+		text  string
+		score float64
+		first int
+	}
+	var ranked []scored
+	total := float64(len(sentences))
+	for text, c := range candidates {
+		var score float64
+		for _, w := range c.words {
+			idf := math.Log(1 + total/sentenceFreq[w])
+			score += degree[w] / freq[w] * idf
+		}
+		score *= 1 + math.Log(float64(c.count))
+		ranked = append(ranked, scored{text: text, score: score, first: c.first})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].first < ranked[j].first
+	})
+
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	keywords := make([]Keyword, 0, len(ranked))
+	for _, r := range ranked {
+		keywords = append(keywords, Keyword{Text: r.text, Score: r.score / ranked[0].score})
+	}
+	return keywords
+}
+
+// isContentWord -> w has a letter and more than one rune; numbers and initials don't make keywords.
+func isContentWord(w string) bool {
+	letters := 0
+	runes := 0
+	for _, r := range w {
+		runes++
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 0 && runes > 1
+}
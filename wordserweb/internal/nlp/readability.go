@@ -0,0 +1,159 @@
+package nlp
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Readability -> counts and readability formulas of a text. the formulas are 0 for text without words.
+type Readability struct {
+	Words     int
+	Sentences int
+	Syllables int
+	// Letters -> letters and digits of the words, for Coleman-Liau.
+	Letters int
+	// Polysyllables -> words of 3 or more syllables, the "complex words" of Gunning Fog and SMOG.
+	Polysyllables int
+	UniqueWords   int
+
+	// FleschReadingEase -> 0-100ish, higher is easier. 60-70 is plain english.
+	FleschReadingEase float64
+	// FleschKincaidGrade/GunningFog/SMOG/ColemanLiau -> us school grade needed to follow the text.
+	FleschKincaidGrade float64
+	GunningFog         float64
+	SMOG               float64
+	ColemanLiau        float64
+
+	// LongestSentences -> the longest n sentences by words, longest first.
+	LongestSentences []Sentence
+}
+
+type Sentence struct {
+	Text  string
+	Words int
+}
+
+// WordsPerSentence -> average sentence length.
+func (r Readability) WordsPerSentence() float64 {
+	if r.Sentences == 0 {
+		return 0
+	}
+	return float64(r.Words) / float64(r.Sentences)
+}
+
+// LexicalDiversity -> unique words over words, the type-token ratio. it falls as texts get longer, so only compare
+// texts of similar length.
+func (r Readability) LexicalDiversity() float64 {
+	if r.Words == 0 {
+		return 0
+	}
+	return float64(r.UniqueWords) / float64(r.Words)
+}
+
+// Analyze -> the Readability of txt, keeping its n longest sentences.
+func Analyze(txt string, n int) Readability {
+	var r Readability
+	unique := map[string]bool{}
+	var sentences []Sentence
+	for _, sentence := range Sentences(txt) {
+		words := Words(sentence)
+		if len(words) == 0 {
+			continue
+		}
+		sentences = append(sentences, Sentence{Text: sentence, Words: len(words)})
+		for _, w := range words {
+			syllables := Syllables(w)
+			r.Syllables += syllables
+			if syllables >= 3 {
+				r.Polysyllables++
+			}
+			for _, c := range w {
+				if unicode.IsLetter(c) || unicode.IsDigit(c) {
+					r.Letters++
+				}
+			}
+			unique[w] = true
+		}
+		r.Words += len(words)
+	}
+	r.Sentences = len(sentences)
+	r.UniqueWords = len(unique)
+	if r.Words == 0 {
+		return r
+	}
+
+	words := float64(r.Words)
+	wordsPerSentence := r.WordsPerSentence()
+	syllablesPerWord := float64(r.Syllables) / words
+	r.FleschReadingEase = 206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord
+	r.FleschKincaidGrade = 0.39*wordsPerSentence + 11.8*syllablesPerWord - 15.59
+	r.GunningFog = 0.4 * (wordsPerSentence + 100*float64(r.Polysyllables)/words)
+	// SMOG is defined over 30 sentences; shorter texts are scaled up to them.
+	r.SMOG = 1.043*math.Sqrt(float64(r.Polysyllables)*30/float64(r.Sentences)) + 3.1291
+	r.ColemanLiau = 0.0588*float64(r.Letters)/words*100 - 0.296*float64(r.Sentences)/words*100 - 15.8
+
+	sort.SliceStable(sentences, func(i, j int) bool {
+		return sentences[i].Words > sentences[j].Words
+	})
+	if len(sentences) > n {
+		sentences = sentences[:n]
+	}
+	r.LongestSentences = sentences
+	return r
+}
+
+// Syllables -> estimated syllables of an english word: its vowel groups less silent endings, at least 1. runes
+// rather than bytes, so accented vowels count as vowels, and one with a diaeresis, as in "naïve", starts a syllable
+// of its own.
+func Syllables(word string) int {
+	w := []rune(strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, word)))
+	if len(w) == 0 {
+		return 0
+	}
+	if len(w) <= 3 {
+		return 1
+	}
+
+	suffix := func(s string) bool {
+		return strings.HasSuffix(string(w), s)
+	}
+	switch {
+	// a final "le" after a consonant is voiced, as in "table".
+	case suffix("le") && !isVowel(w[len(w)-3]):
+	case suffix("es"), suffix("ed"):
+		// "-ted" and "-ded", "-ses" and the like keep their syllable.
+		if !strings.ContainsRune("tdscxzgh", w[len(w)-3]) {
+			w = w[:len(w)-2]
+		}
+	case suffix("e"):
+		w = w[:len(w)-1]
+	}
+	if w[0] == 'y' {
+		w = w[1:]
+	}
+
+	count := 0
+	inVowels := false
+	for _, r := range w {
+		vowel := isVowel(r)
+		if vowel && (!inVowels || strings.ContainsRune(diaeresisVowels, r)) {
+			count++
+		}
+		inVowels = vowel
+	}
+	return max(count, 1)
+}
+
+// diaeresisVowels -> vowels marked as sounded apart from the vowel before them.
+const diaeresisVowels = "äëïöüÿ"
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyàáâãäåæèéêëìíîïòóôõöøœùúûüýÿ", r)
+}
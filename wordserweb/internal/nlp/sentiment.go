@@ -0,0 +1,158 @@
+package nlp
+
+import (
+	"bufio"
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//go:embed lexicon.txt
+var lexiconTxt string
+
+const (
+	// negationScalar -> a negated word keeps about three quarters of its valence, flipped. "not good" is less bad
+	// than "bad".
+	negationScalar = -0.74
+	// boosterIncrement -> added to, or with dampeners taken from, the valence of the word a booster precedes.
+	boosterIncrement = 0.293
+	// capsIncrement -> SHOUTED words weigh more, unless the whole text is upper case.
+	capsIncrement = 0.733
+	// exclamationIncrement -> per exclamation mark, at most maxExclamations of them.
+	exclamationIncrement = 0.292
+	maxExclamations      = 4
+	// normalizationAlpha -> how quickly the compound score approaches ±1 as valence adds up.
+	normalizationAlpha = 15
+	// lookback -> words before a scored word checked for negations and boosters.
+	lookback = 3
+)
+
+var lexicon = map[string]float64{}
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "none": true, "nobody": true, "nothing": true, "neither": true,
+	"nor": true, "nowhere": true, "cannot": true, "without": true, "lack": true, "lacks": true,
+}
+
+// boosters -> intensifiers with +1, dampeners with -1.
+var boosters = map[string]float64{
+	"absolutely": 1, "amazingly": 1, "completely": 1, "considerably": 1, "deeply": 1, "especially": 1,
+	"exceptionally": 1, "extremely": 1, "fully": 1, "greatly": 1, "highly": 1, "hugely": 1, "incredibly": 1,
+	"intensely": 1, "particularly": 1, "really": 1, "remarkably": 1, "so": 1, "substantially": 1, "super": 1,
+	"thoroughly": 1, "totally": 1, "tremendously": 1, "truly": 1, "unbelievably": 1, "utterly": 1, "very": 1,
+	"almost": -1, "barely": -1, "hardly": -1, "marginally": -1, "partly": -1, "scarcely": -1, "slightly": -1,
+	"somewhat": -1, "occasionally": -1,
+}
+
+func init() {
+	scanner := bufio.NewScanner(strings.NewReader(lexiconTxt))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word, value, ok := strings.Cut(line, "\t")
+		if !ok {
+			panic("nlp: malformed lexicon line " + strconv.Quote(line))
+		}
+		valence, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			panic("nlp: malformed lexicon line " + strconv.Quote(line))
+		}
+		lexicon[word] = valence
+	}
+}
+
+// Sentiment -> compound valence of txt from -1, most negative, to 1, most positive, 0 when no word carries any.
+// words are scored from a lexicon; preceding negations flip them, intensifiers and dampeners scale them, and a
+// clause after "but" outweighs the one before it.
+func Sentiment(txt string) float64 {
+	shouting := isShouting(txt)
+	var sum float64
+	for _, sentence := range Sentences(txt) {
+		sum += sentenceValence(sentence, shouting)
+	}
+	if sum != 0 {
+		exclamations := math.Min(float64(strings.Count(txt, "!")), maxExclamations)
+		sum += math.Copysign(exclamations*exclamationIncrement, sum)
+	}
+	return sum / math.Sqrt(sum*sum+normalizationAlpha)
+}
+
+func sentenceValence(sentence string, shouting bool) float64 {
+	var words []string
+	for _, t := range Tokens(sentence) {
+		if t.Word {
+			words = append(words, t.Text)
+		}
+	}
+	but := -1
+	for i, w := range words {
+		if strings.EqualFold(w, "but") {
+			but = i
+		}
+	}
+
+	var sum float64
+	for i, w := range words {
+		lower := strings.ToLower(w)
+		valence, ok := lexicon[lower]
+		if !ok {
+			continue
+		}
+		if !shouting && isUpper(w) {
+			valence += math.Copysign(capsIncrement, valence)
+		}
+		for d := 1; d <= lookback && i-d >= 0; d++ {
+			prev := strings.ToLower(words[i-d])
+			if boost, ok := boosters[prev]; ok {
+				// a booster further away counts for less. a dampener's -1 takes from the valence, whatever its sign.
+				valence += boost * math.Copysign(boosterIncrement*(1-0.05*float64(d-1)), valence)
+			}
+		}
+		for d := 1; d <= lookback && i-d >= 0; d++ {
+			if isNegation(strings.ToLower(words[i-d])) {
+				valence *= negationScalar
+				break
+			}
+		}
+		switch {
+		case but >= 0 && i < but:
+			valence *= 0.5
+		case but >= 0 && i > but:
+			valence *= 1.5
+		}
+		sum += valence
+	}
+	return sum
+}
+
+func isNegation(w string) bool {
+	return negations[w] || strings.HasSuffix(w, "n't") || strings.HasSuffix(w, "n’t")
+}
+
+// isUpper -> w has more than one letter and no lower case one.
+func isUpper(w string) bool {
+	letters := 0
+	for _, r := range w {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
+
+// isShouting -> every cased letter of txt is upper case; caps then say nothing about emphasis.
+func isShouting(txt string) bool {
+	for _, r := range txt {
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
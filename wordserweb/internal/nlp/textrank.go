@@ -0,0 +1,106 @@
+package nlp
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	damping = 0.85
+	// rankIterations/rankTolerance -> power iteration stops at whichever comes first.
+	rankIterations = 100
+	rankTolerance  = 1e-6
+)
+
+// Summarize -> the n most central sentences of txt in their original order (TextRank). sentences are linked by
+// the content words they share, normalized by their lengths, and ranked like pages by PageRank. txt with no more
+// than n sentences is its own summary.
+func Summarize(txt string, n int) string {
+	n = max(n, 0)
+	sentences := Sentences(txt)
+	if len(sentences) <= n {
+		return strings.Join(sentences, " ")
+	}
+
+	bags := make([]map[string]bool, len(sentences))
+	for i, sentence := range sentences {
+		bags[i] = map[string]bool{}
+		for _, w := range Words(sentence) {
+			if !IsStopword(w) && isContentWord(w) {
+				bags[i][w] = true
+			}
+		}
+	}
+
+	weights := make([][]float64, len(sentences))
+	outWeight := make([]float64, len(sentences))
+	for i := range sentences {
+		weights[i] = make([]float64, len(sentences))
+	}
+	for i := range sentences {
+		for j := i + 1; j < len(sentences); j++ {
+			w := similarity(bags[i], bags[j])
+			weights[i][j] = w
+			weights[j][i] = w
+			outWeight[i] += w
+			outWeight[j] += w
+		}
+	}
+
+	scores := make([]float64, len(sentences))
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iter := 0; iter < rankIterations; iter++ {
+		next := make([]float64, len(sentences))
+		delta := 0.0
+		for i := range sentences {
+			var rank float64
+			for j := range sentences {
+				if weights[j][i] > 0 {
+					rank += weights[j][i] / outWeight[j] * scores[j]
+				}
+			}
+			next[i] = 1 - damping + damping*rank
+			delta = math.Max(delta, math.Abs(next[i]-scores[i]))
+		}
+		scores = next
+		if delta < rankTolerance {
+			break
+		}
+	}
+
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	picked := order[:n]
+	sort.Ints(picked)
+
+	summary := make([]string, 0, n)
+	for _, i := range picked {
+		summary = append(summary, sentences[i])
+	}
+	return strings.Join(summary, " ")
+}
+
+// similarity -> shared words over the log lengths of both sentences, as in the TextRank paper.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+	return float64(shared) / (math.Log(1+float64(len(a))) + math.Log(1+float64(len(b))))
+}
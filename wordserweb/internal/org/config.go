@@ -0,0 +1,26 @@
+package org
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	InviteTTL time.Duration `mapstructure:"ORG_INVITE_TTL"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("ORG_INVITE_TTL"); err != nil {
+		return c, fmt.Errorf("failed to bind 'ORG_INVITE_TTL'")
+	}
+	viper.SetDefault("ORG_INVITE_TTL", "72h")
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,31 @@
+package org
+
+import (
+	"context"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type OrgStorer interface {
+	CreateOrganization(ctx context.Context, name string, ownerUsername string, ownerRole string) (*postgres.Organization, error)
+	ListUserOrganizationMemberships(ctx context.Context, username string) ([]*postgres.OrganizationMembership, error)
+	GetOrganizationMembership(ctx context.Context, orgID int, username string) (*postgres.OrganizationMembership, error)
+	ListOrganizationMembers(ctx context.Context, orgID int) ([]*postgres.OrganizationMembership, error)
+	SetOrganizationMemberRole(ctx context.Context, orgID int, username string, role string) error
+	DeleteOrganizationMember(ctx context.Context, orgID int, username string) error
+	CreateOrganizationInvitation(
+		ctx context.Context,
+		orgID int,
+		invitedBy string,
+		inviteeUsername *string,
+		inviteeEmail *string,
+		role string,
+		tokenHash string,
+		expiresAt time.Time,
+	) (*postgres.OrganizationInvitation, error)
+	ListOrganizationInvitations(ctx context.Context, orgID int) ([]*postgres.OrganizationInvitation, error)
+	ListUserInvitations(ctx context.Context, username string) ([]*postgres.OrganizationInvitation, error)
+	GetOrganizationInvitation(ctx context.Context, tokenHash string) (*postgres.OrganizationInvitation, error)
+	AcceptOrganizationInvitation(ctx context.Context, tokenHash string, username string) (int, error)
+}
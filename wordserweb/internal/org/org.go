@@ -0,0 +1,232 @@
+package org
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+func (r Role) String() string {
+	return string(r)
+}
+
+func (r Role) Valid() bool {
+	return r == RoleOwner || r == RoleAdmin || r == RoleMember
+}
+
+// CanManage -> owners and admins may invite, remove and assign roles.
+func (r Role) CanManage() bool {
+	return r == RoleOwner || r == RoleAdmin
+}
+
+var (
+	ErrNotMember = errors.New("not a member of organization")
+	ErrForbidden = errors.New("organization role does not allow this")
+	ErrInvalid   = errors.New("invalid organization request")
+)
+
+// Invitation -> a freshly created invitation. Token is only ever available here; the db keeps its hash.
+type Invitation struct {
+	*postgres.OrganizationInvitation
+	Token string
+}
+
+type Orgs struct {
+	config Config
+	store  OrgStorer
+	now    func() time.Time
+}
+
+func New(config Config, store OrgStorer) *Orgs {
+	return &Orgs{
+		config: config,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (o *Orgs) Create(ctx context.Context, userCtx auth.UserContext, name string) (*postgres.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 80 {
+		return nil, fmt.Errorf("%w; name must be 1 to 80 characters;", ErrInvalid)
+	}
+	return o.store.CreateOrganization(ctx, name, userCtx.Username, RoleOwner.String())
+}
+
+func (o *Orgs) List(ctx context.Context, userCtx auth.UserContext) ([]*postgres.OrganizationMembership, error) {
+	return o.store.ListUserOrganizationMemberships(ctx, userCtx.Username)
+}
+
+// Membership -> ErrNotMember unless userCtx belongs to orgID.
+func (o *Orgs) Membership(ctx context.Context, userCtx auth.UserContext, orgID int) (*postgres.OrganizationMembership, error) {
+	membership, err := o.store.GetOrganizationMembership(ctx, orgID, userCtx.Username)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+func (o *Orgs) Members(ctx context.Context, userCtx auth.UserContext, orgID int) ([]*postgres.OrganizationMembership, error) {
+	if _, err := o.Membership(ctx, userCtx, orgID); err != nil {
+		return nil, err
+	}
+	return o.store.ListOrganizationMembers(ctx, orgID)
+}
+
+func (o *Orgs) Invitations(ctx context.Context, userCtx auth.UserContext, orgID int) ([]*postgres.OrganizationInvitation, error) {
+	if _, err := o.Membership(ctx, userCtx, orgID); err != nil {
+		return nil, err
+	}
+	return o.store.ListOrganizationInvitations(ctx, orgID)
+}
+
+// PendingInvitations -> unexpired invitations addressed to userCtx by username.
+func (o *Orgs) PendingInvitations(ctx context.Context, userCtx auth.UserContext) ([]*postgres.OrganizationInvitation, error) {
+	return o.store.ListUserInvitations(ctx, userCtx.Username)
+}
+
+// Invite -> invitee is a username, or an email address when it contains an '@'.
+// username invitations can only be accepted by that user, email invitations by whoever holds the link.
+func (o *Orgs) Invite(ctx context.Context, userCtx auth.UserContext, orgID int, invitee string, role Role) (*Invitation, error) {
+	membership, err := o.Membership(ctx, userCtx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !Role(membership.Role).CanManage() {
+		return nil, ErrForbidden
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w; role: %s;", ErrInvalid, role)
+	}
+	if role == RoleOwner && Role(membership.Role) != RoleOwner {
+		return nil, ErrForbidden
+	}
+
+	var inviteeUsername, inviteeEmail *string
+	invitee = strings.TrimSpace(invitee)
+	switch {
+	case invitee == "":
+		return nil, fmt.Errorf("%w; invitee can't be empty;", ErrInvalid)
+	case strings.Contains(invitee, "@"):
+		addr, err := mail.ParseAddress(invitee)
+		if err != nil {
+			return nil, fmt.Errorf("%w; invalid email: %s;", ErrInvalid, invitee)
+		}
+		inviteeEmail = &addr.Address
+	default:
+		inviteeUsername = &invitee
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := o.store.CreateOrganizationInvitation(
+		ctx,
+		orgID,
+		userCtx.Username,
+		inviteeUsername,
+		inviteeEmail,
+		role.String(),
+		hashToken(token),
+		o.now().UTC().Add(o.config.InviteTTL),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Invitation{
+		OrganizationInvitation: invitation,
+		Token:                  token,
+	}, nil
+}
+
+func (o *Orgs) Invitation(ctx context.Context, token string) (*postgres.OrganizationInvitation, error) {
+	return o.store.GetOrganizationInvitation(ctx, hashToken(token))
+}
+
+// Accept -> join the org of the invitation token. returns the org id.
+func (o *Orgs) Accept(ctx context.Context, userCtx auth.UserContext, token string) (int, error) {
+	return o.store.AcceptOrganizationInvitation(ctx, hashToken(token), userCtx.Username)
+}
+
+// SetRole -> only owners may grant or take away the owner role.
+func (o *Orgs) SetRole(ctx context.Context, userCtx auth.UserContext, orgID int, username string, role Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("%w; role: %s;", ErrInvalid, role)
+	}
+	membership, err := o.Membership(ctx, userCtx, orgID)
+	if err != nil {
+		return err
+	}
+	if !Role(membership.Role).CanManage() {
+		return ErrForbidden
+	}
+
+	target, err := o.store.GetOrganizationMembership(ctx, orgID, username)
+	if err != nil {
+		return err
+	}
+	if (role == RoleOwner || Role(target.Role) == RoleOwner) && Role(membership.Role) != RoleOwner {
+		return ErrForbidden
+	}
+
+	return o.store.SetOrganizationMemberRole(ctx, orgID, username, role.String())
+}
+
+// Remove -> managers remove members, anyone may remove themselves.
+func (o *Orgs) Remove(ctx context.Context, userCtx auth.UserContext, orgID int, username string) error {
+	membership, err := o.Membership(ctx, userCtx, orgID)
+	if err != nil {
+		return err
+	}
+
+	if username != userCtx.Username {
+		if !Role(membership.Role).CanManage() {
+			return ErrForbidden
+		}
+		target, err := o.store.GetOrganizationMembership(ctx, orgID, username)
+		if err != nil {
+			return err
+		}
+		if Role(target.Role) == RoleOwner && Role(membership.Role) != RoleOwner {
+			return ErrForbidden
+		}
+	}
+
+	return o.store.DeleteOrganizationMember(ctx, orgID, username)
+}
@@ -0,0 +1,152 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateHalfOpen State = "half-open"
+	StateOpen     State = "open"
+)
+
+// gaugeValue -> State as exported by the breaker state gauge.
+func (s State) gaugeValue() float64 {
+	switch s {
+	case StateHalfOpen:
+		return 1
+	case StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// breaker -> consecutive failure circuit breaker. open rejects every call until openTimeout passes,
+// then half-open lets up to maxProbes calls through; a probe success closes it, a probe failure reopens it.
+type breaker struct {
+	mu    sync.Mutex
+	state State
+	// generation -> bumped on every state change. a call's outcome only counts in the generation it was let
+	// through in, so a slow call from before the breaker opened can't close it, or reopen it, afterwards.
+	generation       uint64
+	failures         int
+	probes           int
+	openedAt         time.Time
+	changedAt        time.Time
+	failureThreshold int
+	openTimeout      time.Duration
+	maxProbes        int
+	now              func() time.Time
+	onChange         func(from State, to State)
+}
+
+func newBreaker(config Config, now func() time.Time, onChange func(from State, to State)) *breaker {
+	threshold := config.BreakerFailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	probes := config.BreakerHalfOpenProbes
+	if probes < 1 {
+		probes = 1
+	}
+	return &breaker{
+		state:            StateClosed,
+		changedAt:        now(),
+		failureThreshold: threshold,
+		openTimeout:      config.BreakerOpenTimeout,
+		maxProbes:        probes,
+		now:              now,
+		onChange:         onChange,
+	}
+}
+
+// setState -> callers hold mu.
+func (b *breaker) setState(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	b.generation++
+	b.changedAt = b.now()
+	b.probes = 0
+	if to == StateOpen {
+		b.openedAt = b.changedAt
+	}
+	if to == StateClosed {
+		b.failures = 0
+	}
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
+
+// ticket -> handed out by allow for a call let through, to be given back to done with its outcome.
+type ticket struct {
+	generation uint64
+	probe      bool
+}
+
+// allow -> whether a call may go through, and its ticket. a call let through while half-open is a probe.
+func (b *breaker) allow() (ticket, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.setState(StateHalfOpen)
+	}
+	switch b.state {
+	case StateOpen:
+		return ticket{}, false
+	case StateHalfOpen:
+		if b.probes >= b.maxProbes {
+			return ticket{}, false
+		}
+		b.probes++
+		return ticket{generation: b.generation, probe: true}, true
+	default:
+		return ticket{generation: b.generation}, true
+	}
+}
+
+// done -> record the outcome of the call let through with t. ignored calls (cancelled by the caller, bad
+// requests) only give back their probe slot. a call from an earlier generation isn't counted at all; its probe
+// slot went with the state it was let through in.
+func (b *breaker) done(t ticket, outcome outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t.generation != b.generation {
+		return
+	}
+	if t.probe && b.probes > 0 {
+		b.probes--
+	}
+	switch outcome {
+	case outcomeSuccess:
+		b.failures = 0
+		if b.state == StateHalfOpen {
+			b.setState(StateClosed)
+		}
+	case outcomeFailure:
+		b.failures++
+		if b.state == StateHalfOpen || b.failures >= b.failureThreshold {
+			b.setState(StateOpen)
+		}
+	}
+}
+
+func (b *breaker) snapshot() (State, int, time.Time, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.state
+	// report an expired open breaker as half-open even before the next call moves it there.
+	if state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		state = StateHalfOpen
+	}
+	return state, b.failures, b.changedAt, b.openedAt.Add(b.openTimeout)
+}
@@ -0,0 +1,84 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerIgnoresStaleOutcomes(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(Config{BreakerFailureThreshold: 1, BreakerOpenTimeout: time.Minute, BreakerHalfOpenProbes: 1},
+		func() time.Time { return now }, nil)
+
+	slow, ok := b.allow()
+	if !ok {
+		t.Fatal("a closed breaker must let calls through")
+	}
+	failing, _ := b.allow()
+	b.done(failing, outcomeFailure)
+	if b.state != StateOpen {
+		t.Fatalf("state = %s, want %s", b.state, StateOpen)
+	}
+
+	// a call let through before the breaker opened succeeding now doesn't close it.
+	b.done(slow, outcomeSuccess)
+	if b.state != StateOpen {
+		t.Fatalf("after a stale success: state = %s, want %s", b.state, StateOpen)
+	}
+
+	now = now.Add(time.Minute)
+	probe, ok := b.allow()
+	if !ok || !probe.probe {
+		t.Fatal("an expired open breaker must let a probe through")
+	}
+	if _, ok := b.allow(); ok {
+		t.Fatal("only one probe at a time")
+	}
+	b.done(probe, outcomeSuccess)
+	if b.state != StateClosed {
+		t.Fatalf("after a probe success: state = %s, want %s", b.state, StateClosed)
+	}
+
+	// nor does it failing reopen the breaker closed since.
+	b.done(slow, outcomeFailure)
+	if b.state != StateClosed || b.failures != 0 {
+		t.Fatalf("after a stale failure: state = %s, failures = %d", b.state, b.failures)
+	}
+}
+
+func TestBreakerIgnoredProbeGivesBackItsSlot(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(Config{BreakerFailureThreshold: 1, BreakerOpenTimeout: time.Minute, BreakerHalfOpenProbes: 1},
+		func() time.Time { return now }, nil)
+	call, _ := b.allow()
+	b.done(call, outcomeFailure)
+	now = now.Add(time.Minute)
+
+	probe, _ := b.allow()
+	b.done(probe, outcomeIgnored)
+	if _, ok := b.allow(); !ok {
+		t.Fatal("an ignored probe must give back its slot")
+	}
+}
+
+func TestDoReturnsContextErrorWhenBackoffIsCancelled(t *testing.T) {
+	g := New("test", Config{
+		RetryMaxAttempts:        3,
+		BulkheadMaxConcurrent:   1,
+		BulkheadMaxWait:         time.Second,
+		BreakerFailureThreshold: 10,
+		BreakerOpenTimeout:      time.Minute,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	g.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	upstream := errors.New("upstream failed")
+	err := g.Do(ctx, "endpoint", true, func(ctx context.Context) error { return upstream })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do() = %v, want %v", err, context.Canceled)
+	}
+}
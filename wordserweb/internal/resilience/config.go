@@ -0,0 +1,75 @@
+package resilience
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config -> retry, circuit breaker and bulkhead settings shared by every upstream endpoint.
+type Config struct {
+	// RetryMaxAttempts -> attempts for an idempotent call including the first. 1 disables retries.
+	RetryMaxAttempts int           `mapstructure:"RESILIENCE_RETRY_MAX_ATTEMPTS"`
+	RetryBaseDelay   time.Duration `mapstructure:"RESILIENCE_RETRY_BASE_DELAY"`
+	RetryMaxDelay    time.Duration `mapstructure:"RESILIENCE_RETRY_MAX_DELAY"`
+	// BreakerFailureThreshold -> consecutive failures that open the breaker.
+	BreakerFailureThreshold int `mapstructure:"RESILIENCE_BREAKER_FAILURE_THRESHOLD"`
+	// BreakerOpenTimeout -> how long an open breaker rejects calls before letting probes through.
+	BreakerOpenTimeout time.Duration `mapstructure:"RESILIENCE_BREAKER_OPEN_TIMEOUT"`
+	// BreakerHalfOpenProbes -> concurrent probe calls allowed while half-open.
+	BreakerHalfOpenProbes int `mapstructure:"RESILIENCE_BREAKER_HALF_OPEN_PROBES"`
+	// BulkheadMaxConcurrent -> in flight calls per endpoint.
+	BulkheadMaxConcurrent int64 `mapstructure:"RESILIENCE_BULKHEAD_MAX_CONCURRENT"`
+	// BulkheadMaxWait -> how long a call waits for a free slot before it is rejected.
+	BulkheadMaxWait time.Duration `mapstructure:"RESILIENCE_BULKHEAD_MAX_WAIT"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("RESILIENCE_RETRY_MAX_ATTEMPTS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_RETRY_MAX_ATTEMPTS'")
+	}
+	viper.SetDefault("RESILIENCE_RETRY_MAX_ATTEMPTS", 3)
+
+	if err := viper.BindEnv("RESILIENCE_RETRY_BASE_DELAY"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_RETRY_BASE_DELAY'")
+	}
+	viper.SetDefault("RESILIENCE_RETRY_BASE_DELAY", "200ms")
+
+	if err := viper.BindEnv("RESILIENCE_RETRY_MAX_DELAY"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_RETRY_MAX_DELAY'")
+	}
+	viper.SetDefault("RESILIENCE_RETRY_MAX_DELAY", "2s")
+
+	if err := viper.BindEnv("RESILIENCE_BREAKER_FAILURE_THRESHOLD"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_BREAKER_FAILURE_THRESHOLD'")
+	}
+	viper.SetDefault("RESILIENCE_BREAKER_FAILURE_THRESHOLD", 5)
+
+	if err := viper.BindEnv("RESILIENCE_BREAKER_OPEN_TIMEOUT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_BREAKER_OPEN_TIMEOUT'")
+	}
+	viper.SetDefault("RESILIENCE_BREAKER_OPEN_TIMEOUT", "30s")
+
+	if err := viper.BindEnv("RESILIENCE_BREAKER_HALF_OPEN_PROBES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_BREAKER_HALF_OPEN_PROBES'")
+	}
+	viper.SetDefault("RESILIENCE_BREAKER_HALF_OPEN_PROBES", 1)
+
+	if err := viper.BindEnv("RESILIENCE_BULKHEAD_MAX_CONCURRENT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_BULKHEAD_MAX_CONCURRENT'")
+	}
+	viper.SetDefault("RESILIENCE_BULKHEAD_MAX_CONCURRENT", 8)
+
+	if err := viper.BindEnv("RESILIENCE_BULKHEAD_MAX_WAIT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'RESILIENCE_BULKHEAD_MAX_WAIT'")
+	}
+	viper.SetDefault("RESILIENCE_BULKHEAD_MAX_WAIT", "5s")
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,57 @@
+package resilience
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	breakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "wordserweb",
+			Subsystem: "upstream",
+			Name:      "circuit_breaker_state",
+			Help:      "Circuit breaker state per upstream endpoint: 0 closed, 1 half-open, 2 open.",
+		},
+		[]string{"upstream", "endpoint"},
+	)
+	breakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "wordserweb",
+			Subsystem: "upstream",
+			Name:      "circuit_breaker_transitions_total",
+			Help:      "Circuit breaker state changes per upstream endpoint.",
+		},
+		[]string{"upstream", "endpoint", "to"},
+	)
+	calls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "wordserweb",
+			Subsystem: "upstream",
+			Name:      "calls_total",
+			Help:      "Upstream call attempts by outcome: success, failure, ignored, circuit_open or bulkhead_full.",
+		},
+		[]string{"upstream", "endpoint", "outcome"},
+	)
+	retries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "wordserweb",
+			Subsystem: "upstream",
+			Name:      "retries_total",
+			Help:      "Retried upstream call attempts.",
+		},
+		[]string{"upstream", "endpoint"},
+	)
+	inFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "wordserweb",
+			Subsystem: "upstream",
+			Name:      "in_flight",
+			Help:      "Upstream calls holding a bulkhead slot.",
+		},
+		[]string{"upstream", "endpoint"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(breakerState, breakerTransitions, calls, retries, inFlight)
+}
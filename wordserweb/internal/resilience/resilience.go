@@ -0,0 +1,262 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	ErrCircuitOpen  = errors.New("circuit breaker open")
+	ErrBulkheadFull = errors.New("too many concurrent upstream calls")
+)
+
+type outcome string
+
+const (
+	outcomeSuccess      outcome = "success"
+	outcomeFailure      outcome = "failure"
+	outcomeIgnored      outcome = "ignored"
+	outcomeCircuitOpen  outcome = "circuit_open"
+	outcomeBulkheadFull outcome = "bulkhead_full"
+)
+
+// permanentError -> see Permanent.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent -> mark err as the caller's fault, e.g. a 4xx. it is returned as is: never retried and
+// not counted against the breaker.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent -> err, or an error it wraps, was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// EndpointStatus -> point in time view of one endpoint for the admin status page.
+type EndpointStatus struct {
+	Upstream            string
+	Endpoint            string
+	State               State
+	ConsecutiveFailures int
+	InFlight            int64
+	MaxConcurrent       int64
+	StateChangedAt      time.Time
+	// RetryAt -> when an open breaker lets probes through.
+	RetryAt time.Time
+}
+
+type endpoint struct {
+	name     string
+	breaker  *breaker
+	bulkhead *semaphore.Weighted
+	inFlight atomic.Int64
+}
+
+// Group -> resilience policy for every endpoint of one upstream. safe for concurrent use.
+type Group struct {
+	upstream  string
+	config    Config
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+	now       func() time.Time
+	sleep     func(ctx context.Context, d time.Duration) error
+}
+
+// New -> a Group for upstream. endpoints are listed on the status page before their first call.
+func New(upstream string, config Config, endpoints ...string) *Group {
+	if config.RetryMaxAttempts < 1 {
+		config.RetryMaxAttempts = 1
+	}
+	if config.BulkheadMaxConcurrent < 1 {
+		config.BulkheadMaxConcurrent = 1
+	}
+	g := &Group{
+		upstream:  upstream,
+		config:    config,
+		endpoints: map[string]*endpoint{},
+		now:       time.Now,
+		sleep:     sleepContext,
+	}
+	for _, name := range endpoints {
+		g.endpoint(name)
+	}
+	return g
+}
+
+func (g *Group) Upstream() string {
+	return g.upstream
+}
+
+func (g *Group) endpoint(name string) *endpoint {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ep, ok := g.endpoints[name]; ok {
+		return ep
+	}
+	ep := &endpoint{
+		name:     name,
+		bulkhead: semaphore.NewWeighted(g.config.BulkheadMaxConcurrent),
+	}
+	ep.breaker = newBreaker(g.config, g.now, func(from State, to State) {
+		breakerState.WithLabelValues(g.upstream, name).Set(to.gaugeValue())
+		breakerTransitions.WithLabelValues(g.upstream, name, string(to)).Inc()
+	})
+	breakerState.WithLabelValues(g.upstream, name).Set(StateClosed.gaugeValue())
+	g.endpoints[name] = ep
+	return ep
+}
+
+// Do -> run fn against endpoint behind its bulkhead and circuit breaker. idempotent calls that fail are
+// retried with jittered exponential backoff; fn gets a fresh attempt each time.
+func (g *Group) Do(ctx context.Context, endpointName string, idempotent bool, fn func(ctx context.Context) error) error {
+	ep := g.endpoint(endpointName)
+	attempts := 1
+	if idempotent {
+		attempts = g.config.RetryMaxAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			retries.WithLabelValues(g.upstream, ep.name).Inc()
+			if sleepErr := g.sleep(ctx, g.backoff(attempt)); sleepErr != nil {
+				// the caller gave up waiting; the last attempt's error would hide that.
+				return sleepErr
+			}
+		}
+
+		var retryable bool
+		retryable, err = g.attempt(ctx, ep, fn)
+		if err == nil || !retryable {
+			return err
+		}
+	}
+	return err
+}
+
+// attempt -> one call through the bulkhead and breaker. also reports whether a failure is worth retrying.
+func (g *Group) attempt(ctx context.Context, ep *endpoint, fn func(ctx context.Context) error) (bool, error) {
+	t, ok := ep.breaker.allow()
+	if !ok {
+		calls.WithLabelValues(g.upstream, ep.name, string(outcomeCircuitOpen)).Inc()
+		// the breaker only closes through probes; retrying here would just spin.
+		return false, ErrCircuitOpen
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, g.config.BulkheadMaxWait)
+	err := ep.bulkhead.Acquire(waitCtx, 1)
+	cancel()
+	if err != nil {
+		ep.breaker.done(t, outcomeIgnored)
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		calls.WithLabelValues(g.upstream, ep.name, string(outcomeBulkheadFull)).Inc()
+		return false, ErrBulkheadFull
+	}
+	inFlight.WithLabelValues(g.upstream, ep.name).Set(float64(ep.inFlight.Add(1)))
+
+	err = fn(ctx)
+
+	inFlight.WithLabelValues(g.upstream, ep.name).Set(float64(ep.inFlight.Add(-1)))
+	ep.bulkhead.Release(1)
+
+	result := outcomeSuccess
+	retryable := false
+	var permanent *permanentError
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		// the caller went away; that says nothing about the upstream.
+		result = outcomeIgnored
+		err = ctx.Err()
+	case errors.As(err, &permanent):
+		result = outcomeIgnored
+		err = permanent.err
+	default:
+		result = outcomeFailure
+		retryable = true
+	}
+	ep.breaker.done(t, result)
+	calls.WithLabelValues(g.upstream, ep.name, string(result)).Inc()
+	return retryable, err
+}
+
+// backoff -> full jitter: uniform in [0, min(max, base * 2^(attempt-1))].
+func (g *Group) backoff(attempt int) time.Duration {
+	ceiling := g.config.RetryBaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > g.config.RetryMaxDelay {
+		ceiling = g.config.RetryMaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Status -> every endpoint of the group sorted by name.
+func (g *Group) Status() []EndpointStatus {
+	g.mu.Lock()
+	endpoints := make([]*endpoint, 0, len(g.endpoints))
+	for _, ep := range g.endpoints {
+		endpoints = append(endpoints, ep)
+	}
+	g.mu.Unlock()
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].name < endpoints[j].name
+	})
+
+	statuses := make([]EndpointStatus, 0, len(endpoints))
+	for _, ep := range endpoints {
+		state, failures, changedAt, retryAt := ep.breaker.snapshot()
+		status := EndpointStatus{
+			Upstream:            g.upstream,
+			Endpoint:            ep.name,
+			State:               state,
+			ConsecutiveFailures: failures,
+			InFlight:            ep.inFlight.Load(),
+			MaxConcurrent:       g.config.BulkheadMaxConcurrent,
+			StateChangedAt:      changedAt,
+		}
+		if state == StateOpen {
+			status.RetryAt = retryAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// term -> one search term: a word or a quoted phrase, optionally negated or prefix matched.
+type term struct {
+	words  []string
+	negate bool
+	prefix bool
+	or     bool
+}
+
+// ToTSQuery -> translate a search box query into to_tsquery syntax.
+//
+//	word        all words must match (&)
+//	"a phrase"  words must be adjacent and in order (<->)
+//	pre*        prefix match (:*)
+//	-word       must not match (!)
+//	a OR b      either matches (|)
+//
+// lexemes are reduced to letters and digits and single quoted so user input can never break the tsquery.
+// returns "" when nothing searchable is left.
+func ToTSQuery(q string) string {
+	terms := parseTerms(q)
+
+	var b strings.Builder
+	for _, t := range terms {
+		if b.Len() > 0 {
+			if t.or {
+				b.WriteString(" | ")
+			} else {
+				b.WriteString(" & ")
+			}
+		}
+		if t.negate {
+			b.WriteString("!")
+		}
+		if len(t.words) > 1 {
+			b.WriteString("(")
+		}
+		for i, w := range t.words {
+			if i > 0 {
+				b.WriteString(" <-> ")
+			}
+			b.WriteString("'")
+			b.WriteString(w)
+			b.WriteString("'")
+			if t.prefix && i == len(t.words)-1 {
+				b.WriteString(":*")
+			}
+		}
+		if len(t.words) > 1 {
+			b.WriteString(")")
+		}
+	}
+	return b.String()
+}
+
+func parseTerms(q string) []term {
+	var terms []term
+	pendingOr := false
+	runes := []rune(q)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+
+		t := term{}
+		if r == '-' {
+			t.negate = true
+			i++
+			if i >= len(runes) {
+				break
+			}
+		}
+
+		var raw string
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			raw = string(runes[i+1 : end])
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			raw = string(runes[i:end])
+			i = end
+			if raw == "OR" && !t.negate {
+				pendingOr = len(terms) > 0
+				continue
+			}
+			t.prefix = strings.HasSuffix(raw, "*")
+		}
+
+		t.words = lexemes(raw)
+		if len(t.words) == 0 {
+			continue
+		}
+		t.or = pendingOr
+		pendingOr = false
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// lexemes -> split raw on anything that isn't a letter or digit. "e-mail" becomes the phrase e <-> mail.
+func lexemes(raw string) []string {
+	return strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToTSQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{name: "empty", q: "", want: ""},
+		{name: "whitespace", q: " \t\n ", want: ""},
+		{name: "word", q: "Hello", want: "'hello'"},
+		{name: "words", q: "hello  world", want: "'hello' & 'world'"},
+		{name: "phrase", q: `"quick brown fox"`, want: "('quick' <-> 'brown' <-> 'fox')"},
+		{name: "unterminated phrase", q: `"open phrase`, want: "('open' <-> 'phrase')"},
+		{name: "empty phrase", q: `"" word`, want: "'word'"},
+		{name: "prefix", q: "trans*", want: "'trans':*"},
+		{name: "prefix of a hyphenated word", q: "e-ma*", want: "('e' <-> 'ma':*)"},
+		{name: "star alone", q: "*", want: ""},
+		{name: "negated", q: "-spam eggs", want: "!'spam' & 'eggs'"},
+		{name: "negated phrase", q: `-"bad phrase" good`, want: "!('bad' <-> 'phrase') & 'good'"},
+		{name: "dash alone", q: "-", want: ""},
+		{name: "or", q: "cats OR dogs birds", want: "'cats' | 'dogs' & 'birds'"},
+		{name: "leading and trailing or", q: "OR cats OR", want: "'cats'"},
+		{name: "lowercase or is a word", q: "cats or dogs", want: "'cats' & 'or' & 'dogs'"},
+		{name: "negated or is a word", q: "cats -OR", want: "'cats' & !'or'"},
+		{name: "hyphenated", q: "e-mail", want: "('e' <-> 'mail')"},
+		{name: "apostrophe", q: "don't", want: "('don' <-> 't')"},
+		{name: "digits", q: "covid19 2024", want: "'covid19' & '2024'"},
+		{
+			name: "tsquery operators",
+			q:    `a' | b & !c:* ( ) <-> 'd'`,
+			want: "'a' & 'b' & 'c':* & 'd'",
+		},
+		{name: "operators only", q: `& | ! : * ( ) ' \`, want: ""},
+		{name: "non-latin", q: "Москва café 東京 αθήνα", want: "'москва' & 'café' & '東京' & 'αθήνα'"},
+		{name: "non-latin prefix", q: "naïve* Стр*", want: "'naïve':* & 'стр':*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToTSQuery(tt.q); got != tt.want {
+				t.Errorf("ToTSQuery(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []term
+	}{
+		{q: "", want: nil},
+		{
+			q: `a OR -b "c d"* e*`,
+			want: []term{
+				{words: []string{"a"}},
+				{words: []string{"b"}, negate: true, or: true},
+				{words: []string{"c", "d"}},
+				{words: []string{"e"}, prefix: true},
+			},
+		},
+		{
+			q:    `-"x y`,
+			want: []term{{words: []string{"x", "y"}, negate: true}},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseTerms(tt.q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTerms(%q) = %+v, want %+v", tt.q, got, tt.want)
+		}
+	}
+}
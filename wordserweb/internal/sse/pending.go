@@ -0,0 +1,71 @@
+package sse
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type pendingEntry[T any] struct {
+	owner     string
+	value     T
+	expiresAt time.Time
+}
+
+// Pending -> work handed from the request that starts a stream to the EventSource that runs it. EventSource
+// can only GET, so the POSTed input waits here under a random id. each id can be taken once, by its owner.
+type Pending[T any] struct {
+	mu      sync.Mutex
+	entries map[string]pendingEntry[T]
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+func NewPending[T any](ttl time.Duration) *Pending[T] {
+	return &Pending[T]{
+		entries: map[string]pendingEntry[T]{},
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// Put -> store value for owner and return its id. expired entries are dropped on the way.
+func (p *Pending[T]) Put(owner string, value T) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for key, entry := range p.entries {
+		if now.After(entry.expiresAt) {
+			delete(p.entries, key)
+		}
+	}
+	p.entries[id] = pendingEntry[T]{
+		owner:     owner,
+		value:     value,
+		expiresAt: now.Add(p.ttl),
+	}
+	return id, nil
+}
+
+// Take -> remove and return id's value. false when it is unknown, expired, already taken or not owner's.
+func (p *Pending[T]) Take(owner string, id string) (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var zero T
+	entry, ok := p.entries[id]
+	if !ok || entry.owner != owner {
+		return zero, false
+	}
+	delete(p.entries, id)
+	if p.now().After(entry.expiresAt) {
+		return zero, false
+	}
+	return entry.value, true
+}
@@ -0,0 +1,47 @@
+package sse
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Start -> send the event stream headers. nothing may be written to the response before.
+func Start(c echo.Context) {
+	h := c.Response().Header()
+	h.Set(echo.HeaderContentType, "text/event-stream")
+	h.Set(echo.HeaderCacheControl, "no-cache")
+	h.Set(echo.HeaderConnection, "keep-alive")
+	// stop nginx style proxies from buffering the stream.
+	h.Set("X-Accel-Buffering", "no")
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Flush()
+}
+
+// Event -> write one event and flush it. every line of data becomes its own data field.
+func Event(c echo.Context, event string, data string) error {
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	b.WriteString("\n")
+	if _, err := c.Response().Write([]byte(b.String())); err != nil {
+		return err
+	}
+	c.Response().Flush()
+	return nil
+}
+
+// Ping -> a comment line that keeps idle proxies from closing the stream.
+func Ping(c echo.Context) error {
+	if _, err := c.Response().Write([]byte(": ping\n\n")); err != nil {
+		return err
+	}
+	c.Response().Flush()
+	return nil
+}
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+const selectAnalysisSQL = `SELECT
+	a.id,
+	u.username,
+	a.organization_id,
+	a.original_text,
+	a.options,
+	a.results,
+	a.results->'sentiment'->>'polarity' AS sentiment_polarity,
+	a.timings_ms,
+	a.errors,
+	a.chunks,
+	a.source_url,
+	a.source,
+	a.duration_ms,
+	a.created_at
+FROM analysis.analysis a
+JOIN auth.user_account u ON u.id = a.user_account_id`
+
+// InsertAnalysis -> persist a, owned by a.Username. returns the new analysis id.
+func (d *DB) InsertAnalysis(ctx context.Context, a *Analysis) (int64, error) {
+	results := a.Results
+	if results == nil {
+		results = map[string]json.RawMessage{}
+	}
+	timings := a.TimingsMS
+	if timings == nil {
+		timings = map[string]int64{}
+	}
+	errs := a.Errors
+	if errs == nil {
+		errs = map[string]string{}
+	}
+	chunks := a.Chunks
+	if chunks == nil {
+		chunks = []AnalysisChunk{}
+	}
+
+	var id int64
+	err := d.pool.QueryRow(
+		ctx,
+		`INSERT INTO analysis.analysis (
+			user_account_id,
+			organization_id,
+			original_text,
+			options,
+			results,
+			timings_ms,
+			errors,
+			chunks,
+			source_url,
+			source,
+			duration_ms
+		)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11 FROM auth.user_account WHERE username = $1
+		RETURNING id`,
+		a.Username,
+		a.OrganizationID,
+		a.OriginalText,
+		a.Options,
+		results,
+		timings,
+		errs,
+		chunks,
+		a.SourceURL,
+		a.Source,
+		a.DurationMS,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListAnalyses -> newest first page of username's analyses and the total count.
+func (d *DB) ListAnalyses(ctx context.Context, username string, limit int, offset int) ([]*Analysis, int, error) {
+	var analyses []*Analysis
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&analyses,
+		selectAnalysisSQL+` WHERE u.username = $1 ORDER BY a.created_at DESC, a.id DESC LIMIT $2 OFFSET $3`,
+		username,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := d.pool.QueryRow(
+		ctx,
+		`SELECT count(*) FROM analysis.analysis a
+		JOIN auth.user_account u ON u.id = a.user_account_id
+		WHERE u.username = $1`,
+		username,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	return analyses, total, nil
+}
+
+func (d *DB) GetAnalysis(ctx context.Context, username string, id int64) (*Analysis, error) {
+	var analyses []*Analysis
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&analyses,
+		selectAnalysisSQL+` WHERE u.username = $1 AND a.id = $2`,
+		username,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(analyses) == 0 {
+		return nil, fmt.Errorf("analysis %w", ErrNotFound)
+	}
+	return analyses[0], nil
+}
+
+// UpdateAnalysisResults -> overwrite the results, chunks, timings and errors of a.Username's analysis a.ID.
+func (d *DB) UpdateAnalysisResults(ctx context.Context, a *Analysis) error {
+	results := a.Results
+	if results == nil {
+		results = map[string]json.RawMessage{}
+	}
+	timings := a.TimingsMS
+	if timings == nil {
+		timings = map[string]int64{}
+	}
+	errs := a.Errors
+	if errs == nil {
+		errs = map[string]string{}
+	}
+	chunks := a.Chunks
+	if chunks == nil {
+		chunks = []AnalysisChunk{}
+	}
+
+	tag, err := d.pool.Exec(
+		ctx,
+		`UPDATE analysis.analysis a
+		SET
+			results = $3,
+			timings_ms = $4,
+			errors = $5,
+			chunks = $6
+		FROM auth.user_account u
+		WHERE u.id = a.user_account_id AND u.username = $1 AND a.id = $2`,
+		a.Username,
+		a.ID,
+		results,
+		timings,
+		errs,
+		chunks,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("analysis %w", ErrNotFound)
+	}
+	return nil
+}
+
+func (d *DB) DeleteAnalysis(ctx context.Context, username string, id int64) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`DELETE FROM analysis.analysis a
+		USING auth.user_account u
+		WHERE u.id = a.user_account_id AND u.username = $1 AND a.id = $2`,
+		username,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("analysis %w", ErrNotFound)
+	}
+	return nil
+}
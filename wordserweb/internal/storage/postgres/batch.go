@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	BatchRowStatePending   = "pending"
+	BatchRowStateSucceeded = "succeeded"
+	BatchRowStateFailed    = "failed"
+)
+
+const selectBatchSQL = `SELECT
+	b.id,
+	u.username,
+	b.organization_id,
+	b.filename,
+	b.format,
+	b.text_column,
+	b.columns,
+	b.options,
+	b.job_id,
+	b.created_at,
+	count(r.row_index) AS total_rows,
+	count(r.row_index) FILTER (WHERE r.state = 'pending') AS pending_rows,
+	count(r.row_index) FILTER (WHERE r.state = 'succeeded') AS succeeded_rows,
+	count(r.row_index) FILTER (WHERE r.state = 'failed') AS failed_rows
+FROM batch.batch b
+JOIN auth.user_account u ON u.id = b.user_account_id
+LEFT JOIN batch.row r ON r.batch_id = b.id`
+
+const selectBatchRowSQL = `SELECT
+	r.batch_id,
+	r.row_index,
+	r.source,
+	r.text,
+	r.state,
+	r.results,
+	r.errors,
+	r.job_id,
+	r.job_attempt,
+	r.updated_at
+FROM batch.row r`
+
+// InsertBatch -> persist b, owned by b.Username, and its rows in one go. returns the new batch id.
+func (d *DB) InsertBatch(ctx context.Context, b *Batch, rows []*BatchRow) (int64, error) {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	if err := tx.QueryRow(
+		ctx,
+		`INSERT INTO batch.batch (user_account_id, organization_id, filename, format, text_column, columns, options)
+		SELECT id, $2, $3, $4, $5, $6, $7 FROM auth.user_account WHERE username = $1
+		RETURNING id`,
+		b.Username,
+		b.OrganizationID,
+		b.Filename,
+		b.Format,
+		b.TextColumn,
+		b.Columns,
+		b.Options,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"batch", "row"},
+		[]string{"batch_id", "row_index", "source", "text"},
+		pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+			return []any{id, rows[i].RowIndex, []byte(rows[i].Source), rows[i].Text}, nil
+		}),
+	); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (d *DB) GetBatch(ctx context.Context, username string, id int64) (*Batch, error) {
+	var batches []*Batch
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&batches,
+		selectBatchSQL+` WHERE u.username = $1 AND b.id = $2 GROUP BY b.id, u.username`,
+		username,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, fmt.Errorf("batch %w", ErrNotFound)
+	}
+	return batches[0], nil
+}
+
+// ListBatches -> username's newest batches first.
+func (d *DB) ListBatches(ctx context.Context, username string, limit int) ([]*Batch, error) {
+	var batches []*Batch
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&batches,
+		selectBatchSQL+` WHERE u.username = $1
+		GROUP BY b.id, u.username
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $2`,
+		username,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// SetBatchJob -> record jobID as the job working through batch id.
+func (d *DB) SetBatchJob(ctx context.Context, id int64, jobID int64) error {
+	tag, err := d.pool.Exec(ctx, `UPDATE batch.batch SET job_id = $2 WHERE id = $1`, id, jobID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("batch %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ListBatchRows -> rows of batch id in file order. an empty state lists every row. limit 0 is no limit.
+func (d *DB) ListBatchRows(ctx context.Context, id int64, state string, limit int) ([]*BatchRow, error) {
+	query := selectBatchRowSQL + ` WHERE r.batch_id = $1 AND ($2 = '' OR r.state = $2) ORDER BY r.row_index`
+	args := []any{id, state}
+	if limit > 0 {
+		query += ` LIMIT $3`
+		args = append(args, limit)
+	}
+
+	var rows []*BatchRow
+	if err := pgxscan.Select(ctx, d.pool, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ClaimBatchRows -> claim up to limit pending rows of batch id for job jobID on attempt, in file order. a row
+// claimed by a job that isn't running the attempt that claimed it any more is claimed again; one held by a running
+// attempt is skipped. limit 0 is no limit.
+func (d *DB) ClaimBatchRows(ctx context.Context, id int64, jobID int64, attempt int, limit int) ([]*BatchRow, error) {
+	var rows []*BatchRow
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&rows,
+		`WITH claimed AS (
+			UPDATE batch.row r
+			SET job_id = $2, job_attempt = $3, updated_at = now()
+			WHERE (r.batch_id, r.row_index) IN (
+				SELECT c.batch_id, c.row_index
+				FROM batch.row c
+				LEFT JOIN job.job j ON j.id = c.job_id
+				WHERE c.batch_id = $1 AND c.state = 'pending'
+					AND (j.id IS NULL OR j.state <> 'running' OR j.attempts <> c.job_attempt)
+				ORDER BY c.row_index
+				LIMIT nullif($4, 0)
+				FOR UPDATE OF c SKIP LOCKED
+			)
+			RETURNING r.*
+		)
+		`+strings.Replace(selectBatchRowSQL, "FROM batch.row r", "FROM claimed r", 1)+`
+		ORDER BY r.row_index`,
+		id,
+		jobID,
+		attempt,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ReleaseBatchRows -> give up the claims of job jobID on the rows of batch id still pending, for another job to
+// claim them right away.
+func (d *DB) ReleaseBatchRows(ctx context.Context, id int64, jobID int64) error {
+	_, err := d.pool.Exec(
+		ctx,
+		`UPDATE batch.row SET job_id = NULL, job_attempt = NULL
+		WHERE batch_id = $1 AND job_id = $2 AND state = 'pending'`,
+		id,
+		jobID,
+	)
+	return err
+}
+
+// UpdateBatchRow -> store the state, results and errors of row, only while the job attempt that claimed it still
+// holds it; ErrJobLost otherwise.
+func (d *DB) UpdateBatchRow(ctx context.Context, row *BatchRow) error {
+	results := row.Results
+	if results == nil {
+		results = map[string]json.RawMessage{}
+	}
+	errs := row.Errors
+	if errs == nil {
+		errs = map[string]string{}
+	}
+
+	tag, err := d.pool.Exec(
+		ctx,
+		`UPDATE batch.row
+		SET
+			state = $3,
+			results = $4,
+			errors = $5,
+			updated_at = now()
+		WHERE batch_id = $1 AND row_index = $2
+			AND job_id IS NOT DISTINCT FROM $6 AND job_attempt IS NOT DISTINCT FROM $7`,
+		row.BatchID,
+		row.RowIndex,
+		row.State,
+		results,
+		errs,
+		row.JobID,
+		row.JobAttempt,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("batch row %d: %w", row.RowIndex, ErrJobLost)
+	}
+	return nil
+}
+
+// ResetFailedBatchRows -> make username's failed rows of batch id pending again. returns how many there were.
+func (d *DB) ResetFailedBatchRows(ctx context.Context, username string, id int64) (int64, error) {
+	tag, err := d.pool.Exec(
+		ctx,
+		`UPDATE batch.row r
+		SET state = 'pending', updated_at = now()
+		FROM batch.batch b
+		JOIN auth.user_account u ON u.id = b.user_account_id
+		WHERE b.id = r.batch_id AND u.username = $1 AND r.batch_id = $2 AND r.state = 'failed'`,
+		username,
+		id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
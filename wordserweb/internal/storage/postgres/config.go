@@ -13,6 +13,7 @@ type Config struct {
 	Hostname     string `mapstructure:"POSTGRES_HOSTNAME"`
 	Port         int    `mapstructure:"POSTGRES_PORT"`
 	DatabaseName string `mapstructure:"POSTGRES_DATABASE_NAME"`
+	AutoMigrate  bool   `mapstructure:"POSTGRES_AUTO_MIGRATE"`
 }
 
 func ConfigFromEnv() (Config, error) {
@@ -46,6 +47,11 @@ func ConfigFromEnv() (Config, error) {
 	}
 	viper.SetDefault("POSTGRES_DATABASE_NAME", "wordser")
 
+	if err := viper.BindEnv("POSTGRES_AUTO_MIGRATE"); err != nil {
+		return c, fmt.Errorf("failed to bind 'POSTGRES_AUTO_MIGRATE'")
+	}
+	viper.SetDefault("POSTGRES_AUTO_MIGRATE", false)
+
 	if err := viper.Unmarshal(&c); err != nil {
 		return c, fmt.Errorf("failed to unmarshal config")
 	}
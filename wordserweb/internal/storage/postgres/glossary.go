@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+const selectGlossarySQL = `SELECT
+	g.id,
+	u.username,
+	g.organization_id,
+	g.name,
+	g.source_language,
+	g.target_language,
+	(SELECT count(*) FROM glossary.term t WHERE t.glossary_id = g.id) AS term_count,
+	g.created_at
+FROM glossary.glossary g
+LEFT JOIN auth.user_account u ON u.id = g.user_account_id`
+
+// glossaryWorkspaceSQL -> the glossaries of a workspace: username's own ($1) when orgID ($2) is 0, otherwise the
+// organization's.
+const glossaryWorkspaceSQL = `(CASE WHEN $2 = 0 THEN g.organization_id IS NULL AND u.username = $1
+	ELSE g.organization_id = $2 END)`
+
+const selectGlossaryTermSQL = `SELECT
+	t.id,
+	t.glossary_id,
+	t.source_term,
+	t.target_term,
+	t.created_at
+FROM glossary.term t`
+
+// InsertGlossary -> persist g, owned by g.OrganizationID when set and by g.Username otherwise. returns the new
+// glossary id.
+func (d *DB) InsertGlossary(ctx context.Context, g *Glossary) (int64, error) {
+	var id int64
+	err := d.pool.QueryRow(
+		ctx,
+		`INSERT INTO glossary.glossary (user_account_id, organization_id, name, source_language, target_language)
+		VALUES ((SELECT id FROM auth.user_account WHERE $2::integer IS NULL AND username = $1), $2, $3, $4, $5)
+		RETURNING id`,
+		g.Username,
+		g.OrganizationID,
+		g.Name,
+		g.SourceLanguage,
+		g.TargetLanguage,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetGlossary -> glossary id of the workspace of username and orgID, see glossaryWorkspaceSQL.
+func (d *DB) GetGlossary(ctx context.Context, username string, orgID int, id int64) (*Glossary, error) {
+	var glossaries []*Glossary
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&glossaries,
+		selectGlossarySQL+` WHERE `+glossaryWorkspaceSQL+` AND g.id = $3`,
+		username,
+		orgID,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(glossaries) == 0 {
+		return nil, fmt.Errorf("glossary %w", ErrNotFound)
+	}
+	return glossaries[0], nil
+}
+
+// ListGlossaries -> the glossaries of the workspace of username and orgID, by name.
+func (d *DB) ListGlossaries(ctx context.Context, username string, orgID int) ([]*Glossary, error) {
+	var glossaries []*Glossary
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&glossaries,
+		selectGlossarySQL+` WHERE `+glossaryWorkspaceSQL+` ORDER BY g.name, g.id`,
+		username,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return glossaries, nil
+}
+
+func (d *DB) DeleteGlossary(ctx context.Context, username string, orgID int, id int64) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`DELETE FROM glossary.glossary g
+		WHERE g.id = $3 AND (CASE WHEN $2 = 0
+			THEN g.organization_id IS NULL AND g.user_account_id = (SELECT id FROM auth.user_account WHERE username = $1)
+			ELSE g.organization_id = $2 END)`,
+		username,
+		orgID,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("glossary %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ListGlossaryTerms -> the terms of glossary id, by source term. the caller has checked the glossary is theirs.
+func (d *DB) ListGlossaryTerms(ctx context.Context, glossaryID int64) ([]*GlossaryTerm, error) {
+	var terms []*GlossaryTerm
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&terms,
+		selectGlossaryTermSQL+` WHERE t.glossary_id = $1 ORDER BY lower(t.source_term), t.id`,
+		glossaryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// UpsertGlossaryTerms -> add terms to glossary id in one go. a term whose source term is already there replaces
+// its target term.
+func (d *DB) UpsertGlossaryTerms(ctx context.Context, glossaryID int64, terms []*GlossaryTerm) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, t := range terms {
+		if _, err := tx.Exec(
+			ctx,
+			`INSERT INTO glossary.term (glossary_id, source_term, target_term) VALUES ($1, $2, $3)
+			ON CONFLICT ON CONSTRAINT unique_glossary_source_term DO UPDATE SET target_term = EXCLUDED.target_term`,
+			glossaryID,
+			t.SourceTerm,
+			t.TargetTerm,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (d *DB) DeleteGlossaryTerm(ctx context.Context, glossaryID int64, id int64) error {
+	tag, err := d.pool.Exec(ctx, `DELETE FROM glossary.term WHERE glossary_id = $1 AND id = $2`, glossaryID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("glossary term %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ListApplicableGlossaryTerms -> the terms of the workspace's glossaries for translating source to target,
+// including the glossaries of every language pair.
+func (d *DB) ListApplicableGlossaryTerms(
+	ctx context.Context,
+	username string,
+	orgID int,
+	source string,
+	target string,
+) ([]*GlossaryTerm, error) {
+	var terms []*GlossaryTerm
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&terms,
+		selectGlossaryTermSQL+`
+		JOIN glossary.glossary g ON g.id = t.glossary_id
+		LEFT JOIN auth.user_account u ON u.id = g.user_account_id
+		WHERE `+glossaryWorkspaceSQL+`
+			AND ((g.source_language = $3 AND g.target_language = $4) OR (g.source_language = '' AND g.target_language = ''))
+		ORDER BY t.glossary_id, t.id`,
+		username,
+		orgID,
+		source,
+		target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
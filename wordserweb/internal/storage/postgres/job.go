@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+const (
+	JobStateQueued    = "queued"
+	JobStateRunning   = "running"
+	JobStateSucceeded = "succeeded"
+	JobStateFailed    = "failed"
+)
+
+const jobColumnsSQL = `
+	j.id,
+	u.username,
+	j.organization_id,
+	j.kind,
+	j.state,
+	j.payload,
+	j.result,
+	j.last_error,
+	j.attempts,
+	j.max_attempts,
+	j.run_at,
+	j.locked_at,
+	j.created_at,
+	j.updated_at,
+	j.finished_at`
+
+// InsertJob -> queue j, owned by j.Username, to run now. returns the new job id.
+func (d *DB) InsertJob(ctx context.Context, j *Job) (int64, error) {
+	var id int64
+	err := d.pool.QueryRow(
+		ctx,
+		`INSERT INTO job.job (user_account_id, organization_id, kind, payload, max_attempts)
+		SELECT id, $2, $3, $4, $5 FROM auth.user_account WHERE username = $1
+		RETURNING id`,
+		j.Username,
+		j.OrganizationID,
+		j.Kind,
+		[]byte(j.Payload),
+		j.MaxAttempts,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ClaimJob -> mark the next due job running and count the attempt. a running job whose lock is older than lease
+// is due again; its worker is assumed dead. SKIP LOCKED lets concurrent workers claim different jobs without
+// waiting on each other. nil when nothing is due.
+func (d *DB) ClaimJob(ctx context.Context, lease time.Duration) (*Job, error) {
+	var jobs []*Job
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&jobs,
+		`UPDATE job.job j
+		SET state = 'running', attempts = j.attempts + 1, locked_at = now(), updated_at = now()
+		FROM auth.user_account u
+		WHERE u.id = j.user_account_id AND j.id = (
+			SELECT id FROM job.job
+			WHERE (state = 'queued' AND run_at <= now())
+				OR (state = 'running' AND locked_at < now() - make_interval(secs => $1))
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING`+jobColumnsSQL,
+		lease.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return jobs[0], nil
+}
+
+// CompleteJob -> job id succeeded with result on attempt.
+func (d *DB) CompleteJob(ctx context.Context, id int64, attempt int, result json.RawMessage) error {
+	return d.finishJob(ctx, id, attempt, JobStateSucceeded, []byte(result), nil)
+}
+
+// FailJob -> job id failed for good on attempt.
+func (d *DB) FailJob(ctx context.Context, id int64, attempt int, lastError string) error {
+	return d.finishJob(ctx, id, attempt, JobStateFailed, nil, &lastError)
+}
+
+// finishJob, RetryJob -> only while attempt still holds the job. once its lease ran out and another worker claimed
+// it, the attempt's outcome is dropped with ErrJobLost rather than overwriting the newer attempt's.
+func (d *DB) finishJob(ctx context.Context, id int64, attempt int, state string, result []byte, lastError *string) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`UPDATE job.job
+		SET state = $3, result = $4, last_error = $5, locked_at = NULL, updated_at = now(), finished_at = now()
+		WHERE id = $1 AND state = 'running' AND attempts = $2`,
+		id,
+		attempt,
+		state,
+		result,
+		lastError,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("job %d attempt %d: %w", id, attempt, ErrJobLost)
+	}
+	return nil
+}
+
+// RetryJob -> queue job id again to run at runAt after attempt failed.
+func (d *DB) RetryJob(ctx context.Context, id int64, attempt int, lastError string, runAt time.Time) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`UPDATE job.job
+		SET state = 'queued', last_error = $3, run_at = $4, locked_at = NULL, updated_at = now()
+		WHERE id = $1 AND state = 'running' AND attempts = $2`,
+		id,
+		attempt,
+		lastError,
+		runAt,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("job %d attempt %d: %w", id, attempt, ErrJobLost)
+	}
+	return nil
+}
+
+func (d *DB) GetJob(ctx context.Context, username string, id int64) (*Job, error) {
+	var jobs []*Job
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&jobs,
+		`SELECT`+jobColumnsSQL+`
+		FROM job.job j
+		JOIN auth.user_account u ON u.id = j.user_account_id
+		WHERE u.username = $1 AND j.id = $2`,
+		username,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("job %w", ErrNotFound)
+	}
+	return jobs[0], nil
+}
+
+// ListJobs -> username's newest jobs first.
+func (d *DB) ListJobs(ctx context.Context, username string, limit int) ([]*Job, error) {
+	var jobs []*Job
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&jobs,
+		`SELECT`+jobColumnsSQL+`
+		FROM job.job j
+		JOIN auth.user_account u ON u.id = j.user_account_id
+		WHERE u.username = $1
+		ORDER BY j.created_at DESC, j.id DESC
+		LIMIT $2`,
+		username,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+const selectMemorySegmentSQL = `SELECT
+	s.id,
+	u.username,
+	s.organization_id,
+	s.source_language,
+	s.target_language,
+	s.source_key,
+	s.source_text,
+	s.target_text,
+	s.origin,
+	s.created_at,
+	s.updated_at
+FROM memory.segment s
+LEFT JOIN auth.user_account u ON u.id = s.user_account_id`
+
+// memoryWorkspaceSQL -> the memory of a workspace: username's own ($1) when orgID ($2) is 0, otherwise the
+// organization's.
+const memoryWorkspaceSQL = `(CASE WHEN $2 = 0 THEN s.organization_id IS NULL AND u.username = $1
+	ELSE s.organization_id = $2 END)`
+
+// upsertUserMemorySegmentSQL/upsertOrganizationMemorySegmentSQL -> a segment pair added to a workspace's memory.
+// one it already has for the source key takes the new target text.
+const (
+	upsertUserMemorySegmentSQL = `INSERT INTO memory.segment
+		(user_account_id, source_language, target_language, source_key, source_text, target_text, origin)
+	VALUES ((SELECT id FROM auth.user_account WHERE username = $1), $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_account_id, source_language, target_language, md5(source_key)) WHERE organization_id IS NULL
+	DO UPDATE SET source_text = EXCLUDED.source_text, target_text = EXCLUDED.target_text, origin = EXCLUDED.origin,
+		updated_at = now()`
+	upsertOrganizationMemorySegmentSQL = `INSERT INTO memory.segment
+		(organization_id, source_language, target_language, source_key, source_text, target_text, origin)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (organization_id, source_language, target_language, md5(source_key)) WHERE organization_id IS NOT NULL
+	DO UPDATE SET source_text = EXCLUDED.source_text, target_text = EXCLUDED.target_text, origin = EXCLUDED.origin,
+		updated_at = now()`
+)
+
+// UpsertMemorySegments -> add segments in one go, each to the memory of its OrganizationID when set and of its
+// Username otherwise.
+func (d *DB) UpsertMemorySegments(ctx context.Context, segments []*MemorySegment) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, s := range segments {
+		sql, owner := upsertUserMemorySegmentSQL, any(s.Username)
+		if s.OrganizationID != nil {
+			sql, owner = upsertOrganizationMemorySegmentSQL, s.OrganizationID
+		}
+		if _, err := tx.Exec(
+			ctx,
+			sql,
+			owner,
+			s.SourceLanguage,
+			s.TargetLanguage,
+			s.SourceKey,
+			s.SourceText,
+			s.TargetText,
+			s.Origin,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// GetMemorySegments -> the segment pairs for translating source to target whose source key is one of keys, in the
+// workspace of username and orgID, see memoryWorkspaceSQL.
+func (d *DB) GetMemorySegments(
+	ctx context.Context,
+	username string,
+	orgID int,
+	source string,
+	target string,
+	keys []string,
+) ([]*MemorySegment, error) {
+	var segments []*MemorySegment
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&segments,
+		selectMemorySegmentSQL+` WHERE `+memoryWorkspaceSQL+`
+			AND s.source_language = $3 AND s.target_language = $4
+			AND md5(s.source_key) = ANY(ARRAY(SELECT md5(k) FROM unnest($5::text[]) k))
+			AND s.source_key = ANY($5)`,
+		username,
+		orgID,
+		source,
+		target,
+		keys,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// ListSimilarMemorySegments -> at most limit segment pairs for translating source to target whose source key
+// shares enough trigrams with key, most similar first.
+func (d *DB) ListSimilarMemorySegments(
+	ctx context.Context,
+	username string,
+	orgID int,
+	source string,
+	target string,
+	key string,
+	limit int,
+) ([]*MemorySegment, error) {
+	var segments []*MemorySegment
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&segments,
+		selectMemorySegmentSQL+` WHERE `+memoryWorkspaceSQL+`
+			AND s.source_language = $3 AND s.target_language = $4 AND s.source_key % $5
+		ORDER BY similarity(s.source_key, $5) DESC, s.id
+		LIMIT $6`,
+		username,
+		orgID,
+		source,
+		target,
+		key,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// ListMemorySegments -> the segment pairs for translating source to target in the workspace, oldest first.
+func (d *DB) ListMemorySegments(
+	ctx context.Context,
+	username string,
+	orgID int,
+	source string,
+	target string,
+) ([]*MemorySegment, error) {
+	var segments []*MemorySegment
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&segments,
+		selectMemorySegmentSQL+` WHERE `+memoryWorkspaceSQL+`
+			AND s.source_language = $3 AND s.target_language = $4
+		ORDER BY s.id`,
+		username,
+		orgID,
+		source,
+		target,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// ListMemoryPairs -> the language pairs of the workspace's memory, by source then target language.
+func (d *DB) ListMemoryPairs(ctx context.Context, username string, orgID int) ([]*MemoryPair, error) {
+	var pairs []*MemoryPair
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&pairs,
+		`SELECT s.source_language, s.target_language, count(*) AS segment_count, max(s.updated_at) AS updated_at
+		FROM memory.segment s
+		LEFT JOIN auth.user_account u ON u.id = s.user_account_id
+		WHERE `+memoryWorkspaceSQL+`
+		GROUP BY s.source_language, s.target_language
+		ORDER BY s.source_language, s.target_language`,
+		username,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// DeleteMemoryPair -> remove the segment pairs for translating source to target from the workspace's memory.
+// returns how many were removed.
+func (d *DB) DeleteMemoryPair(ctx context.Context, username string, orgID int, source string, target string) (int64, error) {
+	tag, err := d.pool.Exec(
+		ctx,
+		`DELETE FROM memory.segment s
+		WHERE s.source_language = $3 AND s.target_language = $4 AND (CASE WHEN $2 = 0
+			THEN s.organization_id IS NULL AND s.user_account_id = (SELECT id FROM auth.user_account WHERE username = $1)
+			ELSE s.organization_id = $2 END)`,
+		username,
+		orgID,
+		source,
+		target,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
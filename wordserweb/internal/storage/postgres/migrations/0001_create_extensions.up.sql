@@ -0,0 +1 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
\ No newline at end of file
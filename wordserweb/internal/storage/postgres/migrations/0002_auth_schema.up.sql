@@ -1,6 +1,6 @@
-CREATE SCHEMA auth;
+CREATE SCHEMA IF NOT EXISTS auth;
 
-CREATE TABLE auth.user_account (
+CREATE TABLE IF NOT EXISTS auth.user_account (
     id              integer PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
     username        varchar(40) NOT NULL,
     password        text,
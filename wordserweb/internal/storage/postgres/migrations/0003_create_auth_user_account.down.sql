@@ -0,0 +1 @@
+DELETE FROM auth.user_account WHERE username = 'admin';
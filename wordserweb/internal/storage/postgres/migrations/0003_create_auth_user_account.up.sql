@@ -0,0 +1,7 @@
+INSERT into auth.user_account (
+    username,
+    password
+)
+VALUES 
+('admin', crypt('admin', gen_salt('bf', 8)))
+ON CONFLICT ON CONSTRAINT unique_user_account DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE auth.user_account DROP COLUMN IF EXISTS role;
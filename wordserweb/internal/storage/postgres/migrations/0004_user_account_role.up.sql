@@ -0,0 +1,4 @@
+ALTER TABLE auth.user_account
+    ADD COLUMN IF NOT EXISTS role varchar(20) NOT NULL DEFAULT 'user';
+
+UPDATE auth.user_account SET role = 'admin' WHERE username = 'admin';
@@ -0,0 +1,11 @@
+CREATE SCHEMA IF NOT EXISTS usage;
+
+CREATE TABLE IF NOT EXISTS usage.api_call (
+    id              bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    api             varchar(40) NOT NULL,
+    char_count      integer NOT NULL,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS api_call_user_account_created_at_idx ON usage.api_call (user_account_id, created_at);
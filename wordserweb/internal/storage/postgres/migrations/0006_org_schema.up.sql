@@ -0,0 +1,35 @@
+CREATE SCHEMA IF NOT EXISTS org;
+
+CREATE TABLE IF NOT EXISTS org.organization (
+    id              integer PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    name            varchar(80) NOT NULL,
+    created_by      integer REFERENCES auth.user_account(id) ON DELETE SET NULL,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS org.membership (
+    organization_id integer NOT NULL REFERENCES org.organization(id) ON DELETE CASCADE,
+    user_account_id integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    role            varchar(20) NOT NULL DEFAULT 'member',
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (organization_id, user_account_id)
+);
+
+CREATE INDEX IF NOT EXISTS membership_user_account_idx ON org.membership (user_account_id);
+
+-- exactly one of invitee_username/invitee_email is set. the token itself is never stored, only its sha256.
+CREATE TABLE IF NOT EXISTS org.invitation (
+    id              integer PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    organization_id integer NOT NULL REFERENCES org.organization(id) ON DELETE CASCADE,
+    token_hash      text NOT NULL,
+    invitee_username varchar(40),
+    invitee_email   varchar(254),
+    role            varchar(20) NOT NULL DEFAULT 'member',
+    invited_by      integer REFERENCES auth.user_account(id) ON DELETE SET NULL,
+    expires_at      timestamptz NOT NULL,
+    accepted_at     timestamptz,
+    accepted_by     integer REFERENCES auth.user_account(id) ON DELETE SET NULL,
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT unique_invitation_token_hash UNIQUE(token_hash),
+    CONSTRAINT invitation_invitee CHECK ((invitee_username IS NULL) <> (invitee_email IS NULL))
+);
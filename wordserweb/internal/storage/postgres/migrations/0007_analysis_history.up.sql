@@ -0,0 +1,20 @@
+CREATE SCHEMA IF NOT EXISTS analysis;
+
+-- options holds the selected analyzers (summary, sentiment, keyword). timings_ms and errors are keyed by analyzer.
+CREATE TABLE IF NOT EXISTS analysis.analysis (
+    id                  bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id     integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    organization_id     integer REFERENCES org.organization(id) ON DELETE SET NULL,
+    original_text       text NOT NULL,
+    options             text[] NOT NULL,
+    summary             text,
+    sentiment_polarity  varchar(20),
+    sentiment_score     double precision,
+    keywords            jsonb NOT NULL DEFAULT '[]',
+    timings_ms          jsonb NOT NULL DEFAULT '{}',
+    errors              jsonb NOT NULL DEFAULT '{}',
+    duration_ms         integer NOT NULL,
+    created_at          timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS analysis_user_account_created_at_idx ON analysis.analysis (user_account_id, created_at DESC);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS analysis.analysis_keywords_idx;
+DROP INDEX IF EXISTS analysis.analysis_search_vector_idx;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS search_vector;
+
+DROP SCHEMA IF EXISTS translation CASCADE;
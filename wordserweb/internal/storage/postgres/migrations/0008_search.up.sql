@@ -0,0 +1,31 @@
+CREATE SCHEMA IF NOT EXISTS translation;
+
+CREATE TABLE IF NOT EXISTS translation.translation (
+    id                  bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id     integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    organization_id     integer REFERENCES org.organization(id) ON DELETE SET NULL,
+    source_language     varchar(10) NOT NULL,
+    target_language     varchar(10) NOT NULL,
+    source_text         text NOT NULL,
+    translated_text     text NOT NULL,
+    created_at          timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS translation_user_account_created_at_idx ON translation.translation (user_account_id, created_at DESC);
+
+-- summaries and keywords outrank the original text. keywords jsonb is [{"text": .., "score": ..}]; only the strings are indexed.
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english'::regconfig, coalesce(summary, '')), 'A') ||
+    setweight(jsonb_to_tsvector('english'::regconfig, keywords, '["string"]'), 'A') ||
+    setweight(to_tsvector('english'::regconfig, original_text), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS analysis_search_vector_idx ON analysis.analysis USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS analysis_keywords_idx ON analysis.analysis USING GIN (keywords jsonb_path_ops);
+
+-- translations span languages so they use the language agnostic 'simple' configuration.
+ALTER TABLE translation.translation ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple'::regconfig, source_text) || to_tsvector('simple'::regconfig, translated_text)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS translation_search_vector_idx ON translation.translation USING GIN (search_vector);
@@ -0,0 +1 @@
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS chunks;
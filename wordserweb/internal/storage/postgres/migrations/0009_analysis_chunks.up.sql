@@ -0,0 +1,2 @@
+-- per chunk results of documents too long for a single analyzer call. empty for everything else.
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS chunks jsonb NOT NULL DEFAULT '[]';
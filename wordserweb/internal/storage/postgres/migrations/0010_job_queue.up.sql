@@ -0,0 +1,25 @@
+CREATE SCHEMA IF NOT EXISTS job;
+
+-- kind picks the handler that runs the job; payload is its json input and result its json output.
+-- run_at is when a queued job is next due. locked_at is when a worker claimed a running job; a running job whose
+-- worker died is claimed again once its lease runs out.
+CREATE TABLE IF NOT EXISTS job.job (
+    id               bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id  integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    organization_id  integer REFERENCES org.organization(id) ON DELETE SET NULL,
+    kind             varchar(20) NOT NULL,
+    state            varchar(20) NOT NULL DEFAULT 'queued' CHECK (state IN ('queued', 'running', 'succeeded', 'failed')),
+    payload          jsonb NOT NULL,
+    result           jsonb,
+    last_error       text,
+    attempts         integer NOT NULL DEFAULT 0,
+    max_attempts     integer NOT NULL,
+    run_at           timestamptz NOT NULL DEFAULT now(),
+    locked_at        timestamptz,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    updated_at       timestamptz NOT NULL DEFAULT now(),
+    finished_at      timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS job_due_idx ON job.job (run_at, id) WHERE state IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS job_user_account_created_at_idx ON job.job (user_account_id, created_at DESC);
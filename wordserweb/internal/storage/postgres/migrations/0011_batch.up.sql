@@ -0,0 +1,37 @@
+CREATE SCHEMA IF NOT EXISTS batch;
+
+-- columns are the uploaded file's columns, or jsonl keys, in order. options holds the selected analyzers.
+-- job_id is the latest job working through the batch.
+CREATE TABLE IF NOT EXISTS batch.batch (
+    id               bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id  integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    organization_id  integer REFERENCES org.organization(id) ON DELETE SET NULL,
+    filename         text NOT NULL,
+    format           varchar(10) NOT NULL CHECK (format IN ('csv', 'jsonl')),
+    text_column      text NOT NULL,
+    columns          text[] NOT NULL,
+    options          text[] NOT NULL,
+    job_id           bigint REFERENCES job.job(id) ON DELETE SET NULL,
+    created_at       timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS batch_user_account_created_at_idx ON batch.batch (user_account_id, created_at DESC);
+
+-- source is the original row as a json object. a row is pending until every analyzer has run on it and failed when
+-- any of them failed; errors and the results that did come back are kept either way.
+CREATE TABLE IF NOT EXISTS batch.row (
+    batch_id            bigint NOT NULL REFERENCES batch.batch(id) ON DELETE CASCADE,
+    row_index           integer NOT NULL,
+    source              jsonb NOT NULL,
+    text                text NOT NULL,
+    state               varchar(20) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'succeeded', 'failed')),
+    summary             text,
+    sentiment_polarity  varchar(20),
+    sentiment_score     double precision,
+    keywords            jsonb,
+    errors              jsonb NOT NULL DEFAULT '{}',
+    updated_at          timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (batch_id, row_index)
+);
+
+CREATE INDEX IF NOT EXISTS batch_row_state_idx ON batch.row (batch_id, state);
@@ -0,0 +1,31 @@
+CREATE SCHEMA IF NOT EXISTS webhook;
+
+-- events lists the event names sent to the endpoint. secret signs every payload sent to it.
+CREATE TABLE IF NOT EXISTS webhook.endpoint (
+    id               bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id  integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    url              text NOT NULL,
+    secret           text NOT NULL,
+    events           text[] NOT NULL,
+    created_at       timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS endpoint_user_account_idx ON webhook.endpoint (user_account_id);
+
+-- one row per event sent to an endpoint; the delivery log. payload is the event's data, wrapped and signed when
+-- sent. a delivery is pending until an attempt gets a 2xx or the attempts run out.
+CREATE TABLE IF NOT EXISTS webhook.delivery (
+    id               bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    endpoint_id      bigint NOT NULL REFERENCES webhook.endpoint(id) ON DELETE CASCADE,
+    event            varchar(50) NOT NULL,
+    payload          jsonb NOT NULL,
+    state            varchar(20) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'succeeded', 'failed')),
+    attempts         integer NOT NULL DEFAULT 0,
+    status_code      integer,
+    last_error       text,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    updated_at       timestamptz NOT NULL DEFAULT now(),
+    delivered_at     timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS delivery_endpoint_created_at_idx ON webhook.delivery (endpoint_id, created_at DESC);
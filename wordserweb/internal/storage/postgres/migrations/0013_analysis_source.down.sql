@@ -0,0 +1,2 @@
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS source;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS source_url;
@@ -0,0 +1,3 @@
+-- source_url is the page an analysis was fetched from and source how the fetch went. both NULL for pasted text.
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS source_url text;
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS source jsonb;
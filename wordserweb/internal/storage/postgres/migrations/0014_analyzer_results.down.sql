@@ -0,0 +1,49 @@
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS summary text;
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS sentiment_polarity varchar(20);
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS sentiment_score double precision;
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS keywords jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE batch.row ADD COLUMN IF NOT EXISTS summary text;
+ALTER TABLE batch.row ADD COLUMN IF NOT EXISTS sentiment_polarity varchar(20);
+ALTER TABLE batch.row ADD COLUMN IF NOT EXISTS sentiment_score double precision;
+ALTER TABLE batch.row ADD COLUMN IF NOT EXISTS keywords jsonb;
+
+-- results of analyzers other than these three are lost.
+UPDATE analysis.analysis SET
+    summary = results->'summary'->>'summary',
+    sentiment_polarity = results->'sentiment'->>'polarity',
+    sentiment_score = (results->'sentiment'->>'score')::double precision,
+    keywords = coalesce(results->'keyword'->'keywords', '[]');
+
+UPDATE batch.row SET
+    summary = results->'summary'->>'summary',
+    sentiment_polarity = results->'sentiment'->>'polarity',
+    sentiment_score = (results->'sentiment'->>'score')::double precision,
+    keywords = results->'keyword'->'keywords';
+
+UPDATE analysis.analysis a SET chunks = (
+    SELECT jsonb_agg(jsonb_strip_nulls(jsonb_build_object(
+        'index', c->'index',
+        'text', c->'text',
+        'summary', c->'results'->'summary'->'summary',
+        'sentiment_polarity', c->'results'->'sentiment'->'polarity',
+        'sentiment_score', c->'results'->'sentiment'->'score',
+        'keywords', c->'results'->'keyword'->'keywords',
+        'errors', c->'errors'
+    )) ORDER BY n)
+    FROM jsonb_array_elements(a.chunks) WITH ORDINALITY AS chunk(c, n)
+)
+WHERE jsonb_array_length(a.chunks) > 0;
+
+DROP INDEX IF EXISTS analysis.analysis_search_vector_idx;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS results;
+ALTER TABLE batch.row DROP COLUMN IF EXISTS results;
+
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english'::regconfig, coalesce(summary, '')), 'A') ||
+    setweight(jsonb_to_tsvector('english'::regconfig, keywords, '["string"]'), 'A') ||
+    setweight(to_tsvector('english'::regconfig, original_text), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS analysis_search_vector_idx ON analysis.analysis USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS analysis_keywords_idx ON analysis.analysis USING GIN (keywords jsonb_path_ops);
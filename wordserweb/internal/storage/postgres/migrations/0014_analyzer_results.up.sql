@@ -0,0 +1,57 @@
+-- results holds each analyzer's result keyed by analyzer name, as the analyzer encodes it, replacing a column per
+-- analyzer. chunks hold theirs the same way.
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS results jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE batch.row ADD COLUMN IF NOT EXISTS results jsonb NOT NULL DEFAULT '{}';
+
+UPDATE analysis.analysis SET results = jsonb_strip_nulls(jsonb_build_object(
+    'summary', CASE WHEN summary IS NOT NULL THEN jsonb_build_object('summary', summary) END,
+    'sentiment', CASE WHEN sentiment_polarity IS NOT NULL THEN
+        jsonb_build_object('polarity', sentiment_polarity, 'score', sentiment_score) END,
+    'keyword', CASE WHEN 'keyword' = ANY(options) AND NOT errors ? 'keyword' THEN
+        jsonb_build_object('keywords', keywords) END
+));
+
+UPDATE batch.row SET results = jsonb_strip_nulls(jsonb_build_object(
+    'summary', CASE WHEN summary IS NOT NULL THEN jsonb_build_object('summary', summary) END,
+    'sentiment', CASE WHEN sentiment_polarity IS NOT NULL THEN
+        jsonb_build_object('polarity', sentiment_polarity, 'score', sentiment_score) END,
+    'keyword', CASE WHEN keywords IS NOT NULL THEN jsonb_build_object('keywords', keywords) END
+));
+
+UPDATE analysis.analysis a SET chunks = (
+    SELECT jsonb_agg(jsonb_build_object(
+        'index', c->'index',
+        'text', c->'text',
+        'results', jsonb_strip_nulls(jsonb_build_object(
+            'summary', CASE WHEN c ? 'summary' THEN jsonb_build_object('summary', c->'summary') END,
+            'sentiment', CASE WHEN c ? 'sentiment_polarity' THEN
+                jsonb_build_object('polarity', c->'sentiment_polarity', 'score', c->'sentiment_score') END,
+            'keyword', CASE WHEN c ? 'keywords' THEN jsonb_build_object('keywords', c->'keywords') END
+        )),
+        'errors', coalesce(c->'errors', '{}')
+    ) ORDER BY n)
+    FROM jsonb_array_elements(a.chunks) WITH ORDINALITY AS chunk(c, n)
+)
+WHERE jsonb_array_length(a.chunks) > 0;
+
+DROP INDEX IF EXISTS analysis.analysis_keywords_idx;
+DROP INDEX IF EXISTS analysis.analysis_search_vector_idx;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS summary;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS sentiment_polarity;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS sentiment_score;
+ALTER TABLE analysis.analysis DROP COLUMN IF EXISTS keywords;
+ALTER TABLE batch.row DROP COLUMN IF EXISTS summary;
+ALTER TABLE batch.row DROP COLUMN IF EXISTS sentiment_polarity;
+ALTER TABLE batch.row DROP COLUMN IF EXISTS sentiment_score;
+ALTER TABLE batch.row DROP COLUMN IF EXISTS keywords;
+
+-- as in 0008_search, from the summary and keyword results.
+ALTER TABLE analysis.analysis ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english'::regconfig, coalesce(results->'summary'->>'summary', '')), 'A') ||
+    setweight(jsonb_to_tsvector('english'::regconfig, coalesce(results->'keyword'->'keywords', '[]'), '["string"]'), 'A') ||
+    setweight(to_tsvector('english'::regconfig, original_text), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS analysis_search_vector_idx ON analysis.analysis USING GIN (search_vector);
@@ -0,0 +1 @@
+ALTER TABLE translation.translation DROP COLUMN IF EXISTS provider;
@@ -0,0 +1,3 @@
+-- provider is the translate provider that translated it. translations before providers all came from the partner.
+ALTER TABLE translation.translation ADD COLUMN IF NOT EXISTS provider varchar(40) NOT NULL DEFAULT 'partner';
+ALTER TABLE translation.translation ALTER COLUMN provider DROP DEFAULT;
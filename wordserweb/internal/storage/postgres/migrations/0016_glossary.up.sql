@@ -0,0 +1,27 @@
+CREATE SCHEMA IF NOT EXISTS glossary;
+
+-- a glossary belongs to a user's personal workspace or to an organization, never both. an empty source_language
+-- and target_language apply it to every language pair; it then only holds do not translate terms.
+CREATE TABLE IF NOT EXISTS glossary.glossary (
+    id               bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id  integer REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    organization_id  integer REFERENCES org.organization(id) ON DELETE CASCADE,
+    name             varchar(80) NOT NULL,
+    source_language  varchar(35) NOT NULL DEFAULT '',
+    target_language  varchar(35) NOT NULL DEFAULT '',
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT glossary_owner CHECK ((user_account_id IS NULL) <> (organization_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS glossary_user_account_idx ON glossary.glossary (user_account_id);
+CREATE INDEX IF NOT EXISTS glossary_organization_idx ON glossary.glossary (organization_id);
+
+-- a NULL target_term is a do not translate term: it is kept as it is in the source text.
+CREATE TABLE IF NOT EXISTS glossary.term (
+    id               bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    glossary_id      bigint NOT NULL REFERENCES glossary.glossary(id) ON DELETE CASCADE,
+    source_term      text NOT NULL,
+    target_term      text,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT unique_glossary_source_term UNIQUE (glossary_id, source_term)
+);
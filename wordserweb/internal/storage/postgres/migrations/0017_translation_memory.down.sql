@@ -0,0 +1,2 @@
+-- pg_trgm stays; other schemas may have come to use it.
+DROP SCHEMA IF EXISTS memory CASCADE;
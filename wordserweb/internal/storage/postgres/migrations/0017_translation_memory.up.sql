@@ -0,0 +1,29 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE SCHEMA IF NOT EXISTS memory;
+
+-- a segment pair belongs to a user's personal workspace or to an organization, never both. source_key is the
+-- source text with its whitespace collapsed; exact matches compare it, fuzzy matches its trigrams.
+CREATE TABLE IF NOT EXISTS memory.segment (
+    id               bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id  integer REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    organization_id  integer REFERENCES org.organization(id) ON DELETE CASCADE,
+    source_language  varchar(35) NOT NULL,
+    target_language  varchar(35) NOT NULL,
+    source_key       text NOT NULL,
+    source_text      text NOT NULL,
+    target_text      text NOT NULL,
+    origin           varchar(20) NOT NULL,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    updated_at       timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT segment_owner CHECK ((user_account_id IS NULL) <> (organization_id IS NULL))
+);
+
+-- md5 keeps long segments under the btree row limit.
+CREATE UNIQUE INDEX IF NOT EXISTS segment_user_account_key_idx
+    ON memory.segment (user_account_id, source_language, target_language, md5(source_key))
+    WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS segment_organization_key_idx
+    ON memory.segment (organization_id, source_language, target_language, md5(source_key))
+    WHERE organization_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS segment_source_key_trgm_idx ON memory.segment USING GIN (source_key gin_trgm_ops);
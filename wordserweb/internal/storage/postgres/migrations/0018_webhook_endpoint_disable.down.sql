@@ -0,0 +1,2 @@
+ALTER TABLE webhook.endpoint DROP COLUMN IF EXISTS disabled_at;
+ALTER TABLE webhook.endpoint DROP COLUMN IF EXISTS failed_deliveries;
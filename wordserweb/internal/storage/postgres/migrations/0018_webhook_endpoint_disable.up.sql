@@ -0,0 +1,4 @@
+-- failed_deliveries counts the endpoint's deliveries in a row that failed for good; disabled_at is set once there
+-- are too many, and publishing skips the endpoint until a delivery to it succeeds again.
+ALTER TABLE webhook.endpoint ADD COLUMN IF NOT EXISTS failed_deliveries integer NOT NULL DEFAULT 0;
+ALTER TABLE webhook.endpoint ADD COLUMN IF NOT EXISTS disabled_at timestamptz;
@@ -0,0 +1,2 @@
+ALTER TABLE batch.row DROP COLUMN IF EXISTS job_attempt;
+ALTER TABLE batch.row DROP COLUMN IF EXISTS job_id;
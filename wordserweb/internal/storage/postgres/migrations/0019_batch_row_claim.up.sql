@@ -0,0 +1,4 @@
+-- a pending row is claimed by the job attempt working on it, job_id on attempt job_attempt. the claim holds while
+-- that job is running that attempt, so a continuation or a job claimed again after its lease ran out skips the row.
+ALTER TABLE batch.row ADD COLUMN IF NOT EXISTS job_id bigint REFERENCES job.job(id) ON DELETE SET NULL;
+ALTER TABLE batch.row ADD COLUMN IF NOT EXISTS job_attempt integer;
@@ -0,0 +1,11 @@
+-- characters held against a user's quotas while the calls they were reserved for run, so concurrent requests are
+-- checked against each other. a hold whose request never finished runs out at expires_at.
+CREATE TABLE IF NOT EXISTS usage.reservation (
+    id              bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_account_id integer NOT NULL REFERENCES auth.user_account(id) ON DELETE CASCADE,
+    char_count      integer NOT NULL,
+    expires_at      timestamptz NOT NULL,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS reservation_user_account_expires_at_idx ON usage.reservation (user_account_id, expires_at);
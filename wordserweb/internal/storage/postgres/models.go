@@ -1,5 +1,257 @@
 package  postgres
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type UserAccount struct {
 	Username string `db:"username"`
-}
\ No newline at end of file
+	Role     string `db:"role"`
+}
+
+type UsageTotals struct {
+	Calls int64 `db:"calls"`
+	Chars int64 `db:"chars"`
+}
+
+// UsageReservation -> a hold on a user's quotas and the usage it was checked against, holds included. ID is 0 when
+// the hold would have gone over a limit and wasn't made.
+type UsageReservation struct {
+	ID           int64
+	DailyChars   int64 `db:"daily_chars"`
+	MonthlyChars int64 `db:"monthly_chars"`
+}
+
+type UserUsageReport struct {
+	Username     string `db:"username"`
+	Role         string `db:"role"`
+	DailyCalls   int64  `db:"daily_calls"`
+	DailyChars   int64  `db:"daily_chars"`
+	MonthlyCalls int64  `db:"monthly_calls"`
+	MonthlyChars int64  `db:"monthly_chars"`
+}
+type Organization struct {
+	ID        int       `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type OrganizationMembership struct {
+	OrganizationID   int       `db:"organization_id"`
+	OrganizationName string    `db:"organization_name"`
+	Username         string    `db:"username"`
+	Role             string    `db:"role"`
+	CreatedAt        time.Time `db:"created_at"`
+}
+
+type OrganizationInvitation struct {
+	ID               int        `db:"id"`
+	OrganizationID   int        `db:"organization_id"`
+	OrganizationName string     `db:"organization_name"`
+	InviteeUsername  *string    `db:"invitee_username"`
+	InviteeEmail     *string    `db:"invitee_email"`
+	Role             string     `db:"role"`
+	InvitedBy        *string    `db:"invited_by"`
+	ExpiresAt        time.Time  `db:"expires_at"`
+	AcceptedAt       *time.Time `db:"accepted_at"`
+	CreatedAt        time.Time  `db:"created_at"`
+}
+
+// AnalysisChunk -> one chunk of a long analysis and what each analyzer made of it.
+type AnalysisChunk struct {
+	Index   int                        `json:"index"`
+	Text    string                     `json:"text"`
+	Results map[string]json.RawMessage `json:"results,omitempty"`
+	Errors  map[string]string          `json:"errors,omitempty"`
+}
+
+// AnalysisSource -> how the text of an analysis was fetched from its source url.
+type AnalysisSource struct {
+	FinalURL    string    `json:"final_url"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Lang        string    `json:"lang,omitempty"`
+	Bytes       int       `json:"bytes"`
+	Truncated   bool      `json:"truncated,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	FetchMS     int64     `json:"fetch_ms"`
+}
+
+// Analysis -> Options, Results, TimingsMS and Errors are keyed by analyzer name. a result is the analyzer's json.
+type Analysis struct {
+	ID             int64                      `db:"id"`
+	Username       string                     `db:"username"`
+	OrganizationID *int                       `db:"organization_id"`
+	OriginalText   string                     `db:"original_text"`
+	Options        []string                   `db:"options"`
+	Results        map[string]json.RawMessage `db:"results"`
+	// SentimentPolarity -> read from the sentiment result for listings; not written.
+	SentimentPolarity *string           `db:"sentiment_polarity"`
+	TimingsMS         map[string]int64  `db:"timings_ms"`
+	Errors            map[string]string `db:"errors"`
+	Chunks            []AnalysisChunk   `db:"chunks"`
+	SourceURL         *string           `db:"source_url"`
+	Source            *AnalysisSource   `db:"source"`
+	DurationMS        int64             `db:"duration_ms"`
+	CreatedAt         time.Time         `db:"created_at"`
+}
+
+type Translation struct {
+	ID             int64     `db:"id"`
+	Username       string    `db:"username"`
+	OrganizationID *int      `db:"organization_id"`
+	SourceLanguage string    `db:"source_language"`
+	TargetLanguage string    `db:"target_language"`
+	SourceText     string    `db:"source_text"`
+	TranslatedText string    `db:"translated_text"`
+	Provider       string    `db:"provider"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+type SearchResult struct {
+	Kind              string    `db:"kind"`
+	ID                int64     `db:"id"`
+	Headline          string    `db:"headline"`
+	SecondaryHeadline string    `db:"secondary_headline"`
+	Rank              float64   `db:"rank"`
+	CreatedAt         time.Time `db:"created_at"`
+	SentimentPolarity *string   `db:"sentiment_polarity"`
+	SourceLanguage    *string   `db:"source_language"`
+	TargetLanguage    *string   `db:"target_language"`
+}
+
+// Job -> one unit of background work. Payload and Result are the json input and output of the kind's handler.
+type Job struct {
+	ID             int64           `db:"id"`
+	Username       string          `db:"username"`
+	OrganizationID *int            `db:"organization_id"`
+	Kind           string          `db:"kind"`
+	State          string          `db:"state"`
+	Payload        json.RawMessage `db:"payload"`
+	Result         json.RawMessage `db:"result"`
+	LastError      *string         `db:"last_error"`
+	Attempts       int             `db:"attempts"`
+	MaxAttempts    int             `db:"max_attempts"`
+	RunAt          time.Time       `db:"run_at"`
+	LockedAt       *time.Time      `db:"locked_at"`
+	CreatedAt      time.Time       `db:"created_at"`
+	UpdatedAt      time.Time       `db:"updated_at"`
+	FinishedAt     *time.Time      `db:"finished_at"`
+}
+
+// Batch -> an uploaded file of texts to analyze. the row counts are computed when it is read.
+type Batch struct {
+	ID             int64     `db:"id"`
+	Username       string    `db:"username"`
+	OrganizationID *int      `db:"organization_id"`
+	Filename       string    `db:"filename"`
+	Format         string    `db:"format"`
+	TextColumn     string    `db:"text_column"`
+	Columns        []string  `db:"columns"`
+	Options        []string  `db:"options"`
+	JobID          *int64    `db:"job_id"`
+	CreatedAt      time.Time `db:"created_at"`
+	TotalRows      int       `db:"total_rows"`
+	PendingRows    int       `db:"pending_rows"`
+	SucceededRows  int       `db:"succeeded_rows"`
+	FailedRows     int       `db:"failed_rows"`
+}
+
+// BatchRow -> one row of a batch. Source is the original row as a json object. Results and Errors are keyed by
+// analyzer name like an Analysis'.
+type BatchRow struct {
+	BatchID  int64                      `db:"batch_id"`
+	RowIndex int                        `db:"row_index"`
+	Source   json.RawMessage            `db:"source"`
+	Text     string                     `db:"text"`
+	State    string                     `db:"state"`
+	Results  map[string]json.RawMessage `db:"results"`
+	Errors   map[string]string          `db:"errors"`
+	// JobID, JobAttempt -> the job attempt that claimed the row, see ClaimBatchRows.
+	JobID      *int64    `db:"job_id"`
+	JobAttempt *int      `db:"job_attempt"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+// Number -> 1 based position of the row in the file, not counting a csv header.
+func (r *BatchRow) Number() int {
+	return r.RowIndex + 1
+}
+
+// WebhookEndpoint -> a url that is sent the events it subscribes to, signed with Secret.
+type WebhookEndpoint struct {
+	ID       int64    `db:"id"`
+	Username string   `db:"username"`
+	URL      string   `db:"url"`
+	Secret   string   `db:"secret"`
+	Events   []string `db:"events"`
+	// FailedDeliveries -> deliveries in a row that failed for good. DisabledAt is set once there were too many.
+	FailedDeliveries int        `db:"failed_deliveries"`
+	DisabledAt       *time.Time `db:"disabled_at"`
+	CreatedAt        time.Time  `db:"created_at"`
+}
+
+// WebhookDelivery -> one event sent, or to be sent, to an endpoint. Payload is the event's data.
+type WebhookDelivery struct {
+	ID          int64           `db:"id"`
+	EndpointID  int64           `db:"endpoint_id"`
+	Username    string          `db:"username"`
+	Event       string          `db:"event"`
+	Payload     json.RawMessage `db:"payload"`
+	State       string          `db:"state"`
+	Attempts    int             `db:"attempts"`
+	StatusCode  *int            `db:"status_code"`
+	LastError   *string         `db:"last_error"`
+	CreatedAt   time.Time       `db:"created_at"`
+	UpdatedAt   time.Time       `db:"updated_at"`
+	DeliveredAt *time.Time      `db:"delivered_at"`
+}
+
+// Glossary -> terms enforced on translations in a workspace: a user's when Username is set, an organization's
+// when OrganizationID is. empty languages apply it to every language pair.
+type Glossary struct {
+	ID             int64     `db:"id"`
+	Username       *string   `db:"username"`
+	OrganizationID *int      `db:"organization_id"`
+	Name           string    `db:"name"`
+	SourceLanguage string    `db:"source_language"`
+	TargetLanguage string    `db:"target_language"`
+	TermCount      int       `db:"term_count"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// GlossaryTerm -> SourceTerm is translated as TargetTerm, or kept as it is when TargetTerm is nil.
+type GlossaryTerm struct {
+	ID         int64     `db:"id"`
+	GlossaryID int64     `db:"glossary_id"`
+	SourceTerm string    `db:"source_term"`
+	TargetTerm *string   `db:"target_term"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// MemorySegment -> a segment pair of a translation memory: a user's when Username is set, an organization's when
+// OrganizationID is. SourceKey is SourceText as it is matched.
+type MemorySegment struct {
+	ID             int64     `db:"id"`
+	Username       *string   `db:"username"`
+	OrganizationID *int      `db:"organization_id"`
+	SourceLanguage string    `db:"source_language"`
+	TargetLanguage string    `db:"target_language"`
+	SourceKey      string    `db:"source_key"`
+	SourceText     string    `db:"source_text"`
+	TargetText     string    `db:"target_text"`
+	Origin         string    `db:"origin"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+// MemoryPair -> a language pair of a translation memory and how many segment pairs it has.
+type MemoryPair struct {
+	SourceLanguage string    `db:"source_language"`
+	TargetLanguage string    `db:"target_language"`
+	SegmentCount   int       `db:"segment_count"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
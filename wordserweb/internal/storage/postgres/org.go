@@ -0,0 +1,373 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvitationUnusable = errors.New("invitation expired, already accepted or for another user")
+	ErrLastOwner          = errors.New("organization must keep at least one owner")
+)
+
+const selectInvitationSQL = `SELECT
+	i.id,
+	i.organization_id,
+	o.name AS organization_name,
+	i.invitee_username,
+	i.invitee_email,
+	i.role,
+	u.username AS invited_by,
+	i.expires_at,
+	i.accepted_at,
+	i.created_at
+FROM org.invitation i
+JOIN org.organization o ON o.id = i.organization_id
+LEFT JOIN auth.user_account u ON u.id = i.invited_by`
+
+func (d *DB) CreateOrganization(ctx context.Context, name string, ownerUsername string, ownerRole string) (*Organization, error) {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	organization := &Organization{}
+	if err := pgxscan.Get(
+		ctx,
+		tx,
+		organization,
+		`INSERT INTO org.organization (name, created_by)
+		SELECT $1, id FROM auth.user_account WHERE username = $2
+		RETURNING id, name, created_at`,
+		name,
+		ownerUsername,
+	); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		ctx,
+		`INSERT INTO org.membership (organization_id, user_account_id, role)
+		SELECT $1, id, $3 FROM auth.user_account WHERE username = $2`,
+		organization.ID,
+		ownerUsername,
+		ownerRole,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+func (d *DB) ListUserOrganizationMemberships(ctx context.Context, username string) ([]*OrganizationMembership, error) {
+	var memberships []*OrganizationMembership
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&memberships,
+		`SELECT m.organization_id, o.name AS organization_name, u.username, m.role, m.created_at
+		FROM org.membership m
+		JOIN org.organization o ON o.id = m.organization_id
+		JOIN auth.user_account u ON u.id = m.user_account_id
+		WHERE u.username = $1
+		ORDER BY o.name`,
+		username,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (d *DB) GetOrganizationMembership(ctx context.Context, orgID int, username string) (*OrganizationMembership, error) {
+	var memberships []*OrganizationMembership
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&memberships,
+		`SELECT m.organization_id, o.name AS organization_name, u.username, m.role, m.created_at
+		FROM org.membership m
+		JOIN org.organization o ON o.id = m.organization_id
+		JOIN auth.user_account u ON u.id = m.user_account_id
+		WHERE m.organization_id = $1 AND u.username = $2`,
+		orgID,
+		username,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(memberships) == 0 {
+		return nil, fmt.Errorf("membership %w", ErrNotFound)
+	}
+	return memberships[0], nil
+}
+
+func (d *DB) ListOrganizationMembers(ctx context.Context, orgID int) ([]*OrganizationMembership, error) {
+	var memberships []*OrganizationMembership
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&memberships,
+		`SELECT m.organization_id, o.name AS organization_name, u.username, m.role, m.created_at
+		FROM org.membership m
+		JOIN org.organization o ON o.id = m.organization_id
+		JOIN auth.user_account u ON u.id = m.user_account_id
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// ownerCount -> owners of orgID excluding username. used to refuse leaving an org without an owner.
+func ownerCount(ctx context.Context, tx pgx.Tx, orgID int, username string) (int, error) {
+	var count int
+	err := tx.QueryRow(
+		ctx,
+		`SELECT count(*)
+		FROM org.membership m
+		JOIN auth.user_account u ON u.id = m.user_account_id
+		WHERE m.organization_id = $1 AND m.role = 'owner' AND u.username <> $2`,
+		orgID,
+		username,
+	).Scan(&count)
+	return count, err
+}
+
+func (d *DB) SetOrganizationMemberRole(ctx context.Context, orgID int, username string, role string) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// serialize role changes per org so two owners can't demote each other at once.
+	if _, err := tx.Exec(ctx, `SELECT id FROM org.organization WHERE id = $1 FOR UPDATE`, orgID); err != nil {
+		return err
+	}
+
+	if role != "owner" {
+		owners, err := ownerCount(ctx, tx, orgID, username)
+		if err != nil {
+			return err
+		}
+		if owners == 0 {
+			return ErrLastOwner
+		}
+	}
+
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE org.membership m SET role = $3
+		FROM auth.user_account u
+		WHERE u.id = m.user_account_id AND m.organization_id = $1 AND u.username = $2`,
+		orgID,
+		username,
+		role,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("membership %w", ErrNotFound)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (d *DB) DeleteOrganizationMember(ctx context.Context, orgID int, username string) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT id FROM org.organization WHERE id = $1 FOR UPDATE`, orgID); err != nil {
+		return err
+	}
+
+	owners, err := ownerCount(ctx, tx, orgID, username)
+	if err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+
+	tag, err := tx.Exec(
+		ctx,
+		`DELETE FROM org.membership m
+		USING auth.user_account u
+		WHERE u.id = m.user_account_id AND m.organization_id = $1 AND u.username = $2`,
+		orgID,
+		username,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("membership %w", ErrNotFound)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (d *DB) CreateOrganizationInvitation(
+	ctx context.Context,
+	orgID int,
+	invitedBy string,
+	inviteeUsername *string,
+	inviteeEmail *string,
+	role string,
+	tokenHash string,
+	expiresAt time.Time,
+) (*OrganizationInvitation, error) {
+	var id int
+	if err := d.pool.QueryRow(
+		ctx,
+		`INSERT INTO org.invitation (organization_id, token_hash, invitee_username, invitee_email, role, invited_by, expires_at)
+		SELECT $1, $2, $3, $4, $5, id, $7 FROM auth.user_account WHERE username = $6
+		RETURNING id`,
+		orgID,
+		tokenHash,
+		inviteeUsername,
+		inviteeEmail,
+		role,
+		invitedBy,
+		expiresAt,
+	).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	invitation := &OrganizationInvitation{}
+	if err := pgxscan.Get(
+		ctx,
+		d.pool,
+		invitation,
+		selectInvitationSQL+` WHERE i.id = $1`,
+		id,
+	); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (d *DB) ListOrganizationInvitations(ctx context.Context, orgID int) ([]*OrganizationInvitation, error) {
+	var invitations []*OrganizationInvitation
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&invitations,
+		selectInvitationSQL+` WHERE i.organization_id = $1 AND i.accepted_at IS NULL AND i.expires_at > now()
+		ORDER BY i.created_at DESC`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (d *DB) ListUserInvitations(ctx context.Context, username string) ([]*OrganizationInvitation, error) {
+	var invitations []*OrganizationInvitation
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&invitations,
+		selectInvitationSQL+` WHERE i.invitee_username = $1 AND i.accepted_at IS NULL AND i.expires_at > now()
+		ORDER BY i.created_at DESC`,
+		username,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (d *DB) GetOrganizationInvitation(ctx context.Context, tokenHash string) (*OrganizationInvitation, error) {
+	var invitations []*OrganizationInvitation
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&invitations,
+		selectInvitationSQL+` WHERE i.token_hash = $1`,
+		tokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(invitations) == 0 {
+		return nil, fmt.Errorf("invitation %w", ErrNotFound)
+	}
+	return invitations[0], nil
+}
+
+// AcceptOrganizationInvitation -> marks the invitation accepted and adds username to the org. returns the org id.
+func (d *DB) AcceptOrganizationInvitation(ctx context.Context, tokenHash string, username string) (int, error) {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var orgID int
+	var role string
+	err = tx.QueryRow(
+		ctx,
+		`SELECT organization_id, role FROM org.invitation
+		WHERE token_hash = $1
+			AND accepted_at IS NULL
+			AND expires_at > now()
+			AND (invitee_username IS NULL OR invitee_username = $2)
+		FOR UPDATE`,
+		tokenHash,
+		username,
+	).Scan(&orgID, &role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrInvitationUnusable
+		}
+		return 0, err
+	}
+
+	if _, err := tx.Exec(
+		ctx,
+		`INSERT INTO org.membership (organization_id, user_account_id, role)
+		SELECT $1, id, $3 FROM auth.user_account WHERE username = $2
+		ON CONFLICT (organization_id, user_account_id) DO NOTHING`,
+		orgID,
+		username,
+		role,
+	); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(
+		ctx,
+		`UPDATE org.invitation SET accepted_at = now(), accepted_by = (SELECT id FROM auth.user_account WHERE username = $2)
+		WHERE token_hash = $1`,
+		tokenHash,
+		username,
+	); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return orgID, nil
+}
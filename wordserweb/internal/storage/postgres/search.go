@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+const (
+	SearchKindAnalysis    = "analysis"
+	SearchKindTranslation = "translation"
+
+	// HeadlineStartSel/HeadlineStopSel wrap matches in ts_headline output. private use runes so the
+	// caller can html escape the headline and only then turn them into markup.
+	HeadlineStartSel = "\ue000"
+	HeadlineStopSel  = "\ue001"
+)
+
+var headlineOptions = fmt.Sprintf(
+	`StartSel="%s", StopSel="%s", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`,
+	HeadlineStartSel,
+	HeadlineStopSel,
+)
+
+type SearchParams struct {
+	Username string
+	// TSQuery -> to_tsquery input. empty lists everything matching the filters newest first.
+	TSQuery  string
+	From     *time.Time
+	To       *time.Time
+	Polarity string
+	Keyword  string
+	// Kind -> SearchKindAnalysis, SearchKindTranslation or empty for both.
+	Kind  string
+	Limit int
+}
+
+// searchArgs -> collects query arguments and hands out their placeholders.
+type searchArgs []any
+
+func (a *searchArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+func (d *DB) Search(ctx context.Context, params SearchParams) ([]*SearchResult, error) {
+	args := &searchArgs{}
+	username := args.add(params.Username)
+	options := args.add(headlineOptions)
+	tsquery := ""
+	if params.TSQuery != "" {
+		tsquery = args.add(params.TSQuery)
+	}
+
+	// translations carry no sentiment or keywords so those filters only ever match analyses.
+	analysisOnly := params.Polarity != "" || params.Keyword != ""
+
+	var parts []string
+	if params.Kind != SearchKindTranslation {
+		parts = append(parts, analysisSearchSQL(args, params, username, options, tsquery))
+	}
+	if params.Kind != SearchKindAnalysis && !analysisOnly {
+		parts = append(parts, translationSearchSQL(args, params, username, options, tsquery))
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	limit := args.add(params.Limit)
+	query := strings.Join(parts, "\nUNION ALL\n") +
+		fmt.Sprintf("\nORDER BY rank DESC, created_at DESC LIMIT %s", limit)
+
+	var results []*SearchResult
+	if err := pgxscan.Select(ctx, d.pool, &results, query, *args...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func dateFilters(args *searchArgs, params SearchParams, column string) []string {
+	var where []string
+	if params.From != nil {
+		where = append(where, fmt.Sprintf("%s >= %s", column, args.add(*params.From)))
+	}
+	if params.To != nil {
+		where = append(where, fmt.Sprintf("%s < %s", column, args.add(*params.To)))
+	}
+	return where
+}
+
+func analysisSearchSQL(args *searchArgs, params SearchParams, username string, options string, tsquery string) string {
+	where := []string{"u.username = " + username}
+	where = append(where, dateFilters(args, params, "a.created_at")...)
+	if params.Polarity != "" {
+		where = append(where, fmt.Sprintf("lower(a.results->'sentiment'->>'polarity') = lower(%s)", args.add(params.Polarity)))
+	}
+	if params.Keyword != "" {
+		where = append(where, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM jsonb_array_elements(a.results->'keyword'->'keywords') k WHERE lower(k->>'text') = lower(%s))",
+			args.add(params.Keyword),
+		))
+	}
+
+	headline := "left(a.original_text, 300)"
+	secondary := "coalesce(left(a.results->'summary'->>'summary', 300), '')"
+	rank := "0::real"
+	from := "analysis.analysis a JOIN auth.user_account u ON u.id = a.user_account_id"
+	if tsquery != "" {
+		from += fmt.Sprintf(", to_tsquery('english', %s) q", tsquery)
+		where = append(where, "a.search_vector @@ q")
+		headline = fmt.Sprintf("ts_headline('english', a.original_text, q, %s)", options)
+		secondary = fmt.Sprintf("ts_headline('english', coalesce(a.results->'summary'->>'summary', ''), q, %s)", options)
+		rank = "ts_rank_cd(a.search_vector, q)"
+	}
+
+	return fmt.Sprintf(
+		`SELECT
+			'%s' AS kind,
+			a.id,
+			%s AS headline,
+			%s AS secondary_headline,
+			%s AS rank,
+			a.created_at,
+			a.results->'sentiment'->>'polarity' AS sentiment_polarity,
+			NULL::varchar AS source_language,
+			NULL::varchar AS target_language
+		FROM %s
+		WHERE %s`,
+		SearchKindAnalysis,
+		headline,
+		secondary,
+		rank,
+		from,
+		strings.Join(where, " AND "),
+	)
+}
+
+func translationSearchSQL(args *searchArgs, params SearchParams, username string, options string, tsquery string) string {
+	where := []string{"u.username = " + username}
+	where = append(where, dateFilters(args, params, "t.created_at")...)
+
+	headline := "left(t.source_text, 300)"
+	secondary := "left(t.translated_text, 300)"
+	rank := "0::real"
+	from := "translation.translation t JOIN auth.user_account u ON u.id = t.user_account_id"
+	if tsquery != "" {
+		from += fmt.Sprintf(", to_tsquery('simple', %s) q", tsquery)
+		where = append(where, "t.search_vector @@ q")
+		headline = fmt.Sprintf("ts_headline('simple', t.source_text, q, %s)", options)
+		secondary = fmt.Sprintf("ts_headline('simple', t.translated_text, q, %s)", options)
+		rank = "ts_rank_cd(t.search_vector, q)"
+	}
+
+	return fmt.Sprintf(
+		`SELECT
+			'%s' AS kind,
+			t.id,
+			%s AS headline,
+			%s AS secondary_headline,
+			%s AS rank,
+			t.created_at,
+			NULL::varchar AS sentiment_polarity,
+			t.source_language,
+			t.target_language
+		FROM %s
+		WHERE %s`,
+		SearchKindTranslation,
+		headline,
+		secondary,
+		rank,
+		from,
+		strings.Join(where, " AND "),
+	)
+}
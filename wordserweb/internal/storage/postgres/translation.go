@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+)
+
+// InsertTranslation -> persist t, owned by t.Username. returns the new translation id.
+func (d *DB) InsertTranslation(ctx context.Context, t *Translation) (int64, error) {
+	var id int64
+	err := d.pool.QueryRow(
+		ctx,
+		`INSERT INTO translation.translation (
+			user_account_id,
+			organization_id,
+			source_language,
+			target_language,
+			source_text,
+			translated_text,
+			provider
+		)
+		SELECT id, $2, $3, $4, $5, $6, $7 FROM auth.user_account WHERE username = $1
+		RETURNING id`,
+		t.Username,
+		t.OrganizationID,
+		t.SourceLanguage,
+		t.TargetLanguage,
+		t.SourceText,
+		t.TranslatedText,
+		t.Provider,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
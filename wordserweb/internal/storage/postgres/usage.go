@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+func (d *DB) InsertUsageEvent(ctx context.Context, username string, api string, charCount int) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`INSERT INTO usage.api_call (user_account_id, api, char_count)
+		SELECT id, $2, $3 FROM auth.user_account WHERE username = $1`,
+		username,
+		api,
+		charCount,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (d *DB) GetUsageTotals(ctx context.Context, username string, since time.Time) (*UsageTotals, error) {
+	totals := &UsageTotals{}
+	err := pgxscan.Get(
+		ctx,
+		d.pool,
+		totals,
+		`SELECT count(c.id) AS calls, coalesce(sum(c.char_count), 0) AS chars
+		FROM usage.api_call c
+		JOIN auth.user_account u ON u.id = c.user_account_id
+		WHERE u.username = $1 AND c.created_at >= $2`,
+		username,
+		since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// ReserveUsage -> hold chars of username's quotas until expiresAt, unless the usage since dayStart or monthStart
+// and the holds that haven't run out would go over dailyLimit or monthlyLimit with them. 0 is no limit. the user's
+// row is locked first, so concurrent reservations are checked one after the other.
+func (d *DB) ReserveUsage(
+	ctx context.Context,
+	username string,
+	chars int,
+	dayStart time.Time,
+	monthStart time.Time,
+	dailyLimit int64,
+	monthlyLimit int64,
+	expiresAt time.Time,
+) (*UsageReservation, error) {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `SELECT id FROM auth.user_account WHERE username = $1 FOR UPDATE`, username).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.New("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM usage.reservation WHERE user_account_id = $1 AND expires_at <= now()`, userID); err != nil {
+		return nil, err
+	}
+
+	// holds count as usage now; the calls they are for haven't been recorded yet.
+	reservation := &UsageReservation{}
+	err = pgxscan.Get(
+		ctx,
+		tx,
+		reservation,
+		`SELECT
+			coalesce(sum(char_count) FILTER (WHERE created_at >= $2), 0) AS daily_chars,
+			coalesce(sum(char_count), 0) AS monthly_chars
+		FROM (
+			SELECT char_count, created_at FROM usage.api_call WHERE user_account_id = $1 AND created_at >= $3
+			UNION ALL
+			SELECT char_count, now() FROM usage.reservation WHERE user_account_id = $1
+		) u`,
+		userID,
+		dayStart,
+		monthStart,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if (dailyLimit > 0 && reservation.DailyChars+int64(chars) > dailyLimit) ||
+		(monthlyLimit > 0 && reservation.MonthlyChars+int64(chars) > monthlyLimit) {
+		return reservation, nil
+	}
+
+	err = tx.QueryRow(
+		ctx,
+		`INSERT INTO usage.reservation (user_account_id, char_count, expires_at) VALUES ($1, $2, $3) RETURNING id`,
+		userID,
+		chars,
+		expiresAt,
+	).Scan(&reservation.ID)
+	if err != nil {
+		return nil, err
+	}
+	return reservation, tx.Commit(ctx)
+}
+
+// DeleteUsageReservation -> release a hold of ReserveUsage. one that already ran out is gone already.
+func (d *DB) DeleteUsageReservation(ctx context.Context, id int64) error {
+	_, err := d.pool.Exec(ctx, `DELETE FROM usage.reservation WHERE id = $1`, id)
+	return err
+}
+
+func (d *DB) ListUsageReport(ctx context.Context, dayStart time.Time, monthStart time.Time) ([]*UserUsageReport, error) {
+	var report []*UserUsageReport
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&report,
+		`SELECT
+			u.username,
+			u.role,
+			count(c.id) FILTER (WHERE c.created_at >= $1) AS daily_calls,
+			coalesce(sum(c.char_count) FILTER (WHERE c.created_at >= $1), 0) AS daily_chars,
+			count(c.id) AS monthly_calls,
+			coalesce(sum(c.char_count), 0) AS monthly_chars
+		FROM auth.user_account u
+		LEFT JOIN usage.api_call c ON c.user_account_id = u.id AND c.created_at >= $2
+		GROUP BY u.id, u.username, u.role
+		ORDER BY monthly_chars DESC, u.username`,
+		dayStart,
+		monthStart,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	WebhookDeliveryStatePending   = "pending"
+	WebhookDeliveryStateSucceeded = "succeeded"
+	WebhookDeliveryStateFailed    = "failed"
+)
+
+const selectWebhookEndpointSQL = `SELECT
+	e.id,
+	u.username,
+	e.url,
+	e.secret,
+	e.events,
+	e.failed_deliveries,
+	e.disabled_at,
+	e.created_at
+FROM webhook.endpoint e
+JOIN auth.user_account u ON u.id = e.user_account_id`
+
+const selectWebhookDeliverySQL = `SELECT
+	d.id,
+	d.endpoint_id,
+	u.username,
+	d.event,
+	d.payload,
+	d.state,
+	d.attempts,
+	d.status_code,
+	d.last_error,
+	d.created_at,
+	d.updated_at,
+	d.delivered_at
+FROM webhook.delivery d
+JOIN webhook.endpoint e ON e.id = d.endpoint_id
+JOIN auth.user_account u ON u.id = e.user_account_id`
+
+// InsertWebhookEndpoint -> persist e, owned by e.Username. returns the new endpoint id.
+func (d *DB) InsertWebhookEndpoint(ctx context.Context, e *WebhookEndpoint) (int64, error) {
+	var id int64
+	err := d.pool.QueryRow(
+		ctx,
+		`INSERT INTO webhook.endpoint (user_account_id, url, secret, events)
+		SELECT id, $2, $3, $4 FROM auth.user_account WHERE username = $1
+		RETURNING id`,
+		e.Username,
+		e.URL,
+		e.Secret,
+		e.Events,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (d *DB) GetWebhookEndpoint(ctx context.Context, username string, id int64) (*WebhookEndpoint, error) {
+	var endpoints []*WebhookEndpoint
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&endpoints,
+		selectWebhookEndpointSQL+` WHERE u.username = $1 AND e.id = $2`,
+		username,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("webhook endpoint %w", ErrNotFound)
+	}
+	return endpoints[0], nil
+}
+
+// ListWebhookEndpoints -> username's endpoints, oldest first. a non empty event keeps the enabled ones subscribed to
+// it.
+func (d *DB) ListWebhookEndpoints(ctx context.Context, username string, event string) ([]*WebhookEndpoint, error) {
+	var endpoints []*WebhookEndpoint
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&endpoints,
+		selectWebhookEndpointSQL+` WHERE u.username = $1 AND ($2 = '' OR ($2 = ANY(e.events) AND e.disabled_at IS NULL))
+		ORDER BY e.id`,
+		username,
+		event,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (d *DB) DeleteWebhookEndpoint(ctx context.Context, username string, id int64) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`DELETE FROM webhook.endpoint e
+		USING auth.user_account u
+		WHERE u.id = e.user_account_id AND u.username = $1 AND e.id = $2`,
+		username,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook endpoint %w", ErrNotFound)
+	}
+	return nil
+}
+
+// InsertWebhookDelivery -> log a pending delivery of dl.Event to endpoint dl.EndpointID. returns the new
+// delivery id.
+func (d *DB) InsertWebhookDelivery(ctx context.Context, dl *WebhookDelivery) (int64, error) {
+	var id int64
+	err := d.pool.QueryRow(
+		ctx,
+		`INSERT INTO webhook.delivery (endpoint_id, event, payload) VALUES ($1, $2, $3) RETURNING id`,
+		dl.EndpointID,
+		dl.Event,
+		[]byte(dl.Payload),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (d *DB) GetWebhookDelivery(ctx context.Context, username string, id int64) (*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&deliveries,
+		selectWebhookDeliverySQL+` WHERE u.username = $1 AND d.id = $2`,
+		username,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	return deliveries[0], nil
+}
+
+// ListWebhookDeliveries -> deliveries to endpoint id of username, newest first.
+func (d *DB) ListWebhookDeliveries(ctx context.Context, username string, endpointID int64, limit int) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	err := pgxscan.Select(
+		ctx,
+		d.pool,
+		&deliveries,
+		selectWebhookDeliverySQL+` WHERE u.username = $1 AND d.endpoint_id = $2
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $3`,
+		username,
+		endpointID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt -> count an attempt at delivery id and store how it went. statusCode is nil when no
+// response came back.
+func (d *DB) RecordWebhookAttempt(ctx context.Context, id int64, state string, statusCode *int, lastError *string) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`UPDATE webhook.delivery
+		SET
+			state = $2,
+			attempts = attempts + 1,
+			status_code = $3,
+			last_error = $4,
+			updated_at = now(),
+			delivered_at = CASE WHEN $2 = 'succeeded' THEN now() ELSE delivered_at END
+		WHERE id = $1`,
+		id,
+		state,
+		statusCode,
+		lastError,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ResetWebhookDelivery -> make username's delivery id pending again so it can be replayed.
+func (d *DB) ResetWebhookDelivery(ctx context.Context, username string, id int64) error {
+	tag, err := d.pool.Exec(
+		ctx,
+		`UPDATE webhook.delivery d
+		SET state = 'pending', updated_at = now()
+		FROM webhook.endpoint e
+		JOIN auth.user_account u ON u.id = e.user_account_id
+		WHERE e.id = d.endpoint_id AND u.username = $1 AND d.id = $2`,
+		username,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	return nil
+}
+
+// RecordWebhookEndpointOutcome -> count a delivery to endpoint id that finished. a success resets the count of
+// failures and enables the endpoint again; the disableAfter-th failure in a row disables it, 0 never does. returns
+// whether the endpoint is disabled now.
+func (d *DB) RecordWebhookEndpointOutcome(ctx context.Context, id int64, succeeded bool, disableAfter int) (bool, error) {
+	var disabled bool
+	err := d.pool.QueryRow(
+		ctx,
+		`UPDATE webhook.endpoint
+		SET
+			failed_deliveries = CASE WHEN $2 THEN 0 ELSE failed_deliveries + 1 END,
+			disabled_at = CASE
+				WHEN $2 THEN NULL
+				WHEN $3 > 0 AND failed_deliveries + 1 >= $3 THEN coalesce(disabled_at, now())
+				ELSE disabled_at
+			END
+		WHERE id = $1
+		RETURNING disabled_at IS NOT NULL`,
+		id,
+		succeeded,
+		disableAfter,
+	).Scan(&disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("webhook endpoint %w", ErrNotFound)
+	}
+	return disabled, err
+}
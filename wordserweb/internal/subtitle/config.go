@@ -0,0 +1,39 @@
+package subtitle
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// MaxBytes -> size of the largest subtitle file translated.
+	MaxBytes int64 `mapstructure:"SUBTITLE_MAX_BYTES"`
+	// BatchCues/BatchChars -> most cue lines, and runes of them, sent to a provider in one translation.
+	BatchCues  int `mapstructure:"SUBTITLE_BATCH_CUES"`
+	BatchChars int `mapstructure:"SUBTITLE_BATCH_CHARS"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("SUBTITLE_MAX_BYTES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'SUBTITLE_MAX_BYTES'")
+	}
+	viper.SetDefault("SUBTITLE_MAX_BYTES", 1<<20)
+
+	if err := viper.BindEnv("SUBTITLE_BATCH_CUES"); err != nil {
+		return c, fmt.Errorf("failed to bind 'SUBTITLE_BATCH_CUES'")
+	}
+	viper.SetDefault("SUBTITLE_BATCH_CUES", 25)
+
+	if err := viper.BindEnv("SUBTITLE_BATCH_CHARS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'SUBTITLE_BATCH_CHARS'")
+	}
+	viper.SetDefault("SUBTITLE_BATCH_CHARS", 3000)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
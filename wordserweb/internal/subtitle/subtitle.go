@@ -0,0 +1,149 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatSRT Format = "srt"
+	FormatVTT Format = "vtt"
+)
+
+func (f Format) String() string {
+	return string(f)
+}
+
+func (f Format) ContentType() string {
+	if f == FormatVTT {
+		return "text/vtt"
+	}
+	return "application/x-subrip"
+}
+
+// FormatFromFilename -> the format an upload's extension says it is.
+func FormatFromFilename(name string) (Format, bool) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".srt":
+		return FormatSRT, true
+	case ".vtt":
+		return FormatVTT, true
+	default:
+		return "", false
+	}
+}
+
+var (
+	ErrEmpty  = errors.New("file has no cues")
+	ErrFormat = errors.New("malformed subtitle file")
+)
+
+var (
+	blankLinesRe = regexp.MustCompile(`\n[ \t]*\n\s*`)
+	// timingRe -> "00:00:01,000 --> 00:00:04,000", hours optional and with "." for webvtt. what follows, webvtt cue
+	// settings or srt coordinates, is kept as it is.
+	timingRe = regexp.MustCompile(`^\s*(?:\d{2,}:)?\d{2}:\d{2}[,.]\d{3}\s+-->\s+(?:\d{2,}:)?\d{2}:\d{2}[,.]\d{3}(?:\s|$)`)
+)
+
+// Cue -> a cue of a subtitle file. only Lines are translated.
+type Cue struct {
+	// Identifier -> the srt cue number or webvtt cue identifier, empty when there is none.
+	Identifier string
+	// Timing -> the timing line as the file has it.
+	Timing string
+	Lines  []string
+}
+
+func (c *Cue) Text() string {
+	return strings.Join(c.Lines, "\n")
+}
+
+// block -> a block of a file: a cue, or a webvtt header, NOTE, STYLE or REGION block written back as it was.
+type block struct {
+	raw string
+	cue *Cue
+}
+
+// File -> a parsed srt or webvtt file.
+type File struct {
+	Format Format
+	Cues   []*Cue
+	blocks []block
+}
+
+// Parse -> the cues of an srt or webvtt file, along with whatever else the file holds so Write gives it back as it
+// was but for the cue text.
+func Parse(data []byte, format Format) (*File, error) {
+	txt := strings.TrimPrefix(string(data), "\ufeff")
+	txt = strings.ReplaceAll(txt, "\r\n", "\n")
+	txt = strings.ReplaceAll(txt, "\r", "\n")
+	txt = strings.TrimSpace(txt)
+
+	f := &File{Format: format}
+	for i, raw := range blankLinesRe.Split(txt, -1) {
+		lines := strings.Split(strings.TrimRight(raw, " \t\n"), "\n")
+		if format == FormatVTT {
+			if i == 0 {
+				if !strings.HasPrefix(lines[0], "WEBVTT") {
+					return nil, fmt.Errorf("%w; a webvtt file starts with WEBVTT;", ErrFormat)
+				}
+				f.blocks = append(f.blocks, block{raw: raw})
+				continue
+			}
+			if first := strings.Fields(lines[0]); len(first) > 0 &&
+				(first[0] == "NOTE" || first[0] == "STYLE" || first[0] == "REGION") {
+				f.blocks = append(f.blocks, block{raw: raw})
+				continue
+			}
+		}
+
+		cue := &Cue{}
+		switch {
+		case timingRe.MatchString(lines[0]):
+			cue.Timing, cue.Lines = lines[0], lines[1:]
+		case len(lines) > 1 && timingRe.MatchString(lines[1]):
+			cue.Identifier, cue.Timing, cue.Lines = strings.TrimSpace(lines[0]), lines[1], lines[2:]
+		default:
+			return nil, fmt.Errorf("%w; block %d has no timing line: %q;", ErrFormat, i+1, lines[0])
+		}
+		f.Cues = append(f.Cues, cue)
+		f.blocks = append(f.blocks, block{cue: cue})
+	}
+	if len(f.Cues) == 0 {
+		return nil, ErrEmpty
+	}
+	return f, nil
+}
+
+// Write -> f in its format, its blocks separated by blank lines.
+func (f *File) Write(w io.Writer) error {
+	var b strings.Builder
+	for i, bl := range f.blocks {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if bl.cue == nil {
+			b.WriteString(bl.raw)
+			b.WriteString("\n")
+			continue
+		}
+		if bl.cue.Identifier != "" {
+			b.WriteString(bl.cue.Identifier)
+			b.WriteString("\n")
+		}
+		b.WriteString(bl.cue.Timing)
+		b.WriteString("\n")
+		for _, line := range bl.cue.Lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,137 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWriteRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		// want -> what Write gives back, the input when empty.
+		want  string
+		cues  int
+		lines [][]string
+	}{
+		{
+			name:   "srt",
+			format: FormatSRT,
+			input:  "1\n00:00:01,000 --> 00:00:04,000\nHello there.\n\n2\n00:00:05,000 --> 00:00:07,500\nGeneral Kenobi.\n",
+			cues:   2,
+			lines:  [][]string{{"Hello there."}, {"General Kenobi."}},
+		},
+		{
+			name:   "srt crlf and bom",
+			format: FormatSRT,
+			input:  "\ufeff1\r\n00:00:01,000 --> 00:00:04,000\r\nHello there.\r\nHow are you?\r\n\r\n2\r\n00:00:05,000 --> 00:00:07,500\r\nFine.\r\n",
+			want:   "1\n00:00:01,000 --> 00:00:04,000\nHello there.\nHow are you?\n\n2\n00:00:05,000 --> 00:00:07,500\nFine.\n",
+			cues:   2,
+			lines:  [][]string{{"Hello there.", "How are you?"}, {"Fine."}},
+		},
+		{
+			name:   "srt styling tags and coordinates",
+			format: FormatSRT,
+			input:  "1\n00:00:01,000 --> 00:00:04,000 X1:40 X2:600 Y1:20 Y2:50\n{\\an8}<i>Meanwhile,</i>\n<font color=\"red\">in the castle</font>\n",
+			cues:   1,
+			lines:  [][]string{{"{\\an8}<i>Meanwhile,</i>", "<font color=\"red\">in the castle</font>"}},
+		},
+		{
+			name:   "srt dialogue",
+			format: FormatSRT,
+			input:  "7\n01:02:03,456 --> 01:02:05,000\n- Who's there?\n- Nobody.\n",
+			cues:   1,
+			lines:  [][]string{{"- Who's there?", "- Nobody."}},
+		},
+		{
+			name:   "vtt header note style and identifiers",
+			format: FormatVTT,
+			input: "WEBVTT - a film\nKind: captions\n\nNOTE written by hand\n\nSTYLE\n::cue { color: yellow }\n\n" +
+				"intro\n00:01.000 --> 00:04.000 align:start line:10%\n<c.yellow>Hello</c> <b>there</b>.\n\n" +
+				"00:00:05.000 --> 00:00:07.000\n<v Roger>Hi.\n",
+			cues:  2,
+			lines: [][]string{{"<c.yellow>Hello</c> <b>there</b>."}, {"<v Roger>Hi."}},
+		},
+		{
+			name:   "vtt crlf and bom",
+			format: FormatVTT,
+			input:  "\ufeffWEBVTT\r\n\r\n00:01.000 --> 00:04.000\r\n- Ready?\r\n- Always.\r\n",
+			want:   "WEBVTT\n\n00:01.000 --> 00:04.000\n- Ready?\n- Always.\n",
+			cues:   1,
+			lines:  [][]string{{"- Ready?", "- Always."}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			if len(f.Cues) != tt.cues {
+				t.Fatalf("Parse() has %d cues, want %d", len(f.Cues), tt.cues)
+			}
+			for i, cue := range f.Cues {
+				if !reflect.DeepEqual(cue.Lines, tt.lines[i]) {
+					t.Errorf("cue %d lines = %q, want %q", i+1, cue.Lines, tt.lines[i])
+				}
+			}
+
+			want := tt.want
+			if want == "" {
+				want = tt.input
+			}
+			var b bytes.Buffer
+			if err := f.Write(&b); err != nil {
+				t.Fatalf("Write() = %v", err)
+			}
+			if b.String() != want {
+				t.Fatalf("Write() = %q, want %q", b.String(), want)
+			}
+
+			again, err := Parse(b.Bytes(), tt.format)
+			if err != nil {
+				t.Fatalf("Parse(Write()) = %v", err)
+			}
+			if !reflect.DeepEqual(again.Cues, f.Cues) {
+				t.Fatalf("Parse(Write()) cues differ")
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   error
+	}{
+		{name: "empty", format: FormatSRT, input: "\n\n", want: ErrFormat},
+		{name: "no timing", format: FormatSRT, input: "1\nHello\n", want: ErrFormat},
+		{name: "vtt without header", format: FormatVTT, input: "00:01.000 --> 00:04.000\nHi\n", want: ErrFormat},
+		{name: "vtt header only", format: FormatVTT, input: "WEBVTT\n\nNOTE nothing here\n", want: ErrEmpty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.input), tt.format); !errors.Is(err, tt.want) {
+				t.Fatalf("Parse() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	tests := map[string]Format{"film.srt": FormatSRT, "FILM.VTT": FormatVTT, "film.txt": ""}
+	for name, want := range tests {
+		if got, _ := FormatFromFilename(name); got != want {
+			t.Errorf("FormatFromFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if !strings.HasPrefix(FormatVTT.ContentType(), "text/vtt") {
+		t.Errorf("FormatVTT.ContentType() = %q", FormatVTT.ContentType())
+	}
+}
@@ -0,0 +1,309 @@
+package subtitle
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// tagRe -> styling tags: srt and webvtt markup like <i>, <font color="red"> or <c.yellow>, and ass overrides
+	// like {\an8}.
+	tagRe = regexp.MustCompile(`<[^<>\n]*>|\{\\[^{}\n]*\}`)
+	// dashRe -> the dash starting a line of dialogue.
+	dashRe = regexp.MustCompile(`^[-‐–—]\s*`)
+	// placeholderPattern -> what stands in for a styling tag inside the text sent to a provider, "⟪0⟫". unlike
+	// the glossaries' "⟦0⟧" so both can be in one text.
+	placeholderPattern = regexp.MustCompile(`⟪\s*(\d+)\s*⟫`)
+)
+
+// TranslateFunc -> txt translated. txt holds one cue line per line and the translation must too.
+type TranslateFunc func(ctx context.Context, txt string) (string, error)
+
+// Failure -> a cue left untranslated.
+type Failure struct {
+	// Cue -> the cue's position in the file, from 1.
+	Cue        int    `json:"cue"`
+	Identifier string `json:"identifier,omitempty"`
+	Timing     string `json:"timing"`
+	Text       string `json:"text"`
+	Error      string `json:"error"`
+}
+
+// Report -> how translating a file went. failed cues keep their text.
+type Report struct {
+	Cues     int       `json:"cues"`
+	Failures []Failure `json:"failures,omitempty"`
+	// Chars -> the characters of the cue text translated, what is billed. lines sent again after a batch came
+	// back short and lines of failed cues don't count.
+	Chars int `json:"chars"`
+}
+
+// Translated -> cues translated, or with nothing to translate.
+func (r Report) Translated() int {
+	return r.Cues - len(r.Failures)
+}
+
+// item -> a line sent for translation: a whole cue joined into one line, or a line of a dialogue cue.
+type item struct {
+	cue int
+	// line -> the dialogue line, -1 for a whole cue.
+	line int
+	// prefix/suffix -> the tags, and dialogue dash, around the text. kept out of the translation.
+	prefix string
+	suffix string
+	// text -> what is sent, the tags inside it replaced by placeholders numbered by their index in tags.
+	text string
+	tags []string
+	// lines -> lines of a whole cue, its translation wrapped back into as many.
+	lines      int
+	translated string
+}
+
+// isDialogue -> every one of lines is a speaker's, starting with a dash.
+func isDialogue(lines []string) bool {
+	if len(lines) < 2 {
+		return false
+	}
+	for _, line := range lines {
+		_, rest := leadingTags(line)
+		if !dashRe.MatchString(rest) {
+			return false
+		}
+	}
+	return true
+}
+
+// leadingTags -> the tags, and spaces, at the start of s and the rest of it.
+func leadingTags(s string) (string, string) {
+	rest := strings.TrimLeftFunc(s, unicode.IsSpace)
+	for {
+		loc := tagRe.FindStringIndex(rest)
+		if loc == nil || loc[0] != 0 {
+			return s[:len(s)-len(rest)], rest
+		}
+		rest = strings.TrimLeftFunc(rest[loc[1]:], unicode.IsSpace)
+	}
+}
+
+// trailingTags -> s without the tags, and spaces, at its end and those tags.
+func trailingTags(s string) (string, string) {
+	rest := strings.TrimRightFunc(s, unicode.IsSpace)
+	for {
+		locs := tagRe.FindAllStringIndex(rest, -1)
+		if len(locs) == 0 || locs[len(locs)-1][1] != len(rest) {
+			return rest, s[len(rest):]
+		}
+		rest = strings.TrimRightFunc(rest[:locs[len(locs)-1][0]], unicode.IsSpace)
+	}
+}
+
+func newItem(cue int, line int, text string, lines int) *item {
+	it := &item{cue: cue, line: line, lines: lines}
+	it.prefix, text = leadingTags(text)
+	if dash := dashRe.FindString(text); dash != "" {
+		it.prefix += dash
+		text = text[len(dash):]
+	}
+	text, it.suffix = trailingTags(text)
+	it.text = tagRe.ReplaceAllStringFunc(text, func(tag string) string {
+		it.tags = append(it.tags, tag)
+		return fmt.Sprintf("⟪%d⟫", len(it.tags)-1)
+	})
+	return it
+}
+
+// translatable -> the item has words to translate, not just tags, music notes or punctuation.
+func (it *item) translatable() bool {
+	for _, r := range placeholderPattern.ReplaceAllString(it.text, "") {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// restore -> translated with its placeholders back to the tags they stand for, between prefix and suffix. when the
+// provider lost some placeholders the tags inside the text are dropped rather than left unbalanced.
+func (it *item) restore(translated string) []string {
+	found := 0
+	restored := placeholderPattern.ReplaceAllStringFunc(translated, func(placeholder string) string {
+		i, err := strconv.Atoi(placeholderPattern.FindStringSubmatch(placeholder)[1])
+		if err != nil || i >= len(it.tags) {
+			return ""
+		}
+		found++
+		return it.tags[i]
+	})
+	if found != len(it.tags) {
+		restored = placeholderPattern.ReplaceAllString(translated, "")
+		restored = strings.TrimSpace(tagRe.ReplaceAllString(restored, ""))
+	}
+	lines := wrap(strings.TrimSpace(restored), it.lines)
+	lines[0] = it.prefix + lines[0]
+	lines[len(lines)-1] += it.suffix
+	return lines
+}
+
+// wrap -> txt in n lines of about the same length, broken between words. text without spaces, like chinese or
+// japanese, is broken between runes.
+func wrap(txt string, n int) []string {
+	if n <= 1 {
+		return []string{txt}
+	}
+	words := strings.Fields(txt)
+	sep := " "
+	if len(words) < n {
+		words = strings.Split(txt, "")
+		sep = ""
+	}
+	if len(words) < n {
+		return []string{txt}
+	}
+	target := utf8.RuneCountInString(txt) / n
+	var lines []string
+	var current []string
+	currentLen := 0
+	for i, word := range words {
+		left := len(words) - i
+		wordLen := utf8.RuneCountInString(word)
+		// break before word when the line is long enough, or would overshoot by more than it falls short.
+		long := currentLen >= target || currentLen+wordLen-target > target-currentLen
+		if currentLen > 0 && len(lines) < n-1 && (long || left < n-len(lines)) {
+			lines = append(lines, strings.Join(current, sep))
+			current, currentLen = nil, 0
+		}
+		current = append(current, word)
+		currentLen += wordLen + len(sep)
+	}
+	return append(lines, strings.Join(current, sep))
+}
+
+// Subtitles -> translation of the cue text of subtitle files in batches of cue lines.
+type Subtitles struct {
+	config Config
+}
+
+func New(config Config) *Subtitles {
+	return &Subtitles{config: config}
+}
+
+// items -> the lines of f's cues to translate. a multi-line cue is translated as one sentence and wrapped back
+// into as many lines, unless it is dialogue, whose lines are translated one by one.
+func items(f *File) []*item {
+	var its []*item
+	for i, cue := range f.Cues {
+		if isDialogue(cue.Lines) {
+			for j, line := range cue.Lines {
+				its = append(its, newItem(i, j, line, 1))
+			}
+			continue
+		}
+		if len(cue.Lines) == 0 {
+			continue
+		}
+		its = append(its, newItem(i, -1, strings.Join(cue.Lines, " "), len(cue.Lines)))
+	}
+	return its
+}
+
+// Translate -> the cue text of f translated in place with translate, batches of cue lines at a time. a batch
+// whose translation doesn't come back with as many lines is translated again line by line. cues with a line that
+// failed keep their text and are reported.
+func (s *Subtitles) Translate(ctx context.Context, f *File, translate TranslateFunc) Report {
+	its := items(f)
+	var pending []*item
+	for _, it := range its {
+		if it.translatable() {
+			pending = append(pending, it)
+		} else {
+			it.translated = it.text
+		}
+	}
+
+	failed := map[int]error{}
+	for start := 0; start < len(pending); {
+		end, chars := start, 0
+		for end < len(pending) && end-start < max(s.config.BatchCues, 1) {
+			n := utf8.RuneCountInString(pending[end].text)
+			if end > start && chars+n > s.config.BatchChars {
+				break
+			}
+			chars += n
+			end++
+		}
+		s.translateBatch(ctx, pending[start:end], translate, failed)
+		start = end
+	}
+
+	report := Report{Cues: len(f.Cues)}
+	byCue := map[int][]*item{}
+	translatable := map[int]bool{}
+	for _, it := range its {
+		byCue[it.cue] = append(byCue[it.cue], it)
+		translatable[it.cue] = translatable[it.cue] || it.translatable()
+	}
+	for i, cue := range f.Cues {
+		if err, ok := failed[i]; ok {
+			report.Failures = append(report.Failures, Failure{
+				Cue:        i + 1,
+				Identifier: cue.Identifier,
+				Timing:     cue.Timing,
+				Text:       cue.Text(),
+				Error:      err.Error(),
+			})
+			continue
+		}
+		if !translatable[i] {
+			continue
+		}
+		var lines []string
+		for _, it := range byCue[i] {
+			if it.translatable() {
+				report.Chars += utf8.RuneCountInString(it.text)
+			}
+			lines = append(lines, it.restore(it.translated)...)
+		}
+		cue.Lines = lines
+	}
+	return report
+}
+
+// translateBatch -> set the translation of batch's items, or the error of their cues in failed.
+func (s *Subtitles) translateBatch(ctx context.Context, batch []*item, translate TranslateFunc, failed map[int]error) {
+	texts := make([]string, len(batch))
+	for i, it := range batch {
+		texts[i] = it.text
+	}
+	translated, err := translate(ctx, strings.Join(texts, "\n"))
+	if err != nil {
+		for _, it := range batch {
+			failed[it.cue] = err
+		}
+		return
+	}
+	var lines []string
+	for _, line := range strings.Split(translated, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == len(batch) {
+		for i, it := range batch {
+			it.translated = lines[i]
+		}
+		return
+	}
+	if len(batch) == 1 {
+		// one line came back as several.
+		batch[0].translated = strings.Join(lines, " ")
+		return
+	}
+	for _, it := range batch {
+		s.translateBatch(ctx, []*item{it}, translate, failed)
+	}
+}
@@ -0,0 +1,140 @@
+package subtitle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		n    int
+		want []string
+	}{
+		{name: "one line", txt: "Hello there, how are you?", n: 1, want: []string{"Hello there, how are you?"}},
+		{name: "zero lines", txt: "Hello there", n: 0, want: []string{"Hello there"}},
+		{name: "two lines", txt: "Hello there, how are you?", n: 2, want: []string{"Hello there,", "how are you?"}},
+		{name: "three lines", txt: "one two three four five six", n: 3, want: []string{"one two", "three four", "five six"}},
+		{name: "fewer words than lines", txt: "Hi", n: 2, want: []string{"H", "i"}},
+		{name: "last lines get a word each", txt: "a b c", n: 3, want: []string{"a", "b", "c"}},
+		{name: "a long word", txt: "I said supercalifragilistic", n: 2, want: []string{"I said", "supercalifragilistic"}},
+		{name: "one rune", txt: "A", n: 2, want: []string{"A"}},
+		{name: "no spaces", txt: "こんにちは世界", n: 2, want: []string{"こんに", "ちは世界"}},
+		{name: "empty", txt: "", n: 2, want: []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrap(tt.txt, tt.n)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("wrap(%q, %d) = %q, want %q", tt.txt, tt.n, got, tt.want)
+			}
+			if tt.n > 0 && len(got) > tt.n {
+				t.Fatalf("wrap(%q, %d) has %d lines", tt.txt, tt.n, len(got))
+			}
+		})
+	}
+}
+
+// upper -> a TranslateFunc upper casing txt, counting its calls and the runes sent.
+type upper struct {
+	calls int
+	chars int
+	// fail -> txt containing it fails.
+	fail string
+	// short -> batches of more than one line come back as one line.
+	short bool
+}
+
+func (u *upper) translate(_ context.Context, txt string) (string, error) {
+	u.calls++
+	u.chars += utf8.RuneCountInString(txt)
+	if u.fail != "" && strings.Contains(txt, u.fail) {
+		return "", errors.New("provider down")
+	}
+	if u.short {
+		txt = strings.ReplaceAll(txt, "\n", " ")
+	}
+	return strings.ToUpper(txt), nil
+}
+
+func parse(t *testing.T, input string) *File {
+	t.Helper()
+	f, err := Parse([]byte(input), FormatSRT)
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	return f
+}
+
+func TestTranslate(t *testing.T) {
+	input := "1\n00:00:01,000 --> 00:00:02,000\n<i>Hello</i> there,\nmy friend.\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\n- Who?\n- <b>Me</b>.\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\n♪ ♪\n"
+	want := [][]string{
+		{"<i>HELLO</i> THERE,", "MY FRIEND."},
+		{"- WHO?", "- <b>ME</b>."},
+		{"♪ ♪"},
+	}
+	// the text sent: tags and dashes at the start and end of a line are kept out of it, those inside are
+	// placeholders.
+	chars := utf8.RuneCountInString("Hello⟪0⟫ there, my friend.") + utf8.RuneCountInString("Who?") +
+		utf8.RuneCountInString("⟪0⟫Me⟪1⟫.")
+
+	tests := []struct {
+		name  string
+		u     *upper
+		calls int
+	}{
+		{name: "one batch", u: &upper{}, calls: 1},
+		{name: "short batch translated line by line", u: &upper{short: true}, calls: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := parse(t, input)
+			report := New(Config{BatchCues: 25, BatchChars: 3000}).Translate(context.Background(), f, tt.u.translate)
+			if len(report.Failures) != 0 || report.Translated() != 3 {
+				t.Fatalf("Translate() = %+v, want every cue translated", report)
+			}
+			for i, cue := range f.Cues {
+				if !reflect.DeepEqual(cue.Lines, want[i]) {
+					t.Errorf("cue %d = %q, want %q", i+1, cue.Lines, want[i])
+				}
+			}
+			if tt.u.calls != tt.calls {
+				t.Errorf("translate called %d times, want %d", tt.u.calls, tt.calls)
+			}
+			// lines sent again after a short batch are billed once.
+			if report.Chars != chars {
+				t.Errorf("Report.Chars = %d, want %d (sent %d)", report.Chars, chars, tt.u.chars)
+			}
+		})
+	}
+}
+
+func TestTranslateFailures(t *testing.T) {
+	input := "1\n00:00:01,000 --> 00:00:02,000\nGood morning.\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\n- Bad news.\n- Sorry.\n"
+	f := parse(t, input)
+	// one cue line a batch, so the dialogue's first line fails and its second doesn't.
+	report := New(Config{BatchCues: 1, BatchChars: 3000}).Translate(context.Background(), f, (&upper{fail: "Bad"}).translate)
+
+	if report.Cues != 2 || report.Translated() != 1 || len(report.Failures) != 1 {
+		t.Fatalf("Translate() = %+v, want one failed cue", report)
+	}
+	failure := report.Failures[0]
+	if failure.Cue != 2 || failure.Identifier != "2" || failure.Text != "- Bad news.\n- Sorry." || failure.Error == "" {
+		t.Fatalf("Failures[0] = %+v", failure)
+	}
+	if !reflect.DeepEqual(f.Cues[1].Lines, []string{"- Bad news.", "- Sorry."}) {
+		t.Fatalf("failed cue = %q, want its text kept", f.Cues[1].Lines)
+	}
+	// the failed cue's translated line isn't delivered, so it isn't billed.
+	if want := utf8.RuneCountInString("Good morning."); report.Chars != want {
+		t.Fatalf("Report.Chars = %d, want %d", report.Chars, want)
+	}
+}
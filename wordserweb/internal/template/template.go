@@ -1,10 +1,12 @@
 package template
 
 import (
+	"bytes"
 	"embed"
 	"fmt"
 	htmpl "html/template"
 	"io"
+	"path"
 
 	"github.com/labstack/echo/v4"
 )
@@ -20,18 +22,95 @@ func New() *Templates {
 
 	return &Templates{
 		templates: map[string]*htmpl.Template{
-			"dashboard": htmpl.Must(htmpl.ParseFS(tmplFS, "templates/dashboard.html", "templates/base.html")),
-			"signup":    htmpl.Must(htmpl.ParseFS(tmplFS, "templates/signup.html", "templates/base.html")),
-			"login":     htmpl.Must(htmpl.ParseFS(tmplFS, "templates/login.html", "templates/base.html")),
-			"analysis":  htmpl.Must(htmpl.ParseFS(tmplFS, "templates/analysis.html")),
+			"dashboard": htmpl.Must(htmpl.ParseFS(
+				tmplFS,
+				"templates/dashboard.html",
+				"templates/translate_targets.html",
+				"templates/base.html",
+			)),
+			"signup":           htmpl.Must(htmpl.ParseFS(tmplFS, "templates/signup.html", "templates/base.html")),
+			"login":            htmpl.Must(htmpl.ParseFS(tmplFS, "templates/login.html", "templates/base.html")),
+			"analysis_section": parseWithAnalyzers("templates/analysis_section.html"),
+			"synonyms":         htmpl.Must(htmpl.ParseFS(tmplFS, "templates/synonyms.html")),
+			"usage":            htmpl.Must(htmpl.ParseFS(tmplFS, "templates/usage.html")),
+			"admin_upstreams":  htmpl.Must(htmpl.ParseFS(tmplFS, "templates/admin_upstreams.html", "templates/base.html")),
+			"admin_usage":      htmpl.Must(htmpl.ParseFS(tmplFS, "templates/admin_usage.html", "templates/base.html")),
+			"orgs":             htmpl.Must(htmpl.ParseFS(tmplFS, "templates/orgs.html", "templates/base.html")),
+			"org":              htmpl.Must(htmpl.ParseFS(tmplFS, "templates/org.html", "templates/base.html")),
+			"org_invitation":   htmpl.Must(htmpl.ParseFS(tmplFS, "templates/org_invitation.html")),
+			"invitation":       htmpl.Must(htmpl.ParseFS(tmplFS, "templates/invitation.html", "templates/base.html")),
+			"history":          htmpl.Must(htmpl.ParseFS(tmplFS, "templates/history.html", "templates/base.html")),
+			"history_recent":   htmpl.Must(htmpl.ParseFS(tmplFS, "templates/history_recent.html")),
+			"jobs":             htmpl.Must(htmpl.ParseFS(tmplFS, "templates/jobs.html", "templates/base.html")),
+			"job":              htmpl.Must(htmpl.ParseFS(tmplFS, "templates/job.html", "templates/job_status.html", "templates/base.html")),
+			"job_status":       htmpl.Must(htmpl.ParseFS(tmplFS, "templates/job_status.html")),
+			"batches":          htmpl.Must(htmpl.ParseFS(tmplFS, "templates/batches.html", "templates/base.html")),
+			"batch": htmpl.Must(htmpl.ParseFS(
+				tmplFS,
+				"templates/batch.html",
+				"templates/batch_progress.html",
+				"templates/base.html",
+			)),
+			"batch_progress": htmpl.Must(htmpl.ParseFS(tmplFS, "templates/batch_progress.html")),
+			"webhooks":       htmpl.Must(htmpl.ParseFS(tmplFS, "templates/webhooks.html", "templates/base.html")),
+			"webhook": htmpl.Must(htmpl.ParseFS(
+				tmplFS,
+				"templates/webhook.html",
+				"templates/webhook_deliveries.html",
+				"templates/base.html",
+			)),
+			"webhook_deliveries": htmpl.Must(htmpl.ParseFS(tmplFS, "templates/webhook_deliveries.html")),
+			"search":             htmpl.Must(htmpl.ParseFS(tmplFS, "templates/search.html", "templates/base.html")),
+			"translate_targets":  htmpl.Must(htmpl.ParseFS(tmplFS, "templates/translate_targets.html")),
+			"translate_document": htmpl.Must(htmpl.ParseFS(tmplFS, "templates/translate_document.html")),
+			"glossaries":         htmpl.Must(htmpl.ParseFS(tmplFS, "templates/glossaries.html", "templates/base.html")),
+			"glossary":           htmpl.Must(htmpl.ParseFS(tmplFS, "templates/glossary.html", "templates/base.html")),
+			"memory":             htmpl.Must(htmpl.ParseFS(tmplFS, "templates/memory.html", "templates/base.html")),
+			"analysis": parseWithAnalyzers(
+				"templates/analysis.html",
+				"templates/analysis_section.html",
+				"templates/analysis_language.html",
+			),
+			"analysis_stream": htmpl.Must(htmpl.ParseFS(
+				tmplFS,
+				"templates/analysis_stream.html",
+				"templates/analysis_language.html",
+			)),
+			"history_analysis": parseWithAnalyzers(
+				"templates/history_analysis.html",
+				"templates/analysis.html",
+				"templates/analysis_section.html",
+				"templates/analysis_language.html",
+				"templates/base.html",
+			),
 		},
 	}
 }
 
+// parseWithAnalyzers -> files along with every analyzer_*.html and a fragment func executing one of them by name;
+// an analyzer's template is only known at run time.
+func parseWithAnalyzers(files ...string) *htmpl.Template {
+	var tmpl *htmpl.Template
+	funcs := htmpl.FuncMap{
+		"fragment": func(name string, data any) (htmpl.HTML, error) {
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			// already escaped by html/template while executing name.
+			return htmpl.HTML(buf.String()), nil
+		},
+	}
+	patterns := append(files, "templates/analyzer_*.html")
+	tmpl = htmpl.Must(htmpl.New(path.Base(files[0])).Funcs(funcs).ParseFS(tmplFS, patterns...))
+	return tmpl
+}
+
 func (t *Templates) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
 	if tmpl, ok := t.templates[name]; ok {
-		if name == "analysis" {
-			return tmpl.ExecuteTemplate(w, "analysis.html", data)
+		// fragments are parsed without base.html and rendered standalone for htmx swaps.
+		if tmpl.Lookup("base.html") == nil {
+			return tmpl.ExecuteTemplate(w, name+".html", data)
 		}
 		return tmpl.ExecuteTemplate(w, "base.html", data)
 	}
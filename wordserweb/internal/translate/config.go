@@ -0,0 +1,91 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// Providers -> comma separated provider names, tried in order until one translates. see New.
+	Providers string `mapstructure:"TRANSLATE_PROVIDERS"`
+	// Timeout -> per call deadline of the providers without their own.
+	Timeout time.Duration `mapstructure:"TRANSLATE_TIMEOUT"`
+	// LanguagesTTL -> how long the language pairs a provider lists are cached.
+	LanguagesTTL time.Duration `mapstructure:"TRANSLATE_LANGUAGES_TTL"`
+
+	PartnerBaseURL string        `mapstructure:"TRANSLATE_PARTNER_BASE_URL"`
+	PartnerTimeout time.Duration `mapstructure:"TRANSLATE_PARTNER_TIMEOUT"`
+
+	LibreTranslateBaseURL string        `mapstructure:"TRANSLATE_LIBRETRANSLATE_BASE_URL"`
+	LibreTranslateAPIKey  string        `mapstructure:"TRANSLATE_LIBRETRANSLATE_API_KEY"`
+	LibreTranslateTimeout time.Duration `mapstructure:"TRANSLATE_LIBRETRANSLATE_TIMEOUT"`
+}
+
+// ProviderNames -> Providers split and trimmed.
+func (c Config) ProviderNames() []string {
+	var names []string
+	for _, name := range strings.Split(c.Providers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("TRANSLATE_PROVIDERS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_PROVIDERS'")
+	}
+	viper.SetDefault("TRANSLATE_PROVIDERS", ProviderPartner)
+
+	if err := viper.BindEnv("TRANSLATE_TIMEOUT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_TIMEOUT'")
+	}
+	viper.SetDefault("TRANSLATE_TIMEOUT", "30s")
+
+	if err := viper.BindEnv("TRANSLATE_LANGUAGES_TTL"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_LANGUAGES_TTL'")
+	}
+	viper.SetDefault("TRANSLATE_LANGUAGES_TTL", "1h")
+
+	if err := viper.BindEnv("TRANSLATE_PARTNER_BASE_URL"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_PARTNER_BASE_URL'")
+	}
+	viper.SetDefault("TRANSLATE_PARTNER_BASE_URL", "http://backend:5000")
+
+	if err := viper.BindEnv("TRANSLATE_PARTNER_TIMEOUT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_PARTNER_TIMEOUT'")
+	}
+
+	if err := viper.BindEnv("TRANSLATE_LIBRETRANSLATE_BASE_URL"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_LIBRETRANSLATE_BASE_URL'")
+	}
+	viper.SetDefault("TRANSLATE_LIBRETRANSLATE_BASE_URL", "http://libretranslate:5000")
+
+	if err := viper.BindEnv("TRANSLATE_LIBRETRANSLATE_API_KEY"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_LIBRETRANSLATE_API_KEY'")
+	}
+
+	if err := viper.BindEnv("TRANSLATE_LIBRETRANSLATE_TIMEOUT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'TRANSLATE_LIBRETRANSLATE_TIMEOUT'")
+	}
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	if c.PartnerTimeout == 0 {
+		c.PartnerTimeout = c.Timeout
+	}
+	if c.LibreTranslateTimeout == 0 {
+		c.LibreTranslateTimeout = c.Timeout
+	}
+	if len(c.ProviderNames()) == 0 {
+		return c, fmt.Errorf("'TRANSLATE_PROVIDERS' can't be empty")
+	}
+	return c, nil
+}
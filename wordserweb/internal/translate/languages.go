@@ -0,0 +1,148 @@
+package translate
+
+import (
+	"errors"
+	"slices"
+	"sort"
+
+	"golang.org/x/text/language"
+)
+
+// ErrUnsupportedPair -> no provider translates from the source to the target language.
+var ErrUnsupportedPair = errors.New("language pair not supported")
+
+// defaultLanguages -> languages of the providers that can't list theirs, the ones the partner has always offered.
+var defaultLanguages = []string{"ar", "zh", "en", "fr", "de", "el", "it", "pt", "es", "ru"}
+
+// Pairs -> target language codes by source language code, as the provider names them.
+type Pairs map[string][]string
+
+// allPairs -> every one of codes to every other one.
+func allPairs(codes ...string) Pairs {
+	pairs := Pairs{}
+	for _, source := range codes {
+		for _, target := range codes {
+			if target != source {
+				pairs[source] = append(pairs[source], target)
+			}
+		}
+	}
+	return pairs
+}
+
+func (p Pairs) Supports(source string, target string) bool {
+	return slices.Contains(p[source], target)
+}
+
+// add -> other's pairs into p.
+func (p Pairs) add(other Pairs) {
+	for source, targets := range other {
+		for _, target := range targets {
+			if !p.Supports(source, target) {
+				p[source] = append(p[source], target)
+			}
+		}
+	}
+}
+
+// Catalog -> the language pairs on offer, and BCP 47 matching of the language tags users ask for onto their codes,
+// so "en-US" or "pt_BR" find "en" and "pt".
+type Catalog struct {
+	pairs   Pairs
+	sources codeMatcher
+	targets codeMatcher
+}
+
+func NewCatalog(pairs Pairs) *Catalog {
+	var sources, targets []string
+	for source, sourceTargets := range pairs {
+		sources = append(sources, source)
+		for _, target := range sourceTargets {
+			if !slices.Contains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return &Catalog{pairs: pairs, sources: newCodeMatcher(sources), targets: newCodeMatcher(targets)}
+}
+
+func (c *Catalog) Supports(source string, target string) bool {
+	return c.pairs.Supports(source, target)
+}
+
+// Sources -> every source language code, sorted.
+func (c *Catalog) Sources() []string {
+	return c.sources.codes
+}
+
+// Targets -> the target language codes of source, sorted. every target language when source is empty.
+func (c *Catalog) Targets(source string) []string {
+	if source == "" {
+		return c.targets.codes
+	}
+	targets := slices.Clone(c.pairs[source])
+	sort.Strings(targets)
+	return targets
+}
+
+// ResolveSource -> the source language code matching tag, a code or any BCP 47 tag.
+func (c *Catalog) ResolveSource(tag string) (string, bool) {
+	return c.sources.resolve(tag)
+}
+
+// ResolveTarget -> the target language code matching tag, a code or any BCP 47 tag.
+func (c *Catalog) ResolveTarget(tag string) (string, bool) {
+	return c.targets.resolve(tag)
+}
+
+// PreferredTarget -> the target language code best matching preferred, e.g. an Accept-Language header's tags.
+func (c *Catalog) PreferredTarget(preferred ...language.Tag) (string, bool) {
+	return c.targets.match(preferred...)
+}
+
+// codeMatcher -> matching of language tags onto codes. codes that aren't BCP 47, like LibreTranslate's "zt", only
+// match themselves.
+type codeMatcher struct {
+	codes   []string
+	tagged  []string
+	matcher language.Matcher
+}
+
+func newCodeMatcher(codes []string) codeMatcher {
+	sort.Strings(codes)
+	m := codeMatcher{codes: codes}
+	var tags []language.Tag
+	for _, code := range codes {
+		tag, err := language.Parse(code)
+		if err != nil {
+			continue
+		}
+		m.tagged = append(m.tagged, code)
+		tags = append(tags, tag)
+	}
+	m.matcher = language.NewMatcher(tags)
+	return m
+}
+
+func (m codeMatcher) resolve(tag string) (string, bool) {
+	if slices.Contains(m.codes, tag) {
+		return tag, true
+	}
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return "", false
+	}
+	return m.match(parsed)
+}
+
+// match -> the code best matching tags. "es-419" matches "es", but a mere guess like "sr" for "ru" doesn't.
+func (m codeMatcher) match(tags ...language.Tag) (string, bool) {
+	if len(m.tagged) == 0 || len(tags) == 0 {
+		return "", false
+	}
+	_, i, confidence := m.matcher.Match(tags...)
+	if confidence < language.High {
+		return "", false
+	}
+	return m.tagged[i], true
+}
@@ -0,0 +1,176 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+)
+
+// EndpointLibreTranslate -> resilience endpoint name of LibreTranslate's /translate.
+const EndpointLibreTranslate = "libretranslate"
+
+type libreTranslateReq struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateLanguage struct {
+	Code    string   `json:"code"`
+	Targets []string `json:"targets"`
+}
+
+type libreTranslateResp struct {
+	TranslatedText string `json:"translatedText"`
+	Error          string `json:"error"`
+}
+
+// LibreTranslateClient -> client for a LibreTranslate compatible server, self hosted or not.
+type LibreTranslateClient struct {
+	baseURL    *url.URL
+	apiKey     string
+	timeout    time.Duration
+	http       *http.Client
+	resilience *resilience.Group
+}
+
+func NewLibreTranslateClient(config Config, group *resilience.Group) (*LibreTranslateClient, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(config.LibreTranslateBaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid libretranslate base url: %w", err)
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid libretranslate base url: %s", config.LibreTranslateBaseURL)
+	}
+	return &LibreTranslateClient{
+		baseURL:    baseURL,
+		apiKey:     config.LibreTranslateAPIKey,
+		timeout:    config.LibreTranslateTimeout,
+		http:       &http.Client{},
+		resilience: group,
+	}, nil
+}
+
+func (l *LibreTranslateClient) Name() string {
+	return ProviderLibreTranslate
+}
+
+// Translate -> txt translated from source to target language codes. a POST, but translating is idempotent so it
+// is retried like the partner's GET.
+func (l *LibreTranslateClient) Translate(ctx context.Context, txt string, source string, target string) (string, error) {
+	body, err := json.Marshal(libreTranslateReq{
+		Q:      txt,
+		Source: source,
+		Target: target,
+		Format: "text",
+		APIKey: l.apiKey,
+	})
+	if err != nil {
+		return "", err
+	}
+	u := l.baseURL.JoinPath("translate").String()
+
+	var translated string
+	err = l.resilience.Do(ctx, EndpointLibreTranslate, true, func(ctx context.Context) error {
+		var err error
+		translated, err = l.attempt(ctx, u, body)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 &&
+			statusErr.StatusCode != http.StatusTooManyRequests {
+			return resilience.Permanent(err)
+		}
+		return err
+	})
+	return translated, err
+}
+
+// Languages -> the language pairs of the models the server has loaded.
+func (l *LibreTranslateClient) Languages(ctx context.Context) (Pairs, error) {
+	u := l.baseURL.JoinPath("languages").String()
+
+	var languages []libreTranslateLanguage
+	err := l.resilience.Do(ctx, EndpointLibreTranslate, true, func(ctx context.Context) error {
+		if l.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, l.timeout)
+			defer cancel()
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return resilience.Permanent(err)
+		}
+		resp, err := l.http.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			io.Copy(io.Discard, resp.Body)
+			return &StatusError{StatusCode: resp.StatusCode, Provider: ProviderLibreTranslate}
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&languages); err != nil {
+			return fmt.Errorf("failed to decode libretranslate languages: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := Pairs{}
+	for _, language := range languages {
+		for _, target := range language.Targets {
+			if target != language.Code {
+				pairs[language.Code] = append(pairs[language.Code], target)
+			}
+		}
+	}
+	return pairs, nil
+}
+
+func (l *LibreTranslateClient) attempt(ctx context.Context, u string, body []byte) (string, error) {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	transResp := &libreTranslateResp{}
+	if resp.StatusCode != http.StatusOK {
+		// the error message, e.g. an unsupported language, is worth logging.
+		if json.Unmarshal(data, transResp) == nil && transResp.Error != "" {
+			return "", &StatusError{StatusCode: resp.StatusCode, Provider: ProviderLibreTranslate, Message: transResp.Error}
+		}
+		return "", &StatusError{StatusCode: resp.StatusCode, Provider: ProviderLibreTranslate}
+	}
+	if err := json.Unmarshal(data, transResp); err != nil {
+		return "", fmt.Errorf("failed to decode translation: %w", err)
+	}
+	return transResp.TranslatedText, nil
+}
@@ -0,0 +1,110 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+)
+
+// EndpointPartnerTranslate -> resilience endpoint name of the partner's /translate.
+const EndpointPartnerTranslate = "translate"
+
+type partnerResp struct {
+	TranslatedText string `json:"translated_text"`
+}
+
+// PartnerClient -> client for the partner's translation backend.
+type PartnerClient struct {
+	baseURL    *url.URL
+	timeout    time.Duration
+	http       *http.Client
+	resilience *resilience.Group
+}
+
+func NewPartnerClient(config Config, group *resilience.Group) (*PartnerClient, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(config.PartnerBaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid translate partner base url: %w", err)
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid translate partner base url: %s", config.PartnerBaseURL)
+	}
+	return &PartnerClient{
+		baseURL:    baseURL,
+		timeout:    config.PartnerTimeout,
+		http:       &http.Client{},
+		resilience: group,
+	}, nil
+}
+
+func (p *PartnerClient) Name() string {
+	return ProviderPartner
+}
+
+// Translate -> txt translated from source to target language codes.
+func (p *PartnerClient) Translate(ctx context.Context, txt string, source string, target string) (string, error) {
+	u := p.baseURL.JoinPath("translate")
+	u.RawQuery = url.Values{
+		"inputText":      {txt},
+		"sourceLanguage": {source},
+		"targetLanguage": {target},
+	}.Encode()
+
+	var translated string
+	err := p.resilience.Do(ctx, EndpointPartnerTranslate, true, func(ctx context.Context) error {
+		var err error
+		translated, err = p.attempt(ctx, u.String())
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 &&
+			statusErr.StatusCode != http.StatusTooManyRequests {
+			return resilience.Permanent(err)
+		}
+		return err
+	})
+	return translated, err
+}
+
+// Languages -> the partner has no way to list its languages; it has always translated the defaultLanguages.
+func (p *PartnerClient) Languages(ctx context.Context) (Pairs, error) {
+	return allPairs(defaultLanguages...), nil
+}
+
+func (p *PartnerClient) attempt(ctx context.Context, u string) (string, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return "", &StatusError{StatusCode: resp.StatusCode, Provider: ProviderPartner}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	transResp := &partnerResp{}
+	if err := json.Unmarshal(data, transResp); err != nil {
+		return "", fmt.Errorf("failed to decode translation: %w", err)
+	}
+	return transResp.TranslatedText, nil
+}
@@ -0,0 +1,26 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+)
+
+// Stub -> a provider for local development that needs no backend. the "translation" is txt tagged with the
+// language pair, so it is the same every time.
+type Stub struct{}
+
+func NewStub() *Stub {
+	return &Stub{}
+}
+
+func (s *Stub) Name() string {
+	return ProviderStub
+}
+
+func (s *Stub) Translate(ctx context.Context, txt string, source string, target string) (string, error) {
+	return fmt.Sprintf("[%s->%s] %s", source, target, txt), nil
+}
+
+func (s *Stub) Languages(ctx context.Context) (Pairs, error) {
+	return allPairs(defaultLanguages...), nil
+}
@@ -0,0 +1,213 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	ProviderPartner        = "partner"
+	ProviderLibreTranslate = "libretranslate"
+	ProviderStub           = "stub"
+)
+
+// languagesRetry -> how long a provider whose languages couldn't be fetched isn't asked again, at most
+// languagesTTL. every translation needs them; without this each one would wait on a provider that is down.
+const languagesRetry = 30 * time.Second
+
+// Endpoints -> resilience endpoint names of every provider behind the translate upstream.
+var Endpoints = []string{EndpointPartnerTranslate, EndpointLibreTranslate}
+
+// StatusError -> a provider answered with something other than 200.
+type StatusError struct {
+	StatusCode int
+	Provider   string
+	// Message -> the provider's explanation, when it gave one.
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("failed to get translation from %s; statusCode: %d; %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("failed to get translation from %s; statusCode: %d", e.Provider, e.StatusCode)
+}
+
+// Provider -> a translation backend. source and target are language codes.
+type Provider interface {
+	// Name -> what translations by the provider are recorded as.
+	Name() string
+	Translate(ctx context.Context, txt string, source string, target string) (string, error)
+	// Languages -> the language pairs the provider translates.
+	Languages(ctx context.Context) (Pairs, error)
+}
+
+// Translation -> translated text and the provider that translated it.
+type Translation struct {
+	Text     string
+	Provider string
+}
+
+// Chain -> providers tried in order; each one that fails falls back to the next.
+type Chain struct {
+	providers []Provider
+	// languagesTTL -> how long a provider's language pairs are used before asking it again.
+	languagesTTL time.Duration
+
+	mu        sync.Mutex
+	languages map[string]cachedPairs
+	// fetches -> one fetch of a provider's languages at a time, callers asking meanwhile wait for it.
+	fetches singleflight.Group
+}
+
+type cachedPairs struct {
+	pairs   Pairs
+	fetched time.Time
+	// err -> why the last fetch, at failed, failed. nil once one succeeds.
+	err    error
+	failed time.Time
+}
+
+func NewChain(languagesTTL time.Duration, providers ...Provider) *Chain {
+	return &Chain{providers: providers, languagesTTL: languagesTTL, languages: map[string]cachedPairs{}}
+}
+
+// New -> a Chain of the providers config names. providers calling out go through group.
+func New(config Config, group *resilience.Group) (*Chain, error) {
+	var providers []Provider
+	for _, name := range config.ProviderNames() {
+		var (
+			provider Provider
+			err      error
+		)
+		switch name {
+		case ProviderPartner:
+			provider, err = NewPartnerClient(config, group)
+		case ProviderLibreTranslate:
+			provider, err = NewLibreTranslateClient(config, group)
+		case ProviderStub:
+			provider = NewStub()
+		default:
+			err = fmt.Errorf("unknown translate provider %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return NewChain(config.LanguagesTTL, providers...), nil
+}
+
+// unavailable -> err only says the provider wasn't called, its breaker open or its bulkhead full.
+func unavailable(err error) bool {
+	return errors.Is(err, resilience.ErrCircuitOpen) || errors.Is(err, resilience.ErrBulkheadFull)
+}
+
+// Translate -> txt translated by the first provider that succeeds, skipping the ones without the language pair.
+// when they all fail the error joins the errors of the providers that were called, so a provider that failed
+// outweighs one that was only unavailable; errors.Is finds resilience.ErrCircuitOpen only when every provider was
+// unavailable. ErrUnsupportedPair when no provider has the pair.
+func (c *Chain) Translate(ctx context.Context, txt string, source string, target string) (*Translation, error) {
+	var errs, called []error
+	for _, p := range c.providers {
+		// a provider whose pairs aren't known gets a go anyway; it answers for itself.
+		if pairs, err := c.pairs(ctx, p); err == nil && !pairs.Supports(source, target) {
+			continue
+		}
+		translated, err := p.Translate(ctx, txt, source, target)
+		if err == nil {
+			return &Translation{Text: translated, Provider: p.Name()}, nil
+		}
+		err = fmt.Errorf("%s: %w", p.Name(), err)
+		errs = append(errs, err)
+		if !unavailable(err) {
+			called = append(called, err)
+		}
+		// the caller is gone; the next provider would only fail the same way.
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w: %s to %s", ErrUnsupportedPair, source, target)
+	}
+	if len(called) > 0 {
+		return nil, errors.Join(called...)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Languages -> a Catalog of the language pairs any provider translates. providers that fail to list theirs are
+// left out; the error joins their errors when they all fail.
+func (c *Chain) Languages(ctx context.Context) (*Catalog, error) {
+	all := Pairs{}
+	var errs []error
+	for _, p := range c.providers {
+		pairs, err := c.pairs(ctx, p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+		all.add(pairs)
+	}
+	if len(errs) == len(c.providers) {
+		return nil, errors.Join(errs...)
+	}
+	return NewCatalog(all), nil
+}
+
+// pairs -> p's language pairs, cached for languagesTTL. when asking p again fails its last pairs are used, if any,
+// and p isn't asked again for languagesRetry.
+func (c *Chain) pairs(ctx context.Context, p Provider) (Pairs, error) {
+	c.mu.Lock()
+	cached, ok := c.languages[p.Name()]
+	c.mu.Unlock()
+	switch {
+	case ok && cached.err == nil && time.Since(cached.fetched) < c.languagesTTL:
+		return cached.pairs, nil
+	case ok && cached.err != nil && time.Since(cached.failed) < min(languagesRetry, c.languagesTTL):
+		return cached.fallback()
+	}
+
+	// the fetch is shared, so it isn't cut short by the caller that started it going away; each caller still
+	// stops waiting when its own ctx is done.
+	fetch := c.fetches.DoChan(p.Name(), func() (any, error) {
+		return c.fetch(context.WithoutCancel(ctx), p), nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-fetch:
+		return res.Val.(cachedPairs).fallback()
+	}
+}
+
+// fetch -> ask p for its language pairs and cache the answer, the pairs or the failure.
+func (c *Chain) fetch(ctx context.Context, p Provider) cachedPairs {
+	pairs, err := p.Languages(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached := c.languages[p.Name()]
+	if err != nil {
+		cached.err, cached.failed = err, time.Now()
+	} else {
+		cached = cachedPairs{pairs: pairs, fetched: time.Now()}
+	}
+	c.languages[p.Name()] = cached
+	return cached
+}
+
+// fallback -> the pairs last fetched when the last fetch failed, or its error when none ever succeeded.
+func (cp cachedPairs) fallback() (Pairs, error) {
+	if cp.err != nil && cp.pairs == nil {
+		return nil, cp.err
+	}
+	return cp.pairs, nil
+}
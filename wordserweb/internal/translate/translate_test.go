@@ -0,0 +1,296 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+)
+
+// fakeProvider -> a Provider answering with text or err, counting its calls.
+type fakeProvider struct {
+	name         string
+	text         string
+	err          error
+	pairs        Pairs
+	languagesErr error
+
+	mu             sync.Mutex
+	calls          int
+	languagesCalls int
+}
+
+func (f *fakeProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeProvider) Translate(ctx context.Context, txt string, source string, target string) (string, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.text, nil
+}
+
+func (f *fakeProvider) Languages(ctx context.Context) (Pairs, error) {
+	f.mu.Lock()
+	f.languagesCalls++
+	f.mu.Unlock()
+	if f.languagesErr != nil {
+		return nil, f.languagesErr
+	}
+	if f.pairs == nil {
+		return allPairs("en", "es", "fr"), nil
+	}
+	return f.pairs, nil
+}
+
+func TestChainUsesFirstProviderThatSucceeds(t *testing.T) {
+	first := &fakeProvider{name: "first", text: "hola"}
+	second := &fakeProvider{name: "second", text: "buenas"}
+	c := NewChain(time.Hour, first, second)
+
+	got, err := c.Translate(context.Background(), "hello", "en", "es")
+	if err != nil {
+		t.Fatalf("Translate() = %v", err)
+	}
+	if got.Text != "hola" || got.Provider != "first" {
+		t.Fatalf("Translate() = %+v, want first's translation", got)
+	}
+	if second.calls != 0 {
+		t.Fatal("the second provider was called though the first succeeded")
+	}
+}
+
+func TestChainFallsBack(t *testing.T) {
+	failing := &fakeProvider{name: "failing", err: errors.New("boom")}
+	unsupported := &fakeProvider{name: "unsupported", text: "nope", pairs: allPairs("de", "it")}
+	working := &fakeProvider{name: "working", text: "bonjour"}
+	c := NewChain(time.Hour, failing, unsupported, working)
+
+	got, err := c.Translate(context.Background(), "hello", "en", "fr")
+	if err != nil {
+		t.Fatalf("Translate() = %v", err)
+	}
+	if got.Provider != "working" || got.Text != "bonjour" {
+		t.Fatalf("Translate() = %+v, want working's translation", got)
+	}
+	if failing.calls != 1 || unsupported.calls != 0 {
+		t.Fatalf("calls: failing %d, unsupported %d", failing.calls, unsupported.calls)
+	}
+}
+
+func TestChainTriesProvidersWhosePairsAreUnknown(t *testing.T) {
+	unknown := &fakeProvider{name: "unknown", text: "hola", languagesErr: errors.New("no languages")}
+	c := NewChain(time.Hour, unknown)
+	got, err := c.Translate(context.Background(), "hello", "en", "es")
+	if err != nil || got.Provider != "unknown" {
+		t.Fatalf("Translate() = %+v, %v", got, err)
+	}
+}
+
+func TestChainUnsupportedPair(t *testing.T) {
+	c := NewChain(time.Hour, &fakeProvider{name: "a"}, &fakeProvider{name: "b", pairs: allPairs("de", "it")})
+	_, err := c.Translate(context.Background(), "hello", "en", "ja")
+	if !errors.Is(err, ErrUnsupportedPair) {
+		t.Fatalf("Translate() = %v, want %v", err, ErrUnsupportedPair)
+	}
+}
+
+func TestChainErrors(t *testing.T) {
+	open := &fakeProvider{name: "open", err: resilience.ErrCircuitOpen}
+	full := &fakeProvider{name: "full", err: resilience.ErrBulkheadFull}
+	failing := &fakeProvider{name: "failing", err: &StatusError{StatusCode: 500, Provider: "failing"}}
+
+	t.Run("a provider that was called outweighs unavailable ones", func(t *testing.T) {
+		_, err := NewChain(time.Hour, open, failing, full).Translate(context.Background(), "hello", "en", "es")
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("Translate() = %v, want the StatusError", err)
+		}
+		if errors.Is(err, resilience.ErrCircuitOpen) || errors.Is(err, resilience.ErrBulkheadFull) {
+			t.Fatalf("Translate() = %v, mustn't read as unavailable", err)
+		}
+	})
+
+	t.Run("every provider unavailable", func(t *testing.T) {
+		_, err := NewChain(time.Hour, open, full).Translate(context.Background(), "hello", "en", "es")
+		if !errors.Is(err, resilience.ErrCircuitOpen) || !errors.Is(err, resilience.ErrBulkheadFull) {
+			t.Fatalf("Translate() = %v, want both unavailable errors", err)
+		}
+		if !strings.Contains(err.Error(), "open:") || !strings.Contains(err.Error(), "full:") {
+			t.Fatalf("Translate() = %v, want every provider named", err)
+		}
+	})
+
+	t.Run("a cancelled caller stops the chain", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		cancelled := &fakeProvider{name: "cancelled", err: context.Canceled}
+		next := &fakeProvider{name: "next", text: "hola"}
+		_, err := NewChain(time.Hour, cancelled, next).Translate(ctx, "hello", "en", "es")
+		if !errors.Is(err, context.Canceled) || next.calls != 0 {
+			t.Fatalf("Translate() = %v with %d calls to the next provider", err, next.calls)
+		}
+	})
+}
+
+func TestChainLanguages(t *testing.T) {
+	a := &fakeProvider{name: "a", pairs: allPairs("en", "es")}
+	b := &fakeProvider{name: "b", pairs: allPairs("en", "de")}
+	broken := &fakeProvider{name: "broken", languagesErr: errors.New("down")}
+	catalog, err := NewChain(time.Hour, a, broken, b).Languages(context.Background())
+	if err != nil {
+		t.Fatalf("Languages() = %v", err)
+	}
+	for _, pair := range [][2]string{{"en", "es"}, {"es", "en"}, {"en", "de"}} {
+		if !catalog.Supports(pair[0], pair[1]) {
+			t.Errorf("catalog doesn't support %s to %s", pair[0], pair[1])
+		}
+	}
+	if catalog.Supports("es", "de") {
+		t.Error("no provider translates es to de")
+	}
+
+	if _, err := NewChain(time.Hour, broken).Languages(context.Background()); err == nil {
+		t.Fatal("Languages() = nil, want an error when every provider fails")
+	}
+}
+
+func TestChainCachesLanguages(t *testing.T) {
+	p := &fakeProvider{name: "p", text: "hola"}
+	c := NewChain(time.Hour, p)
+	for i := 0; i < 3; i++ {
+		if _, err := c.Translate(context.Background(), "hello", "en", "es"); err != nil {
+			t.Fatalf("Translate() = %v", err)
+		}
+	}
+	if p.languagesCalls != 1 {
+		t.Fatalf("languages fetched %d times, want 1", p.languagesCalls)
+	}
+}
+
+func TestStub(t *testing.T) {
+	s := NewStub()
+	got, err := s.Translate(context.Background(), "hola", "es", "en")
+	if err != nil || got != "[es->en] hola" {
+		t.Fatalf("Translate() = %q, %v", got, err)
+	}
+	pairs, err := s.Languages(context.Background())
+	if err != nil || !pairs.Supports("en", "es") {
+		t.Fatalf("Languages() = %v, %v", pairs, err)
+	}
+	if s.Name() != ProviderStub {
+		t.Fatalf("Name() = %q", s.Name())
+	}
+
+	got2, err := NewChain(time.Hour, s).Translate(context.Background(), "hola", "es", "en")
+	if err != nil || got2.Provider != ProviderStub || got2.Text != got {
+		t.Fatalf("chained Translate() = %+v, %v", got2, err)
+	}
+}
+
+func TestChainCachesFailedLanguages(t *testing.T) {
+	p := &fakeProvider{name: "p", text: "hola", languagesErr: errors.New("down")}
+	c := NewChain(time.Hour, p)
+	for i := 0; i < 3; i++ {
+		// the provider is given a go anyway, its pairs unknown.
+		if _, err := c.Translate(context.Background(), "hello", "en", "es"); err != nil {
+			t.Fatalf("Translate() = %v", err)
+		}
+	}
+	if p.languagesCalls != 1 {
+		t.Fatalf("languages fetched %d times, want 1 until languagesRetry passes", p.languagesCalls)
+	}
+
+	// once the failure is old enough the provider is asked again, and its pairs cached when it answers.
+	c.mu.Lock()
+	cached := c.languages["p"]
+	cached.failed = cached.failed.Add(-languagesRetry)
+	c.languages["p"] = cached
+	c.mu.Unlock()
+	p.mu.Lock()
+	p.languagesErr = nil
+	p.mu.Unlock()
+	if _, err := c.Languages(context.Background()); err != nil {
+		t.Fatalf("Languages() = %v", err)
+	}
+	if _, err := c.Languages(context.Background()); err != nil {
+		t.Fatalf("Languages() = %v", err)
+	}
+	if p.languagesCalls != 2 {
+		t.Fatalf("languages fetched %d times, want 2", p.languagesCalls)
+	}
+}
+
+func TestChainKeepsPairsWhenRefreshFails(t *testing.T) {
+	p := &fakeProvider{name: "p", pairs: allPairs("en", "de")}
+	c := NewChain(time.Nanosecond, p)
+	if _, err := c.Languages(context.Background()); err != nil {
+		t.Fatalf("Languages() = %v", err)
+	}
+	p.mu.Lock()
+	p.languagesErr = errors.New("down")
+	p.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	catalog, err := c.Languages(context.Background())
+	if err != nil || !catalog.Supports("en", "de") {
+		t.Fatalf("Languages() = %v, %v, want the last pairs", catalog, err)
+	}
+}
+
+// slowLanguages -> a Provider whose Languages blocks until release is closed.
+type slowLanguages struct {
+	fakeProvider
+	release chan struct{}
+}
+
+func (s *slowLanguages) Languages(ctx context.Context) (Pairs, error) {
+	<-s.release
+	return s.fakeProvider.Languages(ctx)
+}
+
+func TestChainCollapsesConcurrentLanguageFetches(t *testing.T) {
+	p := &slowLanguages{fakeProvider: fakeProvider{name: "p"}, release: make(chan struct{})}
+	c := NewChain(time.Hour, p)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Languages(context.Background())
+			errs <- err
+		}()
+	}
+	// let the callers pile up on the one fetch.
+	time.Sleep(20 * time.Millisecond)
+	close(p.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Languages() = %v", err)
+		}
+	}
+	if p.languagesCalls != 1 {
+		t.Fatalf("languages fetched %d times, want 1", p.languagesCalls)
+	}
+}
+
+func TestChainLanguagesWaitRespectsContext(t *testing.T) {
+	p := &slowLanguages{fakeProvider: fakeProvider{name: "p"}, release: make(chan struct{})}
+	defer close(p.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := NewChain(time.Hour, p).Languages(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Languages() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,56 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config -> character quotas per role. a limit of 0 means unlimited.
+type Config struct {
+	UserDailyCharLimit    int64 `mapstructure:"USAGE_USER_DAILY_CHAR_LIMIT"`
+	UserMonthlyCharLimit  int64 `mapstructure:"USAGE_USER_MONTHLY_CHAR_LIMIT"`
+	AdminDailyCharLimit   int64 `mapstructure:"USAGE_ADMIN_DAILY_CHAR_LIMIT"`
+	AdminMonthlyCharLimit int64 `mapstructure:"USAGE_ADMIN_MONTHLY_CHAR_LIMIT"`
+	// ReservationTTL -> how long Reserve holds characters that are never released, e.g. by a job that kept
+	// failing or a stream that was never opened.
+	ReservationTTL time.Duration `mapstructure:"USAGE_RESERVATION_TTL"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("USAGE_USER_DAILY_CHAR_LIMIT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'USAGE_USER_DAILY_CHAR_LIMIT'")
+	}
+	viper.SetDefault("USAGE_USER_DAILY_CHAR_LIMIT", 50000)
+
+	if err := viper.BindEnv("USAGE_USER_MONTHLY_CHAR_LIMIT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'USAGE_USER_MONTHLY_CHAR_LIMIT'")
+	}
+	viper.SetDefault("USAGE_USER_MONTHLY_CHAR_LIMIT", 500000)
+
+	if err := viper.BindEnv("USAGE_ADMIN_DAILY_CHAR_LIMIT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'USAGE_ADMIN_DAILY_CHAR_LIMIT'")
+	}
+	viper.SetDefault("USAGE_ADMIN_DAILY_CHAR_LIMIT", 0)
+
+	if err := viper.BindEnv("USAGE_ADMIN_MONTHLY_CHAR_LIMIT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'USAGE_ADMIN_MONTHLY_CHAR_LIMIT'")
+	}
+	viper.SetDefault("USAGE_ADMIN_MONTHLY_CHAR_LIMIT", 0)
+
+	if err := viper.BindEnv("USAGE_RESERVATION_TTL"); err != nil {
+		return c, fmt.Errorf("failed to bind 'USAGE_RESERVATION_TTL'")
+	}
+	viper.SetDefault("USAGE_RESERVATION_TTL", "1h")
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+	if c.ReservationTTL <= 0 {
+		return c, fmt.Errorf("'USAGE_RESERVATION_TTL' must be positive")
+	}
+
+	return c, nil
+}
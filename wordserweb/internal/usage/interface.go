@@ -0,0 +1,25 @@
+package usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type UsageStorer interface {
+	InsertUsageEvent(ctx context.Context, username string, api string, charCount int) error
+	GetUsageTotals(ctx context.Context, username string, since time.Time) (*postgres.UsageTotals, error)
+	ReserveUsage(
+		ctx context.Context,
+		username string,
+		chars int,
+		dayStart time.Time,
+		monthStart time.Time,
+		dailyLimit int64,
+		monthlyLimit int64,
+		expiresAt time.Time,
+	) (*postgres.UsageReservation, error)
+	DeleteUsageReservation(ctx context.Context, id int64) error
+	ListUsageReport(ctx context.Context, dayStart time.Time, monthStart time.Time) ([]*postgres.UserUsageReport, error)
+}
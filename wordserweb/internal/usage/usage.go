@@ -0,0 +1,217 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+)
+
+type API string
+
+const (
+	APISummary   API = "summary"
+	APISentiment API = "sentiment"
+	APIKeyword   API = "keyword"
+	APITranslate API = "translate"
+	APISynonyms  API = "synonyms"
+	// APIReadability -> runs in process but is metered like the rest of the analyzers.
+	APIReadability API = "readability"
+)
+
+func (a API) String() string {
+	return string(a)
+}
+
+var ErrQuotaExceeded = errors.New("usage quota exceeded")
+
+// Limits -> character quotas for a role. 0 means unlimited.
+type Limits struct {
+	DailyChars   int64
+	MonthlyChars int64
+}
+
+// Allowance -> used and remaining characters in one quota window.
+type Allowance struct {
+	Calls     int64
+	Used      int64
+	Limit     int64
+	Remaining int64
+}
+
+func (a Allowance) Unlimited() bool {
+	return a.Limit == 0
+}
+
+type Summary struct {
+	Username string
+	Role     auth.Role
+	Daily    Allowance
+	Monthly  Allowance
+}
+
+// Reservation -> characters Reserve holds of a user's quotas until Release. the zero Reservation holds none.
+type Reservation int64
+
+type Meter struct {
+	limits         map[auth.Role]Limits
+	reservationTTL time.Duration
+	store          UsageStorer
+	now            func() time.Time
+}
+
+func New(config Config, store UsageStorer) *Meter {
+	return &Meter{
+		limits: map[auth.Role]Limits{
+			auth.RoleUser: {
+				DailyChars:   config.UserDailyCharLimit,
+				MonthlyChars: config.UserMonthlyCharLimit,
+			},
+			auth.RoleAdmin: {
+				DailyChars:   config.AdminDailyCharLimit,
+				MonthlyChars: config.AdminMonthlyCharLimit,
+			},
+		},
+		reservationTTL: config.ReservationTTL,
+		store:          store,
+		now:            time.Now,
+	}
+}
+
+// CharCount -> the metered size of txt. runes not bytes so non latin text isn't penalized.
+func CharCount(txt string) int {
+	return utf8.RuneCountInString(txt)
+}
+
+// limitsFor -> unknown roles get the user role limits.
+func (m *Meter) limitsFor(role auth.Role) Limits {
+	if limits, ok := m.limits[role]; ok {
+		return limits
+	}
+	return m.limits[auth.RoleUser]
+}
+
+// windows -> start of the current utc day and month.
+func (m *Meter) windows() (time.Time, time.Time) {
+	now := m.now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return dayStart, monthStart
+}
+
+func newAllowance(calls int64, used int64, limit int64) Allowance {
+	remaining := limit - used
+	if limit == 0 || remaining < 0 {
+		remaining = 0
+	}
+	return Allowance{
+		Calls:     calls,
+		Used:      used,
+		Limit:     limit,
+		Remaining: remaining,
+	}
+}
+
+func (m *Meter) Summary(ctx context.Context, userCtx auth.UserContext) (Summary, error) {
+	dayStart, monthStart := m.windows()
+	limits := m.limitsFor(userCtx.Role)
+
+	daily, err := m.store.GetUsageTotals(ctx, userCtx.Username, dayStart)
+	if err != nil {
+		return Summary{}, err
+	}
+	monthly, err := m.store.GetUsageTotals(ctx, userCtx.Username, monthStart)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	return Summary{
+		Username: userCtx.Username,
+		Role:     userCtx.Role,
+		Daily:    newAllowance(daily.Calls, daily.Chars, limits.DailyChars),
+		Monthly:  newAllowance(monthly.Calls, monthly.Chars, limits.MonthlyChars),
+	}, nil
+}
+
+// Reserve -> hold chars of userCtx's daily and monthly quotas for the calls about to be made, or ErrQuotaExceeded
+// when they would go over either with what is used and held already. the check and the hold are one locked step,
+// so concurrent requests can't each pass on the same remaining characters. Record the calls, then Release the
+// hold; one that is never released runs out after the reservation ttl.
+func (m *Meter) Reserve(ctx context.Context, userCtx auth.UserContext, chars int) (Reservation, error) {
+	limits := m.limitsFor(userCtx.Role)
+	if limits.DailyChars == 0 && limits.MonthlyChars == 0 {
+		return 0, nil
+	}
+	dayStart, monthStart := m.windows()
+	reservation, err := m.store.ReserveUsage(
+		ctx,
+		userCtx.Username,
+		chars,
+		dayStart,
+		monthStart,
+		limits.DailyChars,
+		limits.MonthlyChars,
+		m.now().UTC().Add(m.reservationTTL),
+	)
+	if err != nil {
+		return 0, err
+	}
+	if reservation.ID != 0 {
+		return Reservation(reservation.ID), nil
+	}
+
+	daily := newAllowance(0, reservation.DailyChars, limits.DailyChars)
+	if !daily.Unlimited() && daily.Used+int64(chars) > daily.Limit {
+		return 0, fmt.Errorf(
+			"%w; daily remaining: %d; requested: %d;",
+			ErrQuotaExceeded,
+			daily.Remaining,
+			chars,
+		)
+	}
+	monthly := newAllowance(0, reservation.MonthlyChars, limits.MonthlyChars)
+	return 0, fmt.Errorf(
+		"%w; monthly remaining: %d; requested: %d;",
+		ErrQuotaExceeded,
+		monthly.Remaining,
+		chars,
+	)
+}
+
+// Release -> stop holding the characters of reservation.
+func (m *Meter) Release(ctx context.Context, reservation Reservation) error {
+	if reservation == 0 {
+		return nil
+	}
+	return m.store.DeleteUsageReservation(ctx, int64(reservation))
+}
+
+// Record -> meter a single call to api on behalf of userCtx.
+func (m *Meter) Record(ctx context.Context, userCtx auth.UserContext, api API, chars int) error {
+	return m.store.InsertUsageEvent(ctx, userCtx.Username, api.String(), chars)
+}
+
+// Report -> usage of every user in the current day and month windows.
+func (m *Meter) Report(ctx context.Context) ([]Summary, error) {
+	dayStart, monthStart := m.windows()
+	rows, err := m.store.ListUsageReport(ctx, dayStart, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]Summary, 0, len(rows))
+	for _, row := range rows {
+		role := auth.Role(row.Role)
+		limits := m.limitsFor(role)
+		report = append(report, Summary{
+			Username: row.Username,
+			Role:     role,
+			Daily:    newAllowance(row.DailyCalls, row.DailyChars, limits.DailyChars),
+			Monthly:  newAllowance(row.MonthlyCalls, row.MonthlyChars, limits.MonthlyChars),
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,320 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// fakeStore -> one user's usage; ReserveUsage checks and holds under one lock like the row lock in postgres.
+type fakeStore struct {
+	mu           sync.Mutex
+	used         map[time.Time]int64
+	held         map[int64]int
+	nextID       int64
+	reserveCalls int
+	deleted      []int64
+	// windows and expiresAt -> as last passed to ReserveUsage.
+	dayStart   time.Time
+	monthStart time.Time
+	expiresAt  time.Time
+}
+
+func (s *fakeStore) InsertUsageEvent(ctx context.Context, username string, api string, charCount int) error {
+	return nil
+}
+
+func (s *fakeStore) GetUsageTotals(ctx context.Context, username string, since time.Time) (*postgres.UsageTotals, error) {
+	return &postgres.UsageTotals{Chars: s.used[since]}, nil
+}
+
+func (s *fakeStore) ReserveUsage(
+	ctx context.Context,
+	username string,
+	chars int,
+	dayStart time.Time,
+	monthStart time.Time,
+	dailyLimit int64,
+	monthlyLimit int64,
+	expiresAt time.Time,
+) (*postgres.UsageReservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reserveCalls++
+	s.dayStart, s.monthStart, s.expiresAt = dayStart, monthStart, expiresAt
+
+	var held int64
+	for _, c := range s.held {
+		held += int64(c)
+	}
+	r := &postgres.UsageReservation{DailyChars: s.used[dayStart] + held, MonthlyChars: s.used[monthStart] + held}
+	if dailyLimit != 0 && r.DailyChars+int64(chars) > dailyLimit {
+		return r, nil
+	}
+	if monthlyLimit != 0 && r.MonthlyChars+int64(chars) > monthlyLimit {
+		return r, nil
+	}
+	s.nextID++
+	if s.held == nil {
+		s.held = map[int64]int{}
+	}
+	s.held[s.nextID] = chars
+	r.ID = s.nextID
+	return r, nil
+}
+
+func (s *fakeStore) DeleteUsageReservation(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.held, id)
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func (s *fakeStore) ListUsageReport(ctx context.Context, dayStart time.Time, monthStart time.Time) ([]*postgres.UserUsageReport, error) {
+	return nil, nil
+}
+
+func newTestMeter(store *fakeStore, now time.Time) *Meter {
+	m := New(Config{
+		UserDailyCharLimit:    100,
+		UserMonthlyCharLimit:  1000,
+		AdminDailyCharLimit:   0,
+		AdminMonthlyCharLimit: 5000,
+		ReservationTTL:        time.Hour,
+	}, store)
+	m.now = func() time.Time { return now }
+	return m
+}
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		name           string
+		now            time.Time
+		wantDayStart   time.Time
+		wantMonthStart time.Time
+	}{
+		{
+			name:           "mid month",
+			now:            time.Date(2024, 5, 17, 13, 45, 0, 0, time.UTC),
+			wantDayStart:   time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC),
+			wantMonthStart: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "midnight",
+			now:            time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			wantDayStart:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			wantMonthStart: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "last instant of the year",
+			now:            time.Date(2024, 12, 31, 23, 59, 59, 999999999, time.UTC),
+			wantDayStart:   time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+			wantMonthStart: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "local time already in the next utc month",
+			now:            time.Date(2024, 2, 29, 20, 30, 0, 0, time.FixedZone("EST", -5*60*60)),
+			wantDayStart:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantMonthStart: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dayStart, monthStart := newTestMeter(&fakeStore{}, tt.now).windows()
+			if !dayStart.Equal(tt.wantDayStart) || !monthStart.Equal(tt.wantMonthStart) {
+				t.Errorf(
+					"windows() = %v, %v; want %v, %v",
+					dayStart,
+					monthStart,
+					tt.wantDayStart,
+					tt.wantMonthStart,
+				)
+			}
+		})
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	m := newTestMeter(&fakeStore{}, time.Now())
+	tests := []struct {
+		role auth.Role
+		want Limits
+	}{
+		{role: auth.RoleUser, want: Limits{DailyChars: 100, MonthlyChars: 1000}},
+		{role: auth.RoleAdmin, want: Limits{DailyChars: 0, MonthlyChars: 5000}},
+		{role: auth.Role("unknown"), want: Limits{DailyChars: 100, MonthlyChars: 1000}},
+	}
+	for _, tt := range tests {
+		if got := m.limitsFor(tt.role); got != tt.want {
+			t.Errorf("limitsFor(%q) = %+v, want %+v", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestNewAllowance(t *testing.T) {
+	tests := []struct {
+		name  string
+		used  int64
+		limit int64
+		want  Allowance
+	}{
+		{name: "unlimited", used: 50, limit: 0, want: Allowance{Calls: 2, Used: 50, Limit: 0, Remaining: 0}},
+		{name: "under", used: 40, limit: 100, want: Allowance{Calls: 2, Used: 40, Limit: 100, Remaining: 60}},
+		{name: "at", used: 100, limit: 100, want: Allowance{Calls: 2, Used: 100, Limit: 100, Remaining: 0}},
+		{name: "over", used: 130, limit: 100, want: Allowance{Calls: 2, Used: 130, Limit: 100, Remaining: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newAllowance(2, tt.used, tt.limit)
+			if got != tt.want {
+				t.Errorf("newAllowance() = %+v, want %+v", got, tt.want)
+			}
+			if got.Unlimited() != (tt.limit == 0) {
+				t.Errorf("Unlimited() = %v, want %v", got.Unlimited(), tt.limit == 0)
+			}
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	now := time.Date(2024, 5, 17, 13, 45, 0, 0, time.UTC)
+	dayStart := time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		role        auth.Role
+		dailyUsed   int64
+		monthlyUsed int64
+		held        int
+		chars       int
+		// wantErr -> the message of an ErrQuotaExceeded, "" when the reservation is made.
+		wantErr string
+	}{
+		{name: "under both", role: auth.RoleUser, dailyUsed: 40, monthlyUsed: 400, chars: 60},
+		{
+			name:      "over daily",
+			role:      auth.RoleUser,
+			dailyUsed: 40, monthlyUsed: 400,
+			chars:   61,
+			wantErr: "usage quota exceeded; daily remaining: 60; requested: 61;",
+		},
+		{
+			name:      "over daily with what is held",
+			role:      auth.RoleUser,
+			dailyUsed: 40, monthlyUsed: 400,
+			held:    30,
+			chars:   31,
+			wantErr: "usage quota exceeded; daily remaining: 30; requested: 31;",
+		},
+		{
+			name:      "over monthly",
+			role:      auth.RoleUser,
+			dailyUsed: 0, monthlyUsed: 950,
+			chars:   60,
+			wantErr: "usage quota exceeded; monthly remaining: 50; requested: 60;",
+		},
+		{name: "admin without a daily limit", role: auth.RoleAdmin, dailyUsed: 4000, monthlyUsed: 4000, chars: 1000},
+		{
+			name:      "admin over monthly",
+			role:      auth.RoleAdmin,
+			dailyUsed: 4000, monthlyUsed: 4000,
+			chars:   1001,
+			wantErr: "usage quota exceeded; monthly remaining: 1000; requested: 1001;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{used: map[time.Time]int64{dayStart: tt.dailyUsed, monthStart: tt.monthlyUsed}}
+			if tt.held > 0 {
+				store.held = map[int64]int{100: tt.held}
+				store.nextID = 100
+			}
+			m := newTestMeter(store, now)
+
+			reservation, err := m.Reserve(context.Background(), auth.UserContext{Username: "ann", Role: tt.role}, tt.chars)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrQuotaExceeded) || err.Error() != tt.wantErr {
+					t.Fatalf("Reserve() error = %v, want %q", err, tt.wantErr)
+				}
+				if reservation != 0 {
+					t.Errorf("Reserve() = %d on error, want 0", reservation)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reservation == 0 || store.held[int64(reservation)] != tt.chars {
+				t.Errorf("Reserve() = %d holding %d, want %d held", reservation, store.held[int64(reservation)], tt.chars)
+			}
+			if !store.dayStart.Equal(dayStart) || !store.monthStart.Equal(monthStart) {
+				t.Errorf("ReserveUsage() windows = %v, %v; want %v, %v", store.dayStart, store.monthStart, dayStart, monthStart)
+			}
+			if want := now.Add(time.Hour); !store.expiresAt.Equal(want) {
+				t.Errorf("ReserveUsage() expires at %v, want %v", store.expiresAt, want)
+			}
+		})
+	}
+}
+
+func TestReserveUnlimited(t *testing.T) {
+	store := &fakeStore{}
+	m := New(Config{ReservationTTL: time.Hour}, store)
+
+	reservation, err := m.Reserve(context.Background(), auth.UserContext{Username: "ann", Role: auth.RoleUser}, 1<<20)
+	if err != nil || reservation != 0 {
+		t.Fatalf("Reserve() = %d, %v; want 0, nil", reservation, err)
+	}
+	if store.reserveCalls != 0 {
+		t.Errorf("Reserve() made %d store calls without limits, want none", store.reserveCalls)
+	}
+	if err := m.Release(context.Background(), reservation); err != nil || len(store.deleted) != 0 {
+		t.Errorf("Release(0) = %v with %d deletes, want nil and none", err, len(store.deleted))
+	}
+}
+
+func TestReserveConcurrent(t *testing.T) {
+	store := &fakeStore{}
+	m := newTestMeter(store, time.Now())
+	userCtx := auth.UserContext{Username: "ann", Role: auth.RoleUser}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var reservations []Reservation
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := m.Reserve(context.Background(), userCtx, 10)
+			if err != nil {
+				if !errors.Is(err, ErrQuotaExceeded) {
+					t.Error(err)
+				}
+				return
+			}
+			mu.Lock()
+			reservations = append(reservations, reservation)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(reservations) != 10 {
+		t.Fatalf("Reserve() made %d reservations of 10 chars under a daily limit of 100, want 10", len(reservations))
+	}
+
+	if _, err := m.Reserve(context.Background(), userCtx, 10); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Reserve() error = %v with the quota held, want %v", err, ErrQuotaExceeded)
+	}
+	if err := m.Release(context.Background(), reservations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Reserve(context.Background(), userCtx, 10); err != nil {
+		t.Errorf("Reserve() error = %v after a Release, want nil", err)
+	}
+}
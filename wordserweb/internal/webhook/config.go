@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// Timeout -> how long an endpoint has to answer a delivery.
+	Timeout time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	// MaxEndpoints -> endpoints a user may register.
+	MaxEndpoints int `mapstructure:"WEBHOOK_MAX_ENDPOINTS"`
+	// Allowlist -> comma separated hosts, ips and cidrs endpoints may be at even though they are private, loopback
+	// or otherwise internal. see fetch.Guard.
+	Allowlist string `mapstructure:"WEBHOOK_ALLOWLIST"`
+	// DisableAfter -> deliveries in a row that fail for good before their endpoint stops being sent events. 0 never
+	// disables an endpoint.
+	DisableAfter int `mapstructure:"WEBHOOK_DISABLE_AFTER"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("WEBHOOK_TIMEOUT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'WEBHOOK_TIMEOUT'")
+	}
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+
+	if err := viper.BindEnv("WEBHOOK_MAX_ENDPOINTS"); err != nil {
+		return c, fmt.Errorf("failed to bind 'WEBHOOK_MAX_ENDPOINTS'")
+	}
+	viper.SetDefault("WEBHOOK_MAX_ENDPOINTS", 10)
+
+	if err := viper.BindEnv("WEBHOOK_ALLOWLIST"); err != nil {
+		return c, fmt.Errorf("failed to bind 'WEBHOOK_ALLOWLIST'")
+	}
+	viper.SetDefault("WEBHOOK_ALLOWLIST", "")
+
+	if err := viper.BindEnv("WEBHOOK_DISABLE_AFTER"); err != nil {
+		return c, fmt.Errorf("failed to bind 'WEBHOOK_DISABLE_AFTER'")
+	}
+	viper.SetDefault("WEBHOOK_DISABLE_AFTER", 5)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/fetch"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+// errorBodyBytes -> how much of a failed response's body is kept in the delivery log.
+const errorBodyBytes = 256
+
+// DeliveryPayload -> input of a jobser.KindWebhook job.
+type DeliveryPayload struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+type DeliveryResult struct {
+	DeliveryID int64 `json:"delivery_id"`
+	EndpointID int64 `json:"endpoint_id"`
+	StatusCode int   `json:"status_code"`
+}
+
+// StatusError -> the endpoint answered with something other than 2xx.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("endpoint answered with statusCode: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("endpoint answered with statusCode: %d; body: %s", e.StatusCode, e.Body)
+}
+
+// Dispatcher -> manages users' webhook endpoints and sends them events. every event sent is logged as a delivery
+// and posted from a jobser.KindWebhook job, so failed attempts are retried with the queue's backoff.
+type Dispatcher struct {
+	config Config
+	store  WebhookStorer
+	jobs   Enqueuer
+	logger Logger
+	// guard -> keeps endpoints off internal addresses, when they are registered and when they are dialed.
+	guard *fetch.Guard
+	http  *http.Client
+	now   func() time.Time
+}
+
+func New(config Config, store WebhookStorer, jobs Enqueuer, logger Logger) (*Dispatcher, error) {
+	guard, err := fetch.NewGuard(strings.Split(config.Allowlist, ","))
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		// no proxy from the environment; it would dial on the guard's behalf.
+		Proxy:               nil,
+		DialContext:         guard.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return &Dispatcher{
+		config: config,
+		store:  store,
+		jobs:   jobs,
+		logger: logger,
+		guard:  guard,
+		http: &http.Client{
+			Transport: transport,
+			// a redirect is answered like any other non 2xx; the signed body isn't sent anywhere else.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}, nil
+}
+
+// CreateEndpoint -> register rawURL to be sent events for userCtx. returns the new endpoint id. urls at private,
+// loopback, link local or otherwise internal addresses are refused unless allowlisted.
+func (d *Dispatcher) CreateEndpoint(ctx context.Context, userCtx auth.UserContext, rawURL string, events []Event) (int64, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, fmt.Errorf("%w; expected an absolute http or https url;", ErrInvalidURL)
+	}
+	if u.User != nil {
+		return 0, fmt.Errorf("%w; credentials in urls aren't allowed;", ErrInvalidURL)
+	}
+	if err := d.guard.Check(ctx, u.Hostname()); err != nil {
+		return 0, fmt.Errorf("%w; %v;", ErrInvalidURL, err)
+	}
+	if len(events) == 0 {
+		return 0, ErrNoEvents
+	}
+
+	existing, err := d.store.ListWebhookEndpoints(ctx, userCtx.Username, "")
+	if err != nil {
+		return 0, err
+	}
+	if d.config.MaxEndpoints > 0 && len(existing) >= d.config.MaxEndpoints {
+		return 0, fmt.Errorf("%w; at most %d;", ErrTooManyEndpoints, d.config.MaxEndpoints)
+	}
+
+	secret, err := NewSecret()
+	if err != nil {
+		return 0, err
+	}
+	endpoint := &postgres.WebhookEndpoint{
+		Username: userCtx.Username,
+		URL:      u.String(),
+		Secret:   secret,
+	}
+	for _, e := range events {
+		endpoint.Events = append(endpoint.Events, e.String())
+	}
+	return d.store.InsertWebhookEndpoint(ctx, endpoint)
+}
+
+func (d *Dispatcher) Endpoint(ctx context.Context, userCtx auth.UserContext, id int64) (*postgres.WebhookEndpoint, error) {
+	return d.store.GetWebhookEndpoint(ctx, userCtx.Username, id)
+}
+
+func (d *Dispatcher) Endpoints(ctx context.Context, userCtx auth.UserContext) ([]*postgres.WebhookEndpoint, error) {
+	return d.store.ListWebhookEndpoints(ctx, userCtx.Username, "")
+}
+
+// DeleteEndpoint -> remove the endpoint and its delivery log. queued deliveries to it are dropped.
+func (d *Dispatcher) DeleteEndpoint(ctx context.Context, userCtx auth.UserContext, id int64) error {
+	return d.store.DeleteWebhookEndpoint(ctx, userCtx.Username, id)
+}
+
+// Deliveries -> the newest deliveries to endpoint id.
+func (d *Dispatcher) Deliveries(ctx context.Context, userCtx auth.UserContext, endpointID int64, limit int) ([]*postgres.WebhookDelivery, error) {
+	return d.store.ListWebhookDeliveries(ctx, userCtx.Username, endpointID, limit)
+}
+
+// Publish -> send event with data as its json payload to every endpoint of userCtx subscribed to it. failures are
+// logged rather than returned; what the event reports on has happened regardless.
+func (d *Dispatcher) Publish(ctx context.Context, userCtx auth.UserContext, event Event, data any) {
+	endpoints, err := d.store.ListWebhookEndpoints(ctx, userCtx.Username, event.String())
+	if err != nil {
+		d.logger.Errorf("failed to list webhook endpoints for %s: %v", event, err)
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		d.logger.Errorf("failed to encode %s: %v", event, err)
+		return
+	}
+	for _, endpoint := range endpoints {
+		if _, err := d.send(ctx, userCtx, endpoint.ID, event, b); err != nil {
+			d.logger.Errorf("failed to queue %s for webhook endpoint %d: %v", event, endpoint.ID, err)
+		}
+	}
+}
+
+// Ping -> send a ping event to endpoint id. returns the delivery id.
+func (d *Dispatcher) Ping(ctx context.Context, userCtx auth.UserContext, id int64) (int64, error) {
+	endpoint, err := d.store.GetWebhookEndpoint(ctx, userCtx.Username, id)
+	if err != nil {
+		return 0, err
+	}
+	b, err := json.Marshal(map[string]any{"endpoint_id": endpoint.ID, "url": endpoint.URL})
+	if err != nil {
+		return 0, err
+	}
+	return d.send(ctx, userCtx, endpoint.ID, EventPing, b)
+}
+
+// Replay -> send delivery id again, with the same id and payload and a fresh signature.
+func (d *Dispatcher) Replay(ctx context.Context, userCtx auth.UserContext, id int64) error {
+	delivery, err := d.store.GetWebhookDelivery(ctx, userCtx.Username, id)
+	if err != nil {
+		return err
+	}
+	if delivery.State == postgres.WebhookDeliveryStatePending {
+		return ErrDeliveryPending
+	}
+	if err := d.store.ResetWebhookDelivery(ctx, userCtx.Username, id); err != nil {
+		return err
+	}
+	_, err = d.jobs.Enqueue(ctx, userCtx, jobser.KindWebhook, DeliveryPayload{DeliveryID: id})
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, userCtx auth.UserContext, endpointID int64, event Event, data json.RawMessage) (int64, error) {
+	id, err := d.store.InsertWebhookDelivery(ctx, &postgres.WebhookDelivery{
+		EndpointID: endpointID,
+		Event:      event.String(),
+		Payload:    data,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := d.jobs.Enqueue(ctx, userCtx, jobser.KindWebhook, DeliveryPayload{DeliveryID: id}); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Deliver -> the jobser.Handler of jobser.KindWebhook. posts the delivery once and logs the attempt; the delivery
+// fails for good when the job's last attempt does.
+func (d *Dispatcher) Deliver(ctx context.Context, job *postgres.Job) (any, error) {
+	var payload DeliveryPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, resilience.Permanent(err)
+	}
+
+	userCtx := jobser.UserContext(job)
+	delivery, err := d.store.GetWebhookDelivery(ctx, userCtx.Username, payload.DeliveryID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			// the endpoint was deleted since.
+			return nil, resilience.Permanent(err)
+		}
+		return nil, err
+	}
+	endpoint, err := d.store.GetWebhookEndpoint(ctx, userCtx.Username, delivery.EndpointID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return nil, resilience.Permanent(err)
+		}
+		return nil, err
+	}
+
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.ID,
+		Event:     Event(delivery.Event),
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return nil, resilience.Permanent(err)
+	}
+
+	statusCode, postErr := d.post(ctx, endpoint, delivery, body)
+
+	state := postgres.WebhookDeliveryStateSucceeded
+	outcome := state
+	var lastError *string
+	if postErr != nil {
+		msg := postErr.Error()
+		lastError = &msg
+		state = postgres.WebhookDeliveryStatePending
+		outcome = "retried"
+		if job.Attempts >= job.MaxAttempts {
+			state = postgres.WebhookDeliveryStateFailed
+			outcome = state
+		}
+	}
+	attempts.WithLabelValues(delivery.Event, outcome).Inc()
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	// logged even when cut off by shutdown; the attempt happened either way.
+	if err := d.store.RecordWebhookAttempt(context.WithoutCancel(ctx), delivery.ID, state, code, lastError); err != nil {
+		return nil, errors.Join(postErr, err)
+	}
+	if state != postgres.WebhookDeliveryStatePending {
+		succeeded := state == postgres.WebhookDeliveryStateSucceeded
+		disabled, err := d.store.RecordWebhookEndpointOutcome(
+			context.WithoutCancel(ctx), endpoint.ID, succeeded, d.config.DisableAfter,
+		)
+		if err != nil {
+			d.logger.Errorf("failed to record delivery %d to webhook endpoint %d: %v", delivery.ID, endpoint.ID, err)
+		}
+		if disabled && !succeeded && endpoint.DisabledAt == nil {
+			d.logger.Errorf("disabled webhook endpoint %d after %d failed deliveries", endpoint.ID, d.config.DisableAfter)
+		}
+	}
+	if postErr != nil {
+		return nil, postErr
+	}
+	return DeliveryResult{DeliveryID: delivery.ID, EndpointID: endpoint.ID, StatusCode: statusCode}, nil
+}
+
+// post -> one signed attempt. returns the response status code, 0 when there was no response.
+func (d *Dispatcher) post(
+	ctx context.Context,
+	endpoint *postgres.WebhookEndpoint,
+	delivery *postgres.WebhookDelivery,
+	body []byte,
+) (int, error) {
+	if d.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wordserweb-webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, d.now(), body))
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyBytes))
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(excerpt))}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
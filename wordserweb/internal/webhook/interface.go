@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/auth"
+	"github.com/nolandseigler/wordser/wordserweb/internal/jobser"
+	"github.com/nolandseigler/wordser/wordserweb/internal/storage/postgres"
+)
+
+type WebhookStorer interface {
+	InsertWebhookEndpoint(ctx context.Context, e *postgres.WebhookEndpoint) (int64, error)
+	GetWebhookEndpoint(ctx context.Context, username string, id int64) (*postgres.WebhookEndpoint, error)
+	ListWebhookEndpoints(ctx context.Context, username string, event string) ([]*postgres.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, username string, id int64) error
+	InsertWebhookDelivery(ctx context.Context, dl *postgres.WebhookDelivery) (int64, error)
+	GetWebhookDelivery(ctx context.Context, username string, id int64) (*postgres.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, username string, endpointID int64, limit int) ([]*postgres.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, id int64, state string, statusCode *int, lastError *string) error
+	ResetWebhookDelivery(ctx context.Context, username string, id int64) error
+	RecordWebhookEndpointOutcome(ctx context.Context, id int64, succeeded bool, disableAfter int) (bool, error)
+}
+
+// Enqueuer -> the job queue deliveries are run from.
+type Enqueuer interface {
+	Enqueue(ctx context.Context, userCtx auth.UserContext, kind jobser.Kind, payload any) (int64, error)
+}
+
+// Logger -> where Publish reports what it can't return.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+}
@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var attempts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "wordserweb",
+		Subsystem: "webhook",
+		Name:      "attempts_total",
+		Help:      "Webhook delivery attempts by event and outcome: succeeded, retried or failed.",
+	},
+	[]string{"event", "outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(attempts)
+}
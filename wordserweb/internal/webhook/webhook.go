@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Event string
+
+const (
+	EventAnalysisCompleted    Event = "analysis.completed"
+	EventTranslationCompleted Event = "translation.completed"
+	EventBatchCompleted       Event = "batch.completed"
+	// EventPing -> sent to one endpoint when its owner asks, to check it is reachable. not subscribable.
+	EventPing Event = "ping"
+)
+
+// Events -> the events an endpoint can subscribe to.
+var Events = []Event{EventAnalysisCompleted, EventTranslationCompleted, EventBatchCompleted}
+
+func (e Event) String() string {
+	return string(e)
+}
+
+func EventFromName(name string) (Event, bool) {
+	for _, e := range Events {
+		if e.String() == name {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+const (
+	// HeaderSignature -> see Sign.
+	HeaderSignature = "Wordser-Signature"
+	HeaderEvent     = "Wordser-Event"
+	// HeaderDelivery -> the delivery id, the same on every attempt and replay so receivers can drop duplicates.
+	HeaderDelivery = "Wordser-Delivery"
+
+	secretPrefix = "whsec_"
+)
+
+var (
+	ErrInvalidURL         = errors.New("invalid webhook url")
+	ErrNoEvents           = errors.New("no webhook events selected")
+	ErrTooManyEndpoints   = errors.New("too many webhook endpoints")
+	ErrDeliveryPending    = errors.New("webhook delivery is still pending")
+	ErrInvalidSignature   = errors.New("invalid webhook signature")
+	ErrSignatureTooOld    = errors.New("webhook signature timestamp outside tolerance")
+	ErrMalformedSignature = errors.New("malformed webhook signature")
+)
+
+// Envelope -> the json body of every delivery.
+type Envelope struct {
+	ID        int64           `json:"id"`
+	Event     Event           `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewSecret -> a random signing secret for a new endpoint.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign -> the Wordser-Signature header for body sent at t: "t=<unix seconds>,v1=<hex hmac>". the hmac is
+// HMAC-SHA256 keyed by secret over "<unix seconds>.<body>", so a captured body can't be resent with a new time.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret string, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify -> check a Wordser-Signature header against body as a receiver would. signatures made more than
+// tolerance from now are rejected; 0 skips that check.
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sigs = append(sigs, value)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrMalformedSignature
+	}
+
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w; age: %s;", ErrSignatureTooOld, age.Round(time.Second))
+		}
+	}
+
+	expected := []byte(signature(secret, ts, body))
+	for _, sig := range sigs {
+		if hmac.Equal(expected, []byte(sig)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
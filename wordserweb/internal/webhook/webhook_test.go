@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":1,"event":"ping"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign(secret, signedAt, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{name: "valid", secret: secret, header: header, body: body, now: signedAt.Add(time.Minute)},
+		{name: "tampered body", secret: secret, header: header, body: []byte(`{"id":2,"event":"ping"}`), now: signedAt, want: ErrInvalidSignature},
+		{name: "other secret", secret: "whsec_other", header: header, body: body, now: signedAt, want: ErrInvalidSignature},
+		{name: "too old", secret: secret, header: header, body: body, now: signedAt.Add(10 * time.Minute), want: ErrSignatureTooOld},
+		{name: "from the future", secret: secret, header: header, body: body, now: signedAt.Add(-10 * time.Minute), want: ErrSignatureTooOld},
+		{name: "no signature", secret: secret, header: "t=1700000000", body: body, now: signedAt, want: ErrMalformedSignature},
+		{name: "no timestamp", secret: secret, header: header[strings.Index(header, "v1="):], body: body, now: signedAt, want: ErrMalformedSignature},
+		{name: "garbage", secret: secret, header: "garbage", body: body, now: signedAt, want: ErrMalformedSignature},
+		{
+			name:   "one of several signatures",
+			secret: secret,
+			header: header + ",v1=" + strings.Repeat("0", 64),
+			body:   body,
+			now:    signedAt,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if tt.want == nil && err != nil {
+				t.Fatalf("Verify() = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyWithoutTolerance(t *testing.T) {
+	body := []byte("{}")
+	header := Sign("whsec_test", time.Unix(0, 0), body)
+	if err := Verify("whsec_test", header, body, 0, time.Now()); err != nil {
+		t.Fatalf("Verify() = %v, want nil", err)
+	}
+}
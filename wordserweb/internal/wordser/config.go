@@ -0,0 +1,41 @@
+package wordser
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config -> where the wordser service lives and how long a single call may take.
+type Config struct {
+	BaseURL string `mapstructure:"WORDSER_BASE_URL"`
+	// Timeout -> per call deadline. the request context can still cancel a call sooner.
+	Timeout             time.Duration `mapstructure:"WORDSER_TIMEOUT"`
+	MaxIdleConnsPerHost int           `mapstructure:"WORDSER_MAX_IDLE_CONNS_PER_HOST"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	c := Config{}
+	if err := viper.BindEnv("WORDSER_BASE_URL"); err != nil {
+		return c, fmt.Errorf("failed to bind 'WORDSER_BASE_URL'")
+	}
+	viper.SetDefault("WORDSER_BASE_URL", "http://wordser:8080")
+
+	// the models are loaded per request so summaries are slow.
+	if err := viper.BindEnv("WORDSER_TIMEOUT"); err != nil {
+		return c, fmt.Errorf("failed to bind 'WORDSER_TIMEOUT'")
+	}
+	viper.SetDefault("WORDSER_TIMEOUT", "2m")
+
+	if err := viper.BindEnv("WORDSER_MAX_IDLE_CONNS_PER_HOST"); err != nil {
+		return c, fmt.Errorf("failed to bind 'WORDSER_MAX_IDLE_CONNS_PER_HOST'")
+	}
+	viper.SetDefault("WORDSER_MAX_IDLE_CONNS_PER_HOST", 16)
+
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal config")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,43 @@
+package wordser
+
+import (
+	"fmt"
+)
+
+// NetworkError -> the call never got a response: dial, timeout, cancelled context, reset connection.
+type NetworkError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("wordser %s: request failed: %v", e.Endpoint, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// StatusError -> wordser answered with something other than 200.
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("wordser %s: unexpected status code: %d", e.Endpoint, e.StatusCode)
+}
+
+// DecodeError -> wordser answered 200 but the body wasn't what we expected.
+type DecodeError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("wordser %s: failed to decode response: %v", e.Endpoint, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,10 @@
+package wordser
+
+import (
+	"net/http"
+)
+
+// HTTPDoer -> what Client sends its requests with. an *http.Client, or a fake in tests.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
@@ -0,0 +1,179 @@
+package wordser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+)
+
+const (
+	EndpointSummary   = "summary"
+	EndpointSentiment = "sentiment"
+	EndpointExtract   = "extract"
+	EndpointSynonyms  = "synonyms"
+)
+
+// maxBodyBytes -> largest response body read. wordser's answers are a few kilobytes; anything past this is
+// taken for a broken response rather than read into memory.
+const maxBodyBytes = 1 << 20
+
+type SummaryResp struct {
+	Summary string `json:"summary"`
+}
+
+type SentimentResp struct {
+	Polarity string  `json:"polarity"`
+	Score    float64 `json:"score"`
+}
+
+type Keyword struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+type ExtractResp struct {
+	Keywords []Keyword `json:"keywords"`
+}
+
+type SynonymsResp struct {
+	// the rust service spells it synonymns.
+	Synonyms []string `json:"synonymns"`
+}
+
+// Client -> typed client for the wordser rust service. safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	timeout    time.Duration
+	http       HTTPDoer
+	resilience *resilience.Group
+}
+
+// Endpoints -> every wordser endpoint, for resilience.New.
+var Endpoints = []string{EndpointSummary, EndpointSentiment, EndpointExtract, EndpointSynonyms}
+
+// New -> every call goes through group, which retries, breaks and limits concurrency per endpoint.
+func New(config Config, group *resilience.Group) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(config.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid wordser base url: %w", err)
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid wordser base url: %s", config.BaseURL)
+	}
+
+	// one keep-alive transport for every call instead of http.DefaultClient.
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        config.MaxIdleConnsPerHost,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &Client{
+		baseURL:    baseURL,
+		timeout:    config.Timeout,
+		http:       &http.Client{Transport: transport},
+		resilience: group,
+	}, nil
+}
+
+func (c *Client) Summary(ctx context.Context, txt string) (*SummaryResp, error) {
+	resp := &SummaryResp{}
+	if err := c.get(ctx, EndpointSummary, url.Values{"txt": {txt}}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Sentiment(ctx context.Context, txt string) (*SentimentResp, error) {
+	resp := &SentimentResp{}
+	if err := c.get(ctx, EndpointSentiment, url.Values{"txt": {txt}}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Extract(ctx context.Context, txt string) (*ExtractResp, error) {
+	resp := &ExtractResp{}
+	if err := c.get(ctx, EndpointExtract, url.Values{"txt": {txt}}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Synonyms(ctx context.Context, word string) (*SynonymsResp, error) {
+	resp := &SynonymsResp{}
+	if err := c.get(ctx, EndpointSynonyms, url.Values{"word": {word}}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// get -> GET /api/v1/<endpoint> and decode the json body into out. the GETs are idempotent so failed
+// attempts are retried.
+func (c *Client) get(ctx context.Context, endpoint string, query url.Values, out any) error {
+	u := c.baseURL.JoinPath("api", "v1", endpoint)
+	u.RawQuery = query.Encode()
+
+	return c.resilience.Do(ctx, endpoint, true, func(ctx context.Context) error {
+		err := c.attempt(ctx, endpoint, u.String(), out)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 &&
+			statusErr.StatusCode != http.StatusTooManyRequests {
+			return resilience.Permanent(err)
+		}
+		return err
+	})
+}
+
+// attempt -> a single GET bounded by the per call timeout.
+func (c *Client) attempt(ctx context.Context, endpoint string, u string, out any) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return &NetworkError{Endpoint: endpoint, Err: err}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &NetworkError{Endpoint: endpoint, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// drain so the connection goes back to the pool; a huge error page is cut off and the connection closed.
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+		return &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes+1))
+	if err != nil {
+		return &NetworkError{Endpoint: endpoint, Err: err}
+	}
+	if len(data) > maxBodyBytes {
+		return &DecodeError{Endpoint: endpoint, Err: fmt.Errorf("response body over %d bytes", maxBodyBytes)}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return &DecodeError{Endpoint: endpoint, Err: err}
+	}
+	return nil
+}
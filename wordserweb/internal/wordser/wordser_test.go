@@ -0,0 +1,223 @@
+package wordser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nolandseigler/wordser/wordserweb/internal/resilience"
+)
+
+// newTestClient -> a Client of baseURL with 3 attempts a call, no backoff and a breaker that doesn't open.
+func newTestClient(t *testing.T, baseURL string, timeout time.Duration) *Client {
+	t.Helper()
+	group := resilience.New("wordser-test", resilience.Config{
+		RetryMaxAttempts:        3,
+		BreakerFailureThreshold: 100,
+		BreakerOpenTimeout:      time.Minute,
+		BreakerHalfOpenProbes:   1,
+		BulkheadMaxConcurrent:   4,
+		BulkheadMaxWait:         time.Second,
+	}, Endpoints...)
+	c, err := New(Config{BaseURL: baseURL, Timeout: timeout, MaxIdleConnsPerHost: 2}, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// fakeDoer -> an HTTPDoer answering every request with status and body, or failing with err.
+type fakeDoer struct {
+	status int
+	body   io.Reader
+	err    error
+	calls  atomic.Int32
+}
+
+func (d *fakeDoer) Do(req *http.Request) (*http.Response, error) {
+	d.calls.Add(1)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &http.Response{StatusCode: d.status, Body: io.NopCloser(d.body), Request: req}, nil
+}
+
+// errReader -> a body that breaks off after its first read.
+type errReader struct {
+	read bool
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.read = true
+	return copy(p, `{"summ`), nil
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "wordser:8080", "http://", "://bad"} {
+		if _, err := New(Config{BaseURL: baseURL}, nil); err == nil {
+			t.Errorf("New(%q) = nil error, want an invalid base url", baseURL)
+		}
+	}
+}
+
+func TestClientEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			http.Error(w, "bad accept", http.StatusNotAcceptable)
+			return
+		}
+		switch r.URL.Path {
+		case "/base/api/v1/summary":
+			w.Write([]byte(`{"summary":"` + r.URL.Query().Get("txt") + `"}`))
+		case "/base/api/v1/sentiment":
+			w.Write([]byte(`{"polarity":"Positive","score":0.75}`))
+		case "/base/api/v1/extract":
+			w.Write([]byte(`{"keywords":[{"text":"go","score":1.5}]}`))
+		case "/base/api/v1/synonyms":
+			w.Write([]byte(`{"synonymns":["fast","` + r.URL.Query().Get("word") + `"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL+"/base/", time.Second)
+	ctx := context.Background()
+
+	summary, err := c.Summary(ctx, "a & b")
+	if err != nil || summary.Summary != "a & b" {
+		t.Errorf("Summary() = %+v, %v; want the text back", summary, err)
+	}
+	sentiment, err := c.Sentiment(ctx, "txt")
+	if err != nil || *sentiment != (SentimentResp{Polarity: "Positive", Score: 0.75}) {
+		t.Errorf("Sentiment() = %+v, %v", sentiment, err)
+	}
+	extract, err := c.Extract(ctx, "txt")
+	if err != nil || len(extract.Keywords) != 1 || extract.Keywords[0] != (Keyword{Text: "go", Score: 1.5}) {
+		t.Errorf("Extract() = %+v, %v", extract, err)
+	}
+	synonyms, err := c.Synonyms(ctx, "quick")
+	if err != nil || strings.Join(synonyms.Synonyms, ",") != "fast,quick" {
+		t.Errorf("Synonyms() = %+v, %v", synonyms, err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		delay   time.Duration
+		wantErr any
+		// wantCalls -> attempts made: 1 when the error is permanent, 3 when it was retried.
+		wantCalls int32
+	}{
+		{name: "bad request", status: http.StatusBadRequest, wantErr: &StatusError{}, wantCalls: 1},
+		{name: "not found", status: http.StatusNotFound, wantErr: &StatusError{}, wantCalls: 1},
+		{name: "unprocessable", status: http.StatusUnprocessableEntity, wantErr: &StatusError{}, wantCalls: 1},
+		{name: "too many requests", status: http.StatusTooManyRequests, wantErr: &StatusError{}, wantCalls: 3},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: &StatusError{}, wantCalls: 3},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: &StatusError{}, wantCalls: 3},
+		{name: "not modified", status: http.StatusNotModified, wantErr: &StatusError{}, wantCalls: 3},
+		{name: "invalid json", status: http.StatusOK, body: `{"summary":`, wantErr: &DecodeError{}, wantCalls: 3},
+		{
+			name:      "body too large",
+			status:    http.StatusOK,
+			body:      `{"summary":"` + strings.Repeat("a", maxBodyBytes) + `"}`,
+			wantErr:   &DecodeError{},
+			wantCalls: 3,
+		},
+		{name: "timeout", status: http.StatusOK, body: `{}`, delay: time.Second, wantErr: &NetworkError{}, wantCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if tt.delay > 0 {
+					select {
+					case <-time.After(tt.delay):
+					case <-r.Context().Done():
+						return
+					}
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			c := newTestClient(t, srv.URL, 50*time.Millisecond)
+			_, err := c.Summary(context.Background(), "txt")
+			checkErr(t, err, tt.wantErr)
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) && (statusErr.StatusCode != tt.status || statusErr.Endpoint != EndpointSummary) {
+				t.Errorf("Summary() error = %+v, want status %d of %s", statusErr, tt.status, EndpointSummary)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("Summary() made %d attempts, want %d", calls.Load(), tt.wantCalls)
+			}
+			// a permanent error is the caller's fault and doesn't count against the breaker.
+			wantFailures := 0
+			if tt.wantCalls > 1 {
+				wantFailures = int(tt.wantCalls)
+			}
+			for _, status := range c.resilience.Status() {
+				if status.Endpoint == EndpointSummary && status.ConsecutiveFailures != wantFailures {
+					t.Errorf("breaker counted %d failures, want %d", status.ConsecutiveFailures, wantFailures)
+				}
+			}
+		})
+	}
+}
+
+func TestClientNetworkErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	_, err := newTestClient(t, srv.URL, time.Second).Sentiment(context.Background(), "txt")
+	checkErr(t, err, &NetworkError{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doer := &fakeDoer{err: errors.New("never sent")}
+	c := newTestClient(t, "http://wordser", time.Second)
+	c.http = doer
+	if _, err := c.Sentiment(ctx, "txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sentiment() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+
+	doer = &fakeDoer{status: http.StatusOK, body: &errReader{}}
+	c.http = doer
+	_, err = c.Summary(context.Background(), "txt")
+	checkErr(t, err, &NetworkError{})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Summary() error = %v, want it to wrap %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// checkErr -> err is of want's type.
+func checkErr(t *testing.T, err error, want any) {
+	t.Helper()
+	var ok bool
+	switch want.(type) {
+	case *StatusError:
+		var target *StatusError
+		ok = errors.As(err, &target)
+	case *DecodeError:
+		var target *DecodeError
+		ok = errors.As(err, &target)
+	case *NetworkError:
+		var target *NetworkError
+		ok = errors.As(err, &target)
+	}
+	if !ok {
+		t.Fatalf("error = %v (%T), want a %T", err, err, want)
+	}
+}